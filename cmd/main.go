@@ -1,7 +1,10 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"log"
+	"os"
 
 	"compress/internal/domain/entities"
 	"compress/internal/domain/repositories"
@@ -14,6 +17,14 @@ import (
 )
 
 func main() {
+	analyzePath := flag.String("analyze", "", "вывести JSON-отчет о структуре размера PDF файла и завершить работу")
+	flag.Parse()
+
+	// Анализ одного файла без запуска TUI
+	if *analyzePath != "" {
+		os.Exit(runAnalysis(*analyzePath))
+	}
+
 	// Загрузка конфигурации
 	configRepo := config.NewRepository()
 	appConfig, err := configRepo.Load("config.yaml")
@@ -47,36 +58,37 @@ func main() {
 	fileRepo := infraRepos.NewFileSystemRepository()
 	compressionConfigRepo := infraRepos.NewConfigRepository()
 
-	// Выбираем компрессор на основе конфигурации
-	var compressor repositories.PDFCompressor
-	switch appConfig.Compression.Algorithm {
-	case "unipdf":
-		compressor = compressors.NewUniPDFCompressor()
-	default:
-		compressor = compressors.NewPDFCPUCompressor()
-	}
-
 	// Инициализация компрессора изображений
-	imageCompressor := compressors.NewImageCompressor()
+	imageCompressor := compressors.NewImageCompressor(appConfig.Compression.CWebPPath, appConfig.Processing.GetTimeout())
 
 	// Инициализация use cases
 	processUseCase := usecases.NewProcessPDFsUseCase(
-		compressor,
+		newPDFCompressor(appConfig, fileRepo, logger),
 		fileRepo,
 		compressionConfigRepo,
 		logger,
 	)
+	// Алгоритм, стратегии и пароли могут быть изменены в TUI, поэтому компрессор
+	// собирается заново для каждого запуска
+	processUseCase.SetCompressorFactory(func(config *entities.Config) repositories.PDFCompressor {
+		return newPDFCompressor(config, fileRepo, logger)
+	})
+	processUseCase.SetRevisionCompressor(compressors.NewRevisionCompressor(fileRepo))
 
 	imageUseCase := usecases.NewCompressImageUseCase(logger, imageCompressor)
 
 	// Создаем объединенный процессор для всех типов файлов
 	allFilesUseCase := usecases.NewProcessAllFilesUseCase(processUseCase, imageUseCase, logger)
 
-	// Подключаем репортер прогресса к TUI
-	processUseCase.SetProgressReporter(func(s entities.ProcessingStatus) {
+	// Подключаем репортер прогресса PDF и изображений к TUI
+	allFilesUseCase.SetProgressReporter(func(s entities.ProcessingStatus) {
 		tuiManager.SendStatusUpdate(s)
 	})
 
+	// Подключаем анализ PDF к TUI
+	analyzeUseCase := usecases.NewAnalyzePDFUseCase(compressors.NewPDFAnalyzer(), fileRepo)
+	tuiManager.SetAnalyzer(analyzeUseCase.Execute, analyzeUseCase.ListFiles)
+
 	// Создание процессора для обработки команд
 	processor := NewApplicationProcessor(
 		processUseCase,
@@ -107,3 +119,68 @@ func main() {
 	// Cleanup при выходе
 	tuiManager.Cleanup()
 }
+
+// runAnalysis печатает JSON-отчет анализа PDF в stdout и возвращает код завершения
+func runAnalysis(path string) int {
+	analyzeUseCase := usecases.NewAnalyzePDFUseCase(
+		compressors.NewPDFAnalyzer(),
+		infraRepos.NewFileSystemRepository(),
+	)
+
+	analysis, err := analyzeUseCase.Execute(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Ошибка анализа %s: %v\n", path, err)
+		return 1
+	}
+
+	if err := analyzeUseCase.WriteReport(os.Stdout, analysis); err != nil {
+		fmt.Fprintf(os.Stderr, "Ошибка записи отчета: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// newPDFCompressor собирает цепочку компрессоров PDF по конфигурации: движок или подбор
+// лучшей стратегии, обработка зашифрованных документов и линеаризация результата
+func newPDFCompressor(appConfig *entities.Config, fileRepo repositories.FileRepository, logger repositories.Logger) repositories.PDFCompressor {
+	var compressor repositories.PDFCompressor
+	switch appConfig.Compression.Algorithm {
+	case "best":
+		var strategies []compressors.BestStrategy
+		for _, strategy := range appConfig.Compression.GetBestStrategies() {
+			strategies = append(strategies, compressors.BestStrategy{
+				Config:     strategy,
+				Compressor: newEngineCompressor(strategy.Algorithm, appConfig, logger),
+			})
+		}
+		compressor = compressors.NewBestCompressor(strategies, fileRepo)
+	default:
+		compressor = newEngineCompressor(appConfig.Compression.Algorithm, appConfig, logger)
+	}
+
+	// Зашифрованные PDF расшифровываются подобранным паролем и шифруются повторно
+	compressor = compressors.NewEncryptedPDFCompressor(
+		compressor,
+		infraRepos.NewPasswordRepository(appConfig.Compression.Passwords),
+		fileRepo,
+	)
+
+	// Линеаризация выполняется последней, над итоговым файлом
+	return compressors.NewLinearizingCompressor(
+		compressor,
+		compressors.NewQPDFLinearizer(appConfig.Compression.QPDFPath, appConfig.Processing.GetTimeout()),
+		logger,
+	)
+}
+
+// newEngineCompressor создает компрессор одного движка по имени алгоритма
+func newEngineCompressor(algorithm string, appConfig *entities.Config, logger repositories.Logger) repositories.PDFCompressor {
+	switch algorithm {
+	case "unipdf":
+		return compressors.NewUniPDFCompressor()
+	case "external":
+		command := appConfig.Compression.ExternalCommand
+		return compressors.NewExternalCompressor(command, command.GetTimeout(appConfig.Processing.TimeoutSeconds), logger)
+	}
+	return compressors.NewPDFCPUCompressor()
+}
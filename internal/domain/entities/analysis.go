@@ -0,0 +1,120 @@
+package entities
+
+import "sort"
+
+// Категории байтов PDF для анализа размера
+const (
+	CategoryImages        = "images"
+	CategoryFonts         = "fonts"
+	CategoryContent       = "content"
+	CategoryEmbeddedFiles = "embedded_files"
+	CategoryMetadata      = "metadata"
+	CategoryThumbnails    = "thumbnails"
+	CategoryUnused        = "unused"
+	CategoryStructure     = "structure" // Дерево страниц, аннотации, xref и прочая служебная часть
+)
+
+// Оптимизации шрифта, которые выполняет этап оптимизации шрифтов
+const (
+	FontOptimizationUnused    = "unused"    // Шрифт не используется ни на одной странице и удаляется
+	FontOptimizationDuplicate = "duplicate" // Программа шрифта совпадает с другой и объединяется с ней
+	FontOptimizationSubset    = "subset"    // Во встроенном TrueType остаются только используемые глифы
+)
+
+// PDFAnalysis разбивка размера PDF по категориям («куда ушли байты»).
+// Размеры объектов оцениваются по длине потоков в файле и записи словарей,
+// остаток до размера файла относится к служебной части
+type PDFAnalysis struct {
+	Path       string             `json:"path"`
+	FileSize   int64              `json:"file_size"`
+	Pages      int                `json:"pages"`
+	Categories []AnalysisCategory `json:"categories"`
+	Images     []ImageAnalysis    `json:"images"`
+	Fonts      []FontAnalysis     `json:"fonts"`
+	// Суммарная экономия от оптимизации шрифтов
+	FontSavings int64 `json:"font_savings"`
+}
+
+// AnalysisCategory объем одной категории
+type AnalysisCategory struct {
+	Name    string  `json:"name"`
+	Bytes   int64   `json:"bytes"`
+	Objects int     `json:"objects"`
+	Percent float64 `json:"percent"`
+}
+
+// ImageAnalysis сведения об изображении документа
+type ImageAnalysis struct {
+	ObjectNumber     int     `json:"object_number"`
+	Width            int     `json:"width"`
+	Height           int     `json:"height"`
+	PPI              float64 `json:"ppi"` // Эффективное разрешение на странице, 0 — изображение не выводится напрямую
+	Filter           string  `json:"filter"`
+	ColorSpace       string  `json:"color_space"`
+	BitsPerComponent int     `json:"bits_per_component"`
+	Bytes            int64   `json:"bytes"`
+}
+
+// FontAnalysis сведения о шрифте документа
+type FontAnalysis struct {
+	ObjectNumber int    `json:"object_number"`
+	Name         string `json:"name"`
+	Subtype      string `json:"subtype"`
+	Embedded     bool   `json:"embedded"`
+	Subset       bool   `json:"subset"`
+	Bytes        int64  `json:"bytes"` // Размер встроенной программы шрифта
+	// Оптимизации шрифта и экономия от них в байтах
+	Optimizations []string `json:"optimizations,omitempty"`
+	Savings       int64    `json:"savings"`
+}
+
+// AddObject учитывает объект указанного размера в категории
+func (a *PDFAnalysis) AddObject(category string, bytes int64) {
+	for i := range a.Categories {
+		if a.Categories[i].Name == category {
+			a.Categories[i].Bytes += bytes
+			a.Categories[i].Objects++
+			return
+		}
+	}
+	a.Categories = append(a.Categories, AnalysisCategory{Name: category, Bytes: bytes, Objects: 1})
+}
+
+// Finalize относит неучтенный остаток файла к служебной части, считает доли категорий
+// и экономию на шрифтах и сортирует категории, изображения и шрифты по убыванию размера
+func (a *PDFAnalysis) Finalize() {
+	a.FontSavings = 0
+	for _, font := range a.Fonts {
+		a.FontSavings += font.Savings
+	}
+
+	var counted int64
+	for _, category := range a.Categories {
+		counted += category.Bytes
+	}
+	if rest := a.FileSize - counted; rest > 0 {
+		for i := range a.Categories {
+			if a.Categories[i].Name == CategoryStructure {
+				a.Categories[i].Bytes += rest
+				rest = 0
+			}
+		}
+		if rest > 0 {
+			a.Categories = append(a.Categories, AnalysisCategory{Name: CategoryStructure, Bytes: rest})
+		}
+	}
+
+	var total int64
+	for _, category := range a.Categories {
+		total += category.Bytes
+	}
+	for i := range a.Categories {
+		if total > 0 {
+			a.Categories[i].Percent = float64(a.Categories[i].Bytes) / float64(total) * 100
+		}
+	}
+
+	sort.SliceStable(a.Categories, func(i, j int) bool { return a.Categories[i].Bytes > a.Categories[j].Bytes })
+	sort.SliceStable(a.Images, func(i, j int) bool { return a.Images[i].Bytes > a.Images[j].Bytes })
+	sort.SliceStable(a.Fonts, func(i, j int) bool { return a.Fonts[i].Bytes > a.Fonts[j].Bytes })
+}
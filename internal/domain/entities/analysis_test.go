@@ -0,0 +1,82 @@
+package entities_test
+
+import (
+	"testing"
+
+	"compress/internal/domain/entities"
+)
+
+func TestPDFAnalysis_Finalize(t *testing.T) {
+	tests := []struct {
+		name     string
+		fileSize int64
+		objects  map[string][]int64
+		expected []entities.AnalysisCategory
+	}{
+		{
+			name:     "Remainder goes to structure",
+			fileSize: 1000,
+			objects: map[string][]int64{
+				entities.CategoryImages: {500, 100},
+				entities.CategoryFonts:  {200},
+			},
+			expected: []entities.AnalysisCategory{
+				{Name: entities.CategoryImages, Bytes: 600, Objects: 2, Percent: 60},
+				{Name: entities.CategoryFonts, Bytes: 200, Objects: 1, Percent: 20},
+				{Name: entities.CategoryStructure, Bytes: 200, Objects: 0, Percent: 20},
+			},
+		},
+		{
+			name:     "Remainder is added to existing structure",
+			fileSize: 1000,
+			objects: map[string][]int64{
+				entities.CategoryContent:   {700},
+				entities.CategoryStructure: {100},
+			},
+			expected: []entities.AnalysisCategory{
+				{Name: entities.CategoryContent, Bytes: 700, Objects: 1, Percent: 70},
+				{Name: entities.CategoryStructure, Bytes: 300, Objects: 1, Percent: 30},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analysis := &entities.PDFAnalysis{FileSize: tt.fileSize}
+			for category, sizes := range tt.objects {
+				for _, size := range sizes {
+					analysis.AddObject(category, size)
+				}
+			}
+			analysis.Finalize()
+
+			if len(analysis.Categories) != len(tt.expected) {
+				t.Fatalf("Expected %d categories, got %+v", len(tt.expected), analysis.Categories)
+			}
+			for i, expected := range tt.expected {
+				if analysis.Categories[i] != expected {
+					t.Errorf("Category %d: expected %+v, got %+v", i, expected, analysis.Categories[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPDFAnalysis_FinalizeFontSavings(t *testing.T) {
+	analysis := &entities.PDFAnalysis{
+		FileSize: 1000,
+		Fonts: []entities.FontAnalysis{
+			{Name: "Arial", Bytes: 300, Savings: 250, Optimizations: []string{entities.FontOptimizationSubset}},
+			{Name: "Unused", Bytes: 400, Savings: 400, Optimizations: []string{entities.FontOptimizationUnused}},
+			{Name: "Symbol", Bytes: 100},
+		},
+	}
+	analysis.Finalize()
+
+	if analysis.FontSavings != 650 {
+		t.Errorf("Expected font savings 650, got %d", analysis.FontSavings)
+	}
+	if analysis.Fonts[0].Name != "Unused" {
+		t.Errorf("Expected fonts sorted by size, got %q first", analysis.Fonts[0].Name)
+	}
+}
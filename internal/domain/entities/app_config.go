@@ -1,6 +1,12 @@
 package entities
 
-import "time"
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
 
 // Config представляет конфигурацию приложения
 type Config struct {
@@ -26,8 +32,512 @@ type AppCompressionConfig struct {
 	// Настройки сжатия изображений
 	EnableJPEG  bool `yaml:"enable_jpeg"`
 	EnablePNG   bool `yaml:"enable_png"`
-	JPEGQuality int  `yaml:"jpeg_quality"` // Качество JPEG в процентах (10-50)
-	PNGQuality  int  `yaml:"png_quality"`  // Качество PNG в процентах (10-50)
+	JPEGQuality int  `yaml:"jpeg_quality"` // Качество JPEG в процентах (1-100)
+	PNGQuality  int  `yaml:"png_quality"`  // Качество PNG в процентах (1-100)
+	// Минимальная экономия, при которой сжатый файл принимается (для всех типов файлов).
+	// Файл, который уменьшился меньше порога или вырос, остается без изменений.
+	MinSavingsPercent float64 `yaml:"min_savings_percent"` // 0 — достаточно любого уменьшения
+	MinSavingsBytes   int64   `yaml:"min_savings_bytes"`   // 0 — без порога в байтах
+	// Пароли для зашифрованных PDF
+	Passwords PasswordConfig `yaml:"passwords"`
+	// Политика для документов с цифровыми подписями: skip, warn или incremental
+	SignaturePolicy string `yaml:"signature_policy"`
+	// Сохранять соответствие PDF/A документов, заявивших его в XMP метаданных
+	PreservePDFA bool `yaml:"preserve_pdfa"`
+	// Стратегии алгоритма best; пусто — pdfcpu и unipdf с основным уровнем
+	BestStrategies []StrategyConfig `yaml:"best_strategies"`
+	// Целевой размер PDF в мегабайтах: уровень подбирается под него; 0 — режим выключен
+	TargetSizeMB float64 `yaml:"target_size_mb"`
+	// Профили с настройками для групп файлов
+	Profiles []ProfileConfig `yaml:"profiles"`
+	// Строгость проверки сжатого PDF перед тем, как он будет принят: strict или relaxed
+	OutputValidation string `yaml:"output_validation"`
+	// Политика метаданных сжатых PDF
+	Metadata MetadataConfig `yaml:"metadata"`
+	// Подтипы аннотаций, сохраняемые при удалении аннотаций; не задано — Link и Widget
+	KeepAnnotations []string `yaml:"keep_annotations"`
+	// Линеаризация результата (быстрый веб-просмотр) внешней утилитой qpdf
+	OptimizeForWeb bool   `yaml:"optimize_for_web"`
+	QPDFPath       string `yaml:"qpdf_path"` // Путь к qpdf; пусто — поиск в PATH
+	// Уменьшение цветности изображений в PDF и отдельных файлах: auto, off, gray или bitonal
+	ColorReduction string `yaml:"color_reduction"`
+	// Внешняя программа сжатия для алгоритма external и стратегий best
+	ExternalCommand ExternalCommandConfig `yaml:"external_command"`
+	// Параметры оптимизатора UniPDF; не заданные вычисляются по уровню сжатия
+	UniPDF UniPDFOptionsConfig `yaml:"unipdf"`
+	// Удаление редко нужных данных PDF; не заданные флаги вычисляются по уровню сжатия
+	Cleanup CleanupConfig `yaml:"cleanup"`
+	// Смена формата отдельных изображений: off, jpeg или webp
+	ImageConversion string `yaml:"image_conversion"`
+	CWebPPath       string `yaml:"cwebp_path"` // Путь к cwebp для WebP; пусто — поиск в PATH
+	// Палитра PNG: рассеивание ошибки (дизеринг) и прежнее уменьшение разрешения по качеству
+	PNGDithering bool `yaml:"png_dithering"`
+	PNGResize    bool `yaml:"png_resize"`
+	// Метаданные отдельных изображений: strip_all, keep_orientation_icc или keep_all
+	ImageMetadata string `yaml:"image_metadata"`
+	// Вписывание отдельных изображений в рамку вместо уменьшения по качеству
+	ImageResize ImageResizeConfig `yaml:"image_resize"`
+}
+
+// ProfileConfig профиль сжатия для файлов, путь которых совпадает с одним из шаблонов.
+// Нулевые значения полей означают «как в основной конфигурации»
+type ProfileConfig struct {
+	Name         string   `yaml:"name"`
+	Patterns     []string `yaml:"patterns"`       // Шаблоны пути относительно исходной директории или имени файла
+	Level        int      `yaml:"level"`          // Уровень сжатия
+	TargetSizeMB float64  `yaml:"target_size_mb"` // Целевой размер в мегабайтах
+	// Линеаризация результата; не задано — как в основной конфигурации
+	OptimizeForWeb *bool `yaml:"optimize_for_web"`
+	// Уменьшение цветности изображений; пусто — как в основной конфигурации
+	ColorReduction string `yaml:"color_reduction"`
+	// Параметры оптимизатора UniPDF; заданные поля заменяют поля основной конфигурации
+	UniPDF UniPDFOptionsConfig `yaml:"unipdf"`
+	// Удаление редко нужных данных; заданные флаги заменяют флаги основной конфигурации
+	Cleanup CleanupConfig `yaml:"cleanup"`
+	// Смена формата изображений; пусто — как в основной конфигурации
+	ImageConversion string `yaml:"image_conversion"`
+	// Метаданные изображений; пусто — как в основной конфигурации
+	ImageMetadata string `yaml:"image_metadata"`
+	// Качество изображений; 0 — как в основной конфигурации
+	JPEGQuality int `yaml:"jpeg_quality"`
+	PNGQuality  int `yaml:"png_quality"`
+	// Рамка изображений; заданные поля заменяют поля основной конфигурации
+	ImageResize ImageResizeConfig `yaml:"image_resize"`
+}
+
+// Matches проверяет, подходит ли профиль файлу с указанным относительным путем
+func (p *ProfileConfig) Matches(relPath string) bool {
+	relPath = filepath.ToSlash(relPath)
+	for _, pattern := range p.Patterns {
+		if ok, _ := path.Match(pattern, relPath); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, path.Base(relPath)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ForFile возвращает настройки сжатия для файла с учетом первого подходящего профиля
+// и имя этого профиля (пустое, если профиль не найден)
+func (c *AppCompressionConfig) ForFile(relPath string) (*AppCompressionConfig, string) {
+	fileConfig := *c
+	for i := range c.Profiles {
+		profile := &c.Profiles[i]
+		if !profile.Matches(relPath) {
+			continue
+		}
+
+		if profile.Level != 0 {
+			fileConfig.Level = profile.Level
+		}
+		if profile.TargetSizeMB != 0 {
+			fileConfig.TargetSizeMB = profile.TargetSizeMB
+		}
+		if profile.OptimizeForWeb != nil {
+			fileConfig.OptimizeForWeb = *profile.OptimizeForWeb
+		}
+		if profile.ColorReduction != "" {
+			fileConfig.ColorReduction = profile.ColorReduction
+		}
+		if profile.ImageConversion != "" {
+			fileConfig.ImageConversion = profile.ImageConversion
+		}
+		if profile.ImageMetadata != "" {
+			fileConfig.ImageMetadata = profile.ImageMetadata
+		}
+		if profile.JPEGQuality != 0 {
+			fileConfig.JPEGQuality = profile.JPEGQuality
+		}
+		if profile.PNGQuality != 0 {
+			fileConfig.PNGQuality = profile.PNGQuality
+		}
+		fileConfig.ImageResize = c.ImageResize.Merge(profile.ImageResize)
+		fileConfig.UniPDF = c.UniPDF.Merge(profile.UniPDF)
+		fileConfig.Cleanup = c.Cleanup.Merge(profile.Cleanup)
+		return &fileConfig, profile.Name
+	}
+	return &fileConfig, ""
+}
+
+// TargetSizeBytes возвращает целевой размер в байтах (0 — режим выключен)
+func (c *AppCompressionConfig) TargetSizeBytes() int64 {
+	return int64(c.TargetSizeMB * 1024 * 1024)
+}
+
+// StrategyConfig вариант сжатия, который пробует алгоритм best
+type StrategyConfig struct {
+	Algorithm string `yaml:"algorithm"` // pdfcpu, unipdf или external
+	Level     int    `yaml:"level"`     // 0 — основной уровень сжатия
+}
+
+// GetBestStrategies возвращает стратегии алгоритма best; по умолчанию pdfcpu и unipdf с основным уровнем
+func (c *AppCompressionConfig) GetBestStrategies() []StrategyConfig {
+	if len(c.BestStrategies) > 0 {
+		return c.BestStrategies
+	}
+	return []StrategyConfig{
+		{Algorithm: "pdfcpu"},
+		{Algorithm: "unipdf"},
+	}
+}
+
+// UsesImageQuality проверяет, учитывает ли выбранный алгоритм качество изображений
+// CompressionConfig.ImageQuality. UniPDF берет качество из своих параметров, внешняя
+// команда получает только уровень, а best учитывает качество через стратегию pdfcpu
+// (при подборе целевого размера ее собственный уровень не применяется)
+func (c *AppCompressionConfig) UsesImageQuality() bool {
+	switch c.Algorithm {
+	case "unipdf", "external":
+		return false
+	case "best":
+		for _, strategy := range c.GetBestStrategies() {
+			if strategy.Algorithm == "pdfcpu" {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// Name возвращает имя стратегии для журнала и результата, например unipdf@70
+func (s StrategyConfig) Name(defaultLevel int) string {
+	level := s.Level
+	if level == 0 {
+		level = defaultLevel
+	}
+	return fmt.Sprintf("%s@%d", s.Algorithm, level)
+}
+
+// Подстановки в аргументах внешней команды сжатия
+const (
+	ExternalPlaceholderInput  = "{input}"  // Путь к исходному PDF
+	ExternalPlaceholderOutput = "{output}" // Путь, по которому команда записывает результат
+	ExternalPlaceholderLevel  = "{level}"  // Уровень сжатия (10-90)
+)
+
+// defaultExternalTimeout таймаут внешней команды, если он не задан ни в команде, ни в processing
+const defaultExternalTimeout = 5 * time.Minute
+
+// ExternalCommandConfig внешняя программа сжатия PDF (собственные утилиты, qpdf, Ghostscript).
+// Команда запускается без оболочки: первый элемент — программа, остальные — аргументы
+type ExternalCommandConfig struct {
+	Command        []string `yaml:"command"`         // Например: [gs, -sDEVICE=pdfwrite, -o, "{output}", "{input}"]
+	TimeoutSeconds int      `yaml:"timeout_seconds"` // 0 — processing.timeout_seconds
+	SuccessCodes   []int    `yaml:"success_codes"`   // Коды завершения, означающие успех; не задано — только 0
+}
+
+// Args возвращает аргументы команды с подставленными путями и уровнем сжатия
+func (c ExternalCommandConfig) Args(inputPath, outputPath string, level int) []string {
+	replacer := strings.NewReplacer(
+		ExternalPlaceholderInput, inputPath,
+		ExternalPlaceholderOutput, outputPath,
+		ExternalPlaceholderLevel, fmt.Sprint(level),
+	)
+	args := make([]string, len(c.Command))
+	for i, arg := range c.Command {
+		args[i] = replacer.Replace(arg)
+	}
+	return args
+}
+
+// IsSuccess проверяет, означает ли код завершения успешное сжатие
+func (c ExternalCommandConfig) IsSuccess(exitCode int) bool {
+	if len(c.SuccessCodes) == 0 {
+		return exitCode == 0
+	}
+	for _, code := range c.SuccessCodes {
+		if code == exitCode {
+			return true
+		}
+	}
+	return false
+}
+
+// GetTimeout возвращает таймаут команды: собственный, общий таймаут обработки файла или 5 минут
+func (c ExternalCommandConfig) GetTimeout(processingTimeoutSeconds int) time.Duration {
+	switch {
+	case c.TimeoutSeconds > 0:
+		return time.Duration(c.TimeoutSeconds) * time.Second
+	case processingTimeoutSeconds > 0:
+		return time.Duration(processingTimeoutSeconds) * time.Second
+	}
+	return defaultExternalTimeout
+}
+
+// Validate проверяет, что команда задана и получает пути исходного файла и результата
+func (c ExternalCommandConfig) Validate() error {
+	if len(c.Command) == 0 || c.Command[0] == "" {
+		return fmt.Errorf("%w: команда не задана", ErrInvalidExternalCommand)
+	}
+	joined := strings.Join(c.Command, " ")
+	for _, placeholder := range []string{ExternalPlaceholderInput, ExternalPlaceholderOutput} {
+		if !strings.Contains(joined, placeholder) {
+			return fmt.Errorf("%w: нет подстановки %s", ErrInvalidExternalCommand, placeholder)
+		}
+	}
+	if c.TimeoutSeconds < 0 {
+		return fmt.Errorf("%w: отрицательный таймаут", ErrInvalidExternalCommand)
+	}
+	return nil
+}
+
+// UniPDFOptionsConfig параметры оптимизатора UniPDF (optimize.Options).
+// Не заданные поля (nil, 0) вычисляются по уровню сжатия
+type UniPDFOptionsConfig struct {
+	CombineDuplicateDirectObjects   *bool   `yaml:"combine_duplicate_direct_objects"`
+	CombineIdenticalIndirectObjects *bool   `yaml:"combine_identical_indirect_objects"`
+	CombineDuplicateStreams         *bool   `yaml:"combine_duplicate_streams"`
+	CompressStreams                 *bool   `yaml:"compress_streams"`   // Flate для несжатых потоков
+	UseObjectStreams                *bool   `yaml:"use_object_streams"` // Объектные потоки и xref-поток
+	CleanUnusedResources            *bool   `yaml:"clean_unused_resources"`
+	CleanContentstream              *bool   `yaml:"clean_contentstream"` // Удаление избыточных операторов в содержимом страниц
+	CleanFonts                      *bool   `yaml:"clean_fonts"`
+	SubsetFonts                     *bool   `yaml:"subset_fonts"`
+	ImageUpperPPI                   float64 `yaml:"image_upper_ppi"` // Максимальное PPI изображений
+	ImageQuality                    int     `yaml:"image_quality"`   // Качество JPEG изображений (1-100)
+}
+
+// Merge возвращает параметры, в которых поля, заданные в override, заменяют текущие
+func (c UniPDFOptionsConfig) Merge(override UniPDFOptionsConfig) UniPDFOptionsConfig {
+	merged := c
+	merged.CombineDuplicateDirectObjects = overrideValue(c.CombineDuplicateDirectObjects, override.CombineDuplicateDirectObjects)
+	merged.CombineIdenticalIndirectObjects = overrideValue(c.CombineIdenticalIndirectObjects, override.CombineIdenticalIndirectObjects)
+	merged.CombineDuplicateStreams = overrideValue(c.CombineDuplicateStreams, override.CombineDuplicateStreams)
+	merged.CompressStreams = overrideValue(c.CompressStreams, override.CompressStreams)
+	merged.UseObjectStreams = overrideValue(c.UseObjectStreams, override.UseObjectStreams)
+	merged.CleanUnusedResources = overrideValue(c.CleanUnusedResources, override.CleanUnusedResources)
+	merged.CleanContentstream = overrideValue(c.CleanContentstream, override.CleanContentstream)
+	merged.CleanFonts = overrideValue(c.CleanFonts, override.CleanFonts)
+	merged.SubsetFonts = overrideValue(c.SubsetFonts, override.SubsetFonts)
+	if override.ImageUpperPPI != 0 {
+		merged.ImageUpperPPI = override.ImageUpperPPI
+	}
+	if override.ImageQuality != 0 {
+		merged.ImageQuality = override.ImageQuality
+	}
+	return merged
+}
+
+// overrideValue возвращает переопределенное значение, если оно задано
+func overrideValue[T any](value, override *T) *T {
+	if override != nil {
+		return override
+	}
+	return value
+}
+
+// Validate проверяет диапазоны PPI и качества изображений
+func (c UniPDFOptionsConfig) Validate() error {
+	if c.ImageUpperPPI < 0 {
+		return fmt.Errorf("%w: image_upper_ppi должен быть неотрицательным", ErrInvalidUniPDFOptions)
+	}
+	if c.ImageQuality < 0 || c.ImageQuality > 100 {
+		return fmt.Errorf("%w: image_quality должен быть от 1 до 100", ErrInvalidUniPDFOptions)
+	}
+	return nil
+}
+
+// CleanupConfig удаление редко нужных данных PDF. Не заданные флаги (nil) вычисляются по уровню сжатия
+type CleanupConfig struct {
+	RemoveThumbnails         *bool `yaml:"remove_thumbnails"`
+	RemoveJavaScript         *bool `yaml:"remove_javascript"`
+	RemoveUnusedDestinations *bool `yaml:"remove_unused_destinations"`
+	RemovePieceInfo          *bool `yaml:"remove_piece_info"`
+	RemoveOldRevisions       *bool `yaml:"remove_old_revisions"` // Выключено — ревизии документа с добавочными обновлениями сжимаются по отдельности
+}
+
+// Merge возвращает флаги, в которых заданные в override заменяют текущие
+func (c CleanupConfig) Merge(override CleanupConfig) CleanupConfig {
+	return CleanupConfig{
+		RemoveThumbnails:         overrideValue(c.RemoveThumbnails, override.RemoveThumbnails),
+		RemoveJavaScript:         overrideValue(c.RemoveJavaScript, override.RemoveJavaScript),
+		RemoveUnusedDestinations: overrideValue(c.RemoveUnusedDestinations, override.RemoveUnusedDestinations),
+		RemovePieceInfo:          overrideValue(c.RemovePieceInfo, override.RemovePieceInfo),
+		RemoveOldRevisions:       overrideValue(c.RemoveOldRevisions, override.RemoveOldRevisions),
+	}
+}
+
+// ImageResizeConfig вписывание отдельных изображений в рамку. Незаданное ограничение в профиле
+// наследуется из основной конфигурации, а 0 выключает его; если действует хотя бы одно
+// ограничение, разрешение не уменьшается по качеству
+type ImageResizeConfig struct {
+	MaxWidth      *int     `yaml:"max_width"`      // Максимальная ширина в пикселях; 0 — без ограничения
+	MaxHeight     *int     `yaml:"max_height"`     // Максимальная высота в пикселях; 0 — без ограничения
+	MaxMegapixels *float64 `yaml:"max_megapixels"` // Максимальное число мегапикселей; 0 — без ограничения
+	Upscale       *bool    `yaml:"upscale"`        // Увеличивать изображения меньше рамки; не задано — нет
+	Filter        string   `yaml:"filter"`         // Фильтр интерполяции; пусто — lanczos3
+}
+
+// Enabled проверяет, действует ли хотя бы одно ограничение рамки
+func (c ImageResizeConfig) Enabled() bool {
+	return c.GetMaxWidth() > 0 || c.GetMaxHeight() > 0 || c.GetMaxMegapixels() > 0
+}
+
+// Merge возвращает рамку, в которой поля, заданные в override, заменяют текущие
+func (c ImageResizeConfig) Merge(override ImageResizeConfig) ImageResizeConfig {
+	merged := ImageResizeConfig{
+		MaxWidth:      overrideValue(c.MaxWidth, override.MaxWidth),
+		MaxHeight:     overrideValue(c.MaxHeight, override.MaxHeight),
+		MaxMegapixels: overrideValue(c.MaxMegapixels, override.MaxMegapixels),
+		Upscale:       overrideValue(c.Upscale, override.Upscale),
+		Filter:        c.Filter,
+	}
+	if override.Filter != "" {
+		merged.Filter = override.Filter
+	}
+	return merged
+}
+
+// GetMaxWidth возвращает максимальную ширину; 0 — без ограничения
+func (c ImageResizeConfig) GetMaxWidth() int {
+	if c.MaxWidth == nil {
+		return 0
+	}
+	return *c.MaxWidth
+}
+
+// GetMaxHeight возвращает максимальную высоту; 0 — без ограничения
+func (c ImageResizeConfig) GetMaxHeight() int {
+	if c.MaxHeight == nil {
+		return 0
+	}
+	return *c.MaxHeight
+}
+
+// GetMaxMegapixels возвращает максимальное число мегапикселей; 0 — без ограничения
+func (c ImageResizeConfig) GetMaxMegapixels() float64 {
+	if c.MaxMegapixels == nil {
+		return 0
+	}
+	return *c.MaxMegapixels
+}
+
+// GetUpscale возвращает, увеличиваются ли изображения меньше рамки; по умолчанию нет
+func (c ImageResizeConfig) GetUpscale() bool {
+	return c.Upscale != nil && *c.Upscale
+}
+
+// GetFilter возвращает фильтр интерполяции; по умолчанию Lanczos3
+func (c ImageResizeConfig) GetFilter() string {
+	if c.Filter == "" {
+		return ResizeFilterLanczos3
+	}
+	return c.Filter
+}
+
+// Validate проверяет ограничения рамки и фильтр интерполяции
+func (c ImageResizeConfig) Validate() error {
+	if c.GetMaxWidth() < 0 || c.GetMaxHeight() < 0 || c.GetMaxMegapixels() < 0 {
+		return fmt.Errorf("%w: ограничения должны быть неотрицательными", ErrInvalidImageResize)
+	}
+	switch c.Filter {
+	case "", ResizeFilterNearest, ResizeFilterBilinear, ResizeFilterBicubic,
+		ResizeFilterMitchell, ResizeFilterLanczos2, ResizeFilterLanczos3:
+	default:
+		return fmt.Errorf("%w: неизвестный фильтр %q", ErrInvalidImageResize, c.Filter)
+	}
+	return nil
+}
+
+// Фильтры интерполяции при изменении размера изображений
+const (
+	ResizeFilterNearest  = "nearest"  // Ближайший сосед: быстро, без сглаживания
+	ResizeFilterBilinear = "bilinear" // Билинейная интерполяция
+	ResizeFilterBicubic  = "bicubic"  // Бикубическая интерполяция
+	ResizeFilterMitchell = "mitchell" // Фильтр Митчелла-Нетравали: мягче бикубического, без ореолов
+	ResizeFilterLanczos2 = "lanczos2" // Ланцош с окном 2
+	ResizeFilterLanczos3 = "lanczos3" // Ланцош с окном 3: наиболее четкий
+)
+
+// Политики обработки подписанных PDF
+const (
+	// SignaturePolicySkip подписанный документ не сжимается
+	SignaturePolicySkip = "skip"
+	// SignaturePolicyWarn документ сжимается, подпись становится недействительной
+	SignaturePolicyWarn = "warn"
+	// SignaturePolicyIncremental подписанные ревизии копируются без изменений, сжимаются только
+	// дописанные после подписи обновления
+	SignaturePolicyIncremental = "incremental"
+)
+
+// Режимы проверки сжатых PDF
+const (
+	// OutputValidationStrict строгая валидация pdfcpu: отклоняются любые отступления от спецификации
+	OutputValidationStrict = "strict"
+	// OutputValidationRelaxed мягкая валидация pdfcpu: допускаются распространенные отступления
+	OutputValidationRelaxed = "relaxed"
+)
+
+// Режимы уменьшения цветности изображений
+const (
+	// ColorReductionAuto режим определяется уровнем сжатия
+	ColorReductionAuto = "auto"
+	// ColorReductionOff цветность изображений не меняется
+	ColorReductionOff = "off"
+	// ColorReductionGray почти серые изображения переводятся в оттенки серого
+	ColorReductionGray = "gray"
+	// ColorReductionBitonal дополнительно почти двухцветные изображения переводятся в 1 бит
+	ColorReductionBitonal = "bitonal"
+)
+
+// Режимы смены формата отдельных изображений
+const (
+	// ImageConversionOff изображения сохраняются в исходном формате
+	ImageConversionOff = "off"
+	// ImageConversionJPEG непрозрачные PNG фотографии могут быть сохранены в JPEG
+	ImageConversionJPEG = "jpeg"
+	// ImageConversionWebP дополнительно рассматривается WebP без потерь и с потерями
+	ImageConversionWebP = "webp"
+)
+
+// Политики метаданных отдельных изображений
+const (
+	// ImageMetadataStripAll метаданные удаляются, изображение поворачивается по EXIF Orientation
+	ImageMetadataStripAll = "strip_all"
+	// ImageMetadataKeepOrientationICC сохраняются ориентация и ICC профиль
+	ImageMetadataKeepOrientationICC = "keep_orientation_icc"
+	// ImageMetadataKeepAll сохраняются EXIF, ICC, XMP и прочие блоки метаданных
+	ImageMetadataKeepAll = "keep_all"
+)
+
+// PasswordConfig источники паролей для зашифрованных PDF
+type PasswordConfig struct {
+	List    []string `yaml:"list"`    // Пароли, перечисленные в конфигурации
+	File    string   `yaml:"file"`    // Файл с паролями (по одному на строку)
+	Sidecar string   `yaml:"sidecar"` // Имя файла с паролями в директории PDF (например, .pdfpasswords)
+}
+
+// MetadataConfig политика метаданных: какие ключи Info сохраняются при удалении метаданных
+// и добавляются ли служебные ключи сжатия
+type MetadataConfig struct {
+	Keep  []string `yaml:"keep"`  // Ключи Info, которые не удаляются; не задано — Title, Author, DocID
+	Stamp bool     `yaml:"stamp"` // Записывать в Info исходный размер, уровень и время сжатия
+}
+
+// DefaultMetadataKeep ключи Info, сохраняемые по умолчанию
+var DefaultMetadataKeep = []string{"Title", "Author", "DocID"}
+
+// GetKeep возвращает сохраняемые ключи Info; пустой список в конфигурации означает «удалять все»
+func (c MetadataConfig) GetKeep() []string {
+	if c.Keep == nil {
+		return DefaultMetadataKeep
+	}
+	return c.Keep
+}
+
+// DefaultKeepAnnotations подтипы аннотаций, сохраняемые по умолчанию:
+// ссылки нужны для навигации, а виджеты являются полями форм
+var DefaultKeepAnnotations = []string{"Link", "Widget"}
+
+// GetKeepAnnotations возвращает сохраняемые подтипы аннотаций; пустой список означает «удалять все»
+func (c *AppCompressionConfig) GetKeepAnnotations() []string {
+	if c.KeepAnnotations == nil {
+		return DefaultKeepAnnotations
+	}
+	return c.KeepAnnotations
 }
 
 // ProcessingConfig настройки обработки
@@ -37,6 +547,14 @@ type ProcessingConfig struct {
 	RetryAttempts   int `yaml:"retry_attempts"`
 }
 
+// GetTimeout возвращает таймаут обработки файла; если он не задан — 5 минут
+func (c ProcessingConfig) GetTimeout() time.Duration {
+	if c.TimeoutSeconds > 0 {
+		return time.Duration(c.TimeoutSeconds) * time.Second
+	}
+	return defaultExternalTimeout
+}
+
 // OutputConfig настройки вывода
 type OutputConfig struct {
 	LogLevel     string `yaml:"log_level"`
@@ -61,6 +579,10 @@ type ProcessingStatus struct {
 	SuccessfulFiles int
 	FailedFiles     int
 	SkippedFiles    int
+	// Пропущенные зашифрованные файлы (пароль не найден), входят в SkippedFiles
+	SkippedEncryptedFiles int
+	// Файлы, которые не удалось уложить в целевой размер, входят в SuccessfulFiles
+	TargetMissedFiles int
 
 	// Прогресс
 	Progress float64
@@ -70,6 +592,8 @@ type ProcessingStatus struct {
 	TotalCompressedSize int64
 	TotalSavedSpace     int64
 	AverageCompression  float64
+	// Статистика по типам файлов в порядке первого появления
+	TypeStats []FileTypeStats
 
 	// Текущий результат
 	LastResult *CompressionResult
@@ -87,6 +611,27 @@ type ProcessingStatus struct {
 	Message string
 }
 
+// FileTypeStats статистика обработки файлов одного типа. Размеры учитываются,
+// как и в общей статистике, только для успешно сжатых файлов
+type FileTypeStats struct {
+	Type            FileType
+	Files           int
+	SuccessfulFiles int
+	SkippedFiles    int
+	FailedFiles     int
+	OriginalSize    int64
+	CompressedSize  int64
+	SavedSpace      int64
+}
+
+// Compression возвращает сжатие файлов типа в процентах
+func (s FileTypeStats) Compression() float64 {
+	if s.OriginalSize == 0 {
+		return 0
+	}
+	return float64(s.SavedSpace) / float64(s.OriginalSize) * 100
+}
+
 // ProcessingPhase фаза обработки
 type ProcessingPhase int
 
@@ -106,6 +651,7 @@ const (
 	UIScreenMenu UIScreen = iota
 	UIScreenConfig
 	UIScreenProcessing
+	UIScreenAnalysis
 	// UIScreenResults
 )
 
@@ -117,22 +663,219 @@ func (c *AppCompressionConfig) Validate() error {
 	}
 
 	// Проверка качества JPEG
-	if c.EnableJPEG {
-		if c.JPEGQuality < 10 || c.JPEGQuality > 50 || c.JPEGQuality%5 != 0 {
-			return ErrInvalidJPEGQuality
-		}
+	if c.EnableJPEG && !isImageQuality(c.JPEGQuality) {
+		return ErrInvalidJPEGQuality
 	}
 
 	// Проверка качества PNG
-	if c.EnablePNG {
-		if c.PNGQuality < 10 || c.PNGQuality > 50 || c.PNGQuality%5 != 0 {
-			return ErrInvalidPNGQuality
+	if c.EnablePNG && !isImageQuality(c.PNGQuality) {
+		return ErrInvalidPNGQuality
+	}
+
+	// Проверка порога экономии
+	if c.MinSavingsPercent < 0 || c.MinSavingsPercent >= 100 || c.MinSavingsBytes < 0 {
+		return ErrInvalidMinSavings
+	}
+
+	// Проверка стратегий алгоритма best
+	usesExternal := c.Algorithm == "external"
+	for _, strategy := range c.BestStrategies {
+		switch strategy.Algorithm {
+		case "pdfcpu", "unipdf":
+		case "external":
+			usesExternal = true
+		default:
+			return ErrInvalidStrategy
+		}
+		if strategy.Level != 0 && (strategy.Level < 10 || strategy.Level > 90) {
+			return ErrInvalidStrategy
 		}
 	}
 
+	// Проверка внешней команды, если она используется
+	if usesExternal {
+		if err := c.ExternalCommand.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Проверка целевого размера и профилей
+	if c.TargetSizeMB < 0 {
+		return ErrInvalidTargetSize
+	}
+	for _, profile := range c.Profiles {
+		if profile.Name == "" || len(profile.Patterns) == 0 {
+			return fmt.Errorf("%w: у профиля должны быть имя и шаблоны", ErrInvalidProfile)
+		}
+		for _, pattern := range profile.Patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("%w %s: шаблон %q: %v", ErrInvalidProfile, profile.Name, pattern, err)
+			}
+		}
+		if profile.Level != 0 && (profile.Level < 10 || profile.Level > 90) {
+			return fmt.Errorf("%w %s: %v", ErrInvalidProfile, profile.Name, ErrInvalidCompressionLevel)
+		}
+		if profile.TargetSizeMB < 0 {
+			return fmt.Errorf("%w %s: %v", ErrInvalidProfile, profile.Name, ErrInvalidTargetSize)
+		}
+		if profile.ColorReduction != "" && !isColorReductionMode(profile.ColorReduction) {
+			return fmt.Errorf("%w %s: %v", ErrInvalidProfile, profile.Name, ErrInvalidColorReduction)
+		}
+		if profile.ImageConversion != "" && !isImageConversionMode(profile.ImageConversion) {
+			return fmt.Errorf("%w %s: %v", ErrInvalidProfile, profile.Name, ErrInvalidImageConversion)
+		}
+		if profile.ImageMetadata != "" && !isImageMetadataPolicy(profile.ImageMetadata) {
+			return fmt.Errorf("%w %s: %v", ErrInvalidProfile, profile.Name, ErrInvalidImageMetadata)
+		}
+		if profile.JPEGQuality != 0 && !isImageQuality(profile.JPEGQuality) {
+			return fmt.Errorf("%w %s: %v", ErrInvalidProfile, profile.Name, ErrInvalidJPEGQuality)
+		}
+		if profile.PNGQuality != 0 && !isImageQuality(profile.PNGQuality) {
+			return fmt.Errorf("%w %s: %v", ErrInvalidProfile, profile.Name, ErrInvalidPNGQuality)
+		}
+		if err := profile.ImageResize.Validate(); err != nil {
+			return fmt.Errorf("%w %s: %v", ErrInvalidProfile, profile.Name, err)
+		}
+		if err := profile.UniPDF.Validate(); err != nil {
+			return fmt.Errorf("%w %s: %v", ErrInvalidProfile, profile.Name, err)
+		}
+	}
+
+	// Проверка политики подписей
+	switch c.SignaturePolicy {
+	case "", SignaturePolicySkip, SignaturePolicyWarn, SignaturePolicyIncremental:
+	default:
+		return ErrInvalidSignaturePolicy
+	}
+
+	// Проверка режима валидации результата
+	switch c.OutputValidation {
+	case "", OutputValidationStrict, OutputValidationRelaxed:
+	default:
+		return ErrInvalidOutputValidation
+	}
+
+	// Проверка режима цветности изображений
+	if c.ColorReduction != "" && !isColorReductionMode(c.ColorReduction) {
+		return ErrInvalidColorReduction
+	}
+
+	// Проверка режима смены формата изображений
+	if c.ImageConversion != "" && !isImageConversionMode(c.ImageConversion) {
+		return ErrInvalidImageConversion
+	}
+
+	// Проверка политики метаданных изображений
+	if c.ImageMetadata != "" && !isImageMetadataPolicy(c.ImageMetadata) {
+		return ErrInvalidImageMetadata
+	}
+
+	// Проверка рамки изображений
+	if err := c.ImageResize.Validate(); err != nil {
+		return err
+	}
+
+	// Проверка параметров оптимизатора UniPDF
+	if err := c.UniPDF.Validate(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// isImageQuality проверяет качество изображения: от 1 до 100
+func isImageQuality(quality int) bool {
+	return quality >= 1 && quality <= 100
+}
+
+// isColorReductionMode проверяет, что режим цветности изображений известен
+func isColorReductionMode(mode string) bool {
+	switch mode {
+	case ColorReductionAuto, ColorReductionOff, ColorReductionGray, ColorReductionBitonal:
+		return true
+	}
+	return false
+}
+
+// isImageConversionMode проверяет, что режим смены формата изображений известен
+func isImageConversionMode(mode string) bool {
+	switch mode {
+	case ImageConversionOff, ImageConversionJPEG, ImageConversionWebP:
+		return true
+	}
+	return false
+}
+
+// isImageMetadataPolicy проверяет, что политика метаданных изображений известна
+func isImageMetadataPolicy(policy string) bool {
+	switch policy {
+	case ImageMetadataStripAll, ImageMetadataKeepOrientationICC, ImageMetadataKeepAll:
+		return true
+	}
+	return false
+}
+
+// GetSignaturePolicy возвращает политику подписей; по умолчанию подписанные документы пропускаются
+func (c *AppCompressionConfig) GetSignaturePolicy() string {
+	if c.SignaturePolicy == "" {
+		return SignaturePolicySkip
+	}
+	return c.SignaturePolicy
+}
+
+// GetOutputValidation возвращает режим проверки сжатых PDF; по умолчанию мягкий
+func (c *AppCompressionConfig) GetOutputValidation() string {
+	if c.OutputValidation == "" {
+		return OutputValidationRelaxed
+	}
+	return c.OutputValidation
+}
+
+// GetColorReduction возвращает режим цветности изображений; по умолчанию — по уровню сжатия
+func (c *AppCompressionConfig) GetColorReduction() string {
+	if c.ColorReduction == "" {
+		return ColorReductionAuto
+	}
+	return c.ColorReduction
+}
+
+// GetImageConversion возвращает режим смены формата изображений; по умолчанию формат не меняется
+func (c *AppCompressionConfig) GetImageConversion() string {
+	if c.ImageConversion == "" {
+		return ImageConversionOff
+	}
+	return c.ImageConversion
+}
+
+// GetImageMetadata возвращает политику метаданных изображений; по умолчанию сохраняются
+// ориентация и ICC профиль, от которых зависит, как изображение выглядит
+func (c *AppCompressionConfig) GetImageMetadata() string {
+	if c.ImageMetadata == "" {
+		return ImageMetadataKeepOrientationICC
+	}
+	return c.ImageMetadata
+}
+
+// CheckSavings проверяет, достаточно ли уменьшился файл.
+// Если порог не достигнут, возвращает false и причину.
+func (c *AppCompressionConfig) CheckSavings(originalSize, compressedSize int64) (bool, string) {
+	saved := originalSize - compressedSize
+	if saved <= 0 {
+		return false, fmt.Sprintf("размер не уменьшился (%d → %d байт)", originalSize, compressedSize)
+	}
+
+	percent := float64(saved) / float64(originalSize) * 100
+	if percent < c.MinSavingsPercent {
+		return false, fmt.Sprintf("экономия %.1f%% меньше порога %.1f%%", percent, c.MinSavingsPercent)
+	}
+
+	if saved < c.MinSavingsBytes {
+		return false, fmt.Sprintf("экономия %d байт меньше порога %d байт", saved, c.MinSavingsBytes)
+	}
+
+	return true, ""
+}
+
 // GetSupportedImageFormats возвращает список поддерживаемых форматов изображений
 func (c *AppCompressionConfig) GetSupportedImageFormats() []string {
 	var formats []string
@@ -175,11 +918,30 @@ func (ps *ProcessingStatus) AddResult(result *CompressionResult) {
 	ps.ProcessedFiles++
 	ps.LastResult = result
 
-	if result.Success && result.Error == nil {
+	stats := &FileTypeStats{}
+	if result.FileType != "" {
+		stats = ps.typeStats(result.FileType)
+	}
+	stats.Files++
+
+	if result.Skipped && result.Error == nil {
+		ps.SkippedFiles++
+		stats.SkippedFiles++
+		if result.Encrypted {
+			ps.SkippedEncryptedFiles++
+		}
+	} else if result.Success && result.Error == nil {
 		ps.SuccessfulFiles++
+		stats.SuccessfulFiles++
+		if result.TargetSize > 0 && !result.TargetReached {
+			ps.TargetMissedFiles++
+		}
 		ps.TotalOriginalSize += result.OriginalSize
 		ps.TotalCompressedSize += result.CompressedSize
 		ps.TotalSavedSpace += result.SavedSpace
+		stats.OriginalSize += result.OriginalSize
+		stats.CompressedSize += result.CompressedSize
+		stats.SavedSpace += result.SavedSpace
 
 		// Пересчитываем среднее сжатие
 		if ps.TotalOriginalSize > 0 {
@@ -187,11 +949,23 @@ func (ps *ProcessingStatus) AddResult(result *CompressionResult) {
 		}
 	} else {
 		ps.FailedFiles++
+		stats.FailedFiles++
 	}
 
 	ps.UpdateProgress()
 }
 
+// typeStats возвращает статистику файлов типа, добавляя ее при первом файле
+func (ps *ProcessingStatus) typeStats(fileType FileType) *FileTypeStats {
+	for i := range ps.TypeStats {
+		if ps.TypeStats[i].Type == fileType {
+			return &ps.TypeStats[i]
+		}
+	}
+	ps.TypeStats = append(ps.TypeStats, FileTypeStats{Type: fileType})
+	return &ps.TypeStats[len(ps.TypeStats)-1]
+}
+
 // SetPhase устанавливает фазу обработки
 func (ps *ProcessingStatus) SetPhase(phase ProcessingPhase, message string) {
 	ps.Phase = phase
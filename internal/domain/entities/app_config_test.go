@@ -0,0 +1,447 @@
+package entities_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"compress/internal/domain/entities"
+)
+
+func TestAppCompressionConfig_CheckSavings(t *testing.T) {
+	tests := []struct {
+		name           string
+		minPercent     float64
+		minBytes       int64
+		originalSize   int64
+		compressedSize int64
+		expectedOK     bool
+	}{
+		{"Any reduction without thresholds", 0, 0, 1000, 999, true},
+		{"Same size", 0, 0, 1000, 1000, false},
+		{"File got bigger", 0, 0, 1000, 1100, false},
+		{"Percent threshold met", 5, 0, 1000, 950, true},
+		{"Percent threshold missed", 5, 0, 1000, 960, false},
+		{"Bytes threshold met", 0, 100, 1000, 900, true},
+		{"Bytes threshold missed", 0, 100, 1000, 901, false},
+		{"Both thresholds, bytes missed", 5, 500, 1000, 900, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &entities.AppCompressionConfig{
+				MinSavingsPercent: tt.minPercent,
+				MinSavingsBytes:   tt.minBytes,
+			}
+
+			ok, reason := config.CheckSavings(tt.originalSize, tt.compressedSize)
+			if ok != tt.expectedOK {
+				t.Errorf("CheckSavings() = %v (%s), want %v", ok, reason, tt.expectedOK)
+			}
+			if !ok && reason == "" {
+				t.Error("Expected non-empty reason when threshold is missed")
+			}
+		})
+	}
+}
+
+func TestProcessingStatus_AddResultSkipped(t *testing.T) {
+	status := entities.NewProcessingStatus(2)
+
+	skipped := &entities.CompressionResult{
+		OriginalSize:   1000,
+		CompressedSize: 1100,
+		Success:        true,
+	}
+	skipped.Skip("размер не уменьшился")
+	status.AddResult(skipped)
+
+	status.AddResult(&entities.CompressionResult{
+		OriginalSize:   1000,
+		CompressedSize: 500,
+		SavedSpace:     500,
+		Success:        true,
+	})
+
+	if status.SkippedFiles != 1 || status.SuccessfulFiles != 1 || status.FailedFiles != 0 {
+		t.Errorf("Expected 1 skipped and 1 successful file, got skipped=%d successful=%d failed=%d",
+			status.SkippedFiles, status.SuccessfulFiles, status.FailedFiles)
+	}
+
+	if skipped.CompressedSize != skipped.OriginalSize || skipped.SavedSpace != 0 {
+		t.Errorf("Skipped result should keep original size, got %d/%d", skipped.CompressedSize, skipped.SavedSpace)
+	}
+
+	if status.TotalSavedSpace != 500 {
+		t.Errorf("Expected total saved space 500, got %d", status.TotalSavedSpace)
+	}
+}
+
+func TestAppCompressionConfig_ForFile(t *testing.T) {
+	optimizeForWeb := true
+	config := &entities.AppCompressionConfig{
+		Level: 50,
+		Profiles: []entities.ProfileConfig{
+			{Name: "portal", Patterns: []string{"portal/*", "*_upload.pdf"}, TargetSizeMB: 10, OptimizeForWeb: &optimizeForWeb},
+			{Name: "scans", Patterns: []string{"scans/*"}, Level: 70, ColorReduction: entities.ColorReductionBitonal},
+		},
+	}
+
+	tests := []struct {
+		relPath         string
+		expectedProfile string
+		expectedLevel   int
+		expectedTarget  float64
+		expectedWeb     bool
+		expectedColor   string
+	}{
+		{"portal/report.pdf", "portal", 50, 10, true, entities.ColorReductionAuto},
+		{"docs/contract_upload.pdf", "portal", 50, 10, true, entities.ColorReductionAuto},
+		{"scans/page.pdf", "scans", 70, 0, false, entities.ColorReductionBitonal},
+		{"docs/other.pdf", "", 50, 0, false, entities.ColorReductionAuto},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.relPath, func(t *testing.T) {
+			fileConfig, profile := config.ForFile(tt.relPath)
+			if profile != tt.expectedProfile {
+				t.Errorf("Expected profile %q, got %q", tt.expectedProfile, profile)
+			}
+			if fileConfig.Level != tt.expectedLevel || fileConfig.TargetSizeMB != tt.expectedTarget {
+				t.Errorf("Expected level %d and target %v, got %d and %v",
+					tt.expectedLevel, tt.expectedTarget, fileConfig.Level, fileConfig.TargetSizeMB)
+			}
+			if fileConfig.OptimizeForWeb != tt.expectedWeb {
+				t.Errorf("Expected optimize_for_web %v, got %v", tt.expectedWeb, fileConfig.OptimizeForWeb)
+			}
+			if color := fileConfig.GetColorReduction(); color != tt.expectedColor {
+				t.Errorf("Expected color_reduction %q, got %q", tt.expectedColor, color)
+			}
+		})
+	}
+
+	if config.Level != 50 || config.TargetSizeMB != 0 || config.OptimizeForWeb {
+		t.Error("ForFile must not modify the base configuration")
+	}
+}
+
+func TestAppCompressionConfig_ValidateColorReduction(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    string
+		profile string
+		wantErr bool
+	}{
+		{"Not set", "", "", false},
+		{"Bitonal", entities.ColorReductionBitonal, "", false},
+		{"Profile override", entities.ColorReductionOff, entities.ColorReductionGray, false},
+		{"Unknown mode", "mono", "", true},
+		{"Unknown profile mode", "", "color", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &entities.AppCompressionConfig{
+				Level:          50,
+				ColorReduction: tt.mode,
+				Profiles: []entities.ProfileConfig{
+					{Name: "scans", Patterns: []string{"scans/*"}, ColorReduction: tt.profile},
+				},
+			}
+			if err := config.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAppCompressionConfig_ImageConversion(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     string
+		profile  string
+		wantErr  bool
+		expected string // Режим для файла из профиля
+	}{
+		{"Not set", "", "", false, entities.ImageConversionOff},
+		{"Global webp", entities.ImageConversionWebP, "", false, entities.ImageConversionWebP},
+		{"Profile override", entities.ImageConversionWebP, entities.ImageConversionJPEG, false, entities.ImageConversionJPEG},
+		{"Unknown mode", "avif", "", true, ""},
+		{"Unknown profile mode", "", "gif", true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &entities.AppCompressionConfig{
+				Level:           50,
+				ImageConversion: tt.mode,
+				Profiles: []entities.ProfileConfig{
+					{Name: "site", Patterns: []string{"site/*"}, ImageConversion: tt.profile},
+				},
+			}
+			err := config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !errors.Is(err, entities.ErrInvalidImageConversion) && !errors.Is(err, entities.ErrInvalidProfile) {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+
+			fileConfig, _ := config.ForFile("site/photo.png")
+			if mode := fileConfig.GetImageConversion(); mode != tt.expected {
+				t.Errorf("Expected conversion %q, got %q", tt.expected, mode)
+			}
+		})
+	}
+}
+
+func TestAppCompressionConfig_UsesImageQuality(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   entities.AppCompressionConfig
+		expected bool
+	}{
+		{"pdfcpu", entities.AppCompressionConfig{Algorithm: "pdfcpu"}, true},
+		{"unipdf", entities.AppCompressionConfig{Algorithm: "unipdf"}, false},
+		{"external", entities.AppCompressionConfig{Algorithm: "external"}, false},
+		{"best with default strategies", entities.AppCompressionConfig{Algorithm: "best"}, true},
+		// При подборе целевого размера уровень стратегии заменяется уровнем поиска
+		{"best with fixed pdfcpu level", entities.AppCompressionConfig{
+			Algorithm:      "best",
+			BestStrategies: []entities.StrategyConfig{{Algorithm: "pdfcpu", Level: 70}, {Algorithm: "unipdf"}},
+		}, true},
+		{"best without pdfcpu", entities.AppCompressionConfig{
+			Algorithm:      "best",
+			BestStrategies: []entities.StrategyConfig{{Algorithm: "unipdf", Level: 70}},
+		}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.UsesImageQuality(); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestExternalCommandConfig(t *testing.T) {
+	command := entities.ExternalCommandConfig{
+		Command: []string{"gs", "-dPDFSETTINGS=/ebook", "-sOutputFile={output}", "{input}", "--level={level}"},
+	}
+
+	args := command.Args("in.pdf", "out.pdf", 70)
+	expected := []string{"gs", "-dPDFSETTINGS=/ebook", "-sOutputFile=out.pdf", "in.pdf", "--level=70"}
+	if strings.Join(args, " ") != strings.Join(expected, " ") {
+		t.Errorf("Args() = %v, expected %v", args, expected)
+	}
+	if command.Command[2] != "-sOutputFile={output}" {
+		t.Error("Args() must not modify the command template")
+	}
+
+	if !command.IsSuccess(0) || command.IsSuccess(1) {
+		t.Error("Only exit code 0 must be successful by default")
+	}
+	command.SuccessCodes = []int{0, 3}
+	if !command.IsSuccess(3) || command.IsSuccess(2) {
+		t.Error("SuccessCodes must replace the default exit codes")
+	}
+
+	if timeout := command.GetTimeout(0); timeout != 5*time.Minute {
+		t.Errorf("Expected default timeout 5m, got %s", timeout)
+	}
+	if timeout := command.GetTimeout(120); timeout != 2*time.Minute {
+		t.Errorf("Expected processing timeout 2m, got %s", timeout)
+	}
+	command.TimeoutSeconds = 30
+	if timeout := command.GetTimeout(120); timeout != 30*time.Second {
+		t.Errorf("Expected command timeout 30s, got %s", timeout)
+	}
+}
+
+func TestAppCompressionConfig_ValidateExternalCommand(t *testing.T) {
+	valid := entities.ExternalCommandConfig{Command: []string{"tool", "{input}", "{output}"}}
+
+	tests := []struct {
+		name       string
+		algorithm  string
+		strategies []entities.StrategyConfig
+		command    entities.ExternalCommandConfig
+		wantErr    bool
+	}{
+		{"Not used", "pdfcpu", nil, entities.ExternalCommandConfig{}, false},
+		{"External algorithm", "external", nil, valid, false},
+		{"Missing command", "external", nil, entities.ExternalCommandConfig{}, true},
+		{"Missing output placeholder", "external", nil, entities.ExternalCommandConfig{Command: []string{"tool", "{input}"}}, true},
+		{"Negative timeout", "external", nil, entities.ExternalCommandConfig{Command: valid.Command, TimeoutSeconds: -1}, true},
+		{"External strategy", "best", []entities.StrategyConfig{{Algorithm: "pdfcpu"}, {Algorithm: "external"}}, valid, false},
+		{"External strategy without command", "best", []entities.StrategyConfig{{Algorithm: "external"}}, entities.ExternalCommandConfig{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &entities.AppCompressionConfig{
+				Level:           50,
+				Algorithm:       tt.algorithm,
+				BestStrategies:  tt.strategies,
+				ExternalCommand: tt.command,
+			}
+			err := config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !errors.Is(err, entities.ErrInvalidExternalCommand) {
+				t.Errorf("Expected ErrInvalidExternalCommand, got %v", err)
+			}
+		})
+	}
+}
+
+func TestAppCompressionConfig_UniPDFProfile(t *testing.T) {
+	enabled, disabled := true, false
+	config := &entities.AppCompressionConfig{
+		Level:  50,
+		UniPDF: entities.UniPDFOptionsConfig{CleanContentstream: &disabled, ImageQuality: 70},
+		Profiles: []entities.ProfileConfig{
+			{
+				Name:     "scans",
+				Patterns: []string{"scans/*"},
+				UniPDF:   entities.UniPDFOptionsConfig{SubsetFonts: &enabled, ImageUpperPPI: 200},
+			},
+		},
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	fileConfig, _ := config.ForFile("scans/a.pdf")
+	options := fileConfig.UniPDF
+	if options.CleanContentstream == nil || *options.CleanContentstream || options.ImageQuality != 70 {
+		t.Errorf("Profile must keep main UniPDF options, got %+v", options)
+	}
+	if options.SubsetFonts == nil || !*options.SubsetFonts || options.ImageUpperPPI != 200 {
+		t.Errorf("Profile UniPDF options must be applied, got %+v", options)
+	}
+	if other, _ := config.ForFile("docs/a.pdf"); other.UniPDF != config.UniPDF {
+		t.Errorf("Files outside profile must use main UniPDF options, got %+v", other.UniPDF)
+	}
+
+	config.Profiles[0].UniPDF.ImageQuality = 101
+	if err := config.Validate(); !errors.Is(err, entities.ErrInvalidProfile) {
+		t.Errorf("Expected ErrInvalidProfile for image_quality 101, got %v", err)
+	}
+	config.Profiles[0].UniPDF.ImageQuality = 0
+	config.UniPDF.ImageUpperPPI = -1
+	if err := config.Validate(); !errors.Is(err, entities.ErrInvalidUniPDFOptions) {
+		t.Errorf("Expected ErrInvalidUniPDFOptions for negative image_upper_ppi, got %v", err)
+	}
+}
+
+func TestAppCompressionConfig_CleanupProfile(t *testing.T) {
+	enabled, disabled := true, false
+	config := &entities.AppCompressionConfig{
+		Level:   50,
+		Cleanup: entities.CleanupConfig{RemoveJavaScript: &disabled},
+		Profiles: []entities.ProfileConfig{
+			{
+				Name:     "archive",
+				Patterns: []string{"archive/*"},
+				Cleanup:  entities.CleanupConfig{RemoveOldRevisions: &disabled, RemoveThumbnails: &enabled},
+			},
+		},
+	}
+
+	fileConfig, _ := config.ForFile("archive/a.pdf")
+	cleanup := fileConfig.Cleanup
+	if cleanup.RemoveJavaScript == nil || *cleanup.RemoveJavaScript {
+		t.Errorf("Profile must keep main cleanup flags, got %+v", cleanup)
+	}
+	if cleanup.RemoveOldRevisions == nil || *cleanup.RemoveOldRevisions || cleanup.RemoveThumbnails == nil || !*cleanup.RemoveThumbnails {
+		t.Errorf("Profile cleanup flags must be applied, got %+v", cleanup)
+	}
+	if other, _ := config.ForFile("docs/a.pdf"); other.Cleanup != config.Cleanup {
+		t.Errorf("Files outside profile must use main cleanup flags, got %+v", other.Cleanup)
+	}
+}
+
+func TestAppCompressionConfig_ImageResize(t *testing.T) {
+	upscale := true
+	width, height, megapixels, off := 2560, 1440, 12.0, 0.0
+	config := &entities.AppCompressionConfig{
+		Level:       50,
+		EnableJPEG:  true,
+		JPEGQuality: 30,
+		ImageResize: entities.ImageResizeConfig{MaxMegapixels: &megapixels},
+		Profiles: []entities.ProfileConfig{
+			{
+				Name:        "web",
+				Patterns:    []string{"web/*"},
+				JPEGQuality: 80,
+				ImageResize: entities.ImageResizeConfig{MaxWidth: &width, MaxHeight: &height, Upscale: &upscale, Filter: entities.ResizeFilterMitchell},
+			},
+			{
+				// Явный 0 выключает ограничение основной конфигурации
+				Name:        "print",
+				Patterns:    []string{"print/*"},
+				ImageResize: entities.ImageResizeConfig{MaxMegapixels: &off},
+			},
+		},
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	fileConfig, _ := config.ForFile("web/banner.jpg")
+	resize := fileConfig.ImageResize
+	if fileConfig.JPEGQuality != 80 || resize.GetMaxWidth() != 2560 || resize.GetMaxHeight() != 1440 || resize.GetMaxMegapixels() != 12 {
+		t.Errorf("Profile is not merged: quality %d, resize %+v", fileConfig.JPEGQuality, resize)
+	}
+	if printConfig, _ := config.ForFile("print/poster.jpg"); printConfig.ImageResize.Enabled() {
+		t.Errorf("Profile must turn off the global bound, got %+v", printConfig.ImageResize)
+	}
+	if !resize.GetUpscale() || resize.GetFilter() != entities.ResizeFilterMitchell {
+		t.Errorf("Expected upscale with mitchell, got %v %q", resize.GetUpscale(), resize.GetFilter())
+	}
+
+	otherConfig, _ := config.ForFile("docs/photo.jpg")
+	if otherConfig.ImageResize.GetUpscale() || otherConfig.ImageResize.GetFilter() != entities.ResizeFilterLanczos3 || !otherConfig.ImageResize.Enabled() {
+		t.Errorf("Unexpected default resize %+v", otherConfig.ImageResize)
+	}
+
+	config.ImageResize.Filter = "sinc"
+	if err := config.Validate(); !errors.Is(err, entities.ErrInvalidImageResize) {
+		t.Errorf("Expected ErrInvalidImageResize, got %v", err)
+	}
+	config.ImageResize.Filter = ""
+	config.Profiles[0].JPEGQuality = 101
+	if err := config.Validate(); !errors.Is(err, entities.ErrInvalidProfile) {
+		t.Errorf("Expected ErrInvalidProfile for quality 101, got %v", err)
+	}
+}
+
+func TestProcessingStatus_AddResultTypeStats(t *testing.T) {
+	status := entities.NewProcessingStatus(4)
+	status.AddResult(&entities.CompressionResult{FileType: entities.FileTypePDF, OriginalSize: 1000, CompressedSize: 600, SavedSpace: 400, Success: true})
+	status.AddResult(&entities.CompressionResult{FileType: entities.FileTypeJPEG, OriginalSize: 500, CompressedSize: 250, SavedSpace: 250, Success: true})
+	status.AddResult(&entities.CompressionResult{FileType: entities.FileTypeJPEG, OriginalSize: 300, Error: entities.ErrCompressionFailed})
+	status.AddResult(&entities.CompressionResult{OriginalSize: 100, CompressedSize: 50, SavedSpace: 50, Success: true})
+
+	if status.TotalSavedSpace != 700 || status.SuccessfulFiles != 3 || status.FailedFiles != 1 {
+		t.Errorf("Unexpected totals: saved=%d successful=%d failed=%d", status.TotalSavedSpace, status.SuccessfulFiles, status.FailedFiles)
+	}
+	if len(status.TypeStats) != 2 {
+		t.Fatalf("Expected stats for PDF and JPEG, got %+v", status.TypeStats)
+	}
+
+	pdf, jpeg := status.TypeStats[0], status.TypeStats[1]
+	if pdf.Type != entities.FileTypePDF || pdf.Files != 1 || pdf.SavedSpace != 400 || pdf.Compression() != 40 {
+		t.Errorf("Unexpected PDF stats %+v", pdf)
+	}
+	if jpeg.Type != entities.FileTypeJPEG || jpeg.Files != 2 || jpeg.SuccessfulFiles != 1 || jpeg.FailedFiles != 1 || jpeg.OriginalSize != 500 {
+		t.Errorf("Unexpected JPEG stats %+v", jpeg)
+	}
+}
@@ -1,17 +1,71 @@
 package entities
 
+import (
+	"strconv"
+	"time"
+)
+
+// Служебные ключи Info, которыми помечается сжатый документ
+const (
+	MetadataKeyOriginalSize = "CompressOriginalSize"
+	MetadataKeyLevel        = "CompressLevel"
+	MetadataKeyDate         = "CompressDate"
+)
+
 // CompressionConfig представляет конфигурацию сжатия
 type CompressionConfig struct {
 	Level             int    // Уровень сжатия (10-90)
 	ImageQuality      int    // Качество изображений (10-100)
+	ImageMaxPPI       int    // Максимальное разрешение изображений на странице (PPI)
 	ImageCompression  bool   // Сжимать изображения
 	RemoveDuplicates  bool   // Удалять дубликаты объектов
 	CompressStreams   bool   // Сжимать потоки данных
+	RecompressStreams bool   // Пересжимать потоки Deflate с максимальной степенью (без потерь)
 	RemoveMetadata    bool   // Удалять метаданные
 	RemoveAnnotations bool   // Удалять аннотации
 	RemoveAttachments bool   // Удалять вложения
-	OptimizeForWeb    bool   // Оптимизировать для веб
+	OptimizeFonts     bool   // Удалять неиспользуемые шрифты и объединять одинаковые программы шрифтов
+	SubsetFonts       bool   // Оставлять во встроенных TrueType шрифтах только используемые глифы
+	OptimizeForWeb    bool   // Линеаризовать результат (быстрый веб-просмотр)
 	UniPDFLicenseKey  string // Лицензионный ключ для UniPDF
+	PDFAPart          int    // Часть PDF/A исходного документа; 0 — документ обрабатывается без ограничений PDF/A
+	SearchLevel       bool   // Уровень выбран подбором целевого размера; фиксированные уровни стратегий best не применяются
+	StrictValidation  bool   // Проверять результат строгой валидацией pdfcpu
+	// Политика метаданных
+	KeepMetadataKeys []string // Ключи Info, сохраняемые при удалении метаданных
+	StampMetadata    bool     // Добавлять служебные ключи сжатия в Info
+	KeepAnnotations  []string // Подтипы аннотаций, сохраняемые при удалении аннотаций
+	// Уменьшение цветности изображений (сканы черно-белых документов)
+	ColorReduction   string // Режим из конфигурации; пусто или auto — по уровню сжатия
+	ConvertGrayscale bool   // Почти серые изображения переводить в DeviceGray
+	ConvertBitonal   bool   // Почти двухцветные изображения переводить в 1 бит (CCITT G4)
+	// Переопределения параметров оптимизатора UniPDF
+	UniPDF UniPDFOptionsConfig
+	// Удаление редко нужных данных
+	RemoveThumbnails         bool // Встроенные миниатюры страниц (Thumb)
+	RemoveJavaScript         bool // JavaScript документа, страниц, аннотаций и полей формы
+	RemoveUnusedDestinations bool // Именованные назначения, на которые нет ссылок в документе
+	RemovePieceInfo          bool // Частные данные приложений (PieceInfo)
+	RemoveOldRevisions       bool // Старые ревизии добавочных обновлений; выключено — ревизии сжимаются по отдельности
+	// Переопределения флагов удаления из конфигурации
+	Cleanup CleanupConfig
+	// Сведения об исходном файле, уже прочитанные вызывающим; компрессоры не читают его повторно
+	Source *PDFDocument
+}
+
+// UniPDFOptions параметры оптимизатора UniPDF для одного файла
+type UniPDFOptions struct {
+	CombineDuplicateDirectObjects   bool
+	CombineIdenticalIndirectObjects bool
+	CombineDuplicateStreams         bool
+	CompressStreams                 bool
+	UseObjectStreams                bool
+	CleanUnusedResources            bool
+	CleanContentstream              bool
+	CleanFonts                      bool
+	SubsetFonts                     bool
+	ImageUpperPPI                   float64
+	ImageQuality                    int
 }
 
 // NewCompressionConfig создает конфигурацию сжатия на основе уровня
@@ -29,53 +83,334 @@ func NewCompressionConfigWithLicense(level int, licenseKey string) *CompressionC
 	}
 
 	config := &CompressionConfig{
-		Level:            level,
-		RemoveDuplicates: true,
-		CompressStreams:  true,
-		OptimizeForWeb:   true,
-		UniPDFLicenseKey: licenseKey,
+		Level:             level,
+		RemoveDuplicates:  true,
+		CompressStreams:   true,
+		RecompressStreams: true,
+		OptimizeFonts:     true,
+		UniPDFLicenseKey:  licenseKey,
+		KeepMetadataKeys:  DefaultMetadataKeep,
+		KeepAnnotations:   DefaultKeepAnnotations,
 	}
 
 	switch {
 	case level <= 20: // Слабое сжатие
 		config.ImageQuality = 90
+		config.ImageMaxPPI = 300
 		config.ImageCompression = true
+		config.ConvertGrayscale = false
+		config.ConvertBitonal = false
+		config.SubsetFonts = false
 		config.RemoveMetadata = false
 		config.RemoveAnnotations = false
 		config.RemoveAttachments = false
+		config.RemoveThumbnails = false
+		config.RemoveJavaScript = false
+		config.RemovePieceInfo = false
+		config.RemoveUnusedDestinations = false
+		config.RemoveOldRevisions = false // Слабое сжатие не отбрасывает историю правок документа
 
 	case level <= 40: // Умеренное сжатие
 		config.ImageQuality = 75
+		config.ImageMaxPPI = 200
 		config.ImageCompression = true
+		config.ConvertGrayscale = true
+		config.ConvertBitonal = false
+		config.SubsetFonts = true
 		config.RemoveMetadata = true
 		config.RemoveAnnotations = false
 		config.RemoveAttachments = false
+		config.RemoveThumbnails = true
+		config.RemoveJavaScript = false
+		config.RemovePieceInfo = true
+		config.RemoveUnusedDestinations = false
+		config.RemoveOldRevisions = true
 
 	case level <= 60: // Среднее сжатие
 		config.ImageQuality = 60
+		config.ImageMaxPPI = 150
 		config.ImageCompression = true
+		config.ConvertGrayscale = true
+		config.ConvertBitonal = false
+		config.SubsetFonts = true
 		config.RemoveMetadata = true
 		config.RemoveAnnotations = true
 		config.RemoveAttachments = false
+		config.RemoveThumbnails = true
+		config.RemoveJavaScript = true
+		config.RemovePieceInfo = true
+		config.RemoveUnusedDestinations = true
+		config.RemoveOldRevisions = true
 
 	case level <= 80: // Высокое сжатие
 		config.ImageQuality = 40
+		config.ImageMaxPPI = 110
 		config.ImageCompression = true
+		config.ConvertGrayscale = true
+		config.ConvertBitonal = false
+		config.SubsetFonts = true
 		config.RemoveMetadata = true
 		config.RemoveAnnotations = true
 		config.RemoveAttachments = true
+		config.RemoveThumbnails = true
+		config.RemoveJavaScript = true
+		config.RemovePieceInfo = true
+		config.RemoveUnusedDestinations = true
+		config.RemoveOldRevisions = true
 
 	default: // Максимальное сжатие (81-90%)
 		config.ImageQuality = 25
+		config.ImageMaxPPI = 72
 		config.ImageCompression = true
+		config.ConvertGrayscale = true
+		config.ConvertBitonal = true
+		config.SubsetFonts = true
 		config.RemoveMetadata = true
 		config.RemoveAnnotations = true
 		config.RemoveAttachments = true
+		config.RemoveThumbnails = true
+		config.RemoveJavaScript = true
+		config.RemovePieceInfo = true
+		config.RemoveUnusedDestinations = true
+		config.RemoveOldRevisions = true
+	}
+
+	return config
+}
+
+// ForPDFA возвращает копию конфигурации без операций, нарушающих соответствие PDF/A указанной части:
+// XMP метаданные сохраняются, шрифты не сокращаются до подмножеств (PDF/A требует для них
+// CIDSet/CharSet), цветность изображений не уменьшается (изображения с ICC профилем
+// стали бы DeviceGray, не связанным с OutputIntent), а для PDF/A-1 (основан на PDF 1.4)
+// отключаются объектные и xref-потоки
+func (c *CompressionConfig) ForPDFA(part int) *CompressionConfig {
+	pdfa := *c
+	pdfa.PDFAPart = part
+	pdfa.RemoveMetadata = false
+	// Ключи Info без соответствия в XMP нарушают требования PDF/A к метаданным
+	pdfa.StampMetadata = false
+	pdfa.SubsetFonts = false
+	pdfa.ConvertGrayscale = false
+	pdfa.ConvertBitonal = false
+	if part == 1 {
+		pdfa.CompressStreams = false
 	}
+	return &pdfa
+}
 
+// WithLevel возвращает конфигурацию для другого уровня сжатия с теми же лицензией,
+// режимом проверки, политиками метаданных и аннотаций, линеаризацией, режимом цветности,
+// параметрами UniPDF, переопределениями удаления, ограничениями PDF/A и сведениями об исходном файле
+func (c *CompressionConfig) WithLevel(level int) *CompressionConfig {
+	config := NewCompressionConfigWithLicense(level, c.UniPDFLicenseKey)
+	config.StrictValidation = c.StrictValidation
+	config.KeepMetadataKeys = c.KeepMetadataKeys
+	config.StampMetadata = c.StampMetadata
+	config.KeepAnnotations = c.KeepAnnotations
+	config.OptimizeForWeb = c.OptimizeForWeb
+	config.UniPDF = c.UniPDF
+	config.Source = c.Source
+	config = config.WithColorReduction(c.ColorReduction).WithCleanup(c.Cleanup)
+	if c.PDFAPart > 0 {
+		config = config.ForPDFA(c.PDFAPart)
+	}
 	return config
 }
 
+// KeepsMetadataKey проверяет, сохраняется ли ключ Info в результате
+func (c *CompressionConfig) KeepsMetadataKey(key string) bool {
+	if !c.RemoveMetadata {
+		return true
+	}
+	for _, kept := range c.KeepMetadataKeys {
+		if kept == key {
+			return true
+		}
+	}
+	return false
+}
+
+// WithSource возвращает конфигурацию со сведениями об исходном файле
+func (c *CompressionConfig) WithSource(source *PDFDocument) *CompressionConfig {
+	config := *c
+	config.Source = source
+	return &config
+}
+
+// SourceDocument возвращает сведения об исходном файле, если они относятся к path
+func (c *CompressionConfig) SourceDocument(path string) *PDFDocument {
+	if c.Source == nil || c.Source.Path != path {
+		return nil
+	}
+	return c.Source
+}
+
+// WithWebOptimization возвращает конфигурацию с включенной или выключенной линеаризацией;
+// если значение не меняется, возвращается та же конфигурация
+func (c *CompressionConfig) WithWebOptimization(enabled bool) *CompressionConfig {
+	if c.OptimizeForWeb == enabled {
+		return c
+	}
+	config := *c
+	config.OptimizeForWeb = enabled
+	return &config
+}
+
+// WithColorReduction возвращает конфигурацию с режимом уменьшения цветности изображений.
+// Пустой режим и auto возвращают значения уровня сжатия
+func (c *CompressionConfig) WithColorReduction(mode string) *CompressionConfig {
+	config := *c
+	config.ColorReduction = mode
+	switch mode {
+	case "", ColorReductionAuto:
+		levelConfig := NewCompressionConfig(c.Level)
+		config.ConvertGrayscale = levelConfig.ConvertGrayscale
+		config.ConvertBitonal = levelConfig.ConvertBitonal
+	case ColorReductionOff:
+		config.ConvertGrayscale = false
+		config.ConvertBitonal = false
+	case ColorReductionGray:
+		config.ConvertGrayscale = true
+		config.ConvertBitonal = false
+	case ColorReductionBitonal:
+		config.ConvertGrayscale = true
+		config.ConvertBitonal = true
+	}
+	return &config
+}
+
+// WithUniPDFOptions возвращает конфигурацию с переопределениями параметров оптимизатора UniPDF
+func (c *CompressionConfig) WithUniPDFOptions(options UniPDFOptionsConfig) *CompressionConfig {
+	config := *c
+	config.UniPDF = options
+	return &config
+}
+
+// WithCleanup возвращает конфигурацию с флагами удаления редко нужных данных:
+// значения уровня сжатия с переопределениями из конфигурации
+func (c *CompressionConfig) WithCleanup(cleanup CleanupConfig) *CompressionConfig {
+	config := *c
+	config.Cleanup = cleanup
+	levelConfig := NewCompressionConfig(c.Level)
+	flags := []struct {
+		value    *bool
+		level    bool
+		override *bool
+	}{
+		{&config.RemoveThumbnails, levelConfig.RemoveThumbnails, cleanup.RemoveThumbnails},
+		{&config.RemoveJavaScript, levelConfig.RemoveJavaScript, cleanup.RemoveJavaScript},
+		{&config.RemoveUnusedDestinations, levelConfig.RemoveUnusedDestinations, cleanup.RemoveUnusedDestinations},
+		{&config.RemovePieceInfo, levelConfig.RemovePieceInfo, cleanup.RemovePieceInfo},
+		{&config.RemoveOldRevisions, levelConfig.RemoveOldRevisions, cleanup.RemoveOldRevisions},
+	}
+	for _, f := range flags {
+		*f.value = f.level
+		if f.override != nil {
+			*f.value = *f.override
+		}
+	}
+	return &config
+}
+
+// UniPDFOptions возвращает параметры оптимизатора UniPDF: значения по уровню сжатия
+// с переопределениями из конфигурации. Ограничения PDF/A переопределениями не снимаются
+func (c *CompressionConfig) UniPDFOptions() UniPDFOptions {
+	options := UniPDFOptions{
+		CombineDuplicateDirectObjects:   c.RemoveDuplicates,
+		CombineIdenticalIndirectObjects: c.RemoveDuplicates,
+		CombineDuplicateStreams:         c.RemoveDuplicates,
+		CompressStreams:                 true,
+		UseObjectStreams:                c.CompressStreams,
+		CleanUnusedResources:            c.OptimizeFonts,
+		CleanContentstream:              c.Level > 40, // Переписывание содержимого страниц — со среднего сжатия
+		CleanFonts:                      c.SubsetFonts,
+		SubsetFonts:                     c.SubsetFonts,
+		ImageUpperPPI:                   float64(150 - c.Level), // Чем выше уровень, тем ниже PPI
+		ImageQuality:                    100 - c.Level,          // Чем выше уровень, тем ниже качество
+	}
+
+	overrides := []struct {
+		value    *bool
+		override *bool
+	}{
+		{&options.CombineDuplicateDirectObjects, c.UniPDF.CombineDuplicateDirectObjects},
+		{&options.CombineIdenticalIndirectObjects, c.UniPDF.CombineIdenticalIndirectObjects},
+		{&options.CombineDuplicateStreams, c.UniPDF.CombineDuplicateStreams},
+		{&options.CompressStreams, c.UniPDF.CompressStreams},
+		{&options.UseObjectStreams, c.UniPDF.UseObjectStreams},
+		{&options.CleanUnusedResources, c.UniPDF.CleanUnusedResources},
+		{&options.CleanContentstream, c.UniPDF.CleanContentstream},
+		{&options.CleanFonts, c.UniPDF.CleanFonts},
+		{&options.SubsetFonts, c.UniPDF.SubsetFonts},
+	}
+	for _, o := range overrides {
+		if o.override != nil {
+			*o.value = *o.override
+		}
+	}
+	if c.UniPDF.ImageUpperPPI != 0 {
+		options.ImageUpperPPI = c.UniPDF.ImageUpperPPI
+	}
+	if c.UniPDF.ImageQuality != 0 {
+		options.ImageQuality = c.UniPDF.ImageQuality
+	}
+
+	// Те же ограничения, что и в ForPDFA
+	if c.PDFAPart > 0 {
+		options.CleanFonts = false
+		options.SubsetFonts = false
+	}
+	if c.PDFAPart == 1 {
+		options.UseObjectStreams = false
+	}
+	return options
+}
+
+// KeepsAnnotation проверяет, сохраняется ли аннотация указанного подтипа при удалении аннотаций
+func (c *CompressionConfig) KeepsAnnotation(subtype string) bool {
+	for _, kept := range c.KeepAnnotations {
+		if kept == subtype {
+			return true
+		}
+	}
+	return false
+}
+
+// MetadataStamp возвращает служебные ключи Info для документа указанного исходного размера
+func (c *CompressionConfig) MetadataStamp(originalSize int64, now time.Time) map[string]string {
+	return map[string]string{
+		MetadataKeyOriginalSize: strconv.FormatInt(originalSize, 10),
+		MetadataKeyLevel:        strconv.Itoa(c.Level),
+		MetadataKeyDate:         now.UTC().Format("D:20060102150405Z"),
+	}
+}
+
+// targetSizeQualities дополнительные шаги качества изображений после максимального уровня
+var targetSizeQualities = []int{15, 10}
+
+// TargetSizeSteps возвращает настройки для подбора под целевой размер, упорядоченные
+// от самых мягких к самым агрессивным: уровни 10–90 с шагом 10, затем, если qualitySteps,
+// максимальный уровень с пониженным качеством изображений. Шаги качества имеют смысл
+// только для движков, которые учитывают ImageQuality
+func (c *CompressionConfig) TargetSizeSteps(qualitySteps bool) []*CompressionConfig {
+	var steps []*CompressionConfig
+	for level := 10; level <= 90; level += 10 {
+		step := c.WithLevel(level)
+		step.SearchLevel = true
+		steps = append(steps, step)
+	}
+	if !qualitySteps {
+		return steps
+	}
+	for _, quality := range targetSizeQualities {
+		step := c.WithLevel(90)
+		step.ImageQuality = quality
+		step.SearchLevel = true
+		steps = append(steps, step)
+	}
+	return steps
+}
+
 // Validate проверяет корректность конфигурации
 func (c *CompressionConfig) Validate() error {
 	if c.Level < 10 || c.Level > 90 {
@@ -3,6 +3,7 @@ package entities_test
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"compress/internal/domain/entities"
 )
@@ -26,6 +27,10 @@ func TestNewCompressionConfig(t *testing.T) {
 			if config.Level != tt.expectedLevel {
 				t.Errorf("Expected level %d, got %d", tt.expectedLevel, config.Level)
 			}
+			// Пересжатие потоков без потерь включено на всех уровнях и в режиме PDF/A
+			if !config.RecompressStreams || !config.ForPDFA(1).RecompressStreams {
+				t.Error("Expected stream recompression at every level")
+			}
 		})
 	}
 }
@@ -92,15 +97,19 @@ func TestCompressionConfigLevels(t *testing.T) {
 	tests := []struct {
 		level                int
 		expectedImageQuality int
+		expectedImageMaxPPI  int
 		expectedMetadata     bool
 		expectedAnnotations  bool
 		expectedAttachments  bool
+		expectedSubsetFonts  bool
+		expectedGrayscale    bool
+		expectedBitonal      bool
 	}{
-		{15, 90, false, false, false}, // Слабое сжатие
-		{30, 75, true, false, false},  // Умеренное сжатие
-		{50, 60, true, true, false},   // Среднее сжатие
-		{70, 40, true, true, true},    // Высокое сжатие
-		{85, 25, true, true, true},    // Максимальное сжатие
+		{15, 90, 300, false, false, false, false, false, false}, // Слабое сжатие
+		{30, 75, 200, true, false, false, true, true, false},    // Умеренное сжатие
+		{50, 60, 150, true, true, false, true, true, false},     // Среднее сжатие
+		{70, 40, 110, true, true, true, true, true, false},      // Высокое сжатие
+		{85, 25, 72, true, true, true, true, true, true},        // Максимальное сжатие
 	}
 
 	for _, tt := range tests {
@@ -111,6 +120,10 @@ func TestCompressionConfigLevels(t *testing.T) {
 				t.Errorf("Expected ImageQuality %d, got %d", tt.expectedImageQuality, config.ImageQuality)
 			}
 
+			if config.ImageMaxPPI != tt.expectedImageMaxPPI {
+				t.Errorf("Expected ImageMaxPPI %d, got %d", tt.expectedImageMaxPPI, config.ImageMaxPPI)
+			}
+
 			if config.RemoveMetadata != tt.expectedMetadata {
 				t.Errorf("Expected RemoveMetadata %v, got %v", tt.expectedMetadata, config.RemoveMetadata)
 			}
@@ -122,6 +135,287 @@ func TestCompressionConfigLevels(t *testing.T) {
 			if config.RemoveAttachments != tt.expectedAttachments {
 				t.Errorf("Expected RemoveAttachments %v, got %v", tt.expectedAttachments, config.RemoveAttachments)
 			}
+
+			if config.SubsetFonts != tt.expectedSubsetFonts {
+				t.Errorf("Expected SubsetFonts %v, got %v", tt.expectedSubsetFonts, config.SubsetFonts)
+			}
+
+			if config.ConvertGrayscale != tt.expectedGrayscale || config.ConvertBitonal != tt.expectedBitonal {
+				t.Errorf("Expected grayscale/bitonal %v/%v, got %v/%v",
+					tt.expectedGrayscale, tt.expectedBitonal, config.ConvertGrayscale, config.ConvertBitonal)
+			}
+
+			if pdfa := config.ForPDFA(2); pdfa.SubsetFonts || pdfa.ConvertGrayscale || pdfa.ConvertBitonal {
+				t.Error("Expected font subsetting and colour reduction to be disabled in PDF/A mode")
+			}
+		})
+	}
+}
+
+func TestCompressionConfig_RareDataLevels(t *testing.T) {
+	tests := []struct {
+		level                int
+		expectedThumbnails   bool
+		expectedJavaScript   bool
+		expectedDestinations bool
+		expectedPieceInfo    bool
+		expectedRevisions    bool
+	}{
+		{15, false, false, false, false, false},
+		{30, true, false, false, true, true},
+		{50, true, true, true, true, true},
+		{85, true, true, true, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("Level %d", tt.level), func(t *testing.T) {
+			config := entities.NewCompressionConfig(tt.level)
+
+			if config.RemoveThumbnails != tt.expectedThumbnails || config.RemoveJavaScript != tt.expectedJavaScript ||
+				config.RemoveUnusedDestinations != tt.expectedDestinations || config.RemovePieceInfo != tt.expectedPieceInfo {
+				t.Errorf("Unexpected toggles: thumbnails %v, javascript %v, destinations %v, pieceinfo %v",
+					config.RemoveThumbnails, config.RemoveJavaScript, config.RemoveUnusedDestinations, config.RemovePieceInfo)
+			}
+			if config.RemoveOldRevisions != tt.expectedRevisions {
+				t.Errorf("Expected RemoveOldRevisions %v, got %v", tt.expectedRevisions, config.RemoveOldRevisions)
+			}
+		})
+	}
+}
+
+func TestCompressionConfig_WithCleanup(t *testing.T) {
+	enabled, disabled := true, false
+	cleanup := entities.CleanupConfig{RemoveOldRevisions: &enabled, RemoveJavaScript: &disabled}
+
+	config := entities.NewCompressionConfig(15).WithCleanup(cleanup)
+	if !config.RemoveOldRevisions || config.RemoveThumbnails {
+		t.Errorf("Expected override of RemoveOldRevisions and level value of RemoveThumbnails, got %v/%v",
+			config.RemoveOldRevisions, config.RemoveThumbnails)
+	}
+
+	// Переопределения сохраняются при смене уровня, остальные флаги берутся из нового уровня
+	config = config.WithLevel(70)
+	if config.RemoveJavaScript || !config.RemoveThumbnails || !config.RemoveOldRevisions {
+		t.Errorf("Expected overrides to survive WithLevel, got javascript %v, thumbnails %v, revisions %v",
+			config.RemoveJavaScript, config.RemoveThumbnails, config.RemoveOldRevisions)
+	}
+
+	// Без переопределений возвращаются значения уровня
+	if config = config.WithCleanup(entities.CleanupConfig{}); !config.RemoveJavaScript {
+		t.Error("Expected level value of RemoveJavaScript without overrides")
+	}
+}
+
+func TestCompressionConfig_TargetSizeSteps(t *testing.T) {
+	steps := entities.NewCompressionConfig(50).TargetSizeSteps(true)
+
+	if len(steps) < 2 || steps[0].Level != 10 {
+		t.Fatalf("Expected steps to start from level 10, got %d steps", len(steps))
+	}
+
+	// Шаги должны становиться только агрессивнее
+	for i := 1; i < len(steps); i++ {
+		prev, cur := steps[i-1], steps[i]
+		if cur.Level < prev.Level || cur.ImageQuality > prev.ImageQuality {
+			t.Errorf("Step %d (level %d, quality %d) is softer than step %d (level %d, quality %d)",
+				i, cur.Level, cur.ImageQuality, i-1, prev.Level, prev.ImageQuality)
+		}
+	}
+
+	// Без шагов качества подбор заканчивается максимальным уровнем
+	levels := entities.NewCompressionConfig(50).TargetSizeSteps(false)
+	if last := levels[len(levels)-1]; len(levels) != 9 || last.Level != 90 || last.ImageQuality != steps[8].ImageQuality {
+		t.Errorf("Expected 9 level steps ending at level 90, got %d", len(levels))
+	}
+}
+
+func TestCompressionConfig_SourceDocument(t *testing.T) {
+	source := &entities.PDFDocument{Path: "in.pdf", Pages: 3}
+	config := entities.NewCompressionConfig(50).WithSource(source)
+
+	if config.SourceDocument("in.pdf") != source {
+		t.Error("Expected source document for its own path")
+	}
+	if config.SourceDocument("decrypted.pdf") != nil {
+		t.Error("Source document must not be returned for another file")
+	}
+	// Сведения сохраняются на всех шагах подбора целевого размера
+	for _, step := range config.TargetSizeSteps(true) {
+		if step.SourceDocument("in.pdf") != source {
+			t.Fatalf("Step at level %d lost source document", step.Level)
+		}
+	}
+}
+
+func TestCompressionConfig_WithColorReduction(t *testing.T) {
+	tests := []struct {
+		mode              string
+		level             int
+		expectedGrayscale bool
+		expectedBitonal   bool
+	}{
+		{"", 15, false, false},
+		{entities.ColorReductionAuto, 85, true, true},
+		{entities.ColorReductionOff, 85, false, false},
+		{entities.ColorReductionGray, 15, true, false},
+		{entities.ColorReductionBitonal, 15, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s at level %d", tt.mode, tt.level), func(t *testing.T) {
+			config := entities.NewCompressionConfig(tt.level).WithColorReduction(tt.mode)
+			if config.ConvertGrayscale != tt.expectedGrayscale || config.ConvertBitonal != tt.expectedBitonal {
+				t.Errorf("Expected grayscale/bitonal %v/%v, got %v/%v",
+					tt.expectedGrayscale, tt.expectedBitonal, config.ConvertGrayscale, config.ConvertBitonal)
+			}
+
+			// Режим из конфигурации сохраняется при смене уровня, в том числе при подборе целевого размера
+			other := config.WithLevel(50)
+			if tt.mode != "" && tt.mode != entities.ColorReductionAuto &&
+				(other.ConvertGrayscale != tt.expectedGrayscale || other.ConvertBitonal != tt.expectedBitonal) {
+				t.Errorf("WithLevel lost color reduction mode %q", tt.mode)
+			}
+		})
+	}
+}
+
+func TestCompressionConfig_UniPDFOptions(t *testing.T) {
+	enabled, disabled := true, false
+
+	tests := []struct {
+		name     string
+		level    int
+		pdfaPart int
+		options  entities.UniPDFOptionsConfig
+		expected entities.UniPDFOptions
+	}{
+		{
+			name:  "Level defaults for weak compression",
+			level: 15,
+			expected: entities.UniPDFOptions{
+				CombineDuplicateDirectObjects: true, CombineIdenticalIndirectObjects: true, CombineDuplicateStreams: true,
+				CompressStreams: true, UseObjectStreams: true, CleanUnusedResources: true,
+				ImageUpperPPI: 135, ImageQuality: 85,
+			},
+		},
+		{
+			name:  "Level defaults for high compression",
+			level: 70,
+			expected: entities.UniPDFOptions{
+				CombineDuplicateDirectObjects: true, CombineIdenticalIndirectObjects: true, CombineDuplicateStreams: true,
+				CompressStreams: true, UseObjectStreams: true, CleanUnusedResources: true,
+				CleanContentstream: true, CleanFonts: true, SubsetFonts: true,
+				ImageUpperPPI: 80, ImageQuality: 30,
+			},
+		},
+		{
+			name:  "Overrides",
+			level: 70,
+			options: entities.UniPDFOptionsConfig{
+				CombineDuplicateStreams: &disabled, CleanContentstream: &disabled, ImageUpperPPI: 200, ImageQuality: 80,
+			},
+			expected: entities.UniPDFOptions{
+				CombineDuplicateDirectObjects: true, CombineIdenticalIndirectObjects: true,
+				CompressStreams: true, UseObjectStreams: true, CleanUnusedResources: true,
+				CleanFonts: true, SubsetFonts: true,
+				ImageUpperPPI: 200, ImageQuality: 80,
+			},
+		},
+		{
+			name:     "PDF/A-1 restrictions win over overrides",
+			level:    70,
+			pdfaPart: 1,
+			options:  entities.UniPDFOptionsConfig{SubsetFonts: &enabled, UseObjectStreams: &enabled},
+			expected: entities.UniPDFOptions{
+				CombineDuplicateDirectObjects: true, CombineIdenticalIndirectObjects: true, CombineDuplicateStreams: true,
+				CompressStreams: true, CleanUnusedResources: true, CleanContentstream: true,
+				ImageUpperPPI: 80, ImageQuality: 30,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := entities.NewCompressionConfig(tt.level).WithUniPDFOptions(tt.options)
+			if tt.pdfaPart > 0 {
+				config = config.ForPDFA(tt.pdfaPart)
+			}
+			if options := config.UniPDFOptions(); options != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, options)
+			}
+
+			// Переопределения сохраняются при смене уровня
+			if other := config.WithLevel(tt.level); other.UniPDFOptions() != tt.expected {
+				t.Errorf("WithLevel lost UniPDF options: %+v", other.UniPDFOptions())
+			}
+		})
+	}
+}
+
+func TestCompressionConfig_KeepsMetadataKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		remove   bool
+		key      string
+		expected bool
+	}{
+		{name: "Metadata is not removed", remove: false, key: "Producer", expected: true},
+		{name: "Whitelisted key", remove: true, key: "DocID", expected: true},
+		{name: "Key outside whitelist", remove: true, key: "Producer", expected: false},
+		{name: "Whitelist is case sensitive", remove: true, key: "title", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := entities.NewCompressionConfig(50)
+			config.RemoveMetadata = tt.remove
+			config.KeepMetadataKeys = entities.DefaultMetadataKeep
+
+			if got := config.KeepsMetadataKey(tt.key); got != tt.expected {
+				t.Errorf("Expected KeepsMetadataKey(%q) = %v, got %v", tt.key, tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestCompressionConfig_MetadataStamp(t *testing.T) {
+	config := entities.NewCompressionConfig(70)
+	stamp := config.MetadataStamp(123456, time.Date(2024, 3, 5, 14, 7, 9, 0, time.UTC))
+
+	expected := map[string]string{
+		entities.MetadataKeyOriginalSize: "123456",
+		entities.MetadataKeyLevel:        "70",
+		entities.MetadataKeyDate:         "D:20240305140709Z",
+	}
+	for key, value := range expected {
+		if stamp[key] != value {
+			t.Errorf("Expected %s = %q, got %q", key, value, stamp[key])
+		}
+	}
+}
+
+func TestCompressionConfig_KeepsAnnotation(t *testing.T) {
+	tests := []struct {
+		name     string
+		keep     []string
+		subtype  string
+		expected bool
+	}{
+		{name: "Link is kept by default", keep: nil, subtype: "Link", expected: true},
+		{name: "Popup is removed by default", keep: nil, subtype: "Popup", expected: false},
+		{name: "Custom keep-list", keep: []string{"Text"}, subtype: "Text", expected: true},
+		{name: "Empty keep-list removes all", keep: []string{}, subtype: "Link", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := entities.AppCompressionConfig{KeepAnnotations: tt.keep}
+			config := entities.NewCompressionConfig(50)
+			config.KeepAnnotations = app.GetKeepAnnotations()
+
+			if got := config.KeepsAnnotation(tt.subtype); got != tt.expected {
+				t.Errorf("Expected KeepsAnnotation(%q) = %v, got %v", tt.subtype, tt.expected, got)
+			}
 		})
 	}
 }
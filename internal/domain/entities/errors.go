@@ -6,11 +6,34 @@ import "errors"
 var (
 	ErrInvalidCompressionLevel = errors.New("уровень сжатия должен быть от 10 до 90")
 	ErrInvalidImageQuality     = errors.New("качество изображения должно быть от 10 до 100")
-	ErrInvalidJPEGQuality      = errors.New("качество JPEG должно быть от 10 до 50 с шагом 5")
-	ErrInvalidPNGQuality       = errors.New("качество PNG должно быть от 10 до 50 с шагом 5")
+	ErrInvalidJPEGQuality      = errors.New("качество JPEG должно быть от 1 до 100")
+	ErrInvalidPNGQuality       = errors.New("качество PNG должно быть от 1 до 100")
+	ErrInvalidMinSavings       = errors.New("порог экономии должен быть от 0 до 100% и не меньше 0 байт")
+	ErrInvalidSignaturePolicy  = errors.New("политика подписей должна быть skip, warn или incremental")
+	ErrInvalidStrategy         = errors.New("стратегия best: алгоритм pdfcpu, unipdf или external, уровень 0 или от 10 до 90")
+	ErrNoValidStrategy         = errors.New("ни одна стратегия не дала корректного результата")
+	ErrInvalidTargetSize       = errors.New("целевой размер не может быть отрицательным")
+	ErrInvalidProfile          = errors.New("некорректный профиль")
+	ErrInvalidOutputValidation = errors.New("режим проверки результата должен быть strict или relaxed")
+	ErrOutputValidationFailed  = errors.New("сжатый файл не прошел проверку структуры")
+	ErrInvalidColorReduction   = errors.New("режим цветности изображений должен быть auto, off, gray или bitonal")
+	ErrInvalidImageConversion  = errors.New("режим смены формата изображений должен быть off, jpeg или webp")
+	ErrInvalidImageMetadata    = errors.New("политика метаданных изображений должна быть strip_all, keep_orientation_icc или keep_all")
+	ErrInvalidImageResize      = errors.New("некорректная рамка изображений")
+	ErrInvalidExternalCommand  = errors.New("некорректная внешняя команда сжатия")
+	ErrExternalCommandFailed   = errors.New("внешняя команда сжатия завершилась с ошибкой")
+	ErrExternalCommandTimeout  = errors.New("внешняя команда сжатия превысила таймаут")
+	ErrInvalidUniPDFOptions    = errors.New("некорректные параметры оптимизатора UniPDF")
+	ErrLinearizationFailed     = errors.New("результат не линеаризован")
+	ErrPostProcessingFailed    = errors.New("политики метаданных и очистки не применены к результату")
 	ErrFileNotFound            = errors.New("файл не найден")
 	ErrInvalidFileFormat       = errors.New("неверный формат файла")
 	ErrCompressionFailed       = errors.New("ошибка сжатия файла")
 	ErrDirectoryNotFound       = errors.New("директория не найдена")
 	ErrNoFilesFound            = errors.New("PDF файлы не найдены")
+	ErrPageCountMismatch       = errors.New("количество страниц после сжатия не совпадает с исходным")
+	ErrPasswordNotFound        = errors.New("файл зашифрован, подходящий пароль не найден")
+	ErrRevisionsNotRewritable  = errors.New("ревизии документа нельзя переписать по отдельности")
+	ErrOwnerPasswordNotFound   = errors.New("файл зашифрован, пароль владельца не найден: повторное шифрование заменило бы его")
+	ErrPDFAConformanceLost     = errors.New("после сжатия нарушено соответствие PDF/A")
 )
@@ -1,6 +1,7 @@
 package entities
 
 import (
+	"fmt"
 	"time"
 )
 
@@ -10,17 +11,84 @@ type PDFDocument struct {
 	Size         int64
 	ModifiedTime time.Time
 	Pages        int
+	Signatures   int   // Количество цифровых подписей
+	SignedBytes  int64 // Длина подписанной части файла (конец последнего /ByteRange), 0 — не определена
+	Revisions    int   // Количество ревизий: исходная и добавочные обновления; 0 — не определено
+	// Заявленное в XMP соответствие PDF/A
+	PDFAPart        int    // Часть стандарта (1, 2, 3), 0 — документ не заявлен как PDF/A
+	PDFAConformance string // Уровень соответствия (A, B, U)
+	OutputIntents   int    // Количество OutputIntent в каталоге
 }
 
+// SignatureOutcome итог обработки подписанного документа
+type SignatureOutcome string
+
+const (
+	// SignatureSkipped документ оставлен без изменений, подписи действительны
+	SignatureSkipped SignatureOutcome = "skipped"
+	// SignatureInvalidated документ сжат, подписи стали недействительны
+	SignatureInvalidated SignatureOutcome = "invalidated"
+	// SignaturePreserved документ сжат без изменения подписанных ревизий
+	SignaturePreserved SignatureOutcome = "preserved"
+)
+
+// FileType тип обрабатываемого файла
+type FileType string
+
+const (
+	FileTypePDF  FileType = "PDF"
+	FileTypeJPEG FileType = "JPEG"
+	FileTypePNG  FileType = "PNG"
+)
+
 // CompressionResult представляет результат сжатия
 type CompressionResult struct {
 	CurrentFile      string
+	Pages            int // Количество страниц (0 — не определено)
 	OriginalSize     int64
 	CompressedSize   int64
 	CompressionRatio float64
 	SavedSpace       int64
 	Success          bool
 	Error            error
+	Skipped          bool   // Файл оставлен без изменений
+	SkipReason       string // Причина пропуска
+	SkipError        error  // Типизированная причина пропуска, если она есть
+	Encrypted        bool   // Исходный файл зашифрован
+	Validated        bool   // Структура результата уже проверена компрессором (до повторного шифрования)
+	Linearized       bool   // Результат линеаризован (быстрый веб-просмотр)
+	Signatures       int    // Количество цифровых подписей в исходном файле
+	SignatureOutcome SignatureOutcome
+	PDFA             string // Сохраненное соответствие PDF/A, например PDF/A-2B
+	Strategy         string // Победившая стратегия алгоритма best, например unipdf@50
+	Profile          string // Профиль, настройки которого применены к файлу
+	Level            int    // Примененный уровень сжатия
+	// Удаленные объекты
+	RemovedAnnotations int // Аннотации, кроме аннотаций-вложений
+	RemovedAttachments int // Встроенные файлы и аннотации-вложения
+	// Удаленные редко нужные данные
+	RemovedThumbnails   int // Встроенные миниатюры страниц
+	RemovedScripts      int // Действия и сценарии JavaScript
+	RemovedDestinations int // Неиспользуемые именованные назначения
+	RemovedPieceInfo    int // Словари частных данных приложений
+	RemovedRevisions    int // Отброшенные добавочные обновления
+	// Оптимизация шрифтов
+	RemovedFonts      int   // Удалены как неиспользуемые
+	DeduplicatedFonts int   // Программа шрифта объединена с одинаковой
+	SubsettedFonts    int   // Программы TrueType, сокращенные до используемых глифов
+	FontSavings       int64 // Экономия на шрифтах в байтах
+	// Уменьшение цветности изображений
+	GrayscaleImages int // Изображения, переведенные в оттенки серого
+	BitonalImages   int // Изображения, переведенные в 1 бит (CCITT G4)
+	// Пересжатие потоков без потерь
+	RecompressedStreams int   // Потоки, пересжатые Deflate с максимальной степенью
+	StreamSavings       int64 // Экономия на потоках в байтах
+	// Режим целевого размера
+	TargetSize    int64 // Целевой размер в байтах (0 — режим выключен)
+	TargetReached bool  // Результат уложился в целевой размер
+	Attempts      int   // Количество попыток сжатия при подборе уровня
+	// Тип исходного файла для статистики по типам; пусто — статистика по типу не ведется
+	FileType FileType
 }
 
 // CalculateCompressionRatio вычисляет коэффициент сжатия
@@ -35,3 +103,67 @@ func (cr *CompressionResult) CalculateCompressionRatio() {
 func (cr *CompressionResult) IsEffective() bool {
 	return cr.Success && cr.CompressionRatio > 0
 }
+
+// Skip отмечает файл как пропущенный: результат сжатия отброшен, файл остается исходным
+func (cr *CompressionResult) Skip(reason string) {
+	cr.Skipped = true
+	cr.SkipReason = reason
+	cr.CompressedSize = cr.OriginalSize
+	cr.CalculateCompressionRatio()
+}
+
+// SkipWithError отмечает файл как пропущенный по причине из доменных ошибок
+func (cr *CompressionResult) SkipWithError(err error) {
+	cr.Skip(err.Error())
+	cr.SkipError = err
+}
+
+// VerifyPageCount проверяет, что в выходном документе столько же страниц, сколько в исходном.
+// Если количество страниц исходного документа не определено, проверка пропускается.
+func (d *PDFDocument) VerifyPageCount(output *PDFDocument) error {
+	if d.Pages == 0 {
+		return nil
+	}
+	if output.Pages != d.Pages {
+		return fmt.Errorf("%w: %d → %d", ErrPageCountMismatch, d.Pages, output.Pages)
+	}
+	return nil
+}
+
+// IsSigned проверяет, содержит ли документ цифровые подписи
+func (d *PDFDocument) IsSigned() bool {
+	return d.Signatures > 0
+}
+
+// HasIncrementalUpdates проверяет, дописывались ли к документу добавочные обновления
+func (d *PDFDocument) HasIncrementalUpdates() bool {
+	return d.Revisions > 1
+}
+
+// IsPDFA проверяет, заявлено ли соответствие PDF/A
+func (d *PDFDocument) IsPDFA() bool {
+	return d.PDFAPart > 0
+}
+
+// PDFAName возвращает заявленное соответствие в виде PDF/A-2B
+func (d *PDFDocument) PDFAName() string {
+	if !d.IsPDFA() {
+		return ""
+	}
+	return fmt.Sprintf("PDF/A-%d%s", d.PDFAPart, d.PDFAConformance)
+}
+
+// VerifyPDFA проверяет, что выходной документ сохранил заявление PDF/A и OutputIntent исходного.
+// Для документов без заявления PDF/A проверка пропускается.
+func (d *PDFDocument) VerifyPDFA(output *PDFDocument) error {
+	if !d.IsPDFA() {
+		return nil
+	}
+	if output.PDFAPart != d.PDFAPart || output.PDFAConformance != d.PDFAConformance {
+		return fmt.Errorf("%w: %s → %q", ErrPDFAConformanceLost, d.PDFAName(), output.PDFAName())
+	}
+	if output.OutputIntents < d.OutputIntents {
+		return fmt.Errorf("%w: удален OutputIntent", ErrPDFAConformanceLost)
+	}
+	return nil
+}
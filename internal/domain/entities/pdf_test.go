@@ -1,6 +1,7 @@
 package entities_test
 
 import (
+	"errors"
 	"testing"
 
 	"compress/internal/domain/entities"
@@ -110,3 +111,61 @@ func TestCompressionResult_IsEffective(t *testing.T) {
 		})
 	}
 }
+
+func TestPDFDocument_VerifyPageCount(t *testing.T) {
+	tests := []struct {
+		name        string
+		inputPages  int
+		outputPages int
+		wantErr     bool
+	}{
+		{"Same page count", 10, 10, false},
+		{"Pages dropped", 10, 9, true},
+		{"Unreadable output", 10, 0, true},
+		{"Unknown input page count", 0, 5, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := &entities.PDFDocument{Pages: tt.inputPages}
+			output := &entities.PDFDocument{Pages: tt.outputPages}
+
+			err := input.VerifyPageCount(output)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("VerifyPageCount() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, entities.ErrPageCountMismatch) {
+				t.Errorf("Expected ErrPageCountMismatch, got %v", err)
+			}
+		})
+	}
+}
+
+func TestPDFDocument_VerifyPDFA(t *testing.T) {
+	pdfa2b := entities.PDFDocument{PDFAPart: 2, PDFAConformance: "B", OutputIntents: 1}
+
+	tests := []struct {
+		name    string
+		input   entities.PDFDocument
+		output  entities.PDFDocument
+		wantErr bool
+	}{
+		{"Claim and OutputIntent kept", pdfa2b, pdfa2b, false},
+		{"Claim lost", pdfa2b, entities.PDFDocument{OutputIntents: 1}, true},
+		{"Conformance changed", pdfa2b, entities.PDFDocument{PDFAPart: 2, PDFAConformance: "U", OutputIntents: 1}, true},
+		{"OutputIntent removed", pdfa2b, entities.PDFDocument{PDFAPart: 2, PDFAConformance: "B"}, true},
+		{"Not a PDF/A document", entities.PDFDocument{}, entities.PDFDocument{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.input.VerifyPDFA(&tt.output)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("VerifyPDFA() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, entities.ErrPDFAConformanceLost) {
+				t.Errorf("Expected ErrPDFAConformanceLost, got %v", err)
+			}
+		})
+	}
+}
@@ -15,6 +15,7 @@ type FileRepository interface {
 	FileExists(path string) bool
 	CreateDirectory(path string) error
 	ListPDFFiles(directory string) ([]string, error)
+	ValidatePDF(path string, strict bool) error
 }
 
 // ConfigRepository интерфейс для работы с конфигурацией
@@ -22,3 +23,18 @@ type ConfigRepository interface {
 	GetCompressionConfig(level int) (*entities.CompressionConfig, error)
 	ValidateConfig(config *entities.CompressionConfig) error
 }
+
+// PasswordProvider интерфейс источника паролей для зашифрованных PDF
+type PasswordProvider interface {
+	Passwords(pdfPath string) []string
+}
+
+// Linearizer интерфейс линеаризации PDF (быстрый веб-просмотр)
+type Linearizer interface {
+	Linearize(inputPath, outputPath string) error
+}
+
+// PDFAnalyzer интерфейс анализа размера PDF по категориям
+type PDFAnalyzer interface {
+	Analyze(path string) (*entities.PDFAnalysis, error)
+}
@@ -0,0 +1,142 @@
+package compressors
+
+import (
+	"fmt"
+	"os"
+
+	"compress/internal/domain/entities"
+	"compress/internal/domain/repositories"
+)
+
+// BestStrategy вариант сжатия для BestCompressor: движок и уровень
+type BestStrategy struct {
+	Config     entities.StrategyConfig
+	Compressor repositories.PDFCompressor
+}
+
+// BestCompressor составной компрессор: сжимает файл каждой стратегией во временный файл
+// и оставляет наименьший результат, прошедший проверку
+type BestCompressor struct {
+	strategies []BestStrategy
+	fileRepo   repositories.FileRepository
+}
+
+// NewBestCompressor создает составной компрессор
+func NewBestCompressor(strategies []BestStrategy, fileRepo repositories.FileRepository) *BestCompressor {
+	return &BestCompressor{
+		strategies: strategies,
+		fileRepo:   fileRepo,
+	}
+}
+
+// Compress сжимает PDF всеми стратегиями и выбирает наименьший корректный результат
+func (b *BestCompressor) Compress(inputPath, outputPath string, config *entities.CompressionConfig) (*entities.CompressionResult, error) {
+	fmt.Printf("🏁 Подбор лучшей стратегии сжатия (вариантов: %d)...\n", len(b.strategies))
+
+	// Сведения об исходном файле обычно уже получены вызывающим
+	input := config.SourceDocument(inputPath)
+	if input == nil {
+		var err error
+		input, err = b.fileRepo.GetFileInfo(inputPath)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка получения информации об исходном файле: %w", err)
+		}
+	}
+
+	var (
+		best     *entities.CompressionResult
+		bestPath string
+		bestSize int64
+		bestName string
+		lastErr  error
+	)
+
+	for i, strategy := range b.strategies {
+		// При подборе целевого размера уровень задает поиск, а не стратегия
+		definition := strategy.Config
+		if config.SearchLevel {
+			definition.Level = 0
+		}
+		name := definition.Name(config.Level)
+		strategyConfig := config
+		if definition.Level != 0 && definition.Level != config.Level {
+			strategyConfig = config.WithLevel(definition.Level)
+		}
+
+		candidatePath := fmt.Sprintf("%s.best%d", outputPath, i)
+		result, err := strategy.Compressor.Compress(inputPath, candidatePath, strategyConfig)
+		if err == nil {
+			err = b.validate(input, candidatePath, strategyConfig)
+		}
+		if err != nil {
+			fmt.Printf("⚠️  Стратегия %s отклонена: %v\n", name, err)
+			_ = os.Remove(candidatePath)
+			lastErr = err
+			continue
+		}
+
+		info, err := os.Stat(candidatePath)
+		if err != nil {
+			_ = os.Remove(candidatePath)
+			lastErr = err
+			continue
+		}
+
+		fmt.Printf("📏 Стратегия %s: %.2f MB\n", name, float64(info.Size())/1024/1024)
+		if best != nil && info.Size() >= bestSize {
+			_ = os.Remove(candidatePath)
+			continue
+		}
+
+		if best != nil {
+			_ = os.Remove(bestPath)
+		}
+		best, bestPath, bestSize, bestName = result, candidatePath, info.Size(), name
+	}
+
+	if best == nil {
+		err := fmt.Errorf("%w: %v", entities.ErrNoValidStrategy, lastErr)
+		return &entities.CompressionResult{
+			OriginalSize: input.Size,
+			Success:      false,
+			Error:        err,
+		}, err
+	}
+
+	if err := os.Rename(bestPath, outputPath); err != nil {
+		_ = os.Remove(bestPath)
+		return &entities.CompressionResult{
+			OriginalSize: input.Size,
+			Success:      false,
+			Error:        err,
+		}, fmt.Errorf("ошибка сохранения результата: %w", err)
+	}
+
+	best.OriginalSize = input.Size
+	best.CompressedSize = bestSize
+	best.Strategy = bestName
+	best.CalculateCompressionRatio()
+
+	fmt.Printf("🏆 Лучшая стратегия: %s\n", bestName)
+	return best, nil
+}
+
+// validate проверяет результат стратегии: структура, количество страниц и, если стратегия
+// сжимала с ограничениями PDF/A, сохранение соответствия
+func (b *BestCompressor) validate(input *entities.PDFDocument, candidatePath string, config *entities.CompressionConfig) error {
+	if err := b.fileRepo.ValidatePDF(candidatePath, config.StrictValidation); err != nil {
+		return fmt.Errorf("результат не прошел валидацию: %w", err)
+	}
+
+	output, err := b.fileRepo.GetFileInfo(candidatePath)
+	if err != nil {
+		return err
+	}
+	if err := input.VerifyPageCount(output); err != nil {
+		return err
+	}
+	if config.PDFAPart > 0 {
+		return input.VerifyPDFA(output)
+	}
+	return nil
+}
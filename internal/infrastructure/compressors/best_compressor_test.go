@@ -0,0 +1,99 @@
+package compressors
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"compress/internal/domain/entities"
+)
+
+// staticInfoRepo возвращает сведения об исходном файле по его пути, а для остальных файлов
+// описывает одностраничный документ без заявления PDF/A
+type staticInfoRepo struct {
+	input *entities.PDFDocument
+}
+
+func (r *staticInfoRepo) GetFileInfo(path string) (*entities.PDFDocument, error) {
+	if path == r.input.Path {
+		return r.input, nil
+	}
+	return &entities.PDFDocument{Path: path, Pages: r.input.Pages}, nil
+}
+
+func (r *staticInfoRepo) FileExists(string) bool                { return true }
+func (r *staticInfoRepo) CreateDirectory(string) error          { return nil }
+func (r *staticInfoRepo) ListPDFFiles(string) ([]string, error) { return nil, nil }
+func (r *staticInfoRepo) ValidatePDF(string, bool) error        { return nil }
+
+// levelRecordingCompressor записывает пустой результат и запоминает уровни вызовов
+type levelRecordingCompressor struct {
+	levels []int
+}
+
+func (c *levelRecordingCompressor) Compress(inputPath, outputPath string, config *entities.CompressionConfig) (*entities.CompressionResult, error) {
+	c.levels = append(c.levels, config.Level)
+	if err := os.WriteFile(outputPath, []byte("%PDF-1.7\n"), 0o644); err != nil {
+		return nil, err
+	}
+	return &entities.CompressionResult{Success: true}, nil
+}
+
+func TestBestCompressorPDFA(t *testing.T) {
+	dir := t.TempDir()
+	input := &entities.PDFDocument{Path: filepath.Join(dir, "in.pdf"), Size: 100, Pages: 1, PDFAPart: 2, PDFAConformance: "B"}
+
+	tests := []struct {
+		name    string
+		config  *entities.CompressionConfig
+		wantErr error
+	}{
+		// Без режима PDF/A потеря заявления соответствия не отклоняет результат
+		{"Preserve off", entities.NewCompressionConfig(50), nil},
+		{"Preserve on", entities.NewCompressionConfig(50).ForPDFA(2), entities.ErrNoValidStrategy},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			strategies := []BestStrategy{{Config: entities.StrategyConfig{Algorithm: "pdfcpu"}, Compressor: &levelRecordingCompressor{}}}
+			best := NewBestCompressor(strategies, &staticInfoRepo{input: input})
+
+			_, err := best.Compress(input.Path, filepath.Join(dir, "out.pdf"), tt.config.WithSource(input))
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestBestCompressorTargetSearchLevel(t *testing.T) {
+	dir := t.TempDir()
+	input := &entities.PDFDocument{Path: filepath.Join(dir, "in.pdf"), Size: 100, Pages: 1}
+
+	tests := []struct {
+		name     string
+		config   *entities.CompressionConfig
+		level    int
+		strategy string
+	}{
+		{"Fixed strategy level", entities.NewCompressionConfig(30), 70, "unipdf@70"},
+		{"Search level wins", entities.NewCompressionConfig(50).TargetSizeSteps(false)[2], 30, "unipdf@30"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compressor := &levelRecordingCompressor{}
+			strategies := []BestStrategy{{Config: entities.StrategyConfig{Algorithm: "unipdf", Level: 70}, Compressor: compressor}}
+			best := NewBestCompressor(strategies, &staticInfoRepo{input: input})
+
+			result, err := best.Compress(input.Path, filepath.Join(dir, "out.pdf"), tt.config.WithSource(input))
+			if err != nil {
+				t.Fatalf("Compress: %v", err)
+			}
+			if len(compressor.levels) != 1 || compressor.levels[0] != tt.level || result.Strategy != tt.strategy {
+				t.Errorf("Expected level %d as %s, got levels %v as %s", tt.level, tt.strategy, compressor.levels, result.Strategy)
+			}
+		})
+	}
+}
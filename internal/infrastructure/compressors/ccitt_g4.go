@@ -0,0 +1,196 @@
+package compressors
+
+import (
+	"image"
+	"sort"
+)
+
+// Коды режимов двумерного кодирования T.6
+const (
+	ccittPassCode       = "0001"
+	ccittHorizontalCode = "001"
+	ccittEOL            = "000000000001"
+)
+
+// ccittVerticalCodes коды вертикального режима для смещений a1 относительно b1 от -3 до 3
+var ccittVerticalCodes = [7]string{"0000010", "000010", "010", "1", "011", "000011", "0000011"}
+
+// ccittWhiteTerminating коды серий белых пикселей длиной 0–63
+var ccittWhiteTerminating = [64]string{
+	"00110101", "000111", "0111", "1000", "1011", "1100", "1110", "1111",
+	"10011", "10100", "00111", "01000", "001000", "000011", "110100", "110101",
+	"101010", "101011", "0100111", "0001100", "0001000", "0010111", "0000011", "0000100",
+	"0101000", "0101011", "0010011", "0100100", "0011000", "00000010", "00000011", "00011010",
+	"00011011", "00010010", "00010011", "00010100", "00010101", "00010110", "00010111", "00101000",
+	"00101001", "00101010", "00101011", "00101100", "00101101", "00000100", "00000101", "00001010",
+	"00001011", "01010010", "01010011", "01010100", "01010101", "00100100", "00100101", "01011000",
+	"01011001", "01011010", "01011011", "01001010", "01001011", "00110010", "00110011", "00110100",
+}
+
+// ccittBlackTerminating коды серий черных пикселей длиной 0–63
+var ccittBlackTerminating = [64]string{
+	"0000110111", "010", "11", "10", "011", "0011", "0010", "00011",
+	"000101", "000100", "0000100", "0000101", "0000111", "00000100", "00000111", "000011000",
+	"0000010111", "0000011000", "0000001000", "00001100111", "00001101000", "00001101100", "00000110111", "00000101000",
+	"00000010111", "00000011000", "000011001010", "000011001011", "000011001100", "000011001101", "000001101000", "000001101001",
+	"000001101010", "000001101011", "000011010010", "000011010011", "000011010100", "000011010101", "000011010110", "000011010111",
+	"000001101100", "000001101101", "000011011010", "000011011011", "000001010100", "000001010101", "000001010110", "000001010111",
+	"000001100100", "000001100101", "000001010010", "000001010011", "000000100100", "000000110111", "000000111000", "000000100111",
+	"000000101000", "000001011000", "000001011001", "000000101011", "000000101100", "000001011010", "000001100110", "000001100111",
+}
+
+// ccittWhiteMakeup коды дополнения белых серий длиной 64–1728 с шагом 64
+var ccittWhiteMakeup = [27]string{
+	"11011", "10010", "010111", "0110111", "00110110", "00110111", "01100100", "01100101",
+	"01101000", "01100111", "011001100", "011001101", "011010010", "011010011", "011010100", "011010101",
+	"011010110", "011010111", "011011000", "011011001", "011011010", "011011011", "010011000", "010011001",
+	"010011010", "011000", "010011011",
+}
+
+// ccittBlackMakeup коды дополнения черных серий длиной 64–1728 с шагом 64
+var ccittBlackMakeup = [27]string{
+	"0000001111", "000011001000", "000011001001", "000001011011", "000000110011", "000000110100", "000000110101", "0000001101100",
+	"0000001101101", "0000001001010", "0000001001011", "0000001001100", "0000001001101", "0000001110010", "0000001110011", "0000001110100",
+	"0000001110101", "0000001110110", "0000001110111", "0000001010010", "0000001010011", "0000001010100", "0000001010101", "0000001011010",
+	"0000001011011", "0000001100100", "0000001100101",
+}
+
+// ccittExtendedMakeup общие коды дополнения серий длиной 1792–2560 с шагом 64
+var ccittExtendedMakeup = [13]string{
+	"00000001000", "00000001100", "00000001101", "000000010010", "000000010011", "000000010100", "000000010101",
+	"000000010110", "000000010111", "000000011100", "000000011101", "000000011110", "000000011111",
+}
+
+// encodeCCITTG4 кодирует изображение как двухцветное по T.6 (CCITT Group 4, /K -1):
+// пиксели темнее порога черные. Результат соответствует параметрам CCITTFaxDecode
+// по умолчанию (BlackIs1 false, EndOfBlock true) и при декодировании дает 0 для черного
+func encodeCCITTG4(gray *image.Gray, threshold uint8) []byte {
+	bounds := gray.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	w := &bitWriter{}
+	// Опорная строка над первой строкой изображения белая
+	var reference, coding []int
+	for y := 0; y < height; y++ {
+		row := gray.Pix[y*gray.Stride : y*gray.Stride+width]
+		coding = changingElements(coding[:0], row, threshold)
+		encodeCCITTG4Line(w, coding, reference, width)
+		reference, coding = coding, reference
+	}
+
+	// EOFB
+	w.writeCode(ccittEOL)
+	w.writeCode(ccittEOL)
+	return w.bytes()
+}
+
+// changingElements возвращает позиции пикселей строки, цвет которых отличается от цвета
+// предыдущего пикселя (перед строкой — белый). Элементы с четными индексами черные
+func changingElements(dst []int, row []byte, threshold uint8) []int {
+	black := false
+	for x, v := range row {
+		if (v < threshold) != black {
+			dst = append(dst, x)
+			black = !black
+		}
+	}
+	return dst
+}
+
+// encodeCCITTG4Line кодирует строку по изменяющимся элементам строки и опорной строки
+func encodeCCITTG4Line(w *bitWriter, coding, reference []int, width int) {
+	a0, black := -1, false
+	for a0 < width {
+		a1 := nextChangingElement(coding, a0, width)
+		b1, b2 := referenceElements(reference, a0, black, width)
+
+		switch {
+		case b2 < a1:
+			w.writeCode(ccittPassCode)
+			a0 = b2
+
+		case a1-b1 >= -3 && a1-b1 <= 3:
+			w.writeCode(ccittVerticalCodes[a1-b1+3])
+			a0 = a1
+			black = !black
+
+		default:
+			a2 := nextChangingElement(coding, a1, width)
+			w.writeCode(ccittHorizontalCode)
+			writeCCITTRun(w, a1-max(a0, 0), black)
+			writeCCITTRun(w, a2-a1, !black)
+			a0 = a2
+		}
+	}
+}
+
+// nextChangingElement возвращает первый изменяющийся элемент правее pos или width
+func nextChangingElement(elements []int, pos, width int) int {
+	i := sort.SearchInts(elements, pos+1)
+	if i < len(elements) {
+		return elements[i]
+	}
+	return width
+}
+
+// referenceElements возвращает b1 — первый изменяющийся элемент опорной строки правее a0
+// с цветом, противоположным цвету a0, — и следующий за ним b2
+func referenceElements(reference []int, a0 int, black bool, width int) (int, int) {
+	i := sort.SearchInts(reference, a0+1)
+	// Цвет элемента с индексом i: черный для четных индексов
+	if (i%2 == 0) == black {
+		i++
+	}
+	b1, b2 := width, width
+	if i < len(reference) {
+		b1 = reference[i]
+	}
+	if i+1 < len(reference) {
+		b2 = reference[i+1]
+	}
+	return b1, b2
+}
+
+// writeCCITTRun записывает длину серии кодами дополнения и завершающим кодом
+func writeCCITTRun(w *bitWriter, run int, black bool) {
+	terminating, makeup := &ccittWhiteTerminating, &ccittWhiteMakeup
+	if black {
+		terminating, makeup = &ccittBlackTerminating, &ccittBlackMakeup
+	}
+
+	for run > 2560 {
+		w.writeCode(ccittExtendedMakeup[len(ccittExtendedMakeup)-1])
+		run -= 2560
+	}
+	switch {
+	case run >= 1792:
+		w.writeCode(ccittExtendedMakeup[(run-1792)/64])
+	case run >= 64:
+		w.writeCode(makeup[run/64-1])
+	}
+	w.writeCode(terminating[run%64])
+}
+
+// bitWriter записывает биты старшим вперед
+type bitWriter struct {
+	data  []byte
+	nbits int
+}
+
+// writeCode записывает код, заданный строкой из символов 0 и 1
+func (w *bitWriter) writeCode(code string) {
+	for i := 0; i < len(code); i++ {
+		if w.nbits%8 == 0 {
+			w.data = append(w.data, 0)
+		}
+		if code[i] == '1' {
+			w.data[len(w.data)-1] |= 0x80 >> (w.nbits % 8)
+		}
+		w.nbits++
+	}
+}
+
+// bytes возвращает записанные данные, последний байт дополнен нулями
+func (w *bitWriter) bytes() []byte {
+	return w.data
+}
@@ -0,0 +1,110 @@
+package compressors
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"io"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/ccitt"
+)
+
+// runCodes коды серий одного цвета: завершающие и коды дополнения
+func runCodes(terminating *[64]string, makeup *[27]string) map[string]int {
+	codes := make(map[string]int)
+	for run, code := range terminating {
+		codes[code] = run
+	}
+	for i, code := range makeup {
+		codes[code] = (i + 1) * 64
+	}
+	for i, code := range ccittExtendedMakeup {
+		codes[code] = 1792 + i*64
+	}
+	return codes
+}
+
+func TestEncodeCCITTG4(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+
+	tests := []struct {
+		name   string
+		width  int
+		height int
+		pixel  func(x, y int) bool
+	}{
+		{"Blank page", 1728, 4, func(x, y int) bool { return false }},
+		{"Black page", 100, 3, func(x, y int) bool { return true }},
+		{"Long runs with extended makeup codes", 6000, 3, func(x, y int) bool { return x >= 100+y && x < 5800-y*70 }},
+		{"Text-like strokes", 300, 40, func(x, y int) bool { return (x/7+y/5)%3 == 0 && y%9 != 0 }},
+		{"Noise", 257, 50, func(x, y int) bool { return random.Intn(4) == 0 }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gray := image.NewGray(image.Rect(0, 0, tt.width, tt.height))
+			for y := 0; y < tt.height; y++ {
+				for x := 0; x < tt.width; x++ {
+					if !tt.pixel(x, y) {
+						gray.SetGray(x, y, color.Gray{Y: 0xff})
+					}
+				}
+			}
+
+			data := encodeCCITTG4(gray, 0x80)
+
+			// Декодер x/image: 0x00 — черный, 0xFF — белый
+			decoded := image.NewGray(gray.Bounds())
+			if err := ccitt.DecodeIntoGray(decoded, bytes.NewReader(data), ccitt.MSB, ccitt.Group4, nil); err != nil {
+				t.Fatalf("DecodeIntoGray: %v", err)
+			}
+			for y := 0; y < tt.height; y++ {
+				for x := 0; x < tt.width; x++ {
+					if (decoded.GrayAt(x, y).Y == 0) != (gray.GrayAt(x, y).Y < 0x80) {
+						t.Fatalf("Pixel (%d, %d) differs after decoding", x, y)
+					}
+				}
+			}
+
+			// Без заданной высоты декодер останавливается на EOFB после последней строки
+			rows, err := io.ReadAll(ccitt.NewReader(bytes.NewReader(data), ccitt.MSB, ccitt.Group4, tt.width, ccitt.AutoDetectHeight, nil))
+			if err != nil {
+				t.Fatalf("Decoding up to EOFB: %v", err)
+			}
+			if stride := (tt.width + 7) / 8; len(rows) != stride*tt.height {
+				t.Errorf("Expected %d rows before EOFB, got %d bytes", tt.height, len(rows))
+			}
+		})
+	}
+}
+
+func TestCCITTCodesArePrefixFree(t *testing.T) {
+	var modes []string
+	modes = append(modes, ccittPassCode, ccittHorizontalCode, ccittEOL)
+	modes = append(modes, ccittVerticalCodes[:]...)
+
+	sets := map[string]map[string]int{
+		"modes": {},
+		"white": runCodes(&ccittWhiteTerminating, &ccittWhiteMakeup),
+		"black": runCodes(&ccittBlackTerminating, &ccittBlackMakeup),
+	}
+	for _, code := range modes {
+		sets["modes"][code] = 0
+	}
+	if len(sets["white"]) != 104 || len(sets["black"]) != 104 {
+		t.Fatalf("Expected 104 distinct codes per color, got white=%d black=%d", len(sets["white"]), len(sets["black"]))
+	}
+
+	for name, codes := range sets {
+		for a := range codes {
+			for b := range codes {
+				if a != b && strings.HasPrefix(b, a) {
+					t.Errorf("%s: code %s is a prefix of %s", name, a, b)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package compressors
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// webpMaxDimension наибольшая сторона изображения, которую принимает cwebp
+const webpMaxDimension = 1<<14 - 1
+
+// CWebPEncoder кодирование WebP внешней утилитой cwebp из libwebp
+type CWebPEncoder struct {
+	binary  string
+	timeout time.Duration
+}
+
+// NewCWebPEncoder создает кодировщик; пустой путь означает поиск cwebp в PATH.
+// Процесс cwebp, не завершившийся за timeout, останавливается
+func NewCWebPEncoder(binary string, timeout time.Duration) *CWebPEncoder {
+	if binary == "" {
+		binary = "cwebp"
+	}
+	return &CWebPEncoder{binary: binary, timeout: timeout}
+}
+
+// Encode кодирует изображение в WebP без потерь или с потерями с качеством quality (1-100).
+// Изображение передается cwebp через временный PNG без сжатия
+func (e *CWebPEncoder) Encode(w io.Writer, img image.Image, lossless bool, quality int) error {
+	path, err := exec.LookPath(e.binary)
+	if err != nil {
+		return fmt.Errorf("cwebp не найден (%s): %w", e.binary, err)
+	}
+
+	dir, err := os.MkdirTemp("", "cwebp-*")
+	if err != nil {
+		return fmt.Errorf("не удалось создать временную директорию: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	var input bytes.Buffer
+	if err := (&png.Encoder{CompressionLevel: png.NoCompression}).Encode(&input, img); err != nil {
+		return fmt.Errorf("не удалось подготовить изображение для cwebp: %w", err)
+	}
+	inputPath := filepath.Join(dir, "in.png")
+	outputPath := filepath.Join(dir, "out.webp")
+	if err := os.WriteFile(inputPath, input.Bytes(), 0o600); err != nil {
+		return fmt.Errorf("не удалось записать временный файл: %w", err)
+	}
+
+	// Метаданные cwebp не переносит: их записывает embedWebPMetadata
+	args := []string{"-quiet", "-metadata", "none"}
+	if lossless {
+		args = append(args, "-lossless")
+	} else {
+		args = append(args, "-q", strconv.Itoa(quality))
+	}
+	args = append(args, inputPath, "-o", outputPath)
+
+	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = externalWaitDelay
+	configureProcessGroup(cmd)
+
+	err = cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("cwebp не завершился за %s", e.timeout)
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка cwebp: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	data, err := os.ReadFile(outputPath)
+	if err != nil {
+		return fmt.Errorf("cwebp не записал результат: %w", err)
+	}
+	_, err = w.Write(data)
+	return err
+}
@@ -0,0 +1,431 @@
+package compressors
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/rc4"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+
+	"compress/internal/domain/entities"
+	"compress/internal/domain/repositories"
+)
+
+// EncryptedPDFCompressor обертка над компрессором для зашифрованных PDF:
+// подбирает пароль, расшифровывает документ во временный файл, сжимает его
+// вложенным компрессором и шифрует результат с теми же правами и стойкостью
+type EncryptedPDFCompressor struct {
+	compressor repositories.PDFCompressor
+	passwords  repositories.PasswordProvider
+	fileRepo   repositories.FileRepository
+}
+
+// NewEncryptedPDFCompressor создает компрессор с поддержкой зашифрованных PDF
+func NewEncryptedPDFCompressor(
+	compressor repositories.PDFCompressor,
+	passwords repositories.PasswordProvider,
+	fileRepo repositories.FileRepository,
+) *EncryptedPDFCompressor {
+	return &EncryptedPDFCompressor{
+		compressor: compressor,
+		passwords:  passwords,
+		fileRepo:   fileRepo,
+	}
+}
+
+// pdfEncryption пароли и параметры шифрования исходного документа
+type pdfEncryption struct {
+	UserPW      string
+	OwnerPW     string
+	OwnerKnown  bool
+	Permissions int
+	KeyLength   int
+	AES         bool
+}
+
+// Compress сжимает PDF, при необходимости расшифровывая и снова шифруя его
+func (c *EncryptedPDFCompressor) Compress(inputPath, outputPath string, config *entities.CompressionConfig) (*entities.CompressionResult, error) {
+	encrypted, err := hasEncryptDictionary(inputPath)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения исходного файла: %w", err)
+	}
+	if encrypted {
+		// Строка /Encrypt могла встретиться вне трейлера: уточняем по самому документу
+		if ctx, err := openPDFWithPasswords(inputPath, "", ""); err == nil && ctx.E == nil {
+			encrypted = false
+		}
+	}
+	if !encrypted {
+		return c.compressor.Compress(inputPath, outputPath, config)
+	}
+
+	originalInfo, err := os.Stat(inputPath)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения информации об исходном файле: %w", err)
+	}
+
+	skipped := func(reason error) (*entities.CompressionResult, error) {
+		result := &entities.CompressionResult{
+			OriginalSize: originalInfo.Size(),
+			Success:      true,
+			Encrypted:    true,
+		}
+		result.SkipWithError(reason)
+		return result, nil
+	}
+
+	fmt.Println("🔐 Файл зашифрован, подбор пароля...")
+	encryption := unlockPDF(inputPath, c.passwords.Passwords(inputPath))
+	if encryption == nil {
+		return skipped(entities.ErrPasswordNotFound)
+	}
+	// Без пароля владельца результат пришлось бы зашифровать новым паролем владельца,
+	// и исходный был бы утерян
+	if !encryption.OwnerKnown {
+		return skipped(entities.ErrOwnerPasswordNotFound)
+	}
+
+	failed := func(err error, format string) (*entities.CompressionResult, error) {
+		return &entities.CompressionResult{
+			OriginalSize: originalInfo.Size(),
+			Success:      false,
+			Encrypted:    true,
+			Error:        err,
+		}, fmt.Errorf(format, err)
+	}
+
+	decryptedPath := outputPath + ".decrypted"
+	compressedPath := outputPath + ".compressed"
+	defer os.Remove(decryptedPath)
+	defer os.Remove(compressedPath)
+
+	if err := api.DecryptFile(inputPath, decryptedPath, encryption.readConfiguration(model.DECRYPT)); err != nil {
+		return failed(err, "ошибка расшифровки PDF: %w")
+	}
+
+	result, err := c.compressor.Compress(decryptedPath, compressedPath, config)
+	if err != nil {
+		if result != nil {
+			result.Encrypted = true
+		}
+		return result, err
+	}
+
+	// Зашифрованный результат без пароля не открыть, поэтому проверяем его до шифрования
+	pages, err := c.verifyDecrypted(decryptedPath, compressedPath, config.StrictValidation)
+	if err != nil {
+		return failed(err, "%w")
+	}
+
+	if err := api.EncryptFile(compressedPath, outputPath, encryption.encryptConfiguration()); err != nil {
+		return failed(err, "ошибка повторного шифрования PDF: %w")
+	}
+	if err := verifyEncrypted(outputPath, encryption, pages); err != nil {
+		return failed(err, "%w")
+	}
+
+	compressedInfo, err := os.Stat(outputPath)
+	if err != nil {
+		return failed(err, "ошибка получения информации о сжатом файле: %w")
+	}
+
+	result.OriginalSize = originalInfo.Size()
+	result.CompressedSize = compressedInfo.Size()
+	result.Encrypted = true
+	result.Validated = true
+	result.CalculateCompressionRatio()
+
+	fmt.Println("🔐 Результат зашифрован повторно")
+	return result, nil
+}
+
+// verifyDecrypted проверяет расшифрованный результат сжатия: структуру и количество страниц.
+// Возвращает количество страниц результата
+func (c *EncryptedPDFCompressor) verifyDecrypted(decryptedPath, compressedPath string, strict bool) (int, error) {
+	if err := c.fileRepo.ValidatePDF(compressedPath, strict); err != nil {
+		return 0, fmt.Errorf("%w: %v", entities.ErrOutputValidationFailed, err)
+	}
+
+	input, err := c.fileRepo.GetFileInfo(decryptedPath)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка получения информации о расшифрованном файле: %w", err)
+	}
+	output, err := c.fileRepo.GetFileInfo(compressedPath)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка получения информации о сжатом файле: %w", err)
+	}
+	if err := input.VerifyPageCount(output); err != nil {
+		return 0, err
+	}
+
+	return output.Pages, nil
+}
+
+// verifyEncrypted проверяет, что повторно зашифрованный документ открывается паролем
+// пользователя и содержит столько же страниц, сколько расшифрованный результат
+func verifyEncrypted(path string, encryption *pdfEncryption, pages int) error {
+	ctx, err := openEncryptedPDF(path, encryption.UserPW, encryption.OwnerPW)
+	if err != nil {
+		return fmt.Errorf("%w: повторно зашифрованный файл не открывается: %v", entities.ErrOutputValidationFailed, err)
+	}
+	if err := ctx.EnsurePageCount(); err != nil {
+		return fmt.Errorf("%w: %v", entities.ErrOutputValidationFailed, err)
+	}
+	if ctx.PageCount != pages {
+		return fmt.Errorf("%w: %d → %d", entities.ErrPageCountMismatch, pages, ctx.PageCount)
+	}
+	return nil
+}
+
+// encryptScanWindow размер областей файла, в которых ищется ссылка на словарь шифрования
+const encryptScanWindow = 64 * 1024
+
+// startXRefPattern последнее смещение startxref в конце файла
+var startXRefPattern = regexp.MustCompile(`startxref\s+(\d+)\s+%%EOF\s*$`)
+
+// hasEncryptDictionary быстро проверяет, ссылается ли трейлер документа на словарь шифрования.
+// Словарь трейлера (и xref-потока) не сжимается, поэтому достаточно поиска по байтам
+// в конце файла и в начале последнего раздела xref, на который указывает startxref
+func hasEncryptDictionary(path string) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	tail, err := readFileRegion(file, max(0, info.Size()-encryptScanWindow), info.Size())
+	if err != nil {
+		return false, err
+	}
+	if bytes.Contains(tail, []byte("/Encrypt")) {
+		return true, nil
+	}
+
+	// Словарь xref-потока стоит перед его данными и может быть далеко от конца файла
+	match := startXRefPattern.FindSubmatch(tail)
+	if match == nil {
+		return false, nil
+	}
+	offset, err := strconv.ParseInt(string(match[1]), 10, 64)
+	if err != nil || offset >= info.Size() {
+		return false, nil
+	}
+	section, err := readFileRegion(file, offset, min(info.Size(), offset+encryptScanWindow))
+	if err != nil {
+		return false, err
+	}
+	return bytes.Contains(section, []byte("/Encrypt")), nil
+}
+
+// readFileRegion читает байты файла в диапазоне [from, to)
+func readFileRegion(file *os.File, from, to int64) ([]byte, error) {
+	buf := make([]byte, to-from)
+	n, err := file.ReadAt(buf, from)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+// unlockPDF подбирает пароли владельца и пользователя среди кандидатов.
+// Возвращает nil, если открыть документ не удалось ни одним паролем
+func unlockPDF(path string, candidates []string) *pdfEncryption {
+	// pdfcpu проверяет сначала пароль владельца, затем пароль пользователя.
+	// Заведомо неверный второй пароль позволяет понять, какой из них подошел
+	wrong := randomPassword()
+	candidates = append([]string{""}, candidates...)
+
+	for _, ownerPW := range candidates {
+		ctx, err := openEncryptedPDF(path, wrong, ownerPW)
+		if err != nil {
+			continue
+		}
+		for _, userPW := range candidates {
+			if _, err := openEncryptedPDF(path, userPW, wrong); err == nil {
+				return newPDFEncryption(ctx, userPW, ownerPW, true)
+			}
+		}
+		// Пароль пользователя вычисляется из записи O паролем владельца (RC4 и AES-128)
+		if userPW, ok := recoverUserPassword(ctx.E, ownerPW); ok {
+			if _, err := openEncryptedPDF(path, userPW, wrong); err == nil {
+				return newPDFEncryption(ctx, userPW, ownerPW, true)
+			}
+		}
+		// Для AES-256 пароль пользователя не восстанавливается, повторно зашифровать документ так же нельзя
+		return nil
+	}
+
+	for _, userPW := range candidates {
+		if ctx, err := openEncryptedPDF(path, userPW, wrong); err == nil {
+			return newPDFEncryption(ctx, userPW, "", false)
+		}
+	}
+
+	return nil
+}
+
+// recoverUserPassword восстанавливает пароль пользователя по паролю владельца
+// (алгоритм 7 ISO 32000-1: расшифровка записи O ключом из пароля владельца).
+// Для ревизий 5 и 6 (AES-256) пароль пользователя хранится только в виде хеша
+func recoverUserPassword(enc *model.Enc, ownerPW string) (string, bool) {
+	if enc == nil || enc.R < 2 || enc.R > 4 || len(enc.O) < 32 {
+		return "", false
+	}
+
+	// Алгоритм 3, шаги a–d: ключ RC4 из дополненного пароля владельца
+	padded := padPassword(ownerPW)
+	sum := md5.Sum(padded)
+	key := sum[:]
+	keyLength := 5
+	if enc.R >= 3 {
+		for i := 0; i < 50; i++ {
+			sum = md5.Sum(key)
+			key = sum[:]
+		}
+		keyLength = enc.L / 8
+		if keyLength < 5 || keyLength > 16 {
+			return "", false
+		}
+	}
+	key = key[:keyLength]
+
+	userPW := make([]byte, 32)
+	copy(userPW, enc.O[:32])
+	if enc.R == 2 {
+		cipher, err := rc4.NewCipher(key)
+		if err != nil {
+			return "", false
+		}
+		cipher.XORKeyStream(userPW, userPW)
+	} else {
+		iterationKey := make([]byte, len(key))
+		for i := 19; i >= 0; i-- {
+			for j := range key {
+				iterationKey[j] = key[j] ^ byte(i)
+			}
+			cipher, err := rc4.NewCipher(iterationKey)
+			if err != nil {
+				return "", false
+			}
+			cipher.XORKeyStream(userPW, userPW)
+		}
+	}
+
+	// Дополнение — начало строки passwordPadding, оставшееся после пароля
+	for n := 0; n <= 32; n++ {
+		if bytes.Equal(userPW[n:], passwordPadding[:32-n]) {
+			return string(userPW[:n]), true
+		}
+	}
+	return "", false
+}
+
+// passwordPadding строка дополнения паролей до 32 байт (ISO 32000-1, алгоритм 2)
+var passwordPadding = []byte{
+	0x28, 0xBF, 0x4E, 0x5E, 0x4E, 0x75, 0x8A, 0x41, 0x64, 0x00, 0x4E, 0x56, 0xFF, 0xFA, 0x01, 0x08,
+	0x2E, 0x2E, 0x00, 0xB6, 0xD0, 0x68, 0x3E, 0x80, 0x2F, 0x0C, 0xA9, 0xFE, 0x64, 0x53, 0x69, 0x7A,
+}
+
+// padPassword дополняет или обрезает пароль до 32 байт
+func padPassword(password string) []byte {
+	padded := []byte(password)
+	if len(padded) >= 32 {
+		return padded[:32]
+	}
+	return append(padded, passwordPadding[:32-len(padded)]...)
+}
+
+// openEncryptedPDF открывает зашифрованный документ с указанными паролями
+func openEncryptedPDF(path, userPW, ownerPW string) (*model.Context, error) {
+	ctx, err := openPDFWithPasswords(path, userPW, ownerPW)
+	if err != nil {
+		return nil, err
+	}
+	if ctx.E == nil {
+		return nil, fmt.Errorf("словарь шифрования не найден")
+	}
+	return ctx, nil
+}
+
+// openPDFWithPasswords читает документ без валидации с указанными паролями
+func openPDFWithPasswords(path, userPW, ownerPW string) (*model.Context, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	pdfConfig := model.NewDefaultConfiguration()
+	pdfConfig.Cmd = model.VALIDATE
+	pdfConfig.ValidationMode = model.ValidationNone
+	pdfConfig.UserPW = userPW
+	pdfConfig.OwnerPW = ownerPW
+
+	return api.ReadContext(file, pdfConfig)
+}
+
+// newPDFEncryption запоминает параметры шифрования открытого документа
+func newPDFEncryption(ctx *model.Context, userPW, ownerPW string, ownerKnown bool) *pdfEncryption {
+	keyLength := ctx.E.L
+	switch {
+	case ctx.E.V == 1:
+		keyLength = 40
+	case keyLength == 0:
+		keyLength = 128
+	}
+
+	return &pdfEncryption{
+		UserPW:      userPW,
+		OwnerPW:     ownerPW,
+		OwnerKnown:  ownerKnown,
+		Permissions: ctx.E.P,
+		KeyLength:   keyLength,
+		AES:         ctx.AES4Streams,
+	}
+}
+
+// readConfiguration конфигурация pdfcpu для открытия документа найденными паролями
+func (e *pdfEncryption) readConfiguration(cmd model.CommandMode) *model.Configuration {
+	pdfConfig := model.NewDefaultConfiguration()
+	pdfConfig.Cmd = cmd
+	pdfConfig.ValidationMode = model.ValidationRelaxed
+	pdfConfig.UserPW = e.UserPW
+	if e.OwnerKnown {
+		pdfConfig.OwnerPW = e.OwnerPW
+	}
+	return pdfConfig
+}
+
+// encryptConfiguration конфигурация pdfcpu для шифрования с исходными правами и стойкостью
+func (e *pdfEncryption) encryptConfiguration() *model.Configuration {
+	var pdfConfig *model.Configuration
+	if e.AES {
+		pdfConfig = model.NewAESConfiguration(e.UserPW, e.OwnerPW, e.KeyLength)
+	} else {
+		pdfConfig = model.NewRC4Configuration(e.UserPW, e.OwnerPW, e.KeyLength)
+	}
+	pdfConfig.Cmd = model.ENCRYPT
+	// Младшие 16 бит P содержат флаги прав доступа
+	pdfConfig.Permissions = model.PermissionFlags(e.Permissions)
+	return pdfConfig
+}
+
+// randomPassword генерирует случайный пароль
+func randomPassword() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
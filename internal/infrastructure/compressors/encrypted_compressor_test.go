@@ -0,0 +1,223 @@
+package compressors
+
+import (
+	"crypto/md5"
+	"crypto/rc4"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+
+	"compress/internal/domain/entities"
+	"compress/internal/infrastructure/repositories"
+)
+
+// ownerEntry вычисляет запись O словаря шифрования (ISO 32000-1, алгоритм 3)
+func ownerEntry(t *testing.T, ownerPW, userPW string, r, keyLength int) []byte {
+	sum := md5.Sum(padPassword(ownerPW))
+	key := sum[:]
+	if r >= 3 {
+		for i := 0; i < 50; i++ {
+			sum = md5.Sum(key)
+			key = sum[:]
+		}
+		key = key[:keyLength/8]
+	} else {
+		key = key[:5]
+	}
+
+	o := padPassword(userPW)
+	for i := 0; i < 20; i++ {
+		iterationKey := make([]byte, len(key))
+		for j := range key {
+			iterationKey[j] = key[j] ^ byte(i)
+		}
+		cipher, err := rc4.NewCipher(iterationKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cipher.XORKeyStream(o, o)
+		if r == 2 {
+			break
+		}
+	}
+	return o
+}
+
+func TestRecoverUserPassword(t *testing.T) {
+	tests := []struct {
+		name      string
+		userPW    string
+		r         int
+		keyLength int
+	}{
+		{"RC4 40 bit", "reader", 2, 40},
+		{"RC4 128 bit", "reader", 3, 128},
+		{"AES-128", "секрет", 4, 128},
+		{"Empty user password", "", 4, 128},
+		{"Long user password", "0123456789abcdef0123456789abcdef-tail", 3, 128},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enc := &model.Enc{O: ownerEntry(t, "owner", tt.userPW, tt.r, tt.keyLength), R: tt.r, L: tt.keyLength}
+
+			got, ok := recoverUserPassword(enc, "owner")
+			want := string(padPassword(tt.userPW)[:min(len(tt.userPW), 32)])
+			if !ok || got != want {
+				t.Errorf("Expected user password %q, got %q (ok %v)", want, got, ok)
+			}
+		})
+	}
+
+	if _, ok := recoverUserPassword(&model.Enc{O: make([]byte, 48), R: 6, L: 256}, "owner"); ok {
+		t.Error("User password must not be recovered for AES-256")
+	}
+}
+
+func TestHasEncryptDictionary(t *testing.T) {
+	filler := make([]byte, 3*encryptScanWindow)
+	for i := range filler {
+		filler[i] = ' '
+	}
+
+	// Строка /Encrypt в теле документа, startxref указывает на таблицу xref в конце
+	body := "%PDF-1.7\n(/Encrypt)\n" + string(filler)
+	bodyOnly := body + fmt.Sprintf("xref\n0 1\ntrailer\n<<>>\nstartxref\n%d\n%%%%EOF\n", len(body))
+
+	tests := []struct {
+		name     string
+		data     string
+		expected bool
+	}{
+		{"Plain trailer", "%PDF-1.7\n" + string(filler) + "trailer\n<< /Size 3 >>\nstartxref\n9\n%%EOF\n", false},
+		{"Encrypted trailer", "%PDF-1.7\n" + string(filler) + "trailer\n<< /Size 3 /Encrypt 2 0 R >>\nstartxref\n9\n%%EOF\n", true},
+		{
+			// Словарь xref-потока далеко от конца файла: за ним идут большие данные потока
+			name:     "Encrypted xref stream",
+			data:     "%PDF-1.7\n5 0 obj\n<< /Type /XRef /Encrypt 2 0 R >>\nstream\n" + string(filler) + "\nendstream\nendobj\nstartxref\n9\n%%EOF\n",
+			expected: true,
+		},
+		{"Encrypt string in body only", bodyOnly, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "in.pdf")
+			if err := os.WriteFile(path, []byte(tt.data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := hasEncryptDictionary(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+// writeTestPDF записывает документ из pages пустых страниц
+func writeTestPDF(t *testing.T, path string, pages int) {
+	t.Helper()
+
+	objects := []string{"<< /Type /Catalog /Pages 2 0 R >>", ""}
+	kids := ""
+	for i := 0; i < pages; i++ {
+		kids += fmt.Sprintf("%d 0 R ", i+3)
+		objects = append(objects, "<< /Type /Page /Parent 2 0 R /MediaBox [0 0 200 200] >>")
+	}
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", kids, pages)
+
+	// pdfcpu ищет startxref в последних 512 байтах и не читает файлы короче
+	pdf := "%PDF-1.7\n%" + strings.Repeat("-", 512) + "\n"
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = len(pdf)
+		pdf += fmt.Sprintf("%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := len(pdf)
+	pdf += fmt.Sprintf("xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		pdf += fmt.Sprintf("%010d 00000 n \n", offset)
+	}
+	pdf += fmt.Sprintf("trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	if err := os.WriteFile(path, []byte(pdf), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// staticPasswords список паролей для любого файла
+type staticPasswords []string
+
+func (p staticPasswords) Passwords(string) []string {
+	return p
+}
+
+// copyingCompressor копирует вход в выход без изменений
+type copyingCompressor struct{}
+
+func (copyingCompressor) Compress(inputPath, outputPath string, config *entities.CompressionConfig) (*entities.CompressionResult, error) {
+	data, err := os.ReadFile(inputPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(outputPath, data, 0o644); err != nil {
+		return nil, err
+	}
+	return &entities.CompressionResult{OriginalSize: int64(len(data)), CompressedSize: int64(len(data)), Success: true}, nil
+}
+
+func TestEncryptedCompressorOwnerPassword(t *testing.T) {
+	dir := t.TempDir()
+	plain := filepath.Join(dir, "plain.pdf")
+	input := filepath.Join(dir, "in.pdf")
+	writeTestPDF(t, plain, 2)
+	if err := api.EncryptFile(plain, input, model.NewAESConfiguration("user", "owner", 128)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		passwords staticPasswords
+		skipErr   error
+	}{
+		{"User password only", staticPasswords{"user"}, entities.ErrOwnerPasswordNotFound},
+		{"No password", staticPasswords{"wrong"}, entities.ErrPasswordNotFound},
+		{"Owner password configured", staticPasswords{"user", "owner"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := filepath.Join(t.TempDir(), "out.pdf")
+			compressor := NewEncryptedPDFCompressor(copyingCompressor{}, tt.passwords, repositories.NewFileSystemRepository())
+
+			result, err := compressor.Compress(input, output, entities.NewCompressionConfig(50))
+			if err != nil {
+				t.Fatalf("Compress: %v", err)
+			}
+			if tt.skipErr == nil {
+				if result.Skipped {
+					t.Fatalf("Expected the document to be compressed, skipped: %s", result.SkipReason)
+				}
+				if _, err := openEncryptedPDF(output, "", "owner"); err != nil {
+					t.Errorf("Owner password does not open the result: %v", err)
+				}
+				return
+			}
+			if !result.Skipped || !errors.Is(result.SkipError, tt.skipErr) {
+				t.Errorf("Expected skip with %v, got skipped=%v, %v", tt.skipErr, result.Skipped, result.SkipError)
+			}
+			if _, err := os.Stat(output); !os.IsNotExist(err) {
+				t.Error("Skipped document must not produce an output file")
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package compressors
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"compress/internal/domain/entities"
+	"compress/internal/domain/repositories"
+)
+
+// externalWaitDelay сколько ждать закрытия вывода после завершения или остановки команды:
+// дочерние процессы оболочки могут держать stderr открытым
+const externalWaitDelay = 5 * time.Second
+
+// ExternalCompressor сжатие PDF внешней программой по шаблону команды
+// с подстановками {input}, {output} и {level}
+type ExternalCompressor struct {
+	command entities.ExternalCommandConfig
+	timeout time.Duration
+	logger  repositories.Logger
+}
+
+// NewExternalCompressor создает компрессор, запускающий внешнюю команду
+func NewExternalCompressor(command entities.ExternalCommandConfig, timeout time.Duration, logger repositories.Logger) *ExternalCompressor {
+	return &ExternalCompressor{
+		command: command,
+		timeout: timeout,
+		logger:  logger,
+	}
+}
+
+// Compress запускает внешнюю команду и проверяет, что она записала результат.
+// Вывод stderr передается в журнал; код завершения, не входящий в success_codes,
+// и превышение таймаута считаются ошибкой, частично записанный результат удаляется
+func (e *ExternalCompressor) Compress(inputPath, outputPath string, config *entities.CompressionConfig) (*entities.CompressionResult, error) {
+	args := e.command.Args(inputPath, outputPath, config.Level)
+	name := filepath.Base(args[0])
+	fmt.Printf("🔄 Сжатие PDF с уровнем %d%% (%s)...\n", config.Level, name)
+
+	originalInfo, err := os.Stat(inputPath)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения информации об исходном файле: %w", err)
+	}
+
+	failed := func(err error) (*entities.CompressionResult, error) {
+		_ = os.Remove(outputPath)
+		return &entities.CompressionResult{
+			OriginalSize: originalInfo.Size(),
+			Success:      false,
+			Error:        err,
+		}, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = externalWaitDelay
+	configureProcessGroup(cmd)
+
+	err = cmd.Run()
+	// Команда запустилась и завершилась сама, если нет ошибки или есть только код завершения
+	var exitErr *exec.ExitError
+	finished := err == nil || errors.As(err, &exitErr) || errors.Is(err, exec.ErrWaitDelay)
+	exitCode := cmd.ProcessState.ExitCode()
+	success := finished && ctx.Err() == nil && e.command.IsSuccess(exitCode)
+
+	e.logOutput(name, &stdout, e.logger.Debug)
+	if success {
+		e.logOutput(name, &stderr, e.logger.Info)
+	} else {
+		e.logOutput(name, &stderr, e.logger.Error)
+	}
+
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		return failed(fmt.Errorf("%w: %s, %s", entities.ErrExternalCommandTimeout, name, e.timeout))
+	case !finished:
+		return failed(fmt.Errorf("%w: %s: %v", entities.ErrExternalCommandFailed, name, err))
+	case !success:
+		return failed(fmt.Errorf("%w: %s, код %d: %s", entities.ErrExternalCommandFailed, name, exitCode, lastLine(&stderr)))
+	}
+
+	compressedInfo, err := os.Stat(outputPath)
+	if err != nil || compressedInfo.Size() == 0 {
+		return failed(fmt.Errorf("%w: %s не записал результат (код %d)", entities.ErrExternalCommandFailed, name, exitCode))
+	}
+
+	result := &entities.CompressionResult{
+		OriginalSize:   originalInfo.Size(),
+		CompressedSize: compressedInfo.Size(),
+		Success:        true,
+	}
+	result.CalculateCompressionRatio()
+
+	fmt.Printf("✅ Сжатие завершено: %s\n", outputPath)
+	return result, nil
+}
+
+// logOutput передает непустые строки вывода команды в журнал
+func (e *ExternalCompressor) logOutput(name string, output *bytes.Buffer, log func(format string, args ...interface{})) {
+	scanner := bufio.NewScanner(bytes.NewReader(output.Bytes()))
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			log("[%s] %s", name, line)
+		}
+	}
+}
+
+// lastLine возвращает последнюю непустую строку вывода — обычно в ней причина ошибки
+func lastLine(output *bytes.Buffer) string {
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
@@ -0,0 +1,141 @@
+package compressors
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"compress/internal/domain/entities"
+)
+
+// recordingLogger запоминает сообщения журнала по уровням
+type recordingLogger struct {
+	messages map[string][]string
+}
+
+func (l *recordingLogger) record(level, format string, args ...interface{}) {
+	if l.messages == nil {
+		l.messages = make(map[string][]string)
+	}
+	l.messages[level] = append(l.messages[level], fmt.Sprintf(format, args...))
+}
+
+func (l *recordingLogger) Debug(format string, args ...interface{}) {
+	l.record("debug", format, args...)
+}
+
+func (l *recordingLogger) Info(format string, args ...interface{}) {
+	l.record("info", format, args...)
+}
+
+func (l *recordingLogger) Warning(format string, args ...interface{}) {
+	l.record("warning", format, args...)
+}
+
+func (l *recordingLogger) Error(format string, args ...interface{}) {
+	l.record("error", format, args...)
+}
+
+func (l *recordingLogger) Success(format string, args ...interface{}) {
+	l.record("success", format, args...)
+}
+
+func (l *recordingLogger) Close() error {
+	return nil
+}
+
+func TestExternalCompressor(t *testing.T) {
+	tests := []struct {
+		name         string
+		script       string
+		successCodes []int
+		timeout      time.Duration
+		expectedErr  error
+		expectedLog  string // Строка stderr и уровень журнала, куда она должна попасть
+		logLevel     string
+	}{
+		{
+			name:        "Success with level placeholder",
+			script:      `[ "$3" = "70" ] || exit 9; echo "level $3" >&2; head -c 10 "$1" > "$2"`,
+			expectedLog: "level 70",
+			logLevel:    "info",
+		},
+		{
+			name:         "Warnings exit code listed as success",
+			script:       `echo "recovered xref" >&2; head -c 10 "$1" > "$2"; exit 3`,
+			successCodes: []int{0, 3},
+			expectedLog:  "recovered xref",
+			logLevel:     "info",
+		},
+		{
+			name:        "Failure exit code",
+			script:      `echo "partial" > "$2"; echo "damaged file" >&2; exit 2`,
+			expectedErr: entities.ErrExternalCommandFailed,
+			expectedLog: "damaged file",
+			logLevel:    "error",
+		},
+		{
+			name:        "No output written",
+			script:      `exit 0`,
+			expectedErr: entities.ErrExternalCommandFailed,
+		},
+		{
+			name:        "Timeout",
+			script:      `sleep 5; head -c 10 "$1" > "$2"`,
+			timeout:     200 * time.Millisecond,
+			expectedErr: entities.ErrExternalCommandTimeout,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			script := filepath.Join(dir, "tool.sh")
+			if err := os.WriteFile(script, []byte("#!/bin/sh\n"+tt.script+"\n"), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			input := filepath.Join(dir, "in.pdf")
+			if err := os.WriteFile(input, []byte(strings.Repeat("%PDF-1.7\n", 10)), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			output := filepath.Join(dir, "out.pdf")
+
+			timeout := tt.timeout
+			if timeout == 0 {
+				timeout = 10 * time.Second
+			}
+			logger := &recordingLogger{}
+			compressor := NewExternalCompressor(entities.ExternalCommandConfig{
+				Command:      []string{script, "{input}", "{output}", "{level}"},
+				SuccessCodes: tt.successCodes,
+			}, timeout, logger)
+
+			started := time.Now()
+			result, err := compressor.Compress(input, output, entities.NewCompressionConfig(70))
+
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("Expected error %v, got %v", tt.expectedErr, err)
+			}
+			if tt.expectedErr == nil {
+				if !result.Success || result.CompressedSize != 10 || result.SavedSpace != 80 {
+					t.Errorf("Unexpected result: %+v", result)
+				}
+			} else if _, statErr := os.Stat(output); !os.IsNotExist(statErr) {
+				t.Error("Partial output must be removed on failure")
+			}
+			if tt.expectedErr == entities.ErrExternalCommandTimeout && time.Since(started) > 3*time.Second {
+				t.Errorf("Command was not stopped on timeout, took %s", time.Since(started))
+			}
+			if tt.expectedLog != "" {
+				messages := strings.Join(logger.messages[tt.logLevel], "\n")
+				if !strings.Contains(messages, tt.expectedLog) {
+					t.Errorf("Expected %q in %s log, got %v", tt.expectedLog, tt.logLevel, logger.messages)
+				}
+			}
+		})
+	}
+}
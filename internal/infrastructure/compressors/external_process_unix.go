@@ -0,0 +1,17 @@
+//go:build !windows
+
+package compressors
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// configureProcessGroup запускает команду в отдельной группе процессов, чтобы по таймауту
+// остановить вместе с ней и запущенные ею процессы (например, из сценария оболочки)
+func configureProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
@@ -0,0 +1,9 @@
+//go:build windows
+
+package compressors
+
+import "os/exec"
+
+// configureProcessGroup на Windows не требуется: по таймауту останавливается сама команда,
+// а ожидание вывода ограничено WaitDelay
+func configureProcessGroup(cmd *exec.Cmd) {}
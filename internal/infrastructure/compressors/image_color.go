@@ -0,0 +1,184 @@
+package compressors
+
+import (
+	"image"
+	"image/color"
+)
+
+const (
+	// grayChromaThreshold разница каналов пикселя, которая еще считается шумом сканера или JPEG
+	grayChromaThreshold = 24
+	// grayColoredShare доля цветных пикселей (печати, пометки), при которой изображение еще считается серым
+	grayColoredShare = 0.002
+	// bitonalMinContrast минимальная разница яркости фона и текста двухцветного изображения
+	bitonalMinContrast = 96
+	// bitonalMidtoneShare доля полутонов (края букв), при которой изображение еще считается двухцветным
+	bitonalMidtoneShare = 0.06
+	// bitonalMinPPI двухцветные изображения не уменьшаются ниже этого разрешения: для текста
+	// важнее разрешение, а 1-битное изображение в CCITT G4 и так занимает мало места
+	bitonalMinPPI = 200
+)
+
+// imageColorMode цветность, в которой изображение сохраняется после перекодирования
+type imageColorMode int
+
+const (
+	imageColorOriginal imageColorMode = iota // Цветность не меняется
+	imageColorGray                           // Оттенки серого
+	imageColorBitonal                        // Два цвета, 1 бит на пиксель
+)
+
+// nearGrayImage возвращает изображение в оттенках серого, если исходное почти серое:
+// цветными (разница каналов больше grayChromaThreshold) допускается не больше
+// grayColoredShare пикселей. Изображения с прозрачностью не преобразуются
+func nearGrayImage(img image.Image) (*image.Gray, bool) {
+	if gray, ok := img.(*image.Gray); ok {
+		return gray, true
+	}
+	if opaque, ok := img.(interface{ Opaque() bool }); ok && !opaque.Opaque() {
+		return nil, false
+	}
+
+	bounds := img.Bounds()
+	gray := image.NewGray(bounds)
+	limit := int(float64(bounds.Dx()*bounds.Dy()) * grayColoredShare)
+	colored := 0
+
+	pixel := rgbPixelFunc(img)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		row := gray.Pix[(y-bounds.Min.Y)*gray.Stride:]
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b := pixel(x, y)
+			if chroma(r, g, b) > grayChromaThreshold {
+				colored++
+				if colored > limit {
+					return nil, false
+				}
+			}
+			// Коэффициенты яркости те же, что в color.GrayModel
+			row[x-bounds.Min.X] = uint8((19595*uint32(r) + 38470*uint32(g) + 7471*uint32(b) + 1<<15) >> 16)
+		}
+	}
+
+	return gray, true
+}
+
+// rgbPixelFunc возвращает функцию чтения пикселя без выделения памяти для
+// распространенных типов изображений
+func rgbPixelFunc(img image.Image) func(x, y int) (uint8, uint8, uint8) {
+	switch img := img.(type) {
+	case *image.YCbCr:
+		return func(x, y int) (uint8, uint8, uint8) {
+			c := img.YCbCrAt(x, y)
+			return color.YCbCrToRGB(c.Y, c.Cb, c.Cr)
+		}
+	case *image.RGBA:
+		return func(x, y int) (uint8, uint8, uint8) {
+			c := img.RGBAAt(x, y)
+			return c.R, c.G, c.B
+		}
+	case *image.NRGBA:
+		return func(x, y int) (uint8, uint8, uint8) {
+			c := img.NRGBAAt(x, y)
+			return c.R, c.G, c.B
+		}
+	}
+	return func(x, y int) (uint8, uint8, uint8) {
+		r, g, b, _ := img.At(x, y).RGBA()
+		return uint8(r >> 8), uint8(g >> 8), uint8(b >> 8)
+	}
+}
+
+// chroma наибольшая разница между каналами пикселя
+func chroma(r, g, b uint8) int {
+	maxC := max(r, g, b)
+	minC := min(r, g, b)
+	return int(maxC) - int(minC)
+}
+
+// bitonalThreshold подбирает порог яркости методом Оцу и проверяет, что изображение
+// почти двухцветное: фон и текст достаточно контрастны, а полутонов мало
+func bitonalThreshold(gray *image.Gray) (uint8, bool) {
+	var histogram [256]int
+	bounds := gray.Bounds()
+	for y := 0; y < bounds.Dy(); y++ {
+		for _, v := range gray.Pix[y*gray.Stride : y*gray.Stride+bounds.Dx()] {
+			histogram[v]++
+		}
+	}
+	total := bounds.Dx() * bounds.Dy()
+	if total == 0 {
+		return 0, false
+	}
+
+	var sum float64
+	for v, count := range histogram {
+		sum += float64(v * count)
+	}
+
+	// Порог, максимизирующий межклассовую дисперсию
+	var threshold int
+	var best, sumBelow float64
+	below := 0
+	for t := 1; t < 256; t++ {
+		below += histogram[t-1]
+		sumBelow += float64((t - 1) * histogram[t-1])
+		above := total - below
+		if below == 0 || above == 0 {
+			continue
+		}
+		meanBelow := sumBelow / float64(below)
+		meanAbove := (sum - sumBelow) / float64(above)
+		variance := float64(below) * float64(above) * (meanAbove - meanBelow) * (meanAbove - meanBelow)
+		if variance > best {
+			best, threshold = variance, t
+		}
+	}
+	if threshold == 0 {
+		return 0, false
+	}
+
+	var dark, light, darkSum, lightSum int
+	for v, count := range histogram {
+		if v < threshold {
+			dark += count
+			darkSum += v * count
+		} else {
+			light += count
+			lightSum += v * count
+		}
+	}
+	darkMean, lightMean := darkSum/dark, lightSum/light
+	contrast := lightMean - darkMean
+	if contrast < bitonalMinContrast {
+		return 0, false
+	}
+
+	// Полутона — пиксели, далекие от средних яркостей и фона, и текста
+	midtones := 0
+	for v := darkMean + contrast/4 + 1; v < lightMean-contrast/4; v++ {
+		midtones += histogram[v]
+	}
+	if float64(midtones) > float64(total)*bitonalMidtoneShare {
+		return 0, false
+	}
+
+	return uint8(threshold), true
+}
+
+// bitonalPaletted переводит изображение в черно-белое с палитрой из двух цветов:
+// пиксели темнее порога становятся черными
+func bitonalPaletted(gray *image.Gray, threshold uint8) *image.Paletted {
+	bounds := gray.Bounds()
+	img := image.NewPaletted(bounds, color.Palette{color.Gray{Y: 0}, color.Gray{Y: 0xff}})
+	for y := 0; y < bounds.Dy(); y++ {
+		src := gray.Pix[y*gray.Stride:]
+		dst := img.Pix[y*img.Stride:]
+		for x := 0; x < bounds.Dx(); x++ {
+			if src[x] >= threshold {
+				dst[x] = 1
+			}
+		}
+	}
+	return img
+}
@@ -0,0 +1,77 @@
+package compressors
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestImageColorDetection(t *testing.T) {
+	const size = 100
+
+	// Скан текста: желтоватая бумага, темные штрихи и немного полутонов на краях
+	scan := func(x, y int) color.RGBA {
+		switch {
+		case y%10 < 3 && x%7 < 4:
+			return color.RGBA{R: 40, G: 38, B: 35, A: 0xff}
+		case y%20 == 3 && x%7 < 4:
+			return color.RGBA{R: 140, G: 136, B: 130, A: 0xff}
+		}
+		return color.RGBA{R: 240, G: 235, B: 222, A: 0xff}
+	}
+
+	tests := []struct {
+		name            string
+		pixel           func(x, y int) color.RGBA
+		expectedGray    bool
+		expectedBitonal bool
+	}{
+		{"Text scan in color", scan, true, true},
+		{
+			name: "Text scan with a red stamp",
+			pixel: func(x, y int) color.RGBA {
+				if x > 70 && y > 70 {
+					return color.RGBA{R: 200, G: 30, B: 30, A: 0xff}
+				}
+				return scan(x, y)
+			},
+			expectedGray: false,
+		},
+		{
+			name: "Grayscale photo",
+			pixel: func(x, y int) color.RGBA {
+				v := uint8((x + y) * 255 / (2 * size))
+				return color.RGBA{R: v, G: v + 2, B: v, A: 0xff}
+			},
+			expectedGray:    true,
+			expectedBitonal: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := image.NewRGBA(image.Rect(0, 0, size, size))
+			for y := 0; y < size; y++ {
+				for x := 0; x < size; x++ {
+					img.SetRGBA(x, y, tt.pixel(x, y))
+				}
+			}
+
+			gray, isGray := nearGrayImage(img)
+			if isGray != tt.expectedGray {
+				t.Fatalf("Expected near-gray %v, got %v", tt.expectedGray, isGray)
+			}
+			if !isGray {
+				return
+			}
+
+			threshold, isBitonal := bitonalThreshold(gray)
+			if isBitonal != tt.expectedBitonal {
+				t.Fatalf("Expected near-bitonal %v, got %v", tt.expectedBitonal, isBitonal)
+			}
+			if isBitonal && (threshold <= 40 || threshold > 235) {
+				t.Errorf("Threshold %d must separate text from paper", threshold)
+			}
+		})
+	}
+}
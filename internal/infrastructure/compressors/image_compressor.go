@@ -1,242 +1,341 @@
 package compressors
 
 import (
+	"bytes"
 	"fmt"
 	"image"
+	"image/color"
 	"image/jpeg"
 	"image/png"
 	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/nfnt/resize"
 )
 
-// ImageCompressor интерфейс для сжатия изображений
+// Форматы изображений
+const (
+	ImageFormatJPEG = "jpeg"
+	ImageFormatPNG  = "png"
+	ImageFormatWebP = "webp"
+)
+
+// photoMinColors число различных цветов, начиная с которого изображение считается фотографией
+const photoMinColors = 4096
+
+// ImageCompressor интерфейс для сжатия изображений. Методы возвращают формат,
+// в котором записан выходной файл
 type ImageCompressor interface {
-	CompressJPEG(inputPath, outputPath string, quality int) error
-	CompressPNG(inputPath, outputPath string, quality int) error
+	CompressJPEG(inputPath, outputPath string, options ImageOptions) (string, error)
+	CompressPNG(inputPath, outputPath string, options ImageOptions) (string, error)
+}
+
+// ImageOptions настройки сжатия отдельного изображения
+type ImageOptions struct {
+	Quality          int  // Качество в процентах (1-100)
+	ConvertGrayscale bool // Почти серые изображения сохранять в оттенках серого
+	ConvertBitonal   bool // Почти двухцветные PNG сохранять с палитрой из двух цветов (1 бит)
+	ConvertToJPEG    bool // Непрозрачные PNG фотографии можно сохранить в JPEG
+	WebP             bool // Рассматривать WebP без потерь и с потерями (нужна утилита cwebp)
+	Dither           bool // Рассеивать ошибку при переводе PNG в палитру
+	Resize           bool // Уменьшать разрешение PNG по качеству, если рамка не задана
+	// Политика метаданных: strip_all, keep_orientation_icc или keep_all
+	Metadata string
+	// Рамка: максимальные ширина и высота в пикселях и число мегапикселей (0 — без ограничения).
+	// Если ограничение задано, изображение вписывается в рамку вместо уменьшения по качеству
+	MaxWidth      int
+	MaxHeight     int
+	MaxMegapixels float64
+	Upscale       bool   // Увеличивать изображения меньше рамки
+	Filter        string // Фильтр интерполяции: nearest, bilinear, bicubic, mitchell, lanczos2, lanczos3
+}
+
+// imageCandidate вариант кодирования изображения
+type imageCandidate struct {
+	format string
+	gray   bool // Изображение в оттенках серого: к нему подходит только ICC профиль GRAY
+	encode func(w io.Writer) error
 }
 
 // DefaultImageCompressor реализация компрессора изображений
-type DefaultImageCompressor struct{}
+type DefaultImageCompressor struct {
+	webp *CWebPEncoder
+}
 
-// NewImageCompressor создает новый компрессор изображений
-func NewImageCompressor() ImageCompressor {
-	return &DefaultImageCompressor{}
+// NewImageCompressor создает новый компрессор изображений. WebP кодируется утилитой cwebp
+// по пути cwebpPath (пусто — поиск в PATH) с ограничением времени timeout
+func NewImageCompressor(cwebpPath string, timeout time.Duration) ImageCompressor {
+	return &DefaultImageCompressor{webp: NewCWebPEncoder(cwebpPath, timeout)}
 }
 
-// CompressJPEG сжимает JPEG файл с указанным качеством
-func (c *DefaultImageCompressor) CompressJPEG(inputPath, outputPath string, quality int) error {
-	// Открываем исходный файл
-	inputFile, err := os.Open(inputPath)
-	if err != nil {
-		return fmt.Errorf("не удалось открыть файл %s: %w", inputPath, err)
-	}
-	defer inputFile.Close()
+// CompressJPEG сжимает JPEG файл с указанными настройками. Если включен WebP,
+// результат может быть записан в WebP с потерями
+func (c *DefaultImageCompressor) CompressJPEG(inputPath, outputPath string, options ImageOptions) (string, error) {
+	quality := options.Quality
 
-	// Декодируем изображение
-	img, err := jpeg.Decode(inputFile)
+	// Читаем исходный файл: метаданные переносятся из его сегментов
+	data, err := os.ReadFile(inputPath)
 	if err != nil {
-		return fmt.Errorf("не удалось декодировать JPEG файл %s: %w", inputPath, err)
+		return "", fmt.Errorf("не удалось прочитать файл %s: %w", inputPath, err)
 	}
 
-	// Получаем размер исходного файла для сравнения
-	inputFileInfo, err := inputFile.Stat()
+	// Декодируем изображение
+	img, err := jpeg.Decode(bytes.NewReader(data))
 	if err != nil {
-		return fmt.Errorf("не удалось получить информацию о файле %s: %w", inputPath, err)
-	}
-	originalSize := inputFileInfo.Size()
-
-	// Вычисляем новый размер на основе качества
-	bounds := img.Bounds()
-	width := bounds.Dx()
-	height := bounds.Dy()
-
-	// Более агрессивное уменьшение размера для достижения реального сжатия
-	// quality 10 -> 0.5 (50%), quality 50 -> 0.9 (90%)
-	scaleFactor := 0.5 + float64(quality-10)/40.0*0.4
-	if scaleFactor > 1.0 {
-		scaleFactor = 1.0
+		return "", fmt.Errorf("не удалось декодировать JPEG файл %s: %w", inputPath, err)
 	}
+	meta, img := applyImageMetadataPolicy(readJPEGMetadata(data), img, options.Metadata)
 
-	newWidth := uint(float64(width) * scaleFactor)
-	newHeight := uint(float64(height) * scaleFactor)
-
-	// Изменяем размер изображения только если есть реальная польза
 	var finalImg image.Image
-	if newWidth < uint(width) && newHeight < uint(height) {
-		finalImg = resize.Resize(newWidth, newHeight, img, resize.Lanczos3)
+	if options.hasBoundingBox() {
+		// Размер определяется рамкой, качество влияет только на кодирование
+		finalImg = fitImage(img, options.forOrientation(meta.orientation))
 	} else {
-		finalImg = img
-	}
+		// Вычисляем новый размер на основе качества
+		bounds := img.Bounds()
+		width := bounds.Dx()
+		height := bounds.Dy()
+
+		// Более агрессивное уменьшение размера для достижения реального сжатия
+		// quality 10 -> 0.5 (50%), quality 50 -> 0.9 (90%)
+		scaleFactor := 0.5 + float64(quality-10)/40.0*0.4
+		if scaleFactor > 1.0 {
+			scaleFactor = 1.0
+		}
 
-	// Маппинг качества: 10->30, 30->55, 50->75 (более консервативно)
-	jpegQuality := 20 + int(float64(quality-10)/40.0*55.0)
-	if jpegQuality < 20 {
-		jpegQuality = 20
+		newWidth := uint(float64(width) * scaleFactor)
+		newHeight := uint(float64(height) * scaleFactor)
+
+		// Изменяем размер изображения только если есть реальная польза
+		if newWidth < uint(width) && newHeight < uint(height) {
+			finalImg = resize.Resize(newWidth, newHeight, img, resizeFilter(options.Filter))
+		} else {
+			finalImg = img
+		}
 	}
-	if jpegQuality > 75 {
-		jpegQuality = 75
+
+	// JPEG не хранит 1-битные изображения: почти двухцветные сохраняются в оттенках серого
+	if options.ConvertGrayscale {
+		if gray, ok := nearGrayImage(finalImg); ok {
+			finalImg = gray
+		}
 	}
 
-	// Создаем временный файл для проверки результата
-	tmpPath := outputPath + ".tmp"
-	tmpFile, err := os.Create(tmpPath)
-	if err != nil {
-		return fmt.Errorf("не удалось создать временный файл: %w", err)
+	candidates := []imageCandidate{jpegCandidate(finalImg, quality)}
+	if options.WebP && fitsWebP(finalImg) {
+		candidates = append(candidates, webpCandidate(c.webp, finalImg, false, quality))
 	}
 
-	// Кодируем во временный файл
-	options := &jpeg.Options{Quality: jpegQuality}
-	err = jpeg.Encode(tmpFile, finalImg, options)
-	tmpFile.Close()
+	return writeSmallestImage(data, outputPath, ImageFormatJPEG, candidates, meta)
+}
+
+// CompressPNG сжимает PNG файл с указанными настройками: переводит его в палитру не более
+// чем из 256 цветов с сохранением прозрачности. Непрозрачные фотографии могут быть
+// записаны в JPEG, любые изображения — в WebP, если эти форматы разрешены и файл получается меньше
+func (c *DefaultImageCompressor) CompressPNG(inputPath, outputPath string, options ImageOptions) (string, error) {
+	quality := options.Quality
 
+	// Читаем исходный файл: метаданные переносятся из его блоков
+	data, err := os.ReadFile(inputPath)
 	if err != nil {
-		os.Remove(tmpPath)
-		return fmt.Errorf("не удалось закодировать JPEG: %w", err)
+		return "", fmt.Errorf("не удалось прочитать файл %s: %w", inputPath, err)
 	}
 
-	// Проверяем размер результата
-	tmpInfo, err := os.Stat(tmpPath)
+	// Декодируем изображение
+	img, err := png.Decode(bytes.NewReader(data))
 	if err != nil {
-		os.Remove(tmpPath)
-		return fmt.Errorf("не удалось получить информацию о временном файле: %w", err)
+		return "", fmt.Errorf("не удалось декодировать PNG файл %s: %w", inputPath, err)
 	}
-
-	// Если сжатие неэффективно (файл больше или почти такой же), копируем оригинал
-	if tmpInfo.Size() >= originalSize*95/100 {
-		os.Remove(tmpPath)
-		// Копируем оригинал
-		inputFile.Seek(0, 0)
-		outputFile, err := os.Create(outputPath)
-		if err != nil {
-			return fmt.Errorf("не удалось создать выходной файл: %w", err)
+	meta, img := applyImageMetadataPolicy(readPNGMetadata(data), img, options.Metadata)
+
+	// Разрешение сохраняется: изображение вписывается в рамку, если она задана, а уменьшение
+	// по качеству выполняется, только если оно запрошено явно
+	finalImg := img
+	if options.hasBoundingBox() {
+		finalImg = fitImage(img, options.forOrientation(meta.orientation))
+	} else if options.Resize {
+		bounds := img.Bounds()
+		width := bounds.Dx()
+		height := bounds.Dy()
+
+		// Более консервативное масштабирование для PNG
+		// quality 10 -> 0.6 (60%), quality 50 -> 0.9 (90%)
+		scaleFactor := 0.6 + float64(quality-10)/40.0*0.3
+		if scaleFactor > 1.0 {
+			scaleFactor = 1.0
 		}
-		defer outputFile.Close()
 
-		_, err = io.Copy(outputFile, inputFile)
-		if err != nil {
-			return fmt.Errorf("не удалось скопировать файл: %w", err)
+		newWidth := uint(float64(width) * scaleFactor)
+		newHeight := uint(float64(height) * scaleFactor)
+
+		// Не изменяем размер для маленьких изображений
+		if width < 400 && height < 400 {
+			newWidth = uint(width)
+			newHeight = uint(height)
 		}
-		return nil
-	}
 
-	// Переименовываем временный файл в выходной
-	err = os.Rename(tmpPath, outputPath)
-	if err != nil {
-		os.Remove(tmpPath)
-		return fmt.Errorf("не удалось переименовать временный файл: %w", err)
+		// Изменяем размер изображения только если это даст выигрыш
+		if newWidth < uint(width) && newHeight < uint(height) {
+			finalImg = resize.Resize(newWidth, newHeight, img, resizeFilter(options.Filter))
+		}
 	}
 
-	return nil
-}
-
-// CompressPNG сжимает PNG файл с указанным качеством
-func (c *DefaultImageCompressor) CompressPNG(inputPath, outputPath string, quality int) error {
-	// Открываем исходный файл
-	inputFile, err := os.Open(inputPath)
-	if err != nil {
-		return fmt.Errorf("не удалось открыть файл %s: %w", inputPath, err)
+	// Уменьшение цветности: оттенки серого или палитра из двух цветов (1 бит на пиксель)
+	bitonal := false
+	if options.ConvertGrayscale {
+		if gray, ok := nearGrayImage(finalImg); ok {
+			finalImg = gray
+			if options.ConvertBitonal {
+				if threshold, ok := bitonalThreshold(gray); ok {
+					finalImg = bitonalPaletted(gray, threshold)
+					bitonal = true
+				}
+			}
+		}
 	}
-	defer inputFile.Close()
 
-	// Получаем размер исходного файла для сравнения
-	inputFileInfo, err := inputFile.Stat()
-	if err != nil {
-		return fmt.Errorf("не удалось получить информацию о файле %s: %w", inputPath, err)
+	// PNG сохраняется с палитрой: размер палитры и сила рассеивания ошибки зависят от качества
+	pngImg := finalImg
+	if !bitonal {
+		dither := 0.0
+		if options.Dither {
+			dither = pngDitherStrength(quality)
+		}
+		pngImg = quantizeImage(finalImg, pngPaletteColors(quality), dither)
 	}
-	originalSize := inputFileInfo.Size()
 
-	// Декодируем изображение
-	img, err := png.Decode(inputFile)
-	if err != nil {
-		return fmt.Errorf("не удалось декодировать PNG файл %s: %w", inputPath, err)
+	// Форматы с потерями кодируют изображение без палитры
+	candidates := []imageCandidate{pngCandidate(pngImg)}
+	photo := isOpaqueImage(finalImg) && isPhotographic(finalImg)
+	if options.ConvertToJPEG && photo {
+		candidates = append(candidates, jpegCandidate(finalImg, quality))
 	}
-
-	// Вычисляем новый размер на основе качества
-	bounds := img.Bounds()
-	width := bounds.Dx()
-	height := bounds.Dy()
-
-	// Более консервативное масштабирование для PNG
-	// quality 10 -> 0.6 (60%), quality 50 -> 0.9 (90%)
-	scaleFactor := 0.6 + float64(quality-10)/40.0*0.3
-	if scaleFactor > 1.0 {
-		scaleFactor = 1.0
+	if options.WebP && fitsWebP(finalImg) {
+		candidates = append(candidates, webpCandidate(c.webp, pngImg, true, quality))
+		if photo {
+			candidates = append(candidates, webpCandidate(c.webp, finalImg, false, quality))
+		}
 	}
 
-	newWidth := uint(float64(width) * scaleFactor)
-	newHeight := uint(float64(height) * scaleFactor)
+	return writeSmallestImage(data, outputPath, ImageFormatPNG, candidates, meta)
+}
 
-	// Не изменяем размер для маленьких изображений
-	if width < 400 && height < 400 {
-		newWidth = uint(width)
-		newHeight = uint(height)
+// writeSmallestImage кодирует все варианты с метаданными meta и записывает наименьший.
+// Варианты, которые не удалось закодировать, пропускаются; ошибка возвращается, только если
+// не удался ни один. Если наименьший вариант не меньше 95% исходного файла, копирует оригинал.
+// Возвращает формат записанного файла
+func writeSmallestImage(original []byte, outputPath, originalFormat string, candidates []imageCandidate, meta imageMetadata) (string, error) {
+	var best []byte
+	bestFormat := ""
+	var encodeErr error
+	for _, candidate := range candidates {
+		var buf bytes.Buffer
+		if err := candidate.encode(&buf); err != nil {
+			if encodeErr == nil {
+				encodeErr = fmt.Errorf("не удалось закодировать %s: %w", strings.ToUpper(candidate.format), err)
+			}
+			continue
+		}
+		encoded := embedMetadata(candidate.format, buf.Bytes(), meta, candidate.gray)
+		if best == nil || len(encoded) < len(best) {
+			best, bestFormat = encoded, candidate.format
+		}
+	}
+	if best == nil {
+		return "", encodeErr
 	}
 
-	// Изменяем размер изображения только если это даст выигрыш
-	var finalImg image.Image
-	if newWidth < uint(width) && newHeight < uint(height) {
-		finalImg = resize.Resize(newWidth, newHeight, img, resize.Lanczos3)
-	} else {
-		finalImg = img
+	// Если сжатие неэффективно (файл больше или почти такой же), копируем оригинал
+	if len(best) >= len(original)*95/100 {
+		if err := os.WriteFile(outputPath, original, 0644); err != nil {
+			return "", fmt.Errorf("не удалось скопировать файл: %w", err)
+		}
+		return originalFormat, nil
 	}
 
-	// Создаем временный файл для проверки результата
-	tmpPath := outputPath + ".tmp"
-	tmpFile, err := os.Create(tmpPath)
-	if err != nil {
-		return fmt.Errorf("не удалось создать временный файл: %w", err)
+	if err := os.WriteFile(outputPath, best, 0644); err != nil {
+		os.Remove(outputPath)
+		return "", fmt.Errorf("не удалось записать выходной файл: %w", err)
 	}
+	return bestFormat, nil
+}
 
-	// Для PNG используем максимальное сжатие
-	encoder := &png.Encoder{
-		CompressionLevel: png.BestCompression,
+// jpegQuality маппинг качества сжатия в качество JPEG: 10->20, 30->47, 50->75, 100->100
+func jpegQuality(quality int) int {
+	if quality > 50 {
+		return min(100, 75+(quality-50)/2)
 	}
+	return max(1, 20+int(float64(quality-10)/40.0*55.0))
+}
 
-	err = encoder.Encode(tmpFile, finalImg)
-	tmpFile.Close()
+// jpegCandidate вариант JPEG
+func jpegCandidate(img image.Image, quality int) imageCandidate {
+	return imageCandidate{format: ImageFormatJPEG, gray: isGrayImage(img), encode: func(w io.Writer) error {
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: jpegQuality(quality)})
+	}}
+}
 
-	if err != nil {
-		os.Remove(tmpPath)
-		return fmt.Errorf("не удалось закодировать PNG: %w", err)
-	}
+// pngCandidate вариант PNG с максимальным сжатием
+func pngCandidate(img image.Image) imageCandidate {
+	return imageCandidate{format: ImageFormatPNG, gray: isGrayImage(img), encode: func(w io.Writer) error {
+		encoder := &png.Encoder{CompressionLevel: png.BestCompression}
+		return encoder.Encode(w, img)
+	}}
+}
 
-	// Проверяем размер результата
-	tmpInfo, err := os.Stat(tmpPath)
-	if err != nil {
-		os.Remove(tmpPath)
-		return fmt.Errorf("не удалось получить информацию о временном файле: %w", err)
-	}
+// webpCandidate вариант WebP без потерь или с потерями с тем же качеством, что и JPEG
+func webpCandidate(encoder *CWebPEncoder, img image.Image, lossless bool, quality int) imageCandidate {
+	return imageCandidate{format: ImageFormatWebP, encode: func(w io.Writer) error {
+		return encoder.Encode(w, img, lossless, jpegQuality(quality))
+	}}
+}
 
-	// Если сжатие неэффективно (файл больше или почти такой же), копируем оригинал
-	if tmpInfo.Size() >= originalSize*95/100 {
-		os.Remove(tmpPath)
-		// Копируем оригинал
-		inputFile.Seek(0, 0)
-		outputFile, err := os.Create(outputPath)
-		if err != nil {
-			return fmt.Errorf("не удалось создать выходной файл: %w", err)
-		}
-		defer outputFile.Close()
+// fitsWebP проверяет, что стороны изображения не превышают предел WebP (webpMaxDimension)
+func fitsWebP(img image.Image) bool {
+	bounds := img.Bounds()
+	return bounds.Dx() <= webpMaxDimension && bounds.Dy() <= webpMaxDimension
+}
+
+// isGrayImage проверяет, что изображение хранится в оттенках серого
+func isGrayImage(img image.Image) bool {
+	model := img.ColorModel()
+	return model == color.GrayModel || model == color.Gray16Model
+}
 
-		_, err = io.Copy(outputFile, inputFile)
-		if err != nil {
-			return fmt.Errorf("не удалось скопировать файл: %w", err)
+// isOpaqueImage проверяет, что у изображения нет прозрачных пикселей
+func isOpaqueImage(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if _, _, _, a := img.At(x, y).RGBA(); a != 0xffff {
+				return false
+			}
 		}
-		return nil
 	}
+	return true
+}
 
-	// Переименовываем временный файл в выходной
-	err = os.Rename(tmpPath, outputPath)
-	if err != nil {
-		os.Remove(tmpPath)
-		return fmt.Errorf("не удалось переименовать временный файл: %w", err)
+// isPhotographic проверяет, что в изображении не меньше photoMinColors различных цветов:
+// графику с небольшим числом цветов форматы с потерями портят заметнее, чем сжимают
+func isPhotographic(img image.Image) bool {
+	colors := make(map[uint32]struct{}, photoMinColors)
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, _ := img.At(x, y).RGBA()
+			colors[r>>8<<16|g>>8<<8|b>>8] = struct{}{}
+			if len(colors) >= photoMinColors {
+				return true
+			}
+		}
 	}
-
-	return nil
+	return false
 }
 
 // IsImageFile проверяет, является ли файл изображением поддерживаемого формата
@@ -250,10 +349,22 @@ func GetImageFormat(filename string) string {
 	ext := strings.ToLower(filepath.Ext(filename))
 	switch ext {
 	case ".jpg", ".jpeg":
-		return "jpeg"
+		return ImageFormatJPEG
 	case ".png":
-		return "png"
+		return ImageFormatPNG
 	default:
 		return ""
 	}
 }
+
+// ImageExtension возвращает расширение файла для формата изображения
+func ImageExtension(format string) string {
+	switch format {
+	case ImageFormatJPEG:
+		return ".jpg"
+	case ImageFormatWebP:
+		return ".webp"
+	default:
+		return "." + format
+	}
+}
@@ -0,0 +1,281 @@
+package compressors
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/image/webp"
+)
+
+// testWebP собирает файл WebP без потерь, в заголовке которого записаны размеры изображения
+func testWebP(width, height int) []byte {
+	payload := []byte{0x2f}
+	// Ширина и высота минус один по 14 бит и признак альфа-канала
+	payload = binary.LittleEndian.AppendUint32(payload, uint32(width-1)|uint32(height-1)<<14|1<<28)
+	payload = append(payload, make([]byte, 5)...)
+	data := append([]byte("VP8L"), binary.LittleEndian.AppendUint32(nil, uint32(len(payload)))...)
+	data = append(data, payload...)
+	return append(append([]byte("RIFF"), binary.LittleEndian.AppendUint32(nil, uint32(4+len(data)))...), append([]byte("WEBP"), data...)...)
+}
+
+// fakeCWebP создает сценарий вместо cwebp: он дописывает свои аргументы в файл args
+// и записывает в выходной путь результат testWebP
+func fakeCWebP(t *testing.T, width, height int) (script, args string) {
+	t.Helper()
+	dir := t.TempDir()
+	fixture := filepath.Join(dir, "fixture.webp")
+	if err := os.WriteFile(fixture, testWebP(width, height), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	script = filepath.Join(dir, "cwebp")
+	args = filepath.Join(dir, "args")
+	body := fmt.Sprintf("#!/bin/sh\necho \"$@\" >> %q\nwhile [ $# -gt 0 ]; do [ \"$1\" = \"-o\" ] && out=\"$2\"; shift; done\ncp %q \"$out\"\n", args, fixture)
+	if err := os.WriteFile(script, []byte(body), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return script, args
+}
+
+func TestCompressPNGConversion(t *testing.T) {
+	const width, height = 160, 120
+	r := rand.New(rand.NewSource(1))
+
+	// Фотография: плавные переходы с шумом сенсора
+	photo := image.NewNRGBA(image.Rect(0, 0, width, height))
+	// Схема: несколько цветов без шума
+	chart := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			noise := r.Intn(24)
+			photo.SetNRGBA(x, y, color.NRGBA{uint8(x + noise), uint8(y*2 + noise), uint8(x + y + noise), 0xff})
+			chart.SetNRGBA(x, y, color.NRGBA{uint8(x / 40 * 60), 0x80, uint8(y / 30 * 60), 0xff})
+		}
+	}
+
+	tests := []struct {
+		name           string
+		img            image.Image
+		options        ImageOptions
+		expectedFormat string
+		expectedArgs   []string // Режимы, в которых вызывается cwebp
+	}{
+		{"Photo stays PNG without conversion", photo, ImageOptions{Quality: 30}, ImageFormatPNG, nil},
+		{"Photo to JPEG", photo, ImageOptions{Quality: 30, ConvertToJPEG: true}, ImageFormatJPEG, nil},
+		{"Photo with WebP", photo, ImageOptions{Quality: 30, ConvertToJPEG: true, WebP: true}, ImageFormatWebP, []string{"-lossless", "-q 47"}},
+		{"Chart is not converted to JPEG", chart, ImageOptions{Quality: 30, ConvertToJPEG: true}, ImageFormatPNG, nil},
+		{"Chart with WebP stays lossless", chart, ImageOptions{Quality: 30, ConvertToJPEG: true, WebP: true}, ImageFormatWebP, []string{"-lossless"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			input := filepath.Join(dir, "in.png")
+			var buf bytes.Buffer
+			// Исходный PNG без сжатия, чтобы любой вариант был заметно меньше
+			if err := (&png.Encoder{CompressionLevel: png.NoCompression}).Encode(&buf, tt.img); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(input, buf.Bytes(), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			output := filepath.Join(dir, "out")
+			cwebp, args := fakeCWebP(t, width, height)
+
+			format, err := NewImageCompressor(cwebp, 10*time.Second).CompressPNG(input, output, tt.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if format != tt.expectedFormat {
+				t.Fatalf("Expected %q, got %q", tt.expectedFormat, format)
+			}
+
+			data, err := os.ReadFile(output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if int64(len(data)) >= int64(buf.Len()) {
+				t.Errorf("Output is not smaller: %d >= %d", len(data), buf.Len())
+			}
+			if format == ImageFormatWebP {
+				checkWebPHeader(t, data, width, height)
+			} else if _, decoded, err := image.Decode(bytes.NewReader(data)); err != nil || decoded != format {
+				t.Errorf("Output is not a valid %s: %v (%s)", format, err, decoded)
+			}
+
+			calls, _ := os.ReadFile(args)
+			lines := strings.Split(strings.TrimSpace(string(calls)), "\n")
+			if len(calls) == 0 {
+				lines = nil
+			}
+			if len(lines) != len(tt.expectedArgs) {
+				t.Fatalf("Expected cwebp calls %v, got %q", tt.expectedArgs, lines)
+			}
+			for i, mode := range tt.expectedArgs {
+				if !strings.Contains(lines[i], mode) {
+					t.Errorf("Expected %q in cwebp call %q", mode, lines[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCWebPEncoder(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 37, 21))
+	cwebp, args := fakeCWebP(t, 37, 21)
+
+	var buf bytes.Buffer
+	if err := NewCWebPEncoder(cwebp, 10*time.Second).Encode(&buf, img, false, 47); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), testWebP(37, 21)) {
+		t.Error("Expected the file written by cwebp")
+	}
+	if calls, _ := os.ReadFile(args); !strings.HasPrefix(string(calls), "-quiet -metadata none -q 47 ") {
+		t.Errorf("Unexpected cwebp arguments %q", calls)
+	}
+
+	if err := NewCWebPEncoder(filepath.Join(t.TempDir(), "missing"), time.Second).Encode(&buf, img, true, 47); err == nil {
+		t.Error("Expected error for missing cwebp")
+	}
+
+	dir := t.TempDir()
+	slow := filepath.Join(dir, "cwebp")
+	if err := os.WriteFile(slow, []byte("#!/bin/sh\nsleep 5\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	started := time.Now()
+	if err := NewCWebPEncoder(slow, 200*time.Millisecond).Encode(&buf, img, true, 47); err == nil {
+		t.Error("Expected error on timeout")
+	}
+	if time.Since(started) > 3*time.Second {
+		t.Errorf("cwebp was not stopped on timeout, took %s", time.Since(started))
+	}
+}
+
+func TestCWebPEncoderDecodes(t *testing.T) {
+	if _, err := exec.LookPath("cwebp"); err != nil {
+		t.Skip("cwebp не найден")
+	}
+	const width, height = 53, 29
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{uint8(x * 4), uint8(y * 8), uint8(x + y), uint8(255 - x*2)})
+		}
+	}
+
+	// Без потерь: декодер восстанавливает все пиксели вместе с прозрачностью
+	var lossless bytes.Buffer
+	if err := NewCWebPEncoder("", time.Minute).Encode(&lossless, img, true, 75); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := webp.Decode(bytes.NewReader(lossless.Bytes()))
+	if err != nil {
+		t.Fatalf("Lossless WebP is not decodable: %v", err)
+	}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if got := color.NRGBAModel.Convert(decoded.At(x, y)); got != img.NRGBAAt(x, y) {
+				t.Fatalf("Pixel %d,%d: expected %v, got %v", x, y, img.NRGBAAt(x, y), got)
+			}
+		}
+	}
+}
+
+func TestWriteSmallestImageSkipsFailedCandidates(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 64, 64))
+	failed := imageCandidate{format: ImageFormatWebP, encode: func(io.Writer) error {
+		return errors.New("encoder failed")
+	}}
+	original := make([]byte, 1<<16)
+	output := filepath.Join(t.TempDir(), "out")
+
+	format, err := writeSmallestImage(original, output, ImageFormatPNG, []imageCandidate{failed, pngCandidate(img)}, imageMetadata{})
+	if err != nil || format != ImageFormatPNG {
+		t.Fatalf("Expected PNG from the remaining candidate, got %q, %v", format, err)
+	}
+	if _, err := writeSmallestImage(original, output, ImageFormatPNG, []imageCandidate{failed}, imageMetadata{}); err == nil {
+		t.Error("Expected error when no candidate can be encoded")
+	}
+}
+
+func TestCompressPNGAboveWebPLimit(t *testing.T) {
+	// Ширина больше предела WebP: cwebp не вызывается, остается PNG
+	img := image.NewNRGBA(image.Rect(0, 0, 17000, 40))
+	for y := 0; y < 40; y++ {
+		for x := 0; x < 17000; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{uint8(x / 100), uint8(y * 6), 0x80, 0xff})
+		}
+	}
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.png")
+	var buf bytes.Buffer
+	if err := (&png.Encoder{CompressionLevel: png.NoCompression}).Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(input, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cwebp, args := fakeCWebP(t, 17000, 40)
+	format, err := NewImageCompressor(cwebp, 10*time.Second).CompressPNG(input, filepath.Join(dir, "out"), ImageOptions{Quality: 30, ConvertToJPEG: true, WebP: true})
+	if err != nil {
+		t.Fatalf("Expected image above the WebP limit to compress, got %v", err)
+	}
+	if _, err := os.Stat(args); format == ImageFormatWebP || err == nil {
+		t.Error("WebP must not be encoded above its size limit")
+	}
+}
+
+// checkWebPHeader проверяет контейнер RIFF и размеры изображения в заголовке VP8 или VP8L
+func checkWebPHeader(t *testing.T, data []byte, width, height int) {
+	t.Helper()
+	if len(data) < 30 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		t.Fatalf("Not a WebP file: % x", data[:min(len(data), 16)])
+	}
+	if size := binary.LittleEndian.Uint32(data[4:8]); int(size) != len(data)-8 {
+		t.Errorf("RIFF size %d, expected %d", size, len(data)-8)
+	}
+
+	var w, h int
+	switch string(data[12:16]) {
+	case "VP8L":
+		bits := binary.LittleEndian.Uint32(data[21:25])
+		if data[20] != 0x2f {
+			t.Fatalf("Bad VP8L signature %#x", data[20])
+		}
+		w, h = int(bits&0x3fff)+1, int(bits>>14&0x3fff)+1
+	case "VP8 ":
+		if !bytes.Equal(data[23:26], []byte{0x9d, 0x01, 0x2a}) || data[20]&1 != 0 {
+			t.Fatalf("Bad VP8 key frame header % x", data[20:26])
+		}
+		w = int(binary.LittleEndian.Uint16(data[26:28]) & 0x3fff)
+		h = int(binary.LittleEndian.Uint16(data[28:30]) & 0x3fff)
+	default:
+		t.Fatalf("Unexpected chunk %q", data[12:16])
+	}
+	if w != width || h != height {
+		t.Errorf("Expected %dx%d, got %dx%d", width, height, w, h)
+	}
+}
+
+func TestJPEGQuality(t *testing.T) {
+	for quality, expected := range map[int]int{1: 8, 10: 20, 30: 47, 50: 75, 80: 90, 100: 100} {
+		if got := jpegQuality(quality); got != expected {
+			t.Errorf("jpegQuality(%d) = %d, expected %d", quality, got, expected)
+		}
+	}
+}
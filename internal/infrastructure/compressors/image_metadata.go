@@ -0,0 +1,470 @@
+package compressors
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/draw"
+	"io"
+
+	"compress/internal/domain/entities"
+)
+
+// Заголовки сегментов метаданных JPEG
+var (
+	jpegExifHeader = []byte("Exif\x00\x00")
+	jpegXMPHeader  = []byte("http://ns.adobe.com/xap/1.0/\x00")
+	jpegICCHeader  = []byte("ICC_PROFILE\x00")
+	jpegMPFHeader  = []byte("MPF\x00")
+	pngSignature   = []byte("\x89PNG\r\n\x1a\n")
+)
+
+// Ограничения размеров метаданных
+const (
+	jpegMaxSegment  = 65533 // Полезная нагрузка сегмента без поля длины
+	jpegICCChunk    = jpegMaxSegment - 14
+	pngXMPKeyword   = "XML:com.adobe.xmp"
+	exifOrientation = 0x0112
+)
+
+// imageMetadata метаданные изображения, которые переносятся в сжатый файл
+type imageMetadata struct {
+	exif        []byte // EXIF в виде структуры TIFF (без заголовка "Exif\0\0")
+	icc         []byte // ICC профиль
+	xmp         []byte // XMP пакет
+	orientation int    // EXIF Orientation: 1-8, 0 — не задана
+	// Прочие блоки в формате исходного файла (сегменты APPn и COM JPEG или текстовые
+	// блоки PNG целиком); переносятся только в файл того же формата
+	extra        [][]byte
+	sourceFormat string
+}
+
+// readJPEGMetadata извлекает метаданные из сегментов APPn и COM файла JPEG. Сегменты JFIF (APP0),
+// Adobe (APP14) и MPF не переносятся: они описывают кодирование и структуру исходного файла
+func readJPEGMetadata(data []byte) imageMetadata {
+	meta := imageMetadata{sourceFormat: ImageFormatJPEG}
+	iccChunks := make(map[byte][]byte)
+	iccCount := byte(0)
+
+	for i := 2; i+4 <= len(data) && data[i] == 0xff; {
+		marker := data[i+1]
+		if marker == 0xd8 || marker == 0x01 || marker >= 0xd0 && marker <= 0xd7 {
+			i += 2
+			continue
+		}
+		if marker == 0xda || marker == 0xd9 {
+			break // Начало сжатых данных
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 || i+2+length > len(data) {
+			break
+		}
+		segment := data[i : i+2+length]
+		payload := segment[4:]
+		i += 2 + length
+
+		switch {
+		case marker == 0xe1 && bytes.HasPrefix(payload, jpegExifHeader):
+			meta.exif = payload[len(jpegExifHeader):]
+		case marker == 0xe1 && bytes.HasPrefix(payload, jpegXMPHeader):
+			meta.xmp = payload[len(jpegXMPHeader):]
+		case marker == 0xe2 && bytes.HasPrefix(payload, jpegICCHeader) && len(payload) > len(jpegICCHeader)+2:
+			seq, count := payload[len(jpegICCHeader)], payload[len(jpegICCHeader)+1]
+			iccChunks[seq] = payload[len(jpegICCHeader)+2:]
+			iccCount = count
+		case marker == 0xe2 && bytes.HasPrefix(payload, jpegMPFHeader):
+			// Смещения дополнительных изображений MPF не соответствуют новому файлу
+		case marker > 0xe0 && marker <= 0xef && marker != 0xee, marker == 0xfe:
+			meta.extra = append(meta.extra, segment)
+		}
+	}
+
+	// Профиль собирается, только если получены все его части
+	for seq := byte(1); seq <= iccCount; seq++ {
+		chunk, ok := iccChunks[seq]
+		if !ok {
+			meta.icc = nil
+			break
+		}
+		meta.icc = append(meta.icc, chunk...)
+	}
+
+	meta.orientation = exifOrientationValue(meta.exif)
+	return meta
+}
+
+// readPNGMetadata извлекает метаданные из блоков iCCP, eXIf и текстовых блоков PNG
+func readPNGMetadata(data []byte) imageMetadata {
+	meta := imageMetadata{sourceFormat: ImageFormatPNG}
+	if !bytes.HasPrefix(data, pngSignature) {
+		return meta
+	}
+
+	for i := len(pngSignature); i+12 <= len(data); {
+		length := int(binary.BigEndian.Uint32(data[i:]))
+		if length < 0 || i+12+length > len(data) {
+			break
+		}
+		chunkType := string(data[i+4 : i+8])
+		chunk := data[i : i+12+length]
+		payload := data[i+8 : i+8+length]
+		i += 12 + length
+
+		switch chunkType {
+		case "iCCP":
+			// Имя профиля, 0, метод сжатия (0), данные zlib
+			if name := bytes.IndexByte(payload, 0); name >= 0 && name+2 <= len(payload) {
+				meta.icc, _ = inflate(payload[name+2:])
+			}
+		case "eXIf":
+			meta.exif = payload
+		case "iTXt":
+			if xmp, ok := pngXMP(payload); ok {
+				meta.xmp = xmp
+			} else {
+				meta.extra = append(meta.extra, chunk)
+			}
+		case "tEXt", "zTXt":
+			meta.extra = append(meta.extra, chunk)
+		case "IDAT", "IEND":
+			i = len(data)
+		}
+	}
+
+	meta.orientation = exifOrientationValue(meta.exif)
+	return meta
+}
+
+// pngXMP возвращает XMP пакет из блока iTXt с ключом XML:com.adobe.xmp
+func pngXMP(payload []byte) ([]byte, bool) {
+	if !bytes.HasPrefix(payload, []byte(pngXMPKeyword+"\x00")) {
+		return nil, false
+	}
+	rest := payload[len(pngXMPKeyword)+1:]
+	if len(rest) < 2 {
+		return nil, false
+	}
+	compressed := rest[0] == 1
+	rest = rest[2:]
+	// Язык и переведенный ключ
+	for n := 0; n < 2; n++ {
+		end := bytes.IndexByte(rest, 0)
+		if end < 0 {
+			return nil, false
+		}
+		rest = rest[end+1:]
+	}
+	if compressed {
+		text, err := inflate(rest)
+		return text, err == nil
+	}
+	return rest, true
+}
+
+// inflate распаковывает данные zlib
+func inflate(data []byte) ([]byte, error) {
+	reader, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// exifOrientationValue возвращает значение тега Orientation из IFD0 структуры TIFF (0 — нет тега)
+func exifOrientationValue(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 0
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:4]) {
+	case "II*\x00":
+		order = binary.LittleEndian
+	case "MM\x00*":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 0
+	}
+	count := int(order.Uint16(tiff[ifd:]))
+	for n := 0; n < count; n++ {
+		entry := ifd + 2 + n*12
+		if entry+12 > len(tiff) {
+			return 0
+		}
+		if order.Uint16(tiff[entry:]) == exifOrientation && order.Uint16(tiff[entry+2:]) == 3 {
+			if value := int(order.Uint16(tiff[entry+8:])); value >= 1 && value <= 8 {
+				return value
+			}
+			return 0
+		}
+	}
+	return 0
+}
+
+// orientationEXIF строит минимальный EXIF с единственным тегом Orientation
+func orientationEXIF(orientation int) []byte {
+	tiff := []byte("MM\x00*\x00\x00\x00\x08")
+	tiff = binary.BigEndian.AppendUint16(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, exifOrientation)
+	tiff = binary.BigEndian.AppendUint16(tiff, 3) // SHORT
+	tiff = binary.BigEndian.AppendUint32(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, uint16(orientation))
+	tiff = binary.BigEndian.AppendUint16(tiff, 0)
+	return binary.BigEndian.AppendUint32(tiff, 0) // Следующего IFD нет
+}
+
+// applyImageMetadataPolicy оставляет метаданные, которые разрешает политика. При удалении
+// метаданных изображение поворачивается согласно EXIF Orientation, чтобы оно выглядело так же
+func applyImageMetadataPolicy(meta imageMetadata, img image.Image, policy string) (imageMetadata, image.Image) {
+	switch policy {
+	case entities.ImageMetadataKeepAll:
+		return meta, img
+	case entities.ImageMetadataStripAll:
+		return imageMetadata{}, orientImage(img, meta.orientation)
+	default:
+		kept := imageMetadata{icc: meta.icc, orientation: meta.orientation}
+		if meta.orientation > 1 {
+			kept.exif = orientationEXIF(meta.orientation)
+		}
+		return kept, img
+	}
+}
+
+// orientImage поворачивает и отражает изображение так, чтобы оно выглядело как при
+// отображении с указанным EXIF Orientation
+func orientImage(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	src := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // Отражение по горизонтали
+				dx, dy = w-1-x, y
+			case 3: // Поворот на 180°
+				dx, dy = w-1-x, h-1-y
+			case 4: // Отражение по вертикали
+				dx, dy = x, h-1-y
+			case 5: // Транспонирование
+				dx, dy = y, x
+			case 6: // Поворот на 90° по часовой стрелке
+				dx, dy = h-1-y, x
+			case 7: // Поперечное транспонирование
+				dx, dy = h-1-y, w-1-x
+			case 8: // Поворот на 90° против часовой стрелки
+				dx, dy = y, w-1-x
+			}
+			copy(dst.Pix[dy*dst.Stride+dx*4:dy*dst.Stride+dx*4+4], src.Pix[y*src.Stride+x*4:])
+		}
+	}
+	return dst
+}
+
+// iccMatchesImage проверяет, что цветовое пространство профиля (RGB или GRAY) совпадает
+// с записанным изображением: профиль другого пространства (например, CMYK исходника) неверен
+func iccMatchesImage(icc []byte, gray bool) bool {
+	if len(icc) < 20 {
+		return false
+	}
+	switch string(icc[16:20]) {
+	case "RGB ":
+		return !gray
+	case "GRAY":
+		return gray
+	}
+	return false
+}
+
+// embedMetadata добавляет метаданные в закодированное изображение формата format
+func embedMetadata(format string, data []byte, meta imageMetadata, gray bool) []byte {
+	icc := meta.icc
+	if !iccMatchesImage(icc, gray) {
+		icc = nil
+	}
+	extra := meta.extra
+	if meta.sourceFormat != format {
+		extra = nil
+	}
+	if icc == nil && meta.exif == nil && meta.xmp == nil && extra == nil {
+		return data
+	}
+
+	switch format {
+	case ImageFormatJPEG:
+		return embedJPEGMetadata(data, meta.exif, icc, meta.xmp, extra)
+	case ImageFormatPNG:
+		return embedPNGMetadata(data, meta.exif, icc, meta.xmp, extra)
+	case ImageFormatWebP:
+		return embedWebPMetadata(data, meta.exif, icc, meta.xmp)
+	}
+	return data
+}
+
+// embedJPEGMetadata вставляет сегменты APP1 (EXIF, XMP), APP2 (ICC) и прочие сегменты после SOI
+func embedJPEGMetadata(data, exif, icc, xmp []byte, extra [][]byte) []byte {
+	if len(data) < 2 {
+		return data
+	}
+
+	var segments bytes.Buffer
+	writeSegment := func(marker byte, parts ...[]byte) {
+		length := 2
+		for _, part := range parts {
+			length += len(part)
+		}
+		if length-2 > jpegMaxSegment {
+			return // Не помещается в сегмент
+		}
+		segments.Write([]byte{0xff, marker, byte(length >> 8), byte(length)})
+		for _, part := range parts {
+			segments.Write(part)
+		}
+	}
+
+	if exif != nil {
+		writeSegment(0xe1, jpegExifHeader, exif)
+	}
+	if xmp != nil {
+		writeSegment(0xe1, jpegXMPHeader, xmp)
+	}
+	count := (len(icc) + jpegICCChunk - 1) / jpegICCChunk
+	for seq := 0; seq < count && count <= 255; seq++ {
+		chunk := icc[seq*jpegICCChunk : min(len(icc), (seq+1)*jpegICCChunk)]
+		writeSegment(0xe2, jpegICCHeader, []byte{byte(seq + 1), byte(count)}, chunk)
+	}
+	for _, segment := range extra {
+		segments.Write(segment)
+	}
+
+	out := make([]byte, 0, len(data)+segments.Len())
+	out = append(out, data[:2]...)
+	out = append(out, segments.Bytes()...)
+	return append(out, data[2:]...)
+}
+
+// embedPNGMetadata вставляет блоки iCCP, eXIf, iTXt (XMP) и прочие текстовые блоки после IHDR
+func embedPNGMetadata(data, exif, icc, xmp []byte, extra [][]byte) []byte {
+	// Сигнатура и IHDR (13 байт данных) имеют фиксированный размер
+	ihdrEnd := len(pngSignature) + 12 + 13
+	if len(data) < ihdrEnd || !bytes.HasPrefix(data, pngSignature) {
+		return data
+	}
+
+	var chunks bytes.Buffer
+	writeChunk := func(chunkType string, payload []byte) {
+		header := binary.BigEndian.AppendUint32(nil, uint32(len(payload)))
+		header = append(header, chunkType...)
+		crc := crc32.NewIEEE()
+		crc.Write(header[4:])
+		crc.Write(payload)
+		chunks.Write(header)
+		chunks.Write(payload)
+		chunks.Write(binary.BigEndian.AppendUint32(nil, crc.Sum32()))
+	}
+
+	if icc != nil {
+		var compressed bytes.Buffer
+		writer, _ := zlib.NewWriterLevel(&compressed, zlib.BestCompression)
+		writer.Write(icc)
+		writer.Close()
+		writeChunk("iCCP", append([]byte("ICC Profile\x00\x00"), compressed.Bytes()...))
+	}
+	if exif != nil {
+		writeChunk("eXIf", exif)
+	}
+	if xmp != nil {
+		// Ключ, без сжатия, метод 0, пустые язык и переведенный ключ
+		writeChunk("iTXt", append([]byte(pngXMPKeyword+"\x00\x00\x00\x00\x00"), xmp...))
+	}
+	for _, chunk := range extra {
+		chunks.Write(chunk)
+	}
+
+	out := make([]byte, 0, len(data)+chunks.Len())
+	out = append(out, data[:ihdrEnd]...)
+	out = append(out, chunks.Bytes()...)
+	return append(out, data[ihdrEnd:]...)
+}
+
+// embedWebPMetadata переводит простой WebP (один блок VP8 или VP8L) в расширенный формат
+// с блоками VP8X, ICCP, EXIF и XMP
+func embedWebPMetadata(data, exif, icc, xmp []byte) []byte {
+	if len(data) < 30 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return data
+	}
+	bitstream := data[12:]
+
+	var width, height int
+	var flags byte
+	switch string(bitstream[0:4]) {
+	case "VP8L":
+		bits := binary.LittleEndian.Uint32(bitstream[9:13])
+		width, height = int(bits&0x3fff)+1, int(bits>>14&0x3fff)+1
+		if bits>>28&1 == 1 {
+			flags |= 0x10 // Альфа-канал
+		}
+	case "VP8 ":
+		width = int(binary.LittleEndian.Uint16(bitstream[14:16]) & 0x3fff)
+		height = int(binary.LittleEndian.Uint16(bitstream[16:18]) & 0x3fff)
+	default:
+		return data
+	}
+
+	var chunks bytes.Buffer
+	writeChunk := func(fourCC string, payload []byte) {
+		chunks.WriteString(fourCC)
+		chunks.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(payload))))
+		chunks.Write(payload)
+		if len(payload)&1 == 1 {
+			chunks.WriteByte(0)
+		}
+	}
+
+	if icc != nil {
+		flags |= 0x20
+	}
+	if exif != nil {
+		flags |= 0x08
+	}
+	if xmp != nil {
+		flags |= 0x04
+	}
+	vp8x := []byte{flags, 0, 0, 0}
+	vp8x = append(vp8x, byte(width-1), byte((width-1)>>8), byte((width-1)>>16))
+	vp8x = append(vp8x, byte(height-1), byte((height-1)>>8), byte((height-1)>>16))
+	writeChunk("VP8X", vp8x)
+	if icc != nil {
+		writeChunk("ICCP", icc)
+	}
+	chunks.Write(bitstream)
+	if exif != nil {
+		writeChunk("EXIF", exif)
+	}
+	if xmp != nil {
+		writeChunk("XMP ", xmp)
+	}
+
+	out := []byte("RIFF")
+	out = binary.LittleEndian.AppendUint32(out, uint32(4+chunks.Len()))
+	out = append(out, "WEBP"...)
+	return append(out, chunks.Bytes()...)
+}
@@ -0,0 +1,235 @@
+package compressors
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"compress/internal/domain/entities"
+)
+
+// testICCProfile профиль заданного цветового пространства; размер больше сегмента JPEG,
+// чтобы проверить разбиение на части
+func testICCProfile(colorSpace string) []byte {
+	icc := make([]byte, 70000)
+	binary.BigEndian.PutUint32(icc, uint32(len(icc)))
+	copy(icc[12:], "mntr")
+	copy(icc[16:], colorSpace)
+	for i := 128; i < len(icc); i++ {
+		icc[i] = byte(i * 7)
+	}
+	return icc
+}
+
+func TestCompressJPEGMetadata(t *testing.T) {
+	const width, height = 120, 60
+	r := rand.New(rand.NewSource(1))
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{uint8(x * 2), uint8(r.Intn(256)), uint8(y * 4), 0xff})
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatal(err)
+	}
+	icc := testICCProfile("RGB ")
+	comment := append([]byte{0xff, 0xfe, 0x00, 0x06}, "test"...)
+	source := embedJPEGMetadata(buf.Bytes(), orientationEXIF(6), icc, []byte("<x:xmpmeta/>"), [][]byte{comment})
+
+	meta := readJPEGMetadata(source)
+	if meta.orientation != 6 || !bytes.Equal(meta.icc, icc) || string(meta.xmp) != "<x:xmpmeta/>" || len(meta.extra) != 1 {
+		t.Fatalf("Metadata is not read back: orientation %d, icc %d bytes, xmp %q, extra %d",
+			meta.orientation, len(meta.icc), meta.xmp, len(meta.extra))
+	}
+
+	tests := []struct {
+		name        string
+		policy      string
+		orientation int
+		icc         bool
+		xmp         bool
+		extra       int
+		portrait    bool // Пиксели повернуты по EXIF Orientation
+	}{
+		{"Strip all", entities.ImageMetadataStripAll, 0, false, false, 0, true},
+		{"Keep orientation and ICC", entities.ImageMetadataKeepOrientationICC, 6, true, false, 0, false},
+		{"Keep all", entities.ImageMetadataKeepAll, 6, true, true, 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			input := filepath.Join(dir, "in.jpg")
+			if err := os.WriteFile(input, source, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			output := filepath.Join(dir, "out.jpg")
+
+			format, err := NewImageCompressor("", time.Minute).CompressJPEG(input, output, ImageOptions{Quality: 30, Metadata: tt.policy})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if format != ImageFormatJPEG {
+				t.Fatalf("Expected JPEG, got %q", format)
+			}
+			data, err := os.ReadFile(output)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := readJPEGMetadata(data)
+			if got.orientation != tt.orientation {
+				t.Errorf("Expected orientation %d, got %d", tt.orientation, got.orientation)
+			}
+			if hasICC := bytes.Equal(got.icc, icc); hasICC != tt.icc {
+				t.Errorf("ICC profile preserved: %v, expected %v", hasICC, tt.icc)
+			}
+			if hasXMP := got.xmp != nil; hasXMP != tt.xmp {
+				t.Errorf("XMP preserved: %v, expected %v", hasXMP, tt.xmp)
+			}
+			if len(got.extra) != tt.extra {
+				t.Errorf("Expected %d extra segments, got %d", tt.extra, len(got.extra))
+			}
+
+			decoded, err := jpeg.Decode(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			bounds := decoded.Bounds()
+			if portrait := bounds.Dy() > bounds.Dx(); portrait != tt.portrait {
+				t.Errorf("Unexpected orientation of pixels: %dx%d", bounds.Dx(), bounds.Dy())
+			}
+		})
+	}
+}
+
+func TestCompressJPEGBoundingBoxOrientation(t *testing.T) {
+	// Хранится 120x60, показывается повернутым (Orientation 6) как 60x120
+	img := image.NewRGBA(image.Rect(0, 0, 120, 60))
+	for y := 0; y < 60; y++ {
+		for x := 0; x < 120; x++ {
+			img.Set(x, y, color.RGBA{uint8(x * 2), 0x80, uint8(y * 4), 0xff})
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatal(err)
+	}
+	source := embedJPEGMetadata(buf.Bytes(), orientationEXIF(6), nil, nil, nil)
+
+	tests := []struct {
+		name           string
+		policy         string
+		expectedWidth  int
+		expectedHeight int
+	}{
+		// Рамка 30x120 вписывает показываемое изображение в 30x60; пиксели остаются повернутыми
+		{"Keep orientation", entities.ImageMetadataKeepOrientationICC, 60, 30},
+		{"Strip all rotates pixels", entities.ImageMetadataStripAll, 30, 60},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			input := filepath.Join(dir, "in.jpg")
+			if err := os.WriteFile(input, source, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			output := filepath.Join(dir, "out.jpg")
+
+			options := ImageOptions{Quality: 30, Metadata: tt.policy, MaxWidth: 30, MaxHeight: 120}
+			if _, err := NewImageCompressor("", time.Minute).CompressJPEG(input, output, options); err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			decoded, err := jpeg.Decode(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bounds := decoded.Bounds(); bounds.Dx() != tt.expectedWidth || bounds.Dy() != tt.expectedHeight {
+				t.Errorf("Expected stored %dx%d, got %dx%d", tt.expectedWidth, tt.expectedHeight, bounds.Dx(), bounds.Dy())
+			}
+		})
+	}
+}
+
+func TestPNGMetadataRoundTrip(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 8, 4))
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	icc := testICCProfile("RGB ")
+	data := embedPNGMetadata(buf.Bytes(), orientationEXIF(3), icc, []byte("<x:xmpmeta/>"), nil)
+
+	if _, err := png.Decode(bytes.NewReader(data)); err != nil {
+		t.Fatalf("PNG with metadata is not decoded: %v", err)
+	}
+	meta := readPNGMetadata(data)
+	if meta.orientation != 3 || !bytes.Equal(meta.icc, icc) || string(meta.xmp) != "<x:xmpmeta/>" {
+		t.Errorf("Metadata is not read back: orientation %d, icc %d bytes, xmp %q", meta.orientation, len(meta.icc), meta.xmp)
+	}
+}
+
+func TestEmbedWebPMetadata(t *testing.T) {
+	buf := bytes.NewBuffer(testWebP(37, 21))
+	meta := imageMetadata{exif: orientationEXIF(6), icc: testICCProfile("RGB ")}
+
+	data := embedMetadata(ImageFormatWebP, buf.Bytes(), meta, false)
+	if string(data[12:16]) != "VP8X" {
+		t.Fatalf("Expected VP8X chunk, got %q", data[12:16])
+	}
+	if flags := data[20]; flags != 0x20|0x08|0x10 {
+		t.Errorf("Unexpected VP8X flags %#x", flags)
+	}
+	if size := binary.LittleEndian.Uint32(data[4:8]); int(size) != len(data)-8 {
+		t.Errorf("RIFF size %d, expected %d", size, len(data)-8)
+	}
+	width := int(data[24]) | int(data[25])<<8 | int(data[26])<<16
+	height := int(data[27]) | int(data[28])<<8 | int(data[29])<<16
+	if width+1 != 37 || height+1 != 21 {
+		t.Errorf("Expected 37x21 canvas, got %dx%d", width+1, height+1)
+	}
+
+	// Профиль RGB не подходит изображению в оттенках серого
+	if gray := embedMetadata(ImageFormatWebP, buf.Bytes(), imageMetadata{icc: meta.icc}, true); !bytes.Equal(gray, buf.Bytes()) {
+		t.Error("RGB profile must not be embedded into a grayscale image")
+	}
+}
+
+func TestOrientImage(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	img.SetNRGBA(0, 0, color.NRGBA{0xff, 0, 0, 0xff}) // Левый верхний угол
+
+	tests := []struct {
+		orientation int
+		x, y        int // Положение угла после поворота
+	}{
+		{2, 2, 0},
+		{3, 2, 1},
+		{4, 0, 1},
+		{5, 0, 0},
+		{6, 1, 0},
+		{7, 1, 2},
+		{8, 0, 2},
+	}
+	for _, tt := range tests {
+		oriented := orientImage(img, tt.orientation)
+		if c := color.NRGBAModel.Convert(oriented.At(tt.x, tt.y)).(color.NRGBA); c.R != 0xff {
+			t.Errorf("Orientation %d: corner is not at %d,%d", tt.orientation, tt.x, tt.y)
+		}
+	}
+}
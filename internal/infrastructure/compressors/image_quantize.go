@@ -0,0 +1,310 @@
+package compressors
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"sort"
+)
+
+// Параметры квантования палитры
+const (
+	// quantizeMaxHistogram число различных цветов, выше которого гистограмма огрубляется
+	quantizeMaxHistogram = 1 << 16
+	// quantizeIterations число уточнений палитры методом k-средних после медианного сечения
+	quantizeIterations = 3
+)
+
+// pngPaletteColors размер палитры по качеству PNG: 10->16, 30->136, 50->256
+func pngPaletteColors(quality int) int {
+	return max(2, min(256, 16+(quality-10)*6))
+}
+
+// pngDitherStrength сила рассеивания ошибки по качеству PNG: чем меньше палитра,
+// тем сильнее рассеивание (10->1.0, 50->0.5)
+func pngDitherStrength(quality int) float64 {
+	return max(0.5, min(1, 1-float64(quality-10)/80))
+}
+
+// quantColor цвет гистограммы с умноженными на альфу каналами (r, g, b, a) и числом пикселей
+type quantColor struct {
+	c      [4]float64
+	weight float64
+}
+
+// quantBox ячейка медианного сечения
+type quantBox struct {
+	colors  []quantColor
+	weight  float64
+	channel int     // Канал с наибольшим разбросом
+	score   float64 // Взвешенная дисперсия по этому каналу
+}
+
+// quantizeImage переводит изображение в палитру не более чем из maxColors цветов с сохранением
+// прозрачности. Если цветов в изображении не больше maxColors, палитра точная. Иначе палитра
+// строится медианным сечением с уточнением k-средними, а dither (0-1) задает силу рассеивания
+// ошибки по Флойду-Стейнбергу (0 — без рассеивания)
+func quantizeImage(img image.Image, maxColors int, dither float64) *image.Paletted {
+	bounds := img.Bounds()
+	src := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+
+	if paletted, ok := exactPaletted(src, maxColors); ok {
+		return paletted
+	}
+
+	colors := colorHistogram(src)
+	palette := medianCut(colors, maxColors)
+	for i := 0; i < quantizeIterations; i++ {
+		palette = refinePalette(colors, palette)
+	}
+	return mapToPalette(src, palette, dither)
+}
+
+// exactPaletted строит палитру из всех цветов изображения, если их не больше maxColors
+func exactPaletted(src *image.NRGBA, maxColors int) (*image.Paletted, bool) {
+	index := make(map[color.NRGBA]uint8, maxColors)
+	var palette color.Palette
+	for i := 0; i < len(src.Pix); i += 4 {
+		c := color.NRGBA{src.Pix[i], src.Pix[i+1], src.Pix[i+2], src.Pix[i+3]}
+		if _, ok := index[c]; ok {
+			continue
+		}
+		if len(palette) == maxColors {
+			return nil, false
+		}
+		index[c] = uint8(len(palette))
+		palette = append(palette, c)
+	}
+
+	dst := image.NewPaletted(src.Rect, palette)
+	for y := 0; y < src.Rect.Dy(); y++ {
+		for x := 0; x < src.Rect.Dx(); x++ {
+			p := src.Pix[y*src.Stride+x*4:]
+			dst.Pix[y*dst.Stride+x] = index[color.NRGBA{p[0], p[1], p[2], p[3]}]
+		}
+	}
+	return dst, true
+}
+
+// premultiply возвращает каналы пикселя NRGBA, умноженные на альфу
+func premultiply(p []uint8) [4]float64 {
+	a := float64(p[3])
+	return [4]float64{float64(p[0]) * a / 255, float64(p[1]) * a / 255, float64(p[2]) * a / 255, a}
+}
+
+// colorHistogram собирает различные цвета изображения. Если их больше quantizeMaxHistogram,
+// младшие биты каналов отбрасываются: близкие цвета объединяются в среднее
+func colorHistogram(src *image.NRGBA) []quantColor {
+	buckets := make(map[uint32]*quantColor)
+	for i := 0; i < len(src.Pix); i += 4 {
+		key := uint32(src.Pix[i])<<24 | uint32(src.Pix[i+1])<<16 | uint32(src.Pix[i+2])<<8 | uint32(src.Pix[i+3])
+		bucket, ok := buckets[key]
+		if !ok {
+			bucket = &quantColor{}
+			buckets[key] = bucket
+		}
+		c := premultiply(src.Pix[i : i+4])
+		for ch := range c {
+			bucket.c[ch] += c[ch]
+		}
+		bucket.weight++
+	}
+
+	for shift := 1; len(buckets) > quantizeMaxHistogram && shift < 8; shift++ {
+		mask := uint32(0xff>>shift<<shift) * 0x01010101
+		merged := make(map[uint32]*quantColor, len(buckets)/2)
+		for key, bucket := range buckets {
+			target, ok := merged[key&mask]
+			if !ok {
+				target = &quantColor{}
+				merged[key&mask] = target
+			}
+			for ch := range bucket.c {
+				target.c[ch] += bucket.c[ch]
+			}
+			target.weight += bucket.weight
+		}
+		buckets = merged
+	}
+
+	colors := make([]quantColor, 0, len(buckets))
+	for _, bucket := range buckets {
+		for ch := range bucket.c {
+			bucket.c[ch] /= bucket.weight
+		}
+		colors = append(colors, *bucket)
+	}
+	// Порядок карты случаен, а результат должен быть воспроизводимым
+	sort.Slice(colors, func(i, j int) bool {
+		for ch := 3; ch >= 0; ch-- {
+			if colors[i].c[ch] != colors[j].c[ch] {
+				return colors[i].c[ch] < colors[j].c[ch]
+			}
+		}
+		return false
+	})
+	return colors
+}
+
+// newQuantBox создает ячейку и находит канал с наибольшей взвешенной дисперсией
+func newQuantBox(colors []quantColor) quantBox {
+	box := quantBox{colors: colors}
+	var sum, sumSq [4]float64
+	for _, c := range colors {
+		box.weight += c.weight
+		for ch := range c.c {
+			sum[ch] += c.c[ch] * c.weight
+			sumSq[ch] += c.c[ch] * c.c[ch] * c.weight
+		}
+	}
+	for ch := range sum {
+		// Сумма квадратов отклонений по каналу: ошибка, которую уберет разбиение
+		if score := sumSq[ch] - sum[ch]*sum[ch]/box.weight; score > box.score {
+			box.channel, box.score = ch, score
+		}
+	}
+	return box
+}
+
+// medianCut делит цветовое пространство на maxColors ячеек: каждый раз ячейка с наибольшей
+// ошибкой делится по взвешенной медиане канала с наибольшим разбросом
+func medianCut(colors []quantColor, maxColors int) [][4]float64 {
+	boxes := []quantBox{newQuantBox(colors)}
+	for len(boxes) < maxColors {
+		best := -1
+		for i, box := range boxes {
+			if len(box.colors) > 1 && box.score > 0 && (best < 0 || box.score > boxes[best].score) {
+				best = i
+			}
+		}
+		if best < 0 {
+			break
+		}
+
+		box := boxes[best]
+		ch := box.channel
+		sort.SliceStable(box.colors, func(i, j int) bool { return box.colors[i].c[ch] < box.colors[j].c[ch] })
+		split, acc := 1, box.colors[0].weight
+		for split < len(box.colors)-1 && acc+box.colors[split].weight <= box.weight/2 {
+			acc += box.colors[split].weight
+			split++
+		}
+		boxes[best] = newQuantBox(box.colors[:split])
+		boxes = append(boxes, newQuantBox(box.colors[split:]))
+	}
+
+	palette := make([][4]float64, len(boxes))
+	for i, box := range boxes {
+		for _, c := range box.colors {
+			for ch := range c.c {
+				palette[i][ch] += c.c[ch] * c.weight / box.weight
+			}
+		}
+	}
+	return palette
+}
+
+// refinePalette одна итерация k-средних: каждый цвет палитры заменяется средним цветов
+// гистограммы, для которых он ближайший
+func refinePalette(colors []quantColor, palette [][4]float64) [][4]float64 {
+	sums := make([][4]float64, len(palette))
+	weights := make([]float64, len(palette))
+	for _, c := range colors {
+		i := nearestColor(palette, c.c)
+		for ch := range c.c {
+			sums[i][ch] += c.c[ch] * c.weight
+		}
+		weights[i] += c.weight
+	}
+
+	refined := make([][4]float64, len(palette))
+	for i := range palette {
+		if weights[i] == 0 {
+			refined[i] = palette[i]
+			continue
+		}
+		for ch := range sums[i] {
+			refined[i][ch] = sums[i][ch] / weights[i]
+		}
+	}
+	return refined
+}
+
+// nearestColor индекс ближайшего цвета палитры
+func nearestColor(palette [][4]float64, c [4]float64) int {
+	best, bestDist := 0, math.MaxFloat64
+	for i, p := range palette {
+		d0, d1, d2, d3 := p[0]-c[0], p[1]-c[1], p[2]-c[2], p[3]-c[3]
+		if dist := d0*d0 + d1*d1 + d2*d2 + d3*d3; dist < bestDist {
+			best, bestDist = i, dist
+		}
+	}
+	return best
+}
+
+// mapToPalette заменяет пиксели ближайшими цветами палитры, рассеивая ошибку с силой dither
+func mapToPalette(src *image.NRGBA, palette [][4]float64, dither float64) *image.Paletted {
+	width, height := src.Rect.Dx(), src.Rect.Dy()
+
+	colors := make(color.Palette, len(palette))
+	for i, p := range palette {
+		a := math.Round(p[3])
+		if a == 0 {
+			colors[i] = color.NRGBA{}
+			continue
+		}
+		unpremultiply := func(v float64) uint8 { return uint8(math.Round(math.Min(255, v*255/p[3]))) }
+		colors[i] = color.NRGBA{unpremultiply(p[0]), unpremultiply(p[1]), unpremultiply(p[2]), uint8(a)}
+	}
+	dst := image.NewPaletted(image.Rect(0, 0, width, height), colors)
+
+	// Ближайший цвет для уже встречавшихся значений пикселя
+	cache := make(map[[4]uint8]uint8)
+	lookup := func(c [4]float64) uint8 {
+		var key [4]uint8
+		for ch := range c {
+			key[ch] = uint8(math.Round(c[ch]))
+		}
+		if i, ok := cache[key]; ok {
+			return i
+		}
+		i := uint8(nearestColor(palette, c))
+		cache[key] = i
+		return i
+	}
+
+	errCur := make([][4]float64, width+2)
+	errNext := make([][4]float64, width+2)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := premultiply(src.Pix[y*src.Stride+x*4:])
+			if dither > 0 {
+				for ch := range c {
+					c[ch] = math.Max(0, math.Min(255, c[ch]+errCur[x+1][ch]))
+				}
+				// Каналы с умножением на альфу не могут ее превышать
+				for ch := 0; ch < 3; ch++ {
+					c[ch] = math.Min(c[ch], c[3])
+				}
+			}
+
+			i := lookup(c)
+			dst.Pix[y*dst.Stride+x] = i
+			if dither == 0 {
+				continue
+			}
+			for ch := range c {
+				e := (c[ch] - palette[i][ch]) * dither
+				errCur[x+2][ch] += e * 7 / 16
+				errNext[x][ch] += e * 3 / 16
+				errNext[x+1][ch] += e * 5 / 16
+				errNext[x+2][ch] += e / 16
+			}
+		}
+		errCur, errNext = errNext, errCur
+		clear(errNext)
+	}
+	return dst
+}
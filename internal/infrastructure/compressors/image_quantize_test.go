@@ -0,0 +1,82 @@
+package compressors
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestQuantizeImage(t *testing.T) {
+	const size = 64
+
+	// Градиент с прозрачной левой четвертью
+	gradient := image.NewNRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			if x < size/4 {
+				gradient.SetNRGBA(x, y, color.NRGBA{})
+				continue
+			}
+			gradient.SetNRGBA(x, y, color.NRGBA{uint8(x * 4), uint8(y * 4), uint8(255 - x*2), 0xff})
+		}
+	}
+
+	// Скриншот: несколько цветов, уже помещается в палитру
+	screenshot := image.NewNRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			screenshot.SetNRGBA(x, y, color.NRGBA{uint8(x / 16 * 80), 0x20, uint8(y / 32 * 200), uint8(0xff - x/32*0x7f)})
+		}
+	}
+
+	tests := []struct {
+		name      string
+		img       *image.NRGBA
+		maxColors int
+		dither    float64
+		exact     bool
+		maxError  float64 // Допустимая средняя ошибка канала
+	}{
+		{"Exact palette", screenshot, 16, 0, true, 0},
+		{"Gradient without dithering", gradient, 64, 0, false, 6},
+		{"Gradient with dithering", gradient, 64, 1, false, 8},
+		{"Small palette", gradient, 16, 0.5, false, 12},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paletted := quantizeImage(tt.img, tt.maxColors, tt.dither)
+			if len(paletted.Palette) > tt.maxColors {
+				t.Fatalf("Palette has %d colors, expected at most %d", len(paletted.Palette), tt.maxColors)
+			}
+
+			var totalError float64
+			for y := 0; y < size; y++ {
+				for x := 0; x < size; x++ {
+					want := tt.img.NRGBAAt(x, y)
+					got := color.NRGBAModel.Convert(paletted.At(x, y)).(color.NRGBA)
+					if got.A != want.A {
+						t.Fatalf("Alpha at %d,%d: expected %d, got %d", x, y, want.A, got.A)
+					}
+					if tt.exact && got != want {
+						t.Fatalf("Pixel %d,%d: expected %v, got %v", x, y, want, got)
+					}
+					if want.A != 0 {
+						totalError += absFloat(float64(got.R)-float64(want.R)) +
+							absFloat(float64(got.G)-float64(want.G)) + absFloat(float64(got.B)-float64(want.B))
+					}
+				}
+			}
+			if meanError := totalError / (3 * size * size); meanError > tt.maxError {
+				t.Errorf("Mean channel error %.2f exceeds %.2f", meanError, tt.maxError)
+			}
+		})
+	}
+}
+
+func absFloat(v float64) float64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,87 @@
+package compressors
+
+import (
+	"image"
+	"math"
+
+	"github.com/nfnt/resize"
+
+	"compress/internal/domain/entities"
+)
+
+// fitSize вычисляет размер изображения, вписанного в рамку options: ширина, высота и число
+// пикселей не превышают заданных ограничений, пропорции сохраняются. Изображение меньше
+// рамки увеличивается, только если разрешено options.Upscale
+func fitSize(width, height int, options ImageOptions) (int, int) {
+	scale := math.Inf(1)
+	if options.MaxWidth > 0 {
+		scale = math.Min(scale, float64(options.MaxWidth)/float64(width))
+	}
+	if options.MaxHeight > 0 {
+		scale = math.Min(scale, float64(options.MaxHeight)/float64(height))
+	}
+	maxPixels := options.MaxMegapixels * 1e6
+	if maxPixels > 0 {
+		scale = math.Min(scale, math.Sqrt(maxPixels/float64(width*height)))
+	}
+	if math.IsInf(scale, 1) || scale == 1 || scale > 1 && !options.Upscale {
+		return width, height
+	}
+
+	newWidth := max(1, int(math.Round(float64(width)*scale)))
+	newHeight := max(1, int(math.Round(float64(height)*scale)))
+	// Округление не должно выводить размер за рамку
+	if options.MaxWidth > 0 {
+		newWidth = min(newWidth, options.MaxWidth)
+	}
+	if options.MaxHeight > 0 {
+		newHeight = min(newHeight, options.MaxHeight)
+	}
+	if maxPixels > 0 && float64(newWidth*newHeight) > maxPixels {
+		newWidth = max(1, int(float64(width)*scale))
+		newHeight = max(1, int(float64(height)*scale))
+	}
+	return newWidth, newHeight
+}
+
+// fitImage вписывает изображение в рамку options выбранным фильтром интерполяции
+func fitImage(img image.Image, options ImageOptions) image.Image {
+	bounds := img.Bounds()
+	width, height := fitSize(bounds.Dx(), bounds.Dy(), options)
+	if width == bounds.Dx() && height == bounds.Dy() {
+		return img
+	}
+	return resize.Resize(uint(width), uint(height), img, resizeFilter(options.Filter))
+}
+
+// forOrientation возвращает параметры с рамкой в пикселях хранимого изображения. Рамка задана
+// для изображения в том виде, в каком его показывают: при EXIF Orientation 5-8 сохраненные
+// пиксели повернуты на 90°, поэтому ширина и высота рамки меняются местами
+func (o ImageOptions) forOrientation(orientation int) ImageOptions {
+	if orientation >= 5 && orientation <= 8 {
+		o.MaxWidth, o.MaxHeight = o.MaxHeight, o.MaxWidth
+	}
+	return o
+}
+
+// resizeFilter возвращает фильтр интерполяции по имени; по умолчанию Lanczos3
+func resizeFilter(name string) resize.InterpolationFunction {
+	switch name {
+	case entities.ResizeFilterNearest:
+		return resize.NearestNeighbor
+	case entities.ResizeFilterBilinear:
+		return resize.Bilinear
+	case entities.ResizeFilterBicubic:
+		return resize.Bicubic
+	case entities.ResizeFilterMitchell:
+		return resize.MitchellNetravali
+	case entities.ResizeFilterLanczos2:
+		return resize.Lanczos2
+	}
+	return resize.Lanczos3
+}
+
+// hasBoundingBox проверяет, задана ли рамка изображения
+func (o ImageOptions) hasBoundingBox() bool {
+	return o.MaxWidth > 0 || o.MaxHeight > 0 || o.MaxMegapixels > 0
+}
@@ -0,0 +1,56 @@
+package compressors
+
+import (
+	"image"
+	"testing"
+)
+
+func TestFitSize(t *testing.T) {
+	tests := []struct {
+		name                 string
+		width, height        int
+		options              ImageOptions
+		expectedW, expectedH int
+	}{
+		{"Panorama fits width", 20000, 2000, ImageOptions{MaxWidth: 2560, MaxHeight: 1440}, 2560, 256},
+		{"Portrait fits height", 3000, 4000, ImageOptions{MaxWidth: 2560, MaxHeight: 1440}, 1080, 1440},
+		{"Icon is not upscaled", 300, 300, ImageOptions{MaxWidth: 2560, MaxHeight: 1440}, 300, 300},
+		{"Icon is upscaled", 300, 200, ImageOptions{MaxWidth: 600, Upscale: true}, 600, 400},
+		{"Megapixels", 6000, 4000, ImageOptions{MaxMegapixels: 6}, 3000, 2000},
+		{"Width only", 4000, 3000, ImageOptions{MaxWidth: 1000}, 1000, 750},
+		{"No limits", 4000, 3000, ImageOptions{Upscale: true}, 4000, 3000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, h := fitSize(tt.width, tt.height, tt.options)
+			if w != tt.expectedW || h != tt.expectedH {
+				t.Errorf("Expected %dx%d, got %dx%d", tt.expectedW, tt.expectedH, w, h)
+			}
+			if tt.options.MaxMegapixels > 0 && float64(w*h) > tt.options.MaxMegapixels*1e6 {
+				t.Errorf("%dx%d exceeds %.1f megapixels", w, h, tt.options.MaxMegapixels)
+			}
+		})
+	}
+
+	// Округление не выводит размер за ограничение в мегапикселях
+	for width := 1000; width < 1100; width++ {
+		w, h := fitSize(width, 777, ImageOptions{MaxMegapixels: 0.3})
+		if w*h > 300000 {
+			t.Fatalf("%dx777: %dx%d exceeds 0.3 megapixels", width, w, h)
+		}
+	}
+}
+
+func TestFitImage(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 400, 100))
+	for _, filter := range []string{"nearest", "bilinear", "bicubic", "mitchell", "lanczos2", "lanczos3"} {
+		fitted := fitImage(img, ImageOptions{MaxWidth: 200, Filter: filter})
+		if bounds := fitted.Bounds(); bounds.Dx() != 200 || bounds.Dy() != 50 {
+			t.Errorf("Filter %s: expected 200x50, got %dx%d", filter, bounds.Dx(), bounds.Dy())
+		}
+	}
+	if fitImage(img, ImageOptions{MaxWidth: 800}) != image.Image(img) {
+		t.Error("Image inside the bounding box must not be resized")
+	}
+}
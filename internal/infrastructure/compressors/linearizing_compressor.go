@@ -0,0 +1,127 @@
+package compressors
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"compress/internal/domain/entities"
+	"compress/internal/domain/repositories"
+)
+
+// linearizationHeadSize словарь линеаризации должен целиком находиться в первых 1024 байтах файла
+const linearizationHeadSize = 1024
+
+var (
+	// linearizationDictPattern словарь линеаризации первого объекта файла
+	linearizationDictPattern = regexp.MustCompile(`<<[^>]*/Linearized\s+[\d.]+[^>]*>>`)
+	// linearizationLengthPattern длина файла, записанная в словаре линеаризации
+	linearizationLengthPattern = regexp.MustCompile(`/L\s+(\d+)`)
+)
+
+// LinearizingCompressor обертка над компрессором: при OptimizeForWeb линеаризует
+// результат, чтобы браузер показывал первую страницу до окончания загрузки файла
+type LinearizingCompressor struct {
+	compressor repositories.PDFCompressor
+	linearizer repositories.Linearizer
+	logger     repositories.Logger
+}
+
+// NewLinearizingCompressor создает компрессор с линеаризацией результата
+func NewLinearizingCompressor(
+	compressor repositories.PDFCompressor,
+	linearizer repositories.Linearizer,
+	logger repositories.Logger,
+) *LinearizingCompressor {
+	return &LinearizingCompressor{
+		compressor: compressor,
+		linearizer: linearizer,
+		logger:     logger,
+	}
+}
+
+// Compress сжимает PDF вложенным компрессором и линеаризует результат
+func (c *LinearizingCompressor) Compress(inputPath, outputPath string, config *entities.CompressionConfig) (*entities.CompressionResult, error) {
+	result, err := c.compressor.Compress(inputPath, outputPath, config)
+	if err != nil || result == nil || result.Skipped || !config.OptimizeForWeb {
+		return result, err
+	}
+
+	// Повторное шифрование pdfcpu записывает файл заново, без линеаризации
+	if result.Encrypted {
+		if c.logger != nil {
+			c.logger.Warning("Линеаризация пропущена для %s: результат зашифрован", filepath.Base(inputPath))
+		}
+		return result, nil
+	}
+
+	fmt.Println("🌐 Линеаризация для быстрого веб-просмотра")
+	linearizedPath := outputPath + ".linearized"
+	defer os.Remove(linearizedPath)
+
+	failed := func(err error) (*entities.CompressionResult, error) {
+		result.Success = false
+		result.Error = err
+		return result, fmt.Errorf("ошибка линеаризации: %w", err)
+	}
+
+	if err := c.linearizer.Linearize(outputPath, linearizedPath); err != nil {
+		return failed(err)
+	}
+	if err := verifyLinearization(linearizedPath); err != nil {
+		return failed(err)
+	}
+	if err := os.Rename(linearizedPath, outputPath); err != nil {
+		return failed(err)
+	}
+
+	info, err := os.Stat(outputPath)
+	if err != nil {
+		return failed(err)
+	}
+	result.CompressedSize = info.Size()
+	result.Linearized = true
+	result.CalculateCompressionRatio()
+
+	return result, nil
+}
+
+// verifyLinearization проверяет словарь линеаризации в начале файла
+// и совпадение записанной в нем длины (/L) с размером файла
+func verifyLinearization(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	head := make([]byte, linearizationHeadSize)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return err
+	}
+
+	dict := linearizationDictPattern.Find(head[:n])
+	if dict == nil {
+		return fmt.Errorf("%w: словарь линеаризации не найден", entities.ErrLinearizationFailed)
+	}
+
+	match := linearizationLengthPattern.FindSubmatch(dict)
+	if match == nil {
+		return fmt.Errorf("%w: в словаре линеаризации нет длины файла", entities.ErrLinearizationFailed)
+	}
+	length, err := strconv.ParseInt(string(match[1]), 10, 64)
+	if err != nil || length != info.Size() {
+		return fmt.Errorf("%w: длина в словаре %s, размер файла %d", entities.ErrLinearizationFailed, match[1], info.Size())
+	}
+
+	return nil
+}
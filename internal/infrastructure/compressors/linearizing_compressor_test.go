@@ -0,0 +1,104 @@
+package compressors
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"compress/internal/domain/entities"
+)
+
+func TestVerifyLinearization(t *testing.T) {
+	// linearizedFile собирает начало файла со словарем линеаризации и дополняет его до нужной длины
+	linearizedFile := func(dictLength, size int) string {
+		head := fmt.Sprintf("%%PDF-1.7\n1 0 obj\n<< /Linearized 1 /L %d /H [ 600 150 ] /O 4 /E 5000 /N 2 /T 9000 >>\nendobj\n", dictLength)
+		return head + strings.Repeat(" ", size-len(head))
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{name: "Linearized", content: linearizedFile(2048, 2048), wantErr: false},
+		{name: "Length mismatch", content: linearizedFile(4096, 2048), wantErr: true},
+		{name: "Not linearized", content: "%PDF-1.7\n1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n", wantErr: true},
+		{
+			name:    "Dictionary beyond first 1024 bytes",
+			content: "%PDF-1.7\n" + strings.Repeat("%", 1100) + "\n<< /Linearized 1 /L 1200 >>\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "out.pdf")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			err := verifyLinearization(path)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("Expected error: %v, got %v", tt.wantErr, err)
+			}
+			if err != nil && !errors.Is(err, entities.ErrLinearizationFailed) {
+				t.Errorf("Expected ErrLinearizationFailed, got %v", err)
+			}
+		})
+	}
+}
+
+func TestQPDFLinearizerTimeout(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "qpdf")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\nsleep 5\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	started := time.Now()
+	err := NewQPDFLinearizer(script, 200*time.Millisecond).Linearize(filepath.Join(dir, "in.pdf"), filepath.Join(dir, "out.pdf"))
+	if !errors.Is(err, entities.ErrLinearizationFailed) {
+		t.Fatalf("Expected ErrLinearizationFailed, got %v", err)
+	}
+	if time.Since(started) > 3*time.Second {
+		t.Errorf("qpdf was not stopped on timeout, took %s", time.Since(started))
+	}
+}
+
+// encryptedResultCompressor возвращает зашифрованный результат
+type encryptedResultCompressor struct{}
+
+func (encryptedResultCompressor) Compress(inputPath, outputPath string, config *entities.CompressionConfig) (*entities.CompressionResult, error) {
+	return &entities.CompressionResult{Success: true, Encrypted: true}, nil
+}
+
+// failingLinearizer линеаризатор, который не должен вызываться
+type failingLinearizer struct {
+	t *testing.T
+}
+
+func (l failingLinearizer) Linearize(inputPath, outputPath string) error {
+	l.t.Error("Encrypted result must not be linearized")
+	return nil
+}
+
+func TestLinearizingCompressorSkipsEncrypted(t *testing.T) {
+	logger := &recordingLogger{}
+	compressor := NewLinearizingCompressor(encryptedResultCompressor{}, failingLinearizer{t}, logger)
+
+	config := entities.NewCompressionConfig(50).WithWebOptimization(true)
+	result, err := compressor.Compress("in.pdf", "out.pdf", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Linearized {
+		t.Error("Encrypted result is marked as linearized")
+	}
+	if len(logger.messages["warning"]) != 1 {
+		t.Errorf("Expected a warning about skipped linearization, got %v", logger.messages)
+	}
+}
@@ -0,0 +1,458 @@
+package compressors
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+
+	"compress/internal/domain/entities"
+)
+
+// PDFAnalyzer анализатор размера PDF на основе pdfcpu
+type PDFAnalyzer struct{}
+
+// NewPDFAnalyzer создает новый анализатор PDF
+func NewPDFAnalyzer() *PDFAnalyzer {
+	return &PDFAnalyzer{}
+}
+
+// Analyze разбивает размер документа по категориям и собирает сведения об изображениях и шрифтах
+func (a *PDFAnalyzer) Analyze(path string) (*entities.PDFAnalysis, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения информации о файле: %w", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	pdfConfig := model.NewDefaultConfiguration()
+	pdfConfig.ValidationMode = model.ValidationNone
+
+	ctx, err := api.ReadContext(file, pdfConfig)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения PDF: %w", err)
+	}
+	if err := ctx.EnsurePageCount(); err != nil {
+		return nil, fmt.Errorf("ошибка чтения дерева страниц: %w", err)
+	}
+
+	analysis := &entities.PDFAnalysis{
+		Path:     path,
+		FileSize: info.Size(),
+		Pages:    ctx.PageCount,
+	}
+
+	categories := classifyObjects(ctx)
+	for objNr, category := range categories {
+		entry, found := ctx.FindTableEntryLight(objNr)
+		if !found || entry == nil {
+			continue
+		}
+		analysis.AddObject(category, objectSize(entry))
+	}
+
+	analysis.Images = analyzeImages(ctx, categories)
+	analysis.Fonts = analyzeFonts(ctx, categories)
+
+	// Оптимизация шрифтов выполняется над прочитанным документом, который дальше не нужен:
+	// экономия по каждому шрифту та же, что получится при сжатии
+	if fonts, err := optimizeFonts(ctx, true); err == nil {
+		for i := range analysis.Fonts {
+			saving := fonts.Fonts[analysis.Fonts[i].ObjectNumber]
+			analysis.Fonts[i].Optimizations = saving.Optimizations
+			analysis.Fonts[i].Savings = saving.Bytes
+		}
+	}
+	analysis.Finalize()
+
+	return analysis, nil
+}
+
+// objectSize оценивает размер объекта в файле: длина потока и запись словаря.
+// Объекты внутри объектных потоков учитываются в размере самого объектного потока
+func objectSize(entry *model.XRefTableEntry) int64 {
+	switch o := entry.Object.(type) {
+	case types.StreamDict:
+		return int64(len(o.Raw) + len(o.Dict.PDFString()))
+	case types.ObjectStreamDict:
+		return int64(len(o.Raw) + len(o.Dict.PDFString()))
+	case types.XRefStreamDict:
+		return int64(len(o.Raw) + len(o.Dict.PDFString()))
+	case nil:
+		return 0
+	default:
+		if entry.Compressed {
+			return 0
+		}
+		return int64(len(o.PDFString()))
+	}
+}
+
+// classifyObjects относит каждый объект таблицы к категории. Поддеревья миниатюр,
+// вложений, метаданных, изображений и шрифтов размечаются в этом порядке,
+// первая категория объекта сохраняется
+func classifyObjects(ctx *model.Context) map[int]string {
+	reachable := reachableObjects(ctx)
+	categories := make(map[int]string)
+
+	markTree := func(obj types.Object, category string) {
+		walkReferences(ctx, obj, func(objNr int) bool {
+			if _, done := categories[objNr]; done || !reachable[objNr] {
+				return false
+			}
+			categories[objNr] = category
+			return true
+		})
+	}
+
+	// Словарь Info
+	if ctx.Info != nil {
+		markTree(*ctx.Info, entities.CategoryMetadata)
+	}
+
+	// Миниатюры страниц
+	for pageNr := 1; pageNr <= ctx.PageCount; pageNr++ {
+		pageDict, _, _, err := ctx.PageDict(pageNr, false)
+		if err != nil || pageDict == nil {
+			continue
+		}
+		if thumb, found := pageDict.Find("Thumb"); found {
+			markTree(thumb, entities.CategoryThumbnails)
+		}
+	}
+
+	// Встроенные файлы из дерева имен
+	if rootDict, err := ctx.Catalog(); err == nil {
+		if obj, found := rootDict.Find("Names"); found {
+			if names, err := ctx.DereferenceDict(obj); err == nil && names != nil {
+				if embedded, found := names.Find("EmbeddedFiles"); found {
+					markTree(embedded, entities.CategoryEmbeddedFiles)
+				}
+			}
+		}
+	}
+
+	// Потоки, категория которых видна по их типу
+	objNrs := sortedObjectNumbers(ctx)
+	for _, objNr := range objNrs {
+		if !reachable[objNr] {
+			continue
+		}
+		if _, done := categories[objNr]; done {
+			continue
+		}
+		entry, _ := ctx.FindTableEntryLight(objNr)
+		sd, ok := entry.Object.(types.StreamDict)
+		if !ok {
+			continue
+		}
+
+		ref := *types.NewIndirectRef(objNr, 0)
+		switch {
+		case dictName(sd.Dict, "Type") == "Metadata":
+			categories[objNr] = entities.CategoryMetadata
+		case dictName(sd.Dict, "Type") == "EmbeddedFile":
+			categories[objNr] = entities.CategoryEmbeddedFiles
+		case dictName(sd.Dict, "Subtype") == "Image":
+			markTree(ref, entities.CategoryImages)
+		}
+	}
+
+	// Шрифты со всеми зависимыми объектами (дескрипторы, программы шрифтов, ToUnicode)
+	for _, objNr := range objNrs {
+		if !reachable[objNr] {
+			continue
+		}
+		entry, _ := ctx.FindTableEntryLight(objNr)
+		if d, ok := entry.Object.(types.Dict); ok && dictName(d, "Type") == "Font" {
+			markTree(*types.NewIndirectRef(objNr, 0), entities.CategoryFonts)
+		}
+	}
+
+	// Потоки содержимого страниц и формы
+	for _, objNr := range objNrs {
+		if _, done := categories[objNr]; done || !reachable[objNr] {
+			continue
+		}
+		entry, _ := ctx.FindTableEntryLight(objNr)
+		if sd, ok := entry.Object.(types.StreamDict); ok && (sd.IsPageContent || dictName(sd.Dict, "Subtype") == "Form") {
+			categories[objNr] = entities.CategoryContent
+		}
+	}
+	for pageNr := 1; pageNr <= ctx.PageCount; pageNr++ {
+		pageDict, _, _, err := ctx.PageDict(pageNr, false)
+		if err != nil || pageDict == nil {
+			continue
+		}
+		if contents, found := pageDict.Find("Contents"); found {
+			markTree(contents, entities.CategoryContent)
+		}
+	}
+
+	// Остальные достижимые объекты — служебная часть, недостижимые — неиспользуемые
+	for _, objNr := range objNrs {
+		if _, done := categories[objNr]; done {
+			continue
+		}
+		entry, _ := ctx.FindTableEntryLight(objNr)
+		switch entry.Object.(type) {
+		case types.ObjectStreamDict, types.XRefStreamDict:
+			categories[objNr] = entities.CategoryStructure
+			continue
+		}
+		if reachable[objNr] {
+			categories[objNr] = entities.CategoryStructure
+		} else {
+			categories[objNr] = entities.CategoryUnused
+		}
+	}
+
+	return categories
+}
+
+// sortedObjectNumbers возвращает номера используемых объектов таблицы по возрастанию
+func sortedObjectNumbers(ctx *model.Context) []int {
+	var objNrs []int
+	for objNr := 1; ctx.Size != nil && objNr < *ctx.Size; objNr++ {
+		entry, found := ctx.FindTableEntryLight(objNr)
+		if !found || entry == nil || entry.Free || entry.Object == nil {
+			continue
+		}
+		objNrs = append(objNrs, objNr)
+	}
+	return objNrs
+}
+
+// reachableObjects возвращает объекты, достижимые из каталога и словаря Info
+func reachableObjects(ctx *model.Context) map[int]bool {
+	reachable := make(map[int]bool)
+	visit := func(objNr int) bool {
+		if reachable[objNr] {
+			return false
+		}
+		reachable[objNr] = true
+		return true
+	}
+
+	if ctx.Root != nil {
+		visit(ctx.Root.ObjectNumber.Value())
+		walkReferences(ctx, ctx.RootDict, visit)
+	}
+	if ctx.Info != nil {
+		walkReferences(ctx, *ctx.Info, visit)
+	}
+
+	return reachable
+}
+
+// walkReferences обходит объект и все объекты, на которые он ссылается. Для каждой
+// косвенной ссылки вызывается visit; спуск в объект происходит, только если visit вернул true.
+// Ссылки на родителя (Parent, P) не обходятся, чтобы поддерево не уходило в дерево страниц
+func walkReferences(ctx *model.Context, obj types.Object, visit func(objNr int) bool) {
+	stack := []types.Object{obj}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		switch o := current.(type) {
+		case types.IndirectRef:
+			if !visit(o.ObjectNumber.Value()) {
+				continue
+			}
+			target, err := ctx.Dereference(o)
+			if err == nil && target != nil {
+				stack = append(stack, target)
+			}
+		case types.Dict:
+			stack = appendDictValues(stack, o)
+		case types.StreamDict:
+			stack = appendDictValues(stack, o.Dict)
+		case types.Array:
+			stack = append(stack, o...)
+		}
+	}
+}
+
+// appendDictValues добавляет значения словаря в стек обхода, кроме ссылок на родителя
+func appendDictValues(stack []types.Object, d types.Dict) []types.Object {
+	for key, value := range d {
+		if key == "Parent" || key == "P" {
+			continue
+		}
+		stack = append(stack, value)
+	}
+	return stack
+}
+
+// dictName возвращает значение записи-имени словаря или пустую строку
+func dictName(d types.Dict, key string) string {
+	if name := d.NameEntry(key); name != nil {
+		return *name
+	}
+	return ""
+}
+
+// analyzeImages собирает сведения об изображениях и их разрешении на странице
+func analyzeImages(ctx *model.Context, categories map[int]string) []entities.ImageAnalysis {
+	placements, _, _ := collectImagePlacements(ctx)
+
+	var images []entities.ImageAnalysis
+	for _, objNr := range sortedObjectNumbers(ctx) {
+		if categories[objNr] != entities.CategoryImages {
+			continue
+		}
+		entry, _ := ctx.FindTableEntryLight(objNr)
+		sd, ok := entry.Object.(types.StreamDict)
+		if !ok || dictName(sd.Dict, "Subtype") != "Image" {
+			continue
+		}
+
+		image := entities.ImageAnalysis{
+			ObjectNumber: objNr,
+			ColorSpace:   colorSpaceName(ctx, sd.Dict),
+			Bytes:        int64(len(sd.Raw)),
+		}
+		if width := sd.IntEntry("Width"); width != nil {
+			image.Width = *width
+		}
+		if height := sd.IntEntry("Height"); height != nil {
+			image.Height = *height
+		}
+		if bpc := sd.IntEntry("BitsPerComponent"); bpc != nil {
+			image.BitsPerComponent = *bpc
+		}
+
+		var filters []string
+		for _, f := range sd.FilterPipeline {
+			filters = append(filters, f.Name)
+		}
+		image.Filter = strings.Join(filters, "+")
+
+		if placement, found := placements[objNr]; found && placement.Width > 0 && placement.Height > 0 {
+			image.PPI = math.Min(
+				float64(image.Width)/(placement.Width/72),
+				float64(image.Height)/(placement.Height/72),
+			)
+		}
+
+		images = append(images, image)
+	}
+
+	return images
+}
+
+// colorSpaceName возвращает имя цветового пространства изображения
+func colorSpaceName(ctx *model.Context, d types.Dict) string {
+	obj, found := d.Find("ColorSpace")
+	if !found {
+		return ""
+	}
+	obj, err := ctx.Dereference(obj)
+	if err != nil {
+		return ""
+	}
+
+	switch cs := obj.(type) {
+	case types.Name:
+		return cs.Value()
+	case types.Array:
+		if len(cs) > 0 {
+			if name, ok := cs[0].(types.Name); ok {
+				return name.Value()
+			}
+		}
+	}
+	return ""
+}
+
+// analyzeFonts собирает сведения о шрифтах: встроен ли шрифт, является ли он подмножеством
+// и размер программы шрифта. Потомки составных шрифтов (CIDFont) учитываются в Type0
+func analyzeFonts(ctx *model.Context, categories map[int]string) []entities.FontAnalysis {
+	var fonts []entities.FontAnalysis
+	for _, objNr := range sortedObjectNumbers(ctx) {
+		if categories[objNr] != entities.CategoryFonts {
+			continue
+		}
+		entry, _ := ctx.FindTableEntryLight(objNr)
+		fontDict, ok := entry.Object.(types.Dict)
+		if !ok || dictName(fontDict, "Type") != "Font" {
+			continue
+		}
+
+		subtype := dictName(fontDict, "Subtype")
+		if subtype == "CIDFontType0" || subtype == "CIDFontType2" {
+			continue
+		}
+
+		font := entities.FontAnalysis{
+			ObjectNumber: objNr,
+			Name:         dictName(fontDict, "BaseFont"),
+			Subtype:      subtype,
+		}
+		font.Subset = isSubsetFontName(font.Name)
+		font.Embedded, font.Bytes = fontProgram(ctx, fontDict)
+
+		fonts = append(fonts, font)
+	}
+
+	return fonts
+}
+
+// fontProgram определяет, встроен ли шрифт, и размер его программы
+func fontProgram(ctx *model.Context, fontDict types.Dict) (bool, int64) {
+	// Глифы Type3 описаны потоками CharProcs прямо в документе
+	if dictName(fontDict, "Subtype") == "Type3" {
+		var size int64
+		if obj, found := fontDict.Find("CharProcs"); found {
+			if charProcs, err := ctx.DereferenceDict(obj); err == nil {
+				for _, proc := range charProcs {
+					if sd, _, err := ctx.DereferenceStreamDict(proc); err == nil && sd != nil {
+						size += int64(len(sd.Raw))
+					}
+				}
+			}
+		}
+		return true, size
+	}
+
+	descriptor := fontDescriptor(ctx, fontDict)
+	if descriptor == nil {
+		return false, 0
+	}
+
+	for _, key := range fontProgramKeys {
+		obj, found := descriptor.Find(key)
+		if !found {
+			continue
+		}
+		sd, _, err := ctx.DereferenceStreamDict(obj)
+		if err != nil || sd == nil {
+			continue
+		}
+		return true, int64(len(sd.Raw))
+	}
+
+	return false, 0
+}
+
+// isSubsetFontName проверяет префикс подмножества шрифта: шесть заглавных букв и «+»
+func isSubsetFontName(name string) bool {
+	if len(name) < 8 || name[6] != '+' {
+		return false
+	}
+	for _, r := range name[:6] {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,280 @@
+package compressors
+
+import (
+	"fmt"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+
+	"compress/internal/domain/entities"
+)
+
+// cleanupStats количество объектов, удаленных из документа, результат оптимизации шрифтов,
+// уменьшения цветности изображений и пересжатия потоков
+type cleanupStats struct {
+	Annotations  int
+	Attachments  int
+	Thumbnails   int
+	JavaScript   int
+	Destinations int
+	PieceInfo    int
+	Fonts        fontStats
+	Images       imageRecompressStats
+	Streams      streamStats
+}
+
+// apply записывает статистику удаления в результат сжатия
+func (s cleanupStats) apply(result *entities.CompressionResult) {
+	result.RemovedAnnotations = s.Annotations
+	result.RemovedAttachments = s.Attachments
+	result.RemovedThumbnails = s.Thumbnails
+	result.RemovedScripts = s.JavaScript
+	result.RemovedDestinations = s.Destinations
+	result.RemovedPieceInfo = s.PieceInfo
+	result.RemovedFonts = s.Fonts.Removed
+	result.DeduplicatedFonts = s.Fonts.Deduplicated
+	result.SubsettedFonts = s.Fonts.Subset
+	result.FontSavings = s.Fonts.SavedBytes
+	result.GrayscaleImages = s.Images.Grayscale
+	result.BitonalImages = s.Images.Bitonal
+	result.RecompressedStreams = s.Streams.Recompressed
+	result.StreamSavings = s.Streams.SavedBytes
+}
+
+// applyCleanup удаляет вложения, аннотации, миниатюры, сценарии, неиспользуемые назначения
+// и частные данные приложений и оптимизирует шрифты согласно флагам CompressionConfig.
+// Назначения проверяются после удаления аннотаций и сценариев, которые могли на них ссылаться;
+// шрифты обрабатываются последними: после удаления аннотаций шрифты их внешнего вида
+// становятся неиспользуемыми
+func applyCleanup(ctx *model.Context, config *entities.CompressionConfig) (cleanupStats, error) {
+	var stats cleanupStats
+
+	if config.RemoveAttachments {
+		removed, err := removeAttachments(ctx)
+		if err != nil {
+			return stats, fmt.Errorf("ошибка удаления вложений: %w", err)
+		}
+		stats.Attachments = removed
+		fmt.Printf("📎 Удалено вложений: %d\n", removed)
+	}
+
+	if config.RemoveAnnotations {
+		removed, err := removeAnnotations(ctx, config.KeepsAnnotation)
+		if err != nil {
+			return stats, fmt.Errorf("ошибка удаления аннотаций: %w", err)
+		}
+		stats.Annotations = removed
+		fmt.Printf("🗒️ Удалено аннотаций: %d (сохраняются: %v)\n", removed, config.KeepAnnotations)
+	}
+
+	if config.RemoveThumbnails {
+		removed, err := removeThumbnails(ctx)
+		if err != nil {
+			return stats, fmt.Errorf("ошибка удаления миниатюр: %w", err)
+		}
+		stats.Thumbnails = removed
+	}
+
+	if config.RemoveJavaScript {
+		removed, err := removeJavaScript(ctx)
+		if err != nil {
+			return stats, fmt.Errorf("ошибка удаления JavaScript: %w", err)
+		}
+		stats.JavaScript = removed
+	}
+
+	if config.RemoveUnusedDestinations {
+		removed, err := removeUnusedDestinations(ctx)
+		if err != nil {
+			return stats, fmt.Errorf("ошибка удаления именованных назначений: %w", err)
+		}
+		stats.Destinations = removed
+	}
+
+	if config.RemovePieceInfo {
+		stats.PieceInfo = removePieceInfo(ctx)
+	}
+
+	if stats.Thumbnails+stats.JavaScript+stats.Destinations+stats.PieceInfo > 0 {
+		fmt.Printf("🧽 Удалено миниатюр: %d, сценариев: %d, назначений: %d, PieceInfo: %d\n",
+			stats.Thumbnails, stats.JavaScript, stats.Destinations, stats.PieceInfo)
+	}
+
+	if config.OptimizeFonts {
+		fonts, err := optimizeFonts(ctx, config.SubsetFonts)
+		if err != nil {
+			return stats, fmt.Errorf("ошибка оптимизации шрифтов: %w", err)
+		}
+		stats.Fonts = *fonts
+		fmt.Printf("🔤 Шрифты: удалено %d, объединено %d, подмножеств %d (сэкономлено %.2f MB)\n",
+			fonts.Removed, fonts.Deduplicated, fonts.Subset, float64(fonts.SavedBytes)/1024/1024)
+	}
+
+	return stats, nil
+}
+
+// applyStreamRecompression пересжимает потоки, если это включено в конфигурации.
+// Выполняется последним, когда набор потоков документа уже не меняется
+func applyStreamRecompression(ctx *model.Context, config *entities.CompressionConfig) streamStats {
+	if !config.RecompressStreams {
+		return streamStats{}
+	}
+	stats := recompressStreams(ctx)
+	fmt.Printf("🗜️ Пересжато потоков: %d (сэкономлено %.2f MB)\n",
+		stats.Recompressed, float64(stats.SavedBytes)/1024/1024)
+	return stats
+}
+
+// removeAttachments удаляет встроенные файлы документа и аннотации-вложения.
+// Возвращает количество удаленных файлов и аннотаций
+func removeAttachments(ctx *model.Context) (int, error) {
+	rootDict, err := ctx.Catalog()
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	if obj, found := rootDict.Find("Names"); found {
+		names, err := ctx.DereferenceDict(obj)
+		if err != nil {
+			return 0, err
+		}
+		if names != nil {
+			if embedded, found := names.Find("EmbeddedFiles"); found {
+				removed += countNameTreeEntries(ctx, embedded)
+				names.Delete("EmbeddedFiles")
+			}
+			if names.Len() == 0 {
+				rootDict.Delete("Names")
+			}
+		}
+	}
+
+	// Ассоциированные файлы (PDF 2.0)
+	rootDict.Delete("AF")
+
+	annotations, err := filterAnnotations(ctx, func(subtype string) bool {
+		return subtype != "FileAttachment"
+	})
+	return removed + annotations, err
+}
+
+// removeAnnotations удаляет аннотации страниц, для подтипа которых keep возвращает false.
+// Если удаляются все виджеты, удаляется и интерактивная форма каталога
+func removeAnnotations(ctx *model.Context, keep func(subtype string) bool) (int, error) {
+	removed, err := filterAnnotations(ctx, keep)
+	if err != nil {
+		return removed, err
+	}
+
+	if !keep("Widget") {
+		rootDict, err := ctx.Catalog()
+		if err != nil {
+			return removed, err
+		}
+		rootDict.Delete("AcroForm")
+	}
+
+	return removed, nil
+}
+
+// filterAnnotations оставляет на каждой странице только аннотации, для которых keep возвращает true.
+// Всплывающие окна (Popup) удаленных аннотаций удаляются вместе с ними.
+// Возвращает количество удаленных аннотаций
+func filterAnnotations(ctx *model.Context, keep func(subtype string) bool) (int, error) {
+	total := 0
+
+	for pageNr := 1; pageNr <= ctx.PageCount; pageNr++ {
+		pageDict, _, _, err := ctx.PageDict(pageNr, false)
+		if err != nil {
+			return total, fmt.Errorf("страница %d: %w", pageNr, err)
+		}
+		if pageDict == nil {
+			continue
+		}
+
+		obj, found := pageDict.Find("Annots")
+		if !found {
+			continue
+		}
+
+		annots, err := ctx.DereferenceArray(obj)
+		if err != nil {
+			return total, fmt.Errorf("страница %d: %w", pageNr, err)
+		}
+
+		// Номера объектов удаленных аннотаций, чтобы найти их всплывающие окна
+		removedRefs := make(map[int]bool)
+		var candidates []types.Object
+		for _, annotObj := range annots {
+			annot, err := ctx.DereferenceDict(annotObj)
+			if err != nil || annot == nil {
+				continue
+			}
+			if keep(dictName(annot, "Subtype")) {
+				candidates = append(candidates, annotObj)
+				continue
+			}
+			total++
+			if ref, ok := annotObj.(types.IndirectRef); ok {
+				removedRefs[ref.ObjectNumber.Value()] = true
+			}
+		}
+
+		kept := types.Array{}
+		for _, annotObj := range candidates {
+			annot, _ := ctx.DereferenceDict(annotObj)
+			if parent := annot.IndirectRefEntry("Parent"); parent != nil && dictName(annot, "Subtype") == "Popup" &&
+				removedRefs[parent.ObjectNumber.Value()] {
+				total++
+				continue
+			}
+			kept = append(kept, annotObj)
+		}
+
+		if len(kept) == 0 {
+			pageDict.Delete("Annots")
+		} else {
+			pageDict.Update("Annots", kept)
+		}
+	}
+
+	return total, nil
+}
+
+// countNameTreeEntries считает записи дерева имен (Names и Kids)
+func countNameTreeEntries(ctx *model.Context, obj types.Object) int {
+	visited := make(map[int]bool)
+
+	var count func(obj types.Object) int
+	count = func(obj types.Object) int {
+		if ref, ok := obj.(types.IndirectRef); ok {
+			if visited[ref.ObjectNumber.Value()] {
+				return 0
+			}
+			visited[ref.ObjectNumber.Value()] = true
+		}
+
+		node, err := ctx.DereferenceDict(obj)
+		if err != nil || node == nil {
+			return 0
+		}
+
+		entries := 0
+		if obj, found := node.Find("Names"); found {
+			if names, err := ctx.DereferenceArray(obj); err == nil {
+				entries += len(names) / 2
+			}
+		}
+		if obj, found := node.Find("Kids"); found {
+			if kids, err := ctx.DereferenceArray(obj); err == nil {
+				for _, kid := range kids {
+					entries += count(kid)
+				}
+			}
+		}
+		return entries
+	}
+
+	return count(obj)
+}
@@ -0,0 +1,346 @@
+package compressors
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math"
+	"strconv"
+)
+
+// contentName имя (/Name) в потоке содержимого страницы
+type contentName string
+
+// contentOperation оператор потока содержимого вместе с операндами.
+// Операнды имеют типы float64, contentName, []byte (строки) и []interface{} (массивы);
+// словари (например, свойства BDC) представлены как nil.
+type contentOperation struct {
+	Operator string
+	Operands []interface{}
+}
+
+// contentMatrix матрица преобразования PDF [a b c d e f]
+type contentMatrix [6]float64
+
+// identityMatrix единичная матрица
+var identityMatrix = contentMatrix{1, 0, 0, 1, 0, 0}
+
+// multiply возвращает m × n
+func (m contentMatrix) multiply(n contentMatrix) contentMatrix {
+	return contentMatrix{
+		m[0]*n[0] + m[1]*n[2],
+		m[0]*n[1] + m[1]*n[3],
+		m[2]*n[0] + m[3]*n[2],
+		m[2]*n[1] + m[3]*n[3],
+		m[4]*n[0] + m[5]*n[2] + n[4],
+		m[4]*n[1] + m[5]*n[3] + n[5],
+	}
+}
+
+// scale возвращает размеры единичного квадрата после преобразования
+func (m contentMatrix) scale() (width, height float64) {
+	return math.Hypot(m[0], m[1]), math.Hypot(m[2], m[3])
+}
+
+// parseContentStream разбирает поток содержимого и вызывает fn для каждого оператора.
+// Встроенные изображения (BI ... ID ... EI) пропускаются целиком.
+func parseContentStream(content []byte, fn func(op contentOperation)) {
+	p := &contentParser{data: content}
+	var operands []interface{}
+
+	for {
+		token, ok := p.next()
+		if !ok {
+			return
+		}
+
+		operator, isOperator := token.(contentOperator)
+		if !isOperator {
+			operands = append(operands, token)
+			continue
+		}
+
+		if operator == "BI" {
+			p.skipInlineImage()
+			operands = nil
+			continue
+		}
+
+		fn(contentOperation{Operator: string(operator), Operands: operands})
+		operands = nil
+	}
+}
+
+// walkXObjectPlacements отслеживает текущую матрицу преобразования (q/Q/cm)
+// и вызывает fn для каждого оператора Do с именем XObject и итоговой матрицей
+func walkXObjectPlacements(content []byte, fn func(name string, ctm contentMatrix)) {
+	ctm := identityMatrix
+	var stack []contentMatrix
+
+	parseContentStream(content, func(op contentOperation) {
+		switch op.Operator {
+		case "q":
+			stack = append(stack, ctm)
+		case "Q":
+			if len(stack) > 0 {
+				ctm = stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+			}
+		case "cm":
+			if len(op.Operands) != 6 {
+				return
+			}
+			var m contentMatrix
+			for i, operand := range op.Operands {
+				v, ok := operand.(float64)
+				if !ok {
+					return
+				}
+				m[i] = v
+			}
+			ctm = m.multiply(ctm)
+		case "Do":
+			if len(op.Operands) != 1 {
+				return
+			}
+			if name, ok := op.Operands[0].(contentName); ok {
+				fn(string(name), ctm)
+			}
+		}
+	})
+}
+
+// contentOperator ключевое слово оператора
+type contentOperator string
+
+// contentParser минимальный лексический анализатор потока содержимого
+type contentParser struct {
+	data []byte
+	pos  int
+}
+
+func isContentWhitespace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isContentDelimiter(c byte) bool {
+	switch c {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return isContentWhitespace(c)
+}
+
+// skipWhitespace пропускает пробелы и комментарии
+func (p *contentParser) skipWhitespace() {
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		if isContentWhitespace(c) {
+			p.pos++
+			continue
+		}
+		if c == '%' {
+			for p.pos < len(p.data) && p.data[p.pos] != '\n' && p.data[p.pos] != '\r' {
+				p.pos++
+			}
+			continue
+		}
+		return
+	}
+}
+
+// next возвращает следующий токен: операнд или contentOperator
+func (p *contentParser) next() (interface{}, bool) {
+	p.skipWhitespace()
+	if p.pos >= len(p.data) {
+		return nil, false
+	}
+
+	c := p.data[p.pos]
+	switch {
+	case c == '/':
+		p.pos++
+		start := p.pos
+		for p.pos < len(p.data) && !isContentDelimiter(p.data[p.pos]) {
+			p.pos++
+		}
+		return contentName(p.data[start:p.pos]), true
+
+	case c == '(':
+		return p.literalString(), true
+
+	case c == '<' && p.pos+1 < len(p.data) && p.data[p.pos+1] == '<':
+		p.skipDict()
+		return nil, true
+
+	case c == '<':
+		return p.hexString(), true
+
+	case c == '[':
+		p.pos++
+		var array []interface{}
+		for {
+			p.skipWhitespace()
+			if p.pos >= len(p.data) {
+				return array, true
+			}
+			if p.data[p.pos] == ']' {
+				p.pos++
+				return array, true
+			}
+			token, ok := p.next()
+			if !ok {
+				return array, true
+			}
+			array = append(array, token)
+		}
+
+	case c == ']' || c == '>' || c == ')' || c == '{' || c == '}':
+		// Непарные разделители пропускаем
+		p.pos++
+		return p.next()
+	}
+
+	start := p.pos
+	for p.pos < len(p.data) && !isContentDelimiter(p.data[p.pos]) {
+		p.pos++
+	}
+	word := string(p.data[start:p.pos])
+
+	if v, err := strconv.ParseFloat(word, 64); err == nil {
+		return v, true
+	}
+
+	return contentOperator(word), true
+}
+
+// literalString читает строку в круглых скобках с учетом вложенности и экранирования
+func (p *contentParser) literalString() []byte {
+	p.pos++ // (
+	var buf bytes.Buffer
+	depth := 1
+
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		p.pos++
+
+		switch c {
+		case '\\':
+			if p.pos >= len(p.data) {
+				return buf.Bytes()
+			}
+			e := p.data[p.pos]
+			p.pos++
+			switch e {
+			case 'n':
+				buf.WriteByte('\n')
+			case 'r':
+				buf.WriteByte('\r')
+			case 't':
+				buf.WriteByte('\t')
+			case 'b':
+				buf.WriteByte('\b')
+			case 'f':
+				buf.WriteByte('\f')
+			case '\r':
+				if p.pos < len(p.data) && p.data[p.pos] == '\n' {
+					p.pos++
+				}
+			case '\n':
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for i := 0; i < 2 && p.pos < len(p.data) && p.data[p.pos] >= '0' && p.data[p.pos] <= '7'; i++ {
+						v = v*8 + int(p.data[p.pos]-'0')
+						p.pos++
+					}
+					buf.WriteByte(byte(v))
+				} else {
+					buf.WriteByte(e)
+				}
+			}
+		case '(':
+			depth++
+			buf.WriteByte(c)
+		case ')':
+			depth--
+			if depth == 0 {
+				return buf.Bytes()
+			}
+			buf.WriteByte(c)
+		default:
+			buf.WriteByte(c)
+		}
+	}
+
+	return buf.Bytes()
+}
+
+// hexString читает шестнадцатеричную строку <...>
+func (p *contentParser) hexString() []byte {
+	p.pos++ // <
+	var digits []byte
+	for p.pos < len(p.data) && p.data[p.pos] != '>' {
+		if !isContentWhitespace(p.data[p.pos]) {
+			digits = append(digits, p.data[p.pos])
+		}
+		p.pos++
+	}
+	p.pos++ // >
+
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	decoded, err := hex.DecodeString(string(digits))
+	if err != nil {
+		return nil
+	}
+	return decoded
+}
+
+// skipDict пропускает словарь << ... >> с учетом вложенности
+func (p *contentParser) skipDict() {
+	p.pos += 2
+	depth := 1
+	for p.pos < len(p.data) && depth > 0 {
+		switch {
+		case p.data[p.pos] == '(':
+			p.literalString()
+			continue
+		case bytes.HasPrefix(p.data[p.pos:], []byte("<<")):
+			depth++
+			p.pos += 2
+			continue
+		case bytes.HasPrefix(p.data[p.pos:], []byte(">>")):
+			depth--
+			p.pos += 2
+			continue
+		}
+		p.pos++
+	}
+}
+
+// skipInlineImage пропускает данные встроенного изображения до оператора EI
+func (p *contentParser) skipInlineImage() {
+	idx := bytes.Index(p.data[p.pos:], []byte("ID"))
+	if idx < 0 {
+		p.pos = len(p.data)
+		return
+	}
+	p.pos += idx + 2
+
+	for p.pos < len(p.data) {
+		idx := bytes.Index(p.data[p.pos:], []byte("EI"))
+		if idx < 0 {
+			p.pos = len(p.data)
+			return
+		}
+		end := p.pos + idx
+		p.pos = end + 2
+
+		before := end == 0 || isContentWhitespace(p.data[end-1])
+		after := p.pos >= len(p.data) || isContentDelimiter(p.data[p.pos])
+		if before && after {
+			return
+		}
+	}
+}
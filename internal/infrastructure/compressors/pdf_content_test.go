@@ -0,0 +1,76 @@
+package compressors
+
+import (
+	"math"
+	"testing"
+)
+
+func TestWalkXObjectPlacements(t *testing.T) {
+	content := []byte(`
+q
+1 0 0 1 50 50 cm
+q 200 0 0 100 0 0 cm /Im1 Do Q
+BI /W 2 /H 2 /BPC 8 /CS /G ID ab EI data EI
+(text with ) and \) paren) Tj
+q 0.5 0 0 0.5 0 0 cm 300 0 0 300 0 0 cm /Im2 Do Q
+Q
+/Im3 Do
+`)
+
+	type size struct{ width, height float64 }
+	want := map[string]size{
+		"Im1": {200, 100},
+		"Im2": {150, 150},
+		"Im3": {1, 1},
+	}
+
+	got := make(map[string]size)
+	walkXObjectPlacements(content, func(name string, ctm contentMatrix) {
+		width, height := ctm.scale()
+		got[name] = size{width, height}
+	})
+
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d placements, got %d: %v", len(want), len(got), got)
+	}
+
+	for name, w := range want {
+		g, found := got[name]
+		if !found {
+			t.Errorf("Placement %s not found", name)
+			continue
+		}
+		if math.Abs(g.width-w.width) > 1e-9 || math.Abs(g.height-w.height) > 1e-9 {
+			t.Errorf("Placement %s: expected %vx%v, got %vx%v", name, w.width, w.height, g.width, g.height)
+		}
+	}
+}
+
+func TestParseContentStreamOperands(t *testing.T) {
+	content := []byte(`/F1 12 Tf [(A) -120 <0042>] TJ /P <</MCID 0>> BDC EMC`)
+
+	var ops []contentOperation
+	parseContentStream(content, func(op contentOperation) {
+		ops = append(ops, op)
+	})
+
+	if len(ops) != 4 {
+		t.Fatalf("Expected 4 operations, got %d", len(ops))
+	}
+
+	if ops[0].Operator != "Tf" || ops[0].Operands[0] != contentName("F1") || ops[0].Operands[1] != 12.0 {
+		t.Errorf("Unexpected Tf operation: %+v", ops[0])
+	}
+
+	array, ok := ops[1].Operands[0].([]interface{})
+	if ops[1].Operator != "TJ" || !ok || len(array) != 3 {
+		t.Fatalf("Unexpected TJ operation: %+v", ops[1])
+	}
+	if string(array[0].([]byte)) != "A" || string(array[2].([]byte)) != "\x00B" {
+		t.Errorf("Unexpected TJ strings: %q %q", array[0], array[2])
+	}
+
+	if ops[2].Operator != "BDC" || len(ops[2].Operands) != 2 {
+		t.Errorf("Unexpected BDC operation: %+v", ops[2])
+	}
+}
@@ -0,0 +1,998 @@
+package compressors
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/filter"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+
+	"compress/internal/domain/entities"
+)
+
+// fontProgramKeys ключи дескриптора шрифта со встроенной программой
+var fontProgramKeys = []string{"FontFile", "FontFile2", "FontFile3"}
+
+// fontStats статистика оптимизации шрифтов
+type fontStats struct {
+	Removed      int
+	Deduplicated int
+	Subset       int
+	SavedBytes   int64
+	// Оптимизации по номерам объектов словарей шрифтов
+	Fonts map[int]fontSaving
+}
+
+// fontSaving оптимизации одного шрифта и экономия от них
+type fontSaving struct {
+	Optimizations []string
+	Bytes         int64
+}
+
+// add учитывает оптимизацию шрифта
+func (s *fontStats) add(objNr int, optimization string, saved int64) {
+	if s.Fonts == nil {
+		s.Fonts = make(map[int]fontSaving)
+	}
+	font := s.Fonts[objNr]
+	font.Optimizations = append(font.Optimizations, optimization)
+	font.Bytes += saved
+	s.Fonts[objNr] = font
+	s.SavedBytes += saved
+}
+
+// fontUsage использование шрифта в потоках содержимого
+type fontUsage struct {
+	ref       types.IndirectRef
+	codeWidth int          // 1 — простой шрифт, 2 — Type0 с кодировкой Identity
+	codes     map[int]bool // Показанные коды символов
+	unknown   bool         // Набор глифов неизвестен: подмножество не строится
+}
+
+// addText учитывает коды символов строки
+func (u *fontUsage) addText(text []byte) {
+	if u.codeWidth == 2 {
+		for i := 0; i+1 < len(text); i += 2 {
+			u.codes[int(text[i])<<8|int(text[i+1])] = true
+		}
+		return
+	}
+	for _, code := range text {
+		u.codes[int(code)] = true
+	}
+}
+
+// optimizeFonts удаляет из ресурсов шрифты, которые не выбирает ни один поток содержимого,
+// объединяет одинаковые программы шрифтов и при subset оставляет в полностью встроенных
+// TrueType шрифтах только показанные глифы
+func optimizeFonts(ctx *model.Context, subset bool) (*fontStats, error) {
+	stats := &fontStats{}
+
+	scanner := newFontScanner(ctx)
+	if err := scanner.scanDocument(); err != nil {
+		return stats, err
+	}
+
+	pruneUnusedFonts(ctx, scanner, stats)
+	deduplicateFontPrograms(ctx, scanner, stats)
+	if subset && !scanner.inheritedText {
+		subsetFonts(ctx, scanner, stats)
+	}
+
+	return stats, nil
+}
+
+// fontScanner собирает использование шрифтов по потокам содержимого страниц, форм,
+// узоров, внешнего вида аннотаций и глифов Type3
+type fontScanner struct {
+	ctx   *model.Context
+	usage map[int]*fontUsage
+	// Словари Font просмотренных ресурсов; из заблокированных шрифты не удаляются,
+	// потому что не все их потоки удалось разобрать
+	fontDicts []types.Dict
+	seen      map[uintptr]bool
+	locked    map[uintptr]bool
+	// Просмотренные формы с собственными ресурсами и формы в процессе просмотра
+	forms  map[int]bool
+	active map[int]bool
+	// Текст показан шрифтом, выбранным вне потока: глифы документа известны не полностью
+	inheritedText bool
+}
+
+// newFontScanner создает сборщик использования шрифтов
+func newFontScanner(ctx *model.Context) *fontScanner {
+	return &fontScanner{
+		ctx:    ctx,
+		usage:  make(map[int]*fontUsage),
+		seen:   make(map[uintptr]bool),
+		locked: make(map[uintptr]bool),
+		forms:  make(map[int]bool),
+		active: make(map[int]bool),
+	}
+}
+
+// scanDocument просматривает все страницы с их аннотациями и шрифты интерактивной формы
+func (s *fontScanner) scanDocument() error {
+	for pageNr := 1; pageNr <= s.ctx.PageCount; pageNr++ {
+		pageDict, _, _, err := s.ctx.PageDict(pageNr, false)
+		if err != nil {
+			return fmt.Errorf("страница %d: %w", pageNr, err)
+		}
+		if pageDict == nil {
+			continue
+		}
+
+		resources := inheritedPageDict(s.ctx, pageDict, "Resources")
+		content, err := pageContent(s.ctx, pageDict)
+		if err != nil {
+			s.lock(resources)
+		} else {
+			s.scan(content, resources)
+		}
+
+		s.scanAnnotations(pageDict)
+	}
+
+	// Шрифты формы нужны для построения внешнего вида полей при редактировании
+	rootDict, err := s.ctx.Catalog()
+	if err != nil {
+		return err
+	}
+	if obj, found := rootDict.Find("AcroForm"); found {
+		if acroForm, err := s.ctx.DereferenceDict(obj); err == nil && acroForm != nil {
+			if obj, found := acroForm.Find("DR"); found {
+				if dr, err := s.ctx.DereferenceDict(obj); err == nil {
+					s.lock(dr)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// scanAnnotations просматривает потоки внешнего вида аннотаций страницы
+func (s *fontScanner) scanAnnotations(pageDict types.Dict) {
+	obj, found := pageDict.Find("Annots")
+	if !found {
+		return
+	}
+	annots, err := s.ctx.DereferenceArray(obj)
+	if err != nil {
+		return
+	}
+
+	for _, annotObj := range annots {
+		annot, err := s.ctx.DereferenceDict(annotObj)
+		if err != nil || annot == nil {
+			continue
+		}
+		obj, found := annot.Find("AP")
+		if !found {
+			continue
+		}
+		appearances, err := s.ctx.DereferenceDict(obj)
+		if err != nil || appearances == nil {
+			continue
+		}
+
+		for _, key := range []string{"N", "R", "D"} {
+			obj, found := appearances.Find(key)
+			if !found {
+				continue
+			}
+			appearance, err := s.ctx.Dereference(obj)
+			if err != nil {
+				continue
+			}
+			switch a := appearance.(type) {
+			case types.StreamDict:
+				s.scanForm(obj, nil)
+			case types.Dict:
+				// Внешний вид по состояниям (например, включенный и выключенный флажок)
+				for _, state := range a {
+					s.scanForm(state, nil)
+				}
+			}
+		}
+	}
+}
+
+// scan разбирает поток содержимого: отслеживает выбор шрифта (Tf, gs) с учетом q/Q,
+// учитывает коды показанного текста и спускается в формы и узоры
+func (s *fontScanner) scan(content []byte, resources types.Dict) {
+	fonts := s.fonts(resources)
+	s.scanPatterns(resources)
+
+	var current *fontUsage
+	var stack []*fontUsage
+
+	showText := func(operand interface{}) {
+		if current == nil {
+			s.inheritedText = true
+			return
+		}
+		switch text := operand.(type) {
+		case []byte:
+			current.addText(text)
+		case []interface{}:
+			for _, element := range text {
+				if str, ok := element.([]byte); ok {
+					current.addText(str)
+				}
+			}
+		}
+	}
+
+	parseContentStream(content, func(op contentOperation) {
+		switch op.Operator {
+		case "q":
+			stack = append(stack, current)
+		case "Q":
+			if len(stack) > 0 {
+				current = stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+			}
+		case "Tf":
+			current = nil
+			if len(op.Operands) == 2 {
+				if name, ok := op.Operands[0].(contentName); ok {
+					if ref, found := fonts[string(name)]; found {
+						current = s.use(ref, resources)
+					}
+				}
+			}
+		case "gs":
+			if len(op.Operands) == 1 {
+				if name, ok := op.Operands[0].(contentName); ok {
+					if usage := s.scanExtGState(resources, string(name)); usage != nil {
+						current = usage
+					}
+				}
+			}
+		case "Tj", "'", "\"", "TJ":
+			if len(op.Operands) > 0 {
+				showText(op.Operands[len(op.Operands)-1])
+			}
+		case "Do":
+			if len(op.Operands) == 1 {
+				if name, ok := op.Operands[0].(contentName); ok {
+					s.scanXObject(resources, string(name))
+				}
+			}
+		}
+	})
+}
+
+// fonts регистрирует словарь Font ресурсов и возвращает ссылки на шрифты по именам
+func (s *fontScanner) fonts(resources types.Dict) map[string]types.IndirectRef {
+	refs := make(map[string]types.IndirectRef)
+
+	fontDict := s.resourceDict(resources, "Font")
+	if fontDict == nil {
+		return refs
+	}
+	if key := reflect.ValueOf(fontDict).Pointer(); !s.seen[key] {
+		s.seen[key] = true
+		s.fontDicts = append(s.fontDicts, fontDict)
+	}
+
+	for name, obj := range fontDict {
+		if ref, ok := obj.(types.IndirectRef); ok {
+			refs[name] = ref
+		}
+	}
+	return refs
+}
+
+// lock запрещает удаление шрифтов из ресурсов и считает все их шрифты используемыми
+// с неизвестным набором глифов
+func (s *fontScanner) lock(resources types.Dict) {
+	fontDict := s.resourceDict(resources, "Font")
+	if fontDict == nil {
+		return
+	}
+	s.locked[reflect.ValueOf(fontDict).Pointer()] = true
+
+	for _, ref := range s.fonts(resources) {
+		s.use(ref, resources).unknown = true
+	}
+}
+
+// resourceDict возвращает словарь ресурсов указанного типа (Font, XObject, ...)
+func (s *fontScanner) resourceDict(resources types.Dict, key string) types.Dict {
+	if resources == nil {
+		return nil
+	}
+	obj, found := resources.Find(key)
+	if !found {
+		return nil
+	}
+	d, err := s.ctx.DereferenceDict(obj)
+	if err != nil {
+		return nil
+	}
+	return d
+}
+
+// use возвращает учет использования шрифта, создавая его при первом выборе шрифта
+func (s *fontScanner) use(ref types.IndirectRef, resources types.Dict) *fontUsage {
+	objNr := ref.ObjectNumber.Value()
+	if usage, found := s.usage[objNr]; found {
+		return usage
+	}
+
+	usage := &fontUsage{ref: ref, codeWidth: 1, codes: make(map[int]bool)}
+	s.usage[objNr] = usage
+
+	fontDict, err := s.ctx.DereferenceDict(ref)
+	if err != nil || fontDict == nil {
+		usage.unknown = true
+		return usage
+	}
+
+	switch dictName(fontDict, "Subtype") {
+	case "Type0":
+		switch dictName(fontDict, "Encoding") {
+		case "Identity-H", "Identity-V":
+			usage.codeWidth = 2
+		default:
+			// Коды прочих CMap не переводятся в CID без разбора самой CMap
+			usage.unknown = true
+		}
+	case "Type3":
+		s.scanType3(fontDict, resources)
+	}
+
+	return usage
+}
+
+// scanType3 просматривает процедуры глифов шрифта Type3: в них тоже могут выбираться шрифты
+func (s *fontScanner) scanType3(fontDict, resources types.Dict) {
+	if obj, found := fontDict.Find("Resources"); found {
+		if own, err := s.ctx.DereferenceDict(obj); err == nil && own != nil {
+			resources = own
+		}
+	}
+
+	obj, found := fontDict.Find("CharProcs")
+	if !found {
+		return
+	}
+	charProcs, err := s.ctx.DereferenceDict(obj)
+	if err != nil {
+		return
+	}
+	for _, proc := range charProcs {
+		sd, _, err := s.ctx.DereferenceStreamDict(proc)
+		if err != nil || sd == nil {
+			continue
+		}
+		if err := sd.Decode(); err != nil {
+			s.lock(resources)
+			continue
+		}
+		s.scan(sd.Content, resources)
+	}
+}
+
+// scanExtGState просматривает мягкую маску параметров графического состояния и возвращает
+// шрифт, который они выбирают (запись Font), или nil
+func (s *fontScanner) scanExtGState(resources types.Dict, name string) *fontUsage {
+	states := s.resourceDict(resources, "ExtGState")
+	if states == nil {
+		return nil
+	}
+	obj, found := states.Find(name)
+	if !found {
+		return nil
+	}
+	state, err := s.ctx.DereferenceDict(obj)
+	if err != nil || state == nil {
+		return nil
+	}
+
+	// Группа прозрачности мягкой маски — такой же поток содержимого, как форма
+	if obj, found := state.Find("SMask"); found {
+		if mask, err := s.ctx.DereferenceDict(obj); err == nil && mask != nil {
+			if group, found := mask.Find("G"); found {
+				s.scanForm(group, resources)
+			}
+		}
+	}
+
+	obj, found = state.Find("Font")
+	if !found {
+		return nil
+	}
+	font, err := s.ctx.DereferenceArray(obj)
+	if err != nil || len(font) == 0 {
+		return nil
+	}
+	ref, ok := font[0].(types.IndirectRef)
+	if !ok {
+		return nil
+	}
+	return s.use(ref, resources)
+}
+
+// scanXObject спускается в форму, выводимую оператором Do
+func (s *fontScanner) scanXObject(resources types.Dict, name string) {
+	xObjects := s.resourceDict(resources, "XObject")
+	if xObjects == nil {
+		return
+	}
+	obj, found := xObjects.Find(name)
+	if !found {
+		return
+	}
+	sd, _, err := s.ctx.DereferenceStreamDict(obj)
+	if err != nil || sd == nil || dictName(sd.Dict, "Subtype") != "Form" {
+		return
+	}
+	s.scanForm(obj, resources)
+}
+
+// scanPatterns просматривает потоки узоров заливки из ресурсов
+func (s *fontScanner) scanPatterns(resources types.Dict) {
+	patterns := s.resourceDict(resources, "Pattern")
+	for _, obj := range patterns {
+		s.scanForm(obj, resources)
+	}
+}
+
+// scanForm разбирает поток формы (или узора) с его собственными ресурсами; форма без
+// ресурсов использует ресурсы вызывающего потока
+func (s *fontScanner) scanForm(obj types.Object, parentResources types.Dict) {
+	ref, indirect := obj.(types.IndirectRef)
+	if indirect && s.active[ref.ObjectNumber.Value()] {
+		return
+	}
+
+	sd, _, err := s.ctx.DereferenceStreamDict(obj)
+	if err != nil || sd == nil {
+		return
+	}
+
+	resources := parentResources
+	ownResources := false
+	if obj, found := sd.Find("Resources"); found {
+		if own, err := s.ctx.DereferenceDict(obj); err == nil && own != nil {
+			resources = own
+			ownResources = true
+		}
+	}
+
+	if indirect {
+		objNr := ref.ObjectNumber.Value()
+		if ownResources && s.forms[objNr] {
+			return
+		}
+		s.forms[objNr] = ownResources
+		s.active[objNr] = true
+		defer delete(s.active, objNr)
+	}
+
+	if err := sd.Decode(); err != nil {
+		s.lock(resources)
+		return
+	}
+	s.scan(sd.Content, resources)
+}
+
+// pruneUnusedFonts удаляет из словарей Font шрифты, которые не выбирает ни один поток.
+// Шрифт учитывается как удаленный, если после этого он стал недостижим в документе
+func pruneUnusedFonts(ctx *model.Context, scanner *fontScanner, stats *fontStats) {
+	reachableBefore := reachableObjects(ctx)
+
+	var pruned []types.IndirectRef
+	for _, fontDict := range scanner.fontDicts {
+		if scanner.locked[reflect.ValueOf(fontDict).Pointer()] {
+			continue
+		}
+		for name, obj := range fontDict {
+			ref, ok := obj.(types.IndirectRef)
+			if !ok {
+				continue
+			}
+			if _, used := scanner.usage[ref.ObjectNumber.Value()]; used {
+				continue
+			}
+			fontDict.Delete(name)
+			pruned = append(pruned, ref)
+		}
+	}
+	if len(pruned) == 0 {
+		return
+	}
+
+	sort.Slice(pruned, func(i, j int) bool { return pruned[i].ObjectNumber < pruned[j].ObjectNumber })
+	reachableAfter := reachableObjects(ctx)
+	claimed := make(map[int]bool)
+
+	for _, ref := range pruned {
+		objNr := ref.ObjectNumber.Value()
+		if claimed[objNr] || reachableAfter[objNr] {
+			continue
+		}
+
+		var saved int64
+		walkReferences(ctx, ref, func(objNr int) bool {
+			if claimed[objNr] || !reachableBefore[objNr] || reachableAfter[objNr] {
+				return false
+			}
+			claimed[objNr] = true
+			if entry, found := ctx.FindTableEntryLight(objNr); found && entry != nil {
+				saved += objectSize(entry)
+			}
+			return true
+		})
+
+		stats.Removed++
+		stats.add(objNr, entities.FontOptimizationUnused, saved)
+	}
+}
+
+// deduplicateFontPrograms заменяет ссылки на одинаковые программы шрифтов ссылкой на
+// первую из них. Программы считаются одинаковыми при совпадении потока и фильтров
+func deduplicateFontPrograms(ctx *model.Context, scanner *fontScanner, stats *fontStats) {
+	programs := make(map[string]types.IndirectRef)
+	merged := make(map[int]bool)
+
+	for _, objNr := range scanner.usedFonts() {
+		fontDict, err := ctx.DereferenceDict(scanner.usage[objNr].ref)
+		if err != nil || fontDict == nil {
+			continue
+		}
+		descriptor := fontDescriptor(ctx, fontDict)
+		key, ref, sd := embeddedFontProgram(ctx, descriptor)
+		if sd == nil {
+			continue
+		}
+
+		hash := sha256.Sum256(sd.Raw)
+		programKey := fmt.Sprintf("%s/%s/%v/%x", key, dictName(sd.Dict, "Subtype"), sd.FilterPipeline, hash)
+		canonical, found := programs[programKey]
+		if !found {
+			programs[programKey] = ref
+			continue
+		}
+		if canonical.ObjectNumber == ref.ObjectNumber {
+			continue
+		}
+
+		descriptor.Update(key, canonical)
+		stats.Deduplicated++
+		var saved int64
+		if !merged[ref.ObjectNumber.Value()] {
+			merged[ref.ObjectNumber.Value()] = true
+			if entry, found := ctx.FindTableEntryLight(ref.ObjectNumber.Value()); found && entry != nil {
+				saved = objectSize(entry)
+			}
+		}
+		stats.add(objNr, entities.FontOptimizationDuplicate, saved)
+	}
+}
+
+// usedFonts возвращает номера объектов используемых шрифтов по возрастанию
+func (s *fontScanner) usedFonts() []int {
+	objNrs := make([]int, 0, len(s.usage))
+	for objNr := range s.usage {
+		objNrs = append(objNrs, objNr)
+	}
+	sort.Ints(objNrs)
+	return objNrs
+}
+
+// subsetGroup шрифты с общей программой TrueType и объединение показанных ими глифов
+type subsetGroup struct {
+	program  types.IndirectRef
+	font     *trueTypeFont
+	fonts    []int
+	glyphs   map[int]bool
+	simple   bool
+	rejected bool
+}
+
+// subsetFonts сокращает полностью встроенные программы TrueType (FontFile2) до глифов,
+// показанных всеми шрифтами, которые их используют, и помечает шрифты префиксом подмножества
+func subsetFonts(ctx *model.Context, scanner *fontScanner, stats *fontStats) {
+	groups := make(map[int]*subsetGroup)
+	var order []int
+
+	for _, objNr := range scanner.usedFonts() {
+		usage := scanner.usage[objNr]
+		fontDict, err := ctx.DereferenceDict(usage.ref)
+		if err != nil || fontDict == nil {
+			continue
+		}
+		descriptor := fontDescriptor(ctx, fontDict)
+		key, ref, sd := embeddedFontProgram(ctx, descriptor)
+		if sd == nil || key != "FontFile2" {
+			continue
+		}
+
+		programNr := ref.ObjectNumber.Value()
+		group, found := groups[programNr]
+		if !found {
+			group = &subsetGroup{program: ref, glyphs: make(map[int]bool)}
+			groups[programNr] = group
+			order = append(order, programNr)
+
+			if err := sd.Decode(); err == nil {
+				group.font, err = parseTrueType(sd.Content)
+			}
+			if group.font == nil {
+				group.rejected = true
+			}
+		}
+		group.fonts = append(group.fonts, objNr)
+
+		if group.rejected || usage.unknown || isSubsetFontName(dictName(fontDict, "BaseFont")) {
+			group.rejected = true
+			continue
+		}
+
+		var glyphs map[int]bool
+		var ok bool
+		switch dictName(fontDict, "Subtype") {
+		case "TrueType":
+			group.simple = true
+			glyphs, ok = simpleFontGlyphs(ctx, fontDict, group.font, usage.codes)
+		case "Type0":
+			glyphs, ok = cidFontGlyphs(ctx, fontDict, usage.codes)
+		}
+		if !ok {
+			group.rejected = true
+			continue
+		}
+		for gid := range glyphs {
+			group.glyphs[gid] = true
+		}
+	}
+
+	for _, programNr := range order {
+		group := groups[programNr]
+		if group.rejected {
+			continue
+		}
+
+		saved, ok := replaceFontProgram(ctx, group)
+		if !ok {
+			continue
+		}
+
+		tag := subsetTag(group.glyphs)
+		for _, objNr := range group.fonts {
+			if fontDict, err := ctx.DereferenceDict(scanner.usage[objNr].ref); err == nil && fontDict != nil {
+				tagSubsetFont(ctx, fontDict, tag)
+			}
+		}
+
+		stats.Subset++
+		stats.add(group.fonts[0], entities.FontOptimizationSubset, saved)
+		for _, objNr := range group.fonts[1:] {
+			stats.add(objNr, entities.FontOptimizationSubset, 0)
+		}
+	}
+}
+
+// replaceFontProgram записывает подмножество программы шрифта, если оно меньше исходной.
+// Возвращает экономию в байтах
+func replaceFontProgram(ctx *model.Context, group *subsetGroup) (int64, bool) {
+	entry, found := ctx.FindTableEntryLight(group.program.ObjectNumber.Value())
+	if !found || entry == nil {
+		return 0, false
+	}
+	original, ok := entry.Object.(types.StreamDict)
+	if !ok {
+		return 0, false
+	}
+
+	data := group.font.subset(group.glyphs, group.simple)
+
+	// Словарь копируется: при отказе от подмножества исходный поток не должен меняться
+	dict := types.Dict{}
+	for key, value := range original.Dict {
+		dict[key] = value
+	}
+	dict.Update("Filter", types.Name(filter.Flate))
+	dict.Delete("DecodeParms")
+	dict.Update("Length1", types.Integer(len(data)))
+
+	sd := types.NewStreamDict(dict, 0, nil, nil, []types.PDFFilter{{Name: filter.Flate}})
+	sd.Content = data
+	if err := sd.Encode(); err != nil {
+		return 0, false
+	}
+	if len(sd.Raw) >= len(original.Raw) {
+		return 0, false
+	}
+
+	length := int64(len(sd.Raw))
+	sd.StreamLength = &length
+	sd.Dict.Update("Length", types.Integer(len(sd.Raw)))
+	entry.Object = sd
+
+	return int64(len(original.Raw) - len(sd.Raw)), true
+}
+
+// fontDescriptor возвращает дескриптор шрифта; для Type0 — дескриптор его CIDFont
+func fontDescriptor(ctx *model.Context, fontDict types.Dict) types.Dict {
+	owner := fontDict
+	if dictName(fontDict, "Subtype") == "Type0" {
+		owner = descendantFont(ctx, fontDict)
+		if owner == nil {
+			return nil
+		}
+	}
+
+	obj, found := owner.Find("FontDescriptor")
+	if !found {
+		return nil
+	}
+	descriptor, err := ctx.DereferenceDict(obj)
+	if err != nil {
+		return nil
+	}
+	return descriptor
+}
+
+// descendantFont возвращает CIDFont составного шрифта Type0
+func descendantFont(ctx *model.Context, fontDict types.Dict) types.Dict {
+	obj, found := fontDict.Find("DescendantFonts")
+	if !found {
+		return nil
+	}
+	descendants, err := ctx.DereferenceArray(obj)
+	if err != nil || len(descendants) == 0 {
+		return nil
+	}
+	descendant, err := ctx.DereferenceDict(descendants[0])
+	if err != nil {
+		return nil
+	}
+	return descendant
+}
+
+// embeddedFontProgram возвращает ключ дескриптора, ссылку и поток встроенной программы шрифта
+func embeddedFontProgram(ctx *model.Context, descriptor types.Dict) (string, types.IndirectRef, *types.StreamDict) {
+	if descriptor == nil {
+		return "", types.IndirectRef{}, nil
+	}
+	for _, key := range fontProgramKeys {
+		obj, found := descriptor.Find(key)
+		if !found {
+			continue
+		}
+		ref, ok := obj.(types.IndirectRef)
+		if !ok {
+			continue
+		}
+		sd, _, err := ctx.DereferenceStreamDict(ref)
+		if err != nil || sd == nil {
+			continue
+		}
+		return key, ref, sd
+	}
+	return "", types.IndirectRef{}, nil
+}
+
+// cidFontGlyphs переводит двухбайтовые коды Type0 (Identity: код равен CID) в глифы
+// через CIDToGIDMap потомка CIDFontType2
+func cidFontGlyphs(ctx *model.Context, fontDict types.Dict, codes map[int]bool) (map[int]bool, bool) {
+	descendant := descendantFont(ctx, fontDict)
+	if descendant == nil || dictName(descendant, "Subtype") != "CIDFontType2" {
+		return nil, false
+	}
+
+	var cidToGID []byte
+	if obj, found := descendant.Find("CIDToGIDMap"); found {
+		if name, ok := obj.(types.Name); !ok || name.Value() != "Identity" {
+			sd, _, err := ctx.DereferenceStreamDict(obj)
+			if err != nil || sd == nil || sd.Decode() != nil {
+				return nil, false
+			}
+			cidToGID = sd.Content
+		}
+	}
+
+	glyphs := make(map[int]bool, len(codes))
+	for cid := range codes {
+		switch {
+		case cidToGID == nil:
+			glyphs[cid] = true
+		case cid*2+1 < len(cidToGID):
+			glyphs[int(binary.BigEndian.Uint16(cidToGID[cid*2:]))] = true
+		}
+	}
+	return glyphs, true
+}
+
+// simpleFontGlyphs переводит однобайтовые коды простого TrueType шрифта в глифы. Просмотрщики
+// выбирают глиф по-разному (cmap (3,0) или (1,0), Unicode через кодировку, имя глифа в post),
+// поэтому сохраняются все возможные кандидаты. Имя из Differences, которое не удается
+// найти в шрифте, делает подмножество невозможным
+func simpleFontGlyphs(ctx *model.Context, fontDict types.Dict, font *trueTypeFont, codes map[int]bool) (map[int]bool, bool) {
+	differences, ok := fontDifferences(ctx, fontDict)
+	if !ok {
+		return nil, false
+	}
+
+	symbolic := font.cmapLookup(3, 0)
+	unicode := font.cmapLookup(3, 1)
+	mac := font.cmapLookup(1, 0)
+	var names map[string]int
+
+	glyphs := make(map[int]bool, len(codes))
+	add := func(lookup func(uint32) int, code uint32) {
+		if lookup != nil {
+			if gid := lookup(code); gid > 0 {
+				glyphs[gid] = true
+			}
+		}
+	}
+
+	for code := range codes {
+		// Без cmap код символа совпадает с номером глифа
+		glyphs[code] = true
+
+		if name, found := differences[code]; found {
+			if names == nil {
+				names = font.glyphNames()
+			}
+			resolved := 0
+			if gid, found := names[name]; found {
+				glyphs[gid] = true
+				resolved++
+			}
+			if r, ok := glyphNameRune(name); ok && unicode != nil {
+				if gid := unicode(uint32(r)); gid > 0 {
+					glyphs[gid] = true
+					resolved++
+				}
+			}
+			if resolved == 0 {
+				return nil, false
+			}
+			continue
+		}
+
+		c := uint32(code)
+		for _, prefix := range []uint32{0, 0xF000, 0xF100, 0xF200} {
+			add(symbolic, prefix+c)
+		}
+		add(mac, c)
+		add(unicode, c)
+		add(unicode, uint32(winAnsiRune(byte(code))))
+	}
+
+	return glyphs, true
+}
+
+// fontDifferences возвращает имена глифов из массива Differences кодировки шрифта.
+// Возвращает false, если кодировка задана потоком CMap
+func fontDifferences(ctx *model.Context, fontDict types.Dict) (map[int]string, bool) {
+	differences := make(map[int]string)
+
+	obj, found := fontDict.Find("Encoding")
+	if !found {
+		return differences, true
+	}
+	obj, err := ctx.Dereference(obj)
+	if err != nil {
+		return nil, false
+	}
+
+	switch encoding := obj.(type) {
+	case types.Name:
+		return differences, true
+	case types.Dict:
+		obj, found := encoding.Find("Differences")
+		if !found {
+			return differences, true
+		}
+		array, err := ctx.DereferenceArray(obj)
+		if err != nil {
+			return nil, false
+		}
+		code := 0
+		for _, element := range array {
+			switch v := element.(type) {
+			case types.Integer:
+				code = v.Value()
+			case types.Name:
+				differences[code] = v.Value()
+				code++
+			}
+		}
+		return differences, true
+	}
+
+	return nil, false
+}
+
+// glyphNameRune переводит имена вида uniXXXX и uXXXX[XX] в символ Unicode
+func glyphNameRune(name string) (rune, bool) {
+	var digits string
+	switch {
+	case strings.HasPrefix(name, "uni") && len(name) == 7:
+		digits = name[3:]
+	case strings.HasPrefix(name, "u") && len(name) >= 5 && len(name) <= 7:
+		digits = name[1:]
+	default:
+		return 0, false
+	}
+	v, err := strconv.ParseUint(digits, 16, 32)
+	if err != nil {
+		return 0, false
+	}
+	return rune(v), true
+}
+
+// winAnsiRunes символы WinAnsiEncoding в диапазоне 0x80–0x9F, отличающиеся от Latin-1
+var winAnsiRunes = map[byte]rune{
+	0x80: '€', 0x82: '‚', 0x83: 'ƒ', 0x84: '„', 0x85: '…', 0x86: '†', 0x87: '‡', 0x88: 'ˆ',
+	0x89: '‰', 0x8A: 'Š', 0x8B: '‹', 0x8C: 'Œ', 0x8E: 'Ž', 0x91: '‘', 0x92: '’', 0x93: '“',
+	0x94: '”', 0x95: '•', 0x96: '–', 0x97: '—', 0x98: '˜', 0x99: '™', 0x9A: 'š', 0x9B: '›',
+	0x9C: 'œ', 0x9E: 'ž', 0x9F: 'Ÿ',
+}
+
+// winAnsiRune возвращает символ Unicode кода WinAnsiEncoding
+func winAnsiRune(code byte) rune {
+	if r, found := winAnsiRunes[code]; found {
+		return r
+	}
+	return rune(code)
+}
+
+// subsetTag строит префикс подмножества из шести заглавных букв по набору глифов
+func subsetTag(glyphs map[int]bool) string {
+	gids := make([]int, 0, len(glyphs))
+	for gid := range glyphs {
+		gids = append(gids, gid)
+	}
+	sort.Ints(gids)
+
+	var buf bytes.Buffer
+	for _, gid := range gids {
+		_ = binary.Write(&buf, binary.BigEndian, uint32(gid))
+	}
+	hash := sha256.Sum256(buf.Bytes())
+
+	tag := make([]byte, 6)
+	for i := range tag {
+		tag[i] = 'A' + hash[i]%26
+	}
+	return string(tag)
+}
+
+// tagSubsetFont добавляет префикс подмножества к имени шрифта, его CIDFont и дескриптора
+// и удаляет CIDSet/CharSet, которые описывали полный набор глифов
+func tagSubsetFont(ctx *model.Context, fontDict types.Dict, tag string) {
+	rename := func(d types.Dict, key string) {
+		name := dictName(d, key)
+		if name != "" && !isSubsetFontName(name) {
+			d.Update(key, types.Name(tag+"+"+name))
+		}
+	}
+
+	rename(fontDict, "BaseFont")
+	if dictName(fontDict, "Subtype") == "Type0" {
+		if descendant := descendantFont(ctx, fontDict); descendant != nil {
+			rename(descendant, "BaseFont")
+		}
+	}
+	if descriptor := fontDescriptor(ctx, fontDict); descriptor != nil {
+		rename(descriptor, "FontName")
+		descriptor.Delete("CIDSet")
+		descriptor.Delete("CharSet")
+	}
+}
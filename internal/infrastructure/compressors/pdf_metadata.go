@@ -0,0 +1,59 @@
+package compressors
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+
+	"compress/internal/domain/entities"
+)
+
+// applyMetadataPolicy применяет политику метаданных к документу: при RemoveMetadata
+// удаляет XMP и все ключи Info, кроме сохраняемых, и при StampMetadata добавляет служебные ключи
+func applyMetadataPolicy(ctx *model.Context, config *entities.CompressionConfig, originalSize int64) error {
+	rootDict, err := ctx.Catalog()
+	if err != nil {
+		return err
+	}
+
+	infoDict := types.Dict{}
+	if ctx.Info != nil {
+		d, err := ctx.DereferenceDict(*ctx.Info)
+		if err != nil {
+			return fmt.Errorf("ошибка чтения словаря Info: %w", err)
+		}
+		if d != nil {
+			infoDict = d
+		}
+	}
+
+	if config.RemoveMetadata {
+		rootDict.Delete("Metadata")
+		for key := range infoDict {
+			if !config.KeepsMetadataKey(key) {
+				infoDict.Delete(key)
+			}
+		}
+	}
+
+	if config.StampMetadata {
+		for key, value := range config.MetadataStamp(originalSize, time.Now()) {
+			infoDict.Update(key, types.StringLiteral(value))
+		}
+	}
+
+	switch {
+	case infoDict.Len() == 0:
+		ctx.Info = nil
+	case ctx.Info == nil:
+		ref, err := ctx.IndRefForNewObject(infoDict)
+		if err != nil {
+			return err
+		}
+		ctx.Info = ref
+	}
+
+	return nil
+}
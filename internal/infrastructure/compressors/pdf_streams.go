@@ -0,0 +1,102 @@
+package compressors
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+
+	"github.com/pdfcpu/pdfcpu/pkg/filter"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// streamStats статистика пересжатия потоков
+type streamStats struct {
+	Recompressed int
+	SavedBytes   int64
+}
+
+// recompressStreams пересжимает без потерь потоки без фильтра и потоки, внешний фильтр
+// которых FlateDecode, с максимальной степенью Deflate (pdfcpu и большинство генераторов
+// используют степень по умолчанию). Новые данные сохраняются, только если они меньше
+// исходных. XMP метаданные остаются как есть: PDF/A требует, чтобы они не были сжаты
+func recompressStreams(ctx *model.Context) streamStats {
+	var stats streamStats
+
+	for _, entry := range ctx.Table {
+		if entry == nil || entry.Free || entry.Object == nil {
+			continue
+		}
+		// Объектные и xref-потоки имеют собственные типы и пишутся заново при сохранении
+		sd, ok := entry.Object.(types.StreamDict)
+		if !ok || dictName(sd.Dict, "Type") == "Metadata" {
+			continue
+		}
+
+		_, hasFilter := sd.Find("Filter")
+		isFlate := len(sd.FilterPipeline) > 0 && sd.FilterPipeline[0].Name == filter.Flate
+		if hasFilter && !isFlate {
+			continue
+		}
+
+		data, ok := recompressStreamData(sd.Raw, isFlate)
+		if !ok {
+			continue
+		}
+
+		stats.Recompressed++
+		stats.SavedBytes += int64(len(sd.Raw) - len(data))
+
+		// Для потока с FlateDecode меняется только внешний слой: DecodeParms (предикторы)
+		// и следующие фильтры относятся к распакованным данным и остаются в силе
+		length := int64(len(data))
+		sd.Raw = data
+		sd.StreamLength = &length
+		sd.Dict.Update("Length", types.Integer(len(data)))
+		if !isFlate {
+			sd.FilterPipeline = []types.PDFFilter{{Name: filter.Flate}}
+			sd.Dict.Update("Filter", types.Name(filter.Flate))
+		}
+		entry.Object = sd
+	}
+
+	return stats
+}
+
+// recompressStreamData сжимает данные потока Deflate с максимальной степенью; данные потока
+// с FlateDecode предварительно распаковываются. Возвращает false, если данные не читаются
+// или результат не меньше исходных
+func recompressStreamData(raw []byte, isFlate bool) ([]byte, bool) {
+	if len(raw) == 0 {
+		return nil, false
+	}
+
+	content := raw
+	if isFlate {
+		reader, err := zlib.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			return nil, false
+		}
+		content, err = io.ReadAll(reader)
+		if err != nil {
+			return nil, false
+		}
+	}
+
+	var buf bytes.Buffer
+	writer, err := zlib.NewWriterLevel(&buf, zlib.BestCompression)
+	if err != nil {
+		return nil, false
+	}
+	if _, err := writer.Write(content); err != nil {
+		return nil, false
+	}
+	if err := writer.Close(); err != nil {
+		return nil, false
+	}
+
+	if buf.Len() >= len(raw) {
+		return nil, false
+	}
+	return buf.Bytes(), true
+}
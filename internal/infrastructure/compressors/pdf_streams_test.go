@@ -0,0 +1,71 @@
+package compressors
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestRecompressStreamData(t *testing.T) {
+	// Содержимое страницы с текстом в разных позициях, как у генераторов отчетов
+	var page strings.Builder
+	for i := 0; i < 400; i++ {
+		fmt.Fprintf(&page, "BT /F%d %d Tf %d %d Td (Row %d: total %d) Tj ET\n", i%3+1, 9+i%4, 72+i%7*11, 760-i%60*12, i, i*i%977)
+	}
+	content := []byte(page.String())
+
+	deflate := func(data []byte, level int) []byte {
+		var buf bytes.Buffer
+		w, _ := zlib.NewWriterLevel(&buf, level)
+		w.Write(data)
+		w.Close()
+		return buf.Bytes()
+	}
+	noise := make([]byte, 4096)
+	rand.New(rand.NewSource(1)).Read(noise)
+
+	tests := []struct {
+		name     string
+		raw      []byte
+		isFlate  bool
+		expected bool
+	}{
+		{"Unfiltered content stream", content, false, true},
+		{"Flate stream written with fastest compression", deflate(content, zlib.BestSpeed), true, true},
+		{"Flate stream already at best compression", deflate(content, zlib.BestCompression), true, false},
+		{"Unfiltered incompressible data", noise, false, false},
+		{"Corrupt Flate stream", content[:100], true, false},
+		{"Empty stream", nil, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, ok := recompressStreamData(tt.raw, tt.isFlate)
+			if ok != tt.expected {
+				t.Fatalf("Expected recompressed %v, got %v", tt.expected, ok)
+			}
+			if !ok {
+				return
+			}
+			if len(data) >= len(tt.raw) {
+				t.Errorf("Result must be smaller: %d >= %d", len(data), len(tt.raw))
+			}
+
+			r, err := zlib.NewReader(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			decoded, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(decoded, content) {
+				t.Error("Recompressed stream must decode to the original content")
+			}
+		})
+	}
+}
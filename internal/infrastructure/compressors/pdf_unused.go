@@ -0,0 +1,349 @@
+package compressors
+
+import (
+	"fmt"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// removeThumbnails удаляет встроенные миниатюры страниц (Thumb): просмотрщики строят их сами.
+// Возвращает количество удаленных миниатюр
+func removeThumbnails(ctx *model.Context) (int, error) {
+	removed := 0
+	err := forEachPage(ctx, func(pageDict types.Dict) {
+		if _, found := pageDict.Find("Thumb"); found {
+			pageDict.Delete("Thumb")
+			removed++
+		}
+	})
+	return removed, err
+}
+
+// removePieceInfo удаляет частные данные приложений (PieceInfo) страниц и форм, например
+// исходные документы Illustrator. Возвращает количество удаленных словарей
+func removePieceInfo(ctx *model.Context) int {
+	removed := 0
+	for _, entry := range ctx.Table {
+		if entry == nil || entry.Free || entry.Object == nil {
+			continue
+		}
+
+		var d types.Dict
+		switch o := entry.Object.(type) {
+		case types.Dict:
+			d = o
+		case types.StreamDict:
+			d = o.Dict
+		default:
+			continue
+		}
+		if _, found := d.Find("PieceInfo"); found {
+			d.Delete("PieceInfo")
+			removed++
+		}
+	}
+	return removed
+}
+
+// removeJavaScript удаляет сценарии документа (дерево имен JavaScript, OpenAction и AA каталога),
+// а также действия JavaScript страниц, аннотаций и полей формы.
+// Возвращает количество удаленных сценариев
+func removeJavaScript(ctx *model.Context) (int, error) {
+	rootDict, err := ctx.Catalog()
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	if obj, found := rootDict.Find("Names"); found {
+		names, err := ctx.DereferenceDict(obj)
+		if err != nil {
+			return 0, err
+		}
+		if names != nil {
+			if scripts, found := names.Find("JavaScript"); found {
+				removed += countNameTreeEntries(ctx, scripts)
+				names.Delete("JavaScript")
+			}
+			if names.Len() == 0 {
+				rootDict.Delete("Names")
+			}
+		}
+	}
+
+	if obj, found := rootDict.Find("OpenAction"); found && isJavaScriptAction(ctx, obj) {
+		rootDict.Delete("OpenAction")
+		removed++
+	}
+	removed += removeJavaScriptActions(ctx, rootDict)
+
+	err = forEachPage(ctx, func(pageDict types.Dict) {
+		removed += removeJavaScriptActions(ctx, pageDict)
+
+		obj, found := pageDict.Find("Annots")
+		if !found {
+			return
+		}
+		annots, err := ctx.DereferenceArray(obj)
+		if err != nil {
+			return
+		}
+		for _, annotObj := range annots {
+			if annot, err := ctx.DereferenceDict(annotObj); err == nil && annot != nil {
+				removed += removeJavaScriptActions(ctx, annot)
+			}
+		}
+	})
+	if err != nil {
+		return removed, err
+	}
+
+	// Сценарии вычисления и проверки полей, не совмещенных с виджетами
+	if obj, found := rootDict.Find("AcroForm"); found {
+		if form, err := ctx.DereferenceDict(obj); err == nil && form != nil {
+			if fields, found := form.Find("Fields"); found {
+				removed += removeFieldJavaScript(ctx, fields, make(map[int]bool))
+			}
+		}
+	}
+
+	return removed, nil
+}
+
+// removeFieldJavaScript удаляет действия JavaScript полей формы и их потомков
+func removeFieldJavaScript(ctx *model.Context, obj types.Object, visited map[int]bool) int {
+	fields, err := ctx.DereferenceArray(obj)
+	if err != nil {
+		return 0
+	}
+
+	removed := 0
+	for _, fieldObj := range fields {
+		if ref, ok := fieldObj.(types.IndirectRef); ok {
+			if visited[ref.ObjectNumber.Value()] {
+				continue
+			}
+			visited[ref.ObjectNumber.Value()] = true
+		}
+		field, err := ctx.DereferenceDict(fieldObj)
+		if err != nil || field == nil {
+			continue
+		}
+		removed += removeJavaScriptActions(ctx, field)
+		if kids, found := field.Find("Kids"); found {
+			removed += removeFieldJavaScript(ctx, kids, visited)
+		}
+	}
+	return removed
+}
+
+// removeJavaScriptActions удаляет из словаря действие A и записи дополнительных действий AA,
+// если это действия JavaScript. Возвращает количество удаленных действий
+func removeJavaScriptActions(ctx *model.Context, d types.Dict) int {
+	removed := 0
+	if obj, found := d.Find("A"); found && isJavaScriptAction(ctx, obj) {
+		d.Delete("A")
+		removed++
+	}
+
+	obj, found := d.Find("AA")
+	if !found {
+		return removed
+	}
+	actions, err := ctx.DereferenceDict(obj)
+	if err != nil || actions == nil {
+		return removed
+	}
+	for trigger, action := range actions {
+		if isJavaScriptAction(ctx, action) {
+			actions.Delete(trigger)
+			removed++
+		}
+	}
+	if actions.Len() == 0 {
+		d.Delete("AA")
+	}
+	return removed
+}
+
+// isJavaScriptAction проверяет, является ли объект действием JavaScript
+func isJavaScriptAction(ctx *model.Context, obj types.Object) bool {
+	action, err := ctx.DereferenceDict(obj)
+	return err == nil && action != nil && dictName(action, "S") == "JavaScript"
+}
+
+// removeUnusedDestinations удаляет именованные назначения (дерево имен Dests и словарь Dests
+// каталога), на которые не ссылаются ссылки, закладки и действия перехода документа.
+// Возвращает количество удаленных назначений
+func removeUnusedDestinations(ctx *model.Context) (int, error) {
+	rootDict, err := ctx.Catalog()
+	if err != nil {
+		return 0, err
+	}
+
+	used := usedDestinationNames(ctx)
+	removed := 0
+
+	if obj, found := rootDict.Find("Dests"); found {
+		if dests, err := ctx.DereferenceDict(obj); err == nil && dests != nil {
+			for name := range dests {
+				if !used[name] {
+					dests.Delete(name)
+					removed++
+				}
+			}
+			if dests.Len() == 0 {
+				rootDict.Delete("Dests")
+			}
+		}
+	}
+
+	obj, found := rootDict.Find("Names")
+	if !found {
+		return removed, nil
+	}
+	names, err := ctx.DereferenceDict(obj)
+	if err != nil || names == nil {
+		return removed, err
+	}
+	tree, found := names.Find("Dests")
+	if !found {
+		return removed, nil
+	}
+
+	// Дерево перестраивается одним листом: записи собираются в исходном (отсортированном) порядке
+	var kept types.Array
+	total := 0
+	walkNameTree(ctx, tree, func(key, value types.Object) {
+		total++
+		if name, ok := destinationName(key); ok && used[name] {
+			kept = append(kept, key, value)
+		}
+	})
+	if len(kept)/2 == total {
+		return removed, nil
+	}
+
+	removed += total - len(kept)/2
+	if len(kept) == 0 {
+		names.Delete("Dests")
+		if names.Len() == 0 {
+			rootDict.Delete("Names")
+		}
+	} else {
+		names.Update("Dests", types.Dict{"Names": kept})
+	}
+	return removed, nil
+}
+
+// usedDestinationNames собирает имена назначений, на которые ссылаются записи Dest
+// (ссылки, закладки) и D действий перехода внутри документа. Учитываются только объекты,
+// достижимые из каталога: удаленные аннотации и закладки назначения не удерживают
+func usedDestinationNames(ctx *model.Context) map[string]bool {
+	used := make(map[string]bool)
+	reachable := reachableObjects(ctx)
+
+	var visit func(obj types.Object)
+	visit = func(obj types.Object) {
+		switch o := obj.(type) {
+		case types.Dict:
+			if dest, found := o.Find("Dest"); found {
+				if name, ok := destinationName(dest); ok {
+					used[name] = true
+				}
+			}
+			if dest, found := o.Find("D"); found && dictName(o, "S") == "GoTo" {
+				if name, ok := destinationName(dest); ok {
+					used[name] = true
+				}
+			}
+			// Вложенные прямые объекты; косвенные обходятся из таблицы
+			for _, value := range o {
+				visit(value)
+			}
+		case types.Array:
+			for _, value := range o {
+				visit(value)
+			}
+		}
+	}
+
+	for objNr, entry := range ctx.Table {
+		if entry == nil || entry.Free || entry.Object == nil || !reachable[objNr] {
+			continue
+		}
+		switch o := entry.Object.(type) {
+		case types.Dict, types.Array:
+			visit(o)
+		case types.StreamDict:
+			visit(o.Dict)
+		}
+	}
+	return used
+}
+
+// destinationName возвращает имя назначения, заданного именем или строкой
+func destinationName(obj types.Object) (string, bool) {
+	switch o := obj.(type) {
+	case types.Name:
+		return o.Value(), true
+	case types.StringLiteral:
+		parser := &contentParser{data: []byte("(" + o.Value() + ")")}
+		return string(parser.literalString()), true
+	case types.HexLiteral:
+		b, err := o.Bytes()
+		return string(b), err == nil
+	}
+	return "", false
+}
+
+// walkNameTree вызывает fn для каждой записи дерева имен (Names и Kids)
+func walkNameTree(ctx *model.Context, obj types.Object, fn func(key, value types.Object)) {
+	visited := make(map[int]bool)
+
+	var walk func(obj types.Object)
+	walk = func(obj types.Object) {
+		if ref, ok := obj.(types.IndirectRef); ok {
+			if visited[ref.ObjectNumber.Value()] {
+				return
+			}
+			visited[ref.ObjectNumber.Value()] = true
+		}
+
+		node, err := ctx.DereferenceDict(obj)
+		if err != nil || node == nil {
+			return
+		}
+		if obj, found := node.Find("Names"); found {
+			if names, err := ctx.DereferenceArray(obj); err == nil {
+				for i := 0; i+1 < len(names); i += 2 {
+					fn(names[i], names[i+1])
+				}
+			}
+		}
+		if obj, found := node.Find("Kids"); found {
+			if kids, err := ctx.DereferenceArray(obj); err == nil {
+				for _, kid := range kids {
+					walk(kid)
+				}
+			}
+		}
+	}
+
+	walk(obj)
+}
+
+// forEachPage вызывает fn для словаря каждой страницы документа
+func forEachPage(ctx *model.Context, fn func(pageDict types.Dict)) error {
+	for pageNr := 1; pageNr <= ctx.PageCount; pageNr++ {
+		pageDict, _, _, err := ctx.PageDict(pageNr, false)
+		if err != nil {
+			return fmt.Errorf("страница %d: %w", pageNr, err)
+		}
+		if pageDict != nil {
+			fn(pageDict)
+		}
+	}
+	return nil
+}
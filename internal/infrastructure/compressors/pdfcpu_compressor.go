@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
 
 	"compress/internal/domain/entities"
 )
@@ -27,23 +28,33 @@ func (p *PDFCPUCompressor) Compress(inputPath, outputPath string, config *entiti
 		return nil, fmt.Errorf("ошибка получения информации об исходном файле: %w", err)
 	}
 
-	// Применяем настройки в зависимости от уровня сжатия
-	if config.ImageCompression {
-		fmt.Printf("📸 Включено сжатие изображений (качество: %d%%)\n", config.ImageQuality)
+	// Читаем и валидируем документ с конфигурацией, построенной по уровню сжатия
+	ctx, err := readPDFCPUContext(inputPath, newPDFCPUConfiguration(config))
+	if err != nil {
+		return &entities.CompressionResult{
+			OriginalSize: originalInfo.Size(),
+			Success:      false,
+			Error:        err,
+		}, fmt.Errorf("ошибка чтения PDF (PDFCPU): %w", err)
 	}
 
-	if config.RemoveDuplicates {
-		fmt.Println("🔄 Удаление дубликатов объектов")
+	// Применяем настройки в зависимости от уровня сжатия
+	cleanup, err := p.applyConfig(ctx, config, originalInfo.Size())
+	if err != nil {
+		return &entities.CompressionResult{
+			OriginalSize: originalInfo.Size(),
+			Success:      false,
+			Error:        err,
+		}, fmt.Errorf("ошибка обработки PDF (PDFCPU): %w", err)
 	}
 
-	// Выполняем оптимизацию с базовыми настройками
-	err = api.OptimizeFile(inputPath, outputPath, nil)
-	if err != nil {
+	// Записываем результат
+	if err := api.WriteContextFile(ctx, outputPath); err != nil {
 		return &entities.CompressionResult{
 			OriginalSize: originalInfo.Size(),
 			Success:      false,
 			Error:        err,
-		}, fmt.Errorf("ошибка оптимизации PDFCPU: %w", err)
+		}, fmt.Errorf("ошибка записи PDF (PDFCPU): %w", err)
 	}
 
 	// Получаем размер сжатого файла
@@ -61,9 +72,85 @@ func (p *PDFCPUCompressor) Compress(inputPath, outputPath string, config *entiti
 		CompressedSize: compressedInfo.Size(),
 		Success:        true,
 	}
+	cleanup.apply(result)
 
 	result.CalculateCompressionRatio()
 
 	fmt.Printf("✅ Сжатие завершено: %s\n", outputPath)
 	return result, nil
 }
+
+// applyConfig выполняет обработку документа согласно флагам CompressionConfig
+// и возвращает количество удаленных объектов
+func (p *PDFCPUCompressor) applyConfig(ctx *model.Context, config *entities.CompressionConfig, originalSize int64) (cleanupStats, error) {
+	if config.RemoveMetadata {
+		fmt.Printf("🧹 Удаление метаданных (сохраняются: %v)\n", config.KeepMetadataKeys)
+	}
+	if err := applyMetadataPolicy(ctx, config, originalSize); err != nil {
+		return cleanupStats{}, fmt.Errorf("ошибка обработки метаданных: %w", err)
+	}
+
+	cleanup, err := applyCleanup(ctx, config)
+	if err != nil {
+		return cleanup, err
+	}
+
+	if config.ImageCompression {
+		fmt.Printf("📸 Сжатие изображений (качество: %d%%, до %d PPI)\n", config.ImageQuality, config.ImageMaxPPI)
+		stats, err := recompressImages(ctx, config, false)
+		if err != nil {
+			return cleanup, fmt.Errorf("ошибка сжатия изображений: %w", err)
+		}
+		cleanup.Images = *stats
+		fmt.Printf("📸 Пересжато изображений: %d из %d (сэкономлено %.2f MB)\n",
+			stats.Recompressed, stats.Candidates, float64(stats.SavedBytes)/1024/1024)
+		printColorReduction(stats)
+	}
+
+	if config.RemoveDuplicates {
+		fmt.Println("🔄 Удаление дубликатов объектов")
+		if err := api.OptimizeContext(ctx); err != nil {
+			return cleanup, fmt.Errorf("ошибка оптимизации: %w", err)
+		}
+	}
+
+	cleanup.Streams = applyStreamRecompression(ctx, config)
+
+	return cleanup, nil
+}
+
+// newPDFCPUConfiguration строит конфигурацию pdfcpu на основе CompressionConfig
+func newPDFCPUConfiguration(config *entities.CompressionConfig) *model.Configuration {
+	pdfConfig := model.NewDefaultConfiguration()
+	pdfConfig.Cmd = model.OPTIMIZE
+	pdfConfig.ValidationMode = model.ValidationRelaxed
+
+	// Объектные потоки и xref-поток заметно уменьшают служебную часть файла
+	pdfConfig.WriteObjectStream = config.CompressStreams
+	pdfConfig.WriteXRefStream = config.CompressStreams
+
+	// Одинаковые потоки содержимого страниц объединяются вместе с остальными дубликатами
+	pdfConfig.OptimizeDuplicateContentStreams = config.RemoveDuplicates
+
+	return pdfConfig
+}
+
+// readPDFCPUContext читает и валидирует PDF документ
+func readPDFCPUContext(inputPath string, pdfConfig *model.Configuration) (*model.Context, error) {
+	file, err := os.Open(inputPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	ctx, err := api.ReadContext(file, pdfConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := api.ValidateContext(ctx); err != nil {
+		return nil, err
+	}
+
+	return ctx, nil
+}
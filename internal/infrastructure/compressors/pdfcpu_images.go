@@ -0,0 +1,548 @@
+package compressors
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"math"
+
+	"github.com/nfnt/resize"
+	"github.com/pdfcpu/pdfcpu/pkg/filter"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+
+	"compress/internal/domain/entities"
+)
+
+const (
+	// minRecompressImageSide изображения меньше этого размера (px) не трогаем: иконки, маркеры списков
+	minRecompressImageSide = 32
+	// minDownsampleFactor уменьшение меньше чем на 10% не дает заметного выигрыша
+	minDownsampleFactor = 0.9
+)
+
+// imagePlacement максимальный размер, с которым изображение выводится на странице (в пунктах)
+type imagePlacement struct {
+	Width  float64
+	Height float64
+}
+
+// imageRecompressStats статистика прохода по изображениям
+type imageRecompressStats struct {
+	Candidates   int
+	Recompressed int
+	Grayscale    int // Переведены в оттенки серого
+	Bitonal      int // Переведены в 1 бит
+	SavedBytes   int64
+
+	// Form XObject, изображения внутри которых не обработаны
+	SkippedForms int
+}
+
+// recompressImages уменьшает разрешение изображений до config.ImageMaxPPI с учетом
+// их размера на странице и перекодирует их в JPEG с качеством config.ImageQuality
+// или, для почти двухцветных, в CCITT G4. При reducedOnly перекодируются только
+// изображения, цветность которых уменьшается.
+// Изображения, которые после перекодирования становятся больше, остаются без изменений.
+func recompressImages(ctx *model.Context, config *entities.CompressionConfig, reducedOnly bool) (*imageRecompressStats, error) {
+	placements, skippedForms, err := collectImagePlacements(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &imageRecompressStats{SkippedForms: skippedForms}
+
+	for objNr, placement := range placements {
+		entry, found := ctx.FindTableEntryLight(objNr)
+		if !found || entry == nil || entry.Free || entry.Object == nil {
+			continue
+		}
+
+		sd, ok := entry.Object.(types.StreamDict)
+		if !ok {
+			continue
+		}
+
+		stats.Candidates++
+
+		originalLength := len(sd.Raw)
+		recompressed, mode, ok := recompressImage(ctx, sd, placement, config, reducedOnly)
+		if !ok {
+			continue
+		}
+
+		entry.Object = recompressed
+		stats.Recompressed++
+		switch mode {
+		case imageColorGray:
+			stats.Grayscale++
+		case imageColorBitonal:
+			stats.Bitonal++
+		}
+		stats.SavedBytes += int64(originalLength - len(recompressed.Raw))
+	}
+
+	return stats, nil
+}
+
+// printColorReduction выводит количество изображений с уменьшенной цветностью
+// и форм, изображения внутри которых не обработаны
+func printColorReduction(stats *imageRecompressStats) {
+	if stats.Grayscale > 0 || stats.Bitonal > 0 {
+		fmt.Printf("🩶 Цветность уменьшена: в оттенки серого %d, в 1 бит %d\n", stats.Grayscale, stats.Bitonal)
+	}
+	if stats.SkippedForms > 0 {
+		fmt.Printf("⚠️  Пропущено форм XObject: %d (изображения внутри не обработаны)\n", stats.SkippedForms)
+	}
+}
+
+// maxFormDepth наибольшая вложенность Form XObject, которую обходит collectImagePlacements
+const maxFormDepth = 16
+
+// collectImagePlacements находит изображения, выводимые операторами Do на страницах
+// и внутри Form XObject, и для каждого объекта запоминает наибольший размер вывода.
+// Возвращает также число форм, которые не удалось обойти (нечитаемое содержимое,
+// циклические ссылки или слишком глубокая вложенность)
+func collectImagePlacements(ctx *model.Context) (map[int]imagePlacement, int, error) {
+	walker := &placementWalker{
+		ctx:        ctx,
+		placements: make(map[int]imagePlacement),
+		active:     make(map[int]bool),
+	}
+
+	for pageNr := 1; pageNr <= ctx.PageCount; pageNr++ {
+		pageDict, _, _, err := ctx.PageDict(pageNr, false)
+		if err != nil {
+			return nil, 0, fmt.Errorf("страница %d: %w", pageNr, err)
+		}
+		if pageDict == nil {
+			continue
+		}
+
+		resources := inheritedPageDict(ctx, pageDict, "Resources")
+		images, forms := resourceXObjects(ctx, resources)
+		if len(images) == 0 && len(forms) == 0 {
+			continue
+		}
+
+		content, err := pageContent(ctx, pageDict)
+		if err != nil {
+			// Страницы с нечитаемым содержимым просто пропускаем
+			continue
+		}
+
+		walker.walk(content, resources, images, forms, identityMatrix, 0)
+	}
+
+	return walker.placements, walker.skippedForms, nil
+}
+
+// placementWalker обходит содержимое страниц и вложенных форм
+type placementWalker struct {
+	ctx          *model.Context
+	placements   map[int]imagePlacement
+	active       map[int]bool // Формы, которые обходятся в данный момент
+	skippedForms int
+}
+
+// walk обходит поток содержимого, выводимый с матрицей base
+func (w *placementWalker) walk(content []byte, resources types.Dict, images, forms map[string]int, base contentMatrix, depth int) {
+	walkXObjectPlacements(content, func(name string, ctm contentMatrix) {
+		ctm = ctm.multiply(base)
+		if objNr, found := images[name]; found {
+			width, height := ctm.scale()
+			placement := w.placements[objNr]
+			placement.Width = math.Max(placement.Width, width)
+			placement.Height = math.Max(placement.Height, height)
+			w.placements[objNr] = placement
+			return
+		}
+		if objNr, found := forms[name]; found {
+			w.walkForm(objNr, resources, ctm, depth+1)
+		}
+	})
+}
+
+// walkForm обходит содержимое Form XObject с учетом его матрицы /Matrix.
+// Форма без собственных ресурсов использует ресурсы того, кто ее выводит
+func (w *placementWalker) walkForm(objNr int, parentResources types.Dict, ctm contentMatrix, depth int) {
+	if depth > maxFormDepth || w.active[objNr] {
+		w.skippedForms++
+		return
+	}
+
+	entry, found := w.ctx.FindTableEntryLight(objNr)
+	if !found || entry == nil || entry.Free {
+		w.skippedForms++
+		return
+	}
+	sd, ok := entry.Object.(types.StreamDict)
+	if !ok {
+		w.skippedForms++
+		return
+	}
+	if err := sd.Decode(); err != nil {
+		w.skippedForms++
+		return
+	}
+
+	resources := parentResources
+	if obj, found := sd.Find("Resources"); found {
+		if d, err := w.ctx.DereferenceDict(obj); err == nil && d != nil {
+			resources = d
+		}
+	}
+	images, forms := resourceXObjects(w.ctx, resources)
+	if len(images) == 0 && len(forms) == 0 {
+		return
+	}
+
+	w.active[objNr] = true
+	w.walk(sd.Content, resources, images, forms, formMatrix(w.ctx, sd.Dict).multiply(ctm), depth)
+	delete(w.active, objNr)
+}
+
+// formMatrix возвращает матрицу /Matrix формы или единичную матрицу
+func formMatrix(ctx *model.Context, d types.Dict) contentMatrix {
+	obj, found := d.Find("Matrix")
+	if !found {
+		return identityMatrix
+	}
+	arr, err := ctx.DereferenceArray(obj)
+	if err != nil || len(arr) != 6 {
+		return identityMatrix
+	}
+	var m contentMatrix
+	for i, v := range arr {
+		switch n := v.(type) {
+		case types.Integer:
+			m[i] = float64(n.Value())
+		case types.Float:
+			m[i] = n.Value()
+		default:
+			return identityMatrix
+		}
+	}
+	return m
+}
+
+// resourceXObjects возвращает номера объектов изображений и форм из словаря ресурсов по их именам
+func resourceXObjects(ctx *model.Context, resources types.Dict) (images, forms map[string]int) {
+	images = make(map[string]int)
+	forms = make(map[string]int)
+	if resources == nil {
+		return images, forms
+	}
+
+	obj, found := resources.Find("XObject")
+	if !found {
+		return images, forms
+	}
+	xObjects, err := ctx.DereferenceDict(obj)
+	if err != nil || xObjects == nil {
+		return images, forms
+	}
+
+	for name, ref := range xObjects {
+		indRef, ok := ref.(types.IndirectRef)
+		if !ok {
+			continue
+		}
+		sd, _, err := ctx.DereferenceStreamDict(indRef)
+		if err != nil || sd == nil {
+			continue
+		}
+		switch dictName(sd.Dict, "Subtype") {
+		case "Image":
+			images[name] = indRef.ObjectNumber.Value()
+		case "Form":
+			forms[name] = indRef.ObjectNumber.Value()
+		}
+	}
+
+	return images, forms
+}
+
+// inheritedPageDict возвращает словарь страницы по ключу с учетом наследования от родительских узлов
+func inheritedPageDict(ctx *model.Context, pageDict types.Dict, key string) types.Dict {
+	for node := pageDict; node != nil; {
+		if obj, found := node.Find(key); found {
+			d, err := ctx.DereferenceDict(obj)
+			if err != nil {
+				return nil
+			}
+			return d
+		}
+
+		parent, found := node.Find("Parent")
+		if !found {
+			return nil
+		}
+		next, err := ctx.DereferenceDict(parent)
+		if err != nil {
+			return nil
+		}
+		node = next
+	}
+	return nil
+}
+
+// pageContent возвращает декодированное содержимое страницы
+func pageContent(ctx *model.Context, pageDict types.Dict) ([]byte, error) {
+	obj, found := pageDict.Find("Contents")
+	if !found {
+		return nil, nil
+	}
+
+	obj, err := ctx.Dereference(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	var streams types.Array
+	switch o := obj.(type) {
+	case types.StreamDict:
+		streams = types.Array{o}
+	case types.Array:
+		streams = o
+	default:
+		return nil, nil
+	}
+
+	var content bytes.Buffer
+	for _, s := range streams {
+		sd, _, err := ctx.DereferenceStreamDict(s)
+		if err != nil {
+			return nil, err
+		}
+		if sd == nil {
+			continue
+		}
+		if err := sd.Decode(); err != nil {
+			return nil, err
+		}
+		content.Write(sd.Content)
+		content.WriteByte('\n')
+	}
+
+	return content.Bytes(), nil
+}
+
+// recompressImage уменьшает и перекодирует одно изображение. Почти серые изображения
+// сохраняются в DeviceGray, почти двухцветные — в 1 бит с фильтром CCITTFaxDecode,
+// если это разрешено конфигурацией. При reducedOnly изображение меняется, только если
+// уменьшается его цветность. Возвращает false, если изображение не поддерживается
+// или результат не меньше исходного.
+func recompressImage(ctx *model.Context, sd types.StreamDict, placement imagePlacement, config *entities.CompressionConfig, reducedOnly bool) (types.StreamDict, imageColorMode, bool) {
+	img, ok := decodeImageStream(ctx, &sd)
+	if !ok {
+		return sd, imageColorOriginal, false
+	}
+
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width < minRecompressImageSide || height < minRecompressImageSide {
+		return sd, imageColorOriginal, false
+	}
+
+	mode := imageColorOriginal
+	var threshold uint8
+	if config.ConvertGrayscale {
+		if gray, isGray := nearGrayImage(img); isGray {
+			img, mode = gray, imageColorGray
+			if config.ConvertBitonal {
+				if t, isBitonal := bitonalThreshold(gray); isBitonal {
+					threshold, mode = t, imageColorBitonal
+				}
+			}
+		}
+	}
+	// Изображение, которое уже было в оттенках серого, не считается преобразованным
+	if mode == imageColorGray && imageColorComponents(ctx, sd.Dict) == 1 {
+		mode = imageColorOriginal
+	}
+	if reducedOnly && mode == imageColorOriginal {
+		return sd, imageColorOriginal, false
+	}
+
+	maxPPI := config.ImageMaxPPI
+	if mode == imageColorBitonal {
+		maxPPI = max(maxPPI, bitonalMinPPI)
+	}
+
+	// Эффективное разрешение на странице: пиксели на дюйм (72 пункта)
+	if placement.Width > 0 && placement.Height > 0 && maxPPI > 0 {
+		ppi := math.Min(
+			float64(width)/(placement.Width/72),
+			float64(height)/(placement.Height/72),
+		)
+		factor := float64(maxPPI) / ppi
+		if factor < minDownsampleFactor {
+			newWidth := uint(math.Max(1, math.Round(float64(width)*factor)))
+			newHeight := uint(math.Max(1, math.Round(float64(height)*factor)))
+			img = resize.Resize(newWidth, newHeight, img, resize.Lanczos3)
+		}
+	}
+
+	var data []byte
+	if mode == imageColorBitonal {
+		gray, ok := img.(*image.Gray)
+		if !ok {
+			return sd, imageColorOriginal, false
+		}
+		data = encodeCCITTG4(gray, threshold)
+	} else {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: config.ImageQuality}); err != nil {
+			return sd, imageColorOriginal, false
+		}
+		data = buf.Bytes()
+	}
+
+	if len(data) >= len(sd.Raw) {
+		return sd, imageColorOriginal, false
+	}
+
+	length := int64(len(data))
+	newBounds := img.Bounds()
+
+	sd.Raw = data
+	sd.Content = nil
+	sd.StreamLength = &length
+	sd.Dict.Update("Length", types.Integer(len(data)))
+	sd.Dict.Update("Width", types.Integer(newBounds.Dx()))
+	sd.Dict.Update("Height", types.Integer(newBounds.Dy()))
+	if mode != imageColorOriginal {
+		sd.Dict.Update("ColorSpace", types.Name("DeviceGray"))
+	}
+
+	if mode == imageColorBitonal {
+		sd.FilterPipeline = []types.PDFFilter{{Name: filter.CCITTFax}}
+		sd.Dict.Update("Filter", types.Name(filter.CCITTFax))
+		sd.Dict.Update("DecodeParms", types.Dict{
+			"K":       types.Integer(-1),
+			"Columns": types.Integer(newBounds.Dx()),
+			"Rows":    types.Integer(newBounds.Dy()),
+		})
+		sd.Dict.Update("BitsPerComponent", types.Integer(1))
+		return sd, mode, true
+	}
+
+	sd.FilterPipeline = []types.PDFFilter{{Name: filter.DCT}}
+	sd.Dict.Update("Filter", types.Name(filter.DCT))
+	sd.Dict.Delete("DecodeParms")
+	sd.Dict.Update("BitsPerComponent", types.Integer(8))
+
+	return sd, mode, true
+}
+
+// decodeImageStream декодирует изображение из JPEG (DCTDecode) или Flate потока
+// с 8 битами на компонент в пространствах Gray и RGB
+func decodeImageStream(ctx *model.Context, sd *types.StreamDict) (image.Image, bool) {
+	// Маски и нестандартные Decode опираются на точные значения пикселей — не трогаем
+	if mask := sd.BooleanEntry("ImageMask"); mask != nil && *mask {
+		return nil, false
+	}
+	if _, found := sd.Find("Mask"); found {
+		return nil, false
+	}
+	if _, found := sd.Find("Decode"); found {
+		return nil, false
+	}
+
+	components := imageColorComponents(ctx, sd.Dict)
+	if components != 1 && components != 3 {
+		return nil, false
+	}
+
+	switch {
+	case len(sd.FilterPipeline) == 1 && sd.FilterPipeline[0].Name == filter.DCT:
+		img, err := jpeg.Decode(bytes.NewReader(sd.Raw))
+		if err != nil {
+			return nil, false
+		}
+		switch img.(type) {
+		case *image.Gray, *image.YCbCr:
+			return img, true
+		}
+		return nil, false
+
+	case len(sd.FilterPipeline) == 0 || (len(sd.FilterPipeline) == 1 && sd.FilterPipeline[0].Name == filter.Flate):
+		if bpc := sd.IntEntry("BitsPerComponent"); bpc == nil || *bpc != 8 {
+			return nil, false
+		}
+		width, height := sd.IntEntry("Width"), sd.IntEntry("Height")
+		if width == nil || height == nil || *width <= 0 || *height <= 0 {
+			return nil, false
+		}
+		if err := sd.Decode(); err != nil {
+			return nil, false
+		}
+		return rawPixelsToImage(sd.Content, *width, *height, components)
+	}
+
+	return nil, false
+}
+
+// rawPixelsToImage собирает изображение из несжатых пикселей
+func rawPixelsToImage(pixels []byte, width, height, components int) (image.Image, bool) {
+	if len(pixels) < width*height*components {
+		return nil, false
+	}
+
+	rect := image.Rect(0, 0, width, height)
+	if components == 1 {
+		img := image.NewGray(rect)
+		copy(img.Pix, pixels[:width*height])
+		return img, true
+	}
+
+	img := image.NewRGBA(rect)
+	for i, j := 0, 0; i < width*height*3; i, j = i+3, j+4 {
+		img.Pix[j] = pixels[i]
+		img.Pix[j+1] = pixels[i+1]
+		img.Pix[j+2] = pixels[i+2]
+		img.Pix[j+3] = 0xff
+	}
+	return img, true
+}
+
+// imageColorComponents возвращает число цветовых компонент изображения
+// или 0 для неподдерживаемых цветовых пространств
+func imageColorComponents(ctx *model.Context, d types.Dict) int {
+	obj, found := d.Find("ColorSpace")
+	if !found {
+		return 0
+	}
+	obj, err := ctx.Dereference(obj)
+	if err != nil {
+		return 0
+	}
+
+	switch cs := obj.(type) {
+	case types.Name:
+		switch cs.Value() {
+		case "DeviceGray":
+			return 1
+		case "DeviceRGB":
+			return 3
+		}
+	case types.Array:
+		if len(cs) != 2 {
+			return 0
+		}
+		if name, ok := cs[0].(types.Name); !ok || name.Value() != "ICCBased" {
+			return 0
+		}
+		profile, _, err := ctx.DereferenceStreamDict(cs[1])
+		if err != nil || profile == nil {
+			return 0
+		}
+		if n := profile.IntEntry("N"); n != nil {
+			return *n
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,62 @@
+package compressors
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"compress/internal/domain/entities"
+)
+
+// qpdfWarningsExitCode код завершения qpdf, когда файл записан, но с предупреждениями
+const qpdfWarningsExitCode = 3
+
+// QPDFLinearizer линеаризация PDF внешней утилитой qpdf
+type QPDFLinearizer struct {
+	binary  string
+	timeout time.Duration
+}
+
+// NewQPDFLinearizer создает линеаризатор; пустой путь означает поиск qpdf в PATH.
+// Процесс qpdf, не завершившийся за timeout, останавливается
+func NewQPDFLinearizer(binary string, timeout time.Duration) *QPDFLinearizer {
+	if binary == "" {
+		binary = "qpdf"
+	}
+	return &QPDFLinearizer{binary: binary, timeout: timeout}
+}
+
+// Linearize записывает линеаризованную копию документа
+func (l *QPDFLinearizer) Linearize(inputPath, outputPath string) error {
+	path, err := exec.LookPath(l.binary)
+	if err != nil {
+		return fmt.Errorf("qpdf не найден (%s): %w", l.binary, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), l.timeout)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, "--linearize", inputPath, outputPath)
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = externalWaitDelay
+	configureProcessGroup(cmd)
+
+	err = cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("%w: qpdf не завершился за %s", entities.ErrLinearizationFailed, l.timeout)
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == qpdfWarningsExitCode {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка qpdf: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
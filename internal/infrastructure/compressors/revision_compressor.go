@@ -0,0 +1,106 @@
+package compressors
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"compress/internal/domain/entities"
+	"compress/internal/domain/repositories"
+	"compress/internal/infrastructure/pdfraw"
+)
+
+// RevisionCompressor сжимает документ, не сливая его ревизии: подписанная часть файла
+// (до конца последнего /ByteRange) копируется побайтно, а каждая следующая ревизия
+// переписывается отдельно с пересжатием потоков без потерь. Подписи остаются действительными,
+// история изменений документа сохраняется
+type RevisionCompressor struct {
+	fileRepo repositories.FileRepository
+}
+
+// NewRevisionCompressor создает компрессор с сохранением ревизий
+func NewRevisionCompressor(fileRepo repositories.FileRepository) *RevisionCompressor {
+	return &RevisionCompressor{fileRepo: fileRepo}
+}
+
+// Compress переписывает неподписанные ревизии документа. Если структуру ревизий
+// нельзя разобрать, возвращает ошибку entities.ErrRevisionsNotRewritable
+func (c *RevisionCompressor) Compress(inputPath, outputPath string, config *entities.CompressionConfig) (*entities.CompressionResult, error) {
+	input := config.SourceDocument(inputPath)
+	if input == nil {
+		var err error
+		input, err = c.fileRepo.GetFileInfo(inputPath)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка получения информации об исходном файле: %w", err)
+		}
+	}
+
+	file, err := os.Open(inputPath)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения исходного файла: %w", err)
+	}
+	defer file.Close()
+
+	reader, err := pdfraw.Open(file, input.Size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", entities.ErrRevisionsNotRewritable, err)
+	}
+
+	// Подписанная часть дополняется до конца своей ревизии: ByteRange может не включать
+	// перевод строки после %%EOF, а ревизия копируется только целиком
+	keep, err := signedRevisionsEnd(reader, input.SignedBytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", entities.ErrRevisionsNotRewritable, err)
+	}
+
+	output, err := os.Create(outputPath)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания выходного файла: %w", err)
+	}
+	stats, err := reader.RewriteRevisions(output, keep)
+	if closeErr := output.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(outputPath)
+		if errors.Is(err, pdfraw.ErrSyntax) || errors.Is(err, pdfraw.ErrRevisionBoundary) {
+			return nil, fmt.Errorf("%w: %v", entities.ErrRevisionsNotRewritable, err)
+		}
+		return nil, fmt.Errorf("ошибка записи ревизий: %w", err)
+	}
+
+	info, err := os.Stat(outputPath)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения информации о сжатом файле: %w", err)
+	}
+
+	fmt.Printf("🧾 Переписано ревизий: %d, пересжато потоков: %d\n", stats.Rewritten, stats.RecompressedStreams)
+
+	result := &entities.CompressionResult{
+		OriginalSize:        input.Size,
+		CompressedSize:      info.Size(),
+		Success:             true,
+		RecompressedStreams: stats.RecompressedStreams,
+	}
+	result.CalculateCompressionRatio()
+	return result, nil
+}
+
+// signedRevisionsEnd возвращает конец ревизии, в которой заканчивается подписанная часть
+// длиной signedBytes, или 0 для документа без подписей
+func signedRevisionsEnd(reader *pdfraw.Reader, signedBytes int64) (int64, error) {
+	if signedBytes <= 0 {
+		return 0, nil
+	}
+
+	revisions, err := reader.Revisions()
+	if err != nil {
+		return 0, err
+	}
+	for _, revision := range revisions {
+		if revision.End >= signedBytes {
+			return revision.End, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: подписанная часть длиннее документа", pdfraw.ErrRevisionBoundary)
+}
@@ -0,0 +1,97 @@
+package compressors
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"compress/internal/domain/entities"
+)
+
+// appendTestRevision дописывает объекты ревизии и классическую таблицу ссылок с трейлером
+func appendTestRevision(pdf []byte, objects map[int]string, trailer string) []byte {
+	offsets := map[int]int{}
+	maxNum := 0
+	for num := 1; len(offsets) < len(objects); num++ {
+		if body, ok := objects[num]; ok {
+			offsets[num] = len(pdf)
+			pdf = fmt.Appendf(pdf, "%d 0 obj\n%s\nendobj\n", num, body)
+			maxNum = num
+		}
+	}
+
+	xref := len(pdf)
+	pdf = append(pdf, "xref\n"...)
+	for num := 1; num <= maxNum; num++ {
+		if offset, ok := offsets[num]; ok {
+			pdf = fmt.Appendf(pdf, "%d 1\n%010d 00000 n \n", num, offset)
+		}
+	}
+	return fmt.Appendf(pdf, "trailer\n%s\nstartxref\n%d\n%%%%EOF\n", trailer, xref)
+}
+
+func TestRevisionCompressorKeepsSignedRevision(t *testing.T) {
+	content := bytes.Repeat([]byte("0 0 m 100 100 l S\n"), 200)
+	pdf := appendTestRevision([]byte("%PDF-1.7\n"), map[int]string{
+		1: "<< /Type /Catalog /Pages 2 0 R /AcroForm << /Fields [5 0 R] >> >>",
+		2: "<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		3: "<< /Type /Page /Parent 2 0 R /MediaBox [0 0 200 200] /Contents 4 0 R >>",
+		4: fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+		5: "<< /FT /Sig /T (Signature1) /V << /Type /Sig /ByteRange [0 10 20 30] >> >>",
+	}, "<< /Size 6 /Root 1 0 R >>")
+	signed := len(pdf)
+	pdf = appendTestRevision(pdf, map[int]string{
+		3: "<< /Type /Page /Parent 2 0 R /MediaBox [0 0 200 200] /Contents 6 0 R >>",
+		6: fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+	}, fmt.Sprintf("<< /Size 7 /Root 1 0 R /Prev %d >>", bytes.LastIndex(pdf, []byte("\nxref"))+1))
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "signed.pdf")
+	if err := os.WriteFile(input, pdf, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		signedBytes int64
+		wantErr     error
+	}{
+		// Подпись охватывает ревизию без перевода строки после %%EOF
+		{"Signed part rounded up to its revision", int64(signed - 1), nil},
+		{"Signed part past the end of file", int64(len(pdf) + 1), entities.ErrRevisionsNotRewritable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := &entities.PDFDocument{Path: input, Size: int64(len(pdf)), Signatures: 1, SignedBytes: tt.signedBytes, Revisions: 2}
+			config := (&entities.CompressionConfig{}).WithSource(source)
+			output := filepath.Join(dir, "output.pdf")
+
+			result, err := NewRevisionCompressor(nil).Compress(input, output, config)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Compress: %v", err)
+			}
+
+			data, err := os.ReadFile(output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(data[:signed], pdf[:signed]) {
+				t.Error("Signed revision is not byte-identical")
+			}
+			if result.CompressedSize >= result.OriginalSize || result.RecompressedStreams != 1 {
+				t.Errorf("Expected the update to shrink with 1 recompressed stream, got %d -> %d bytes, %d streams",
+					result.OriginalSize, result.CompressedSize, result.RecompressedStreams)
+			}
+		})
+	}
+}
@@ -0,0 +1,434 @@
+package compressors
+
+import (
+	"encoding/binary"
+	"errors"
+	"sort"
+	"strings"
+)
+
+// errUnsupportedTrueType программа шрифта не является поддерживаемым TrueType
+var errUnsupportedTrueType = errors.New("неподдерживаемая программа шрифта TrueType")
+
+// Флаги компонента составного глифа
+const (
+	glyphArgsAreWords   = 0x0001
+	glyphHaveScale      = 0x0008
+	glyphMoreComponents = 0x0020
+	glyphHaveXYScale    = 0x0040
+	glyphHaveTwoByTwo   = 0x0080
+)
+
+// trueTypeSubsetTables таблицы, которые остаются в подмножестве шрифта. Таблицы
+// OpenType-разметки (GSUB, GPOS, kern и т.п.) в PDF не используются: позиции глифов
+// задаются потоком содержимого. cmap и post нужны только простым шрифтам
+var trueTypeSubsetTables = map[string]bool{
+	"head": true, "hhea": true, "hmtx": true, "maxp": true, "loca": true, "glyf": true,
+	"cvt ": true, "fpgm": true, "prep": true, "OS/2": true, "name": true,
+	"cmap": true, "post": true,
+}
+
+// trueTypeFont разобранная программа шрифта TrueType
+type trueTypeFont struct {
+	tables    map[string][]byte
+	numGlyphs int
+	loca      []uint32 // Смещения глифов в glyf, numGlyphs+1 значений
+}
+
+// parseTrueType разбирает таблицы программы шрифта TrueType
+func parseTrueType(data []byte) (*trueTypeFont, error) {
+	if len(data) < 12 {
+		return nil, errUnsupportedTrueType
+	}
+	version := binary.BigEndian.Uint32(data)
+	if version != 0x00010000 && string(data[:4]) != "true" {
+		return nil, errUnsupportedTrueType
+	}
+
+	numTables := int(binary.BigEndian.Uint16(data[4:]))
+	if len(data) < 12+numTables*16 {
+		return nil, errUnsupportedTrueType
+	}
+
+	font := &trueTypeFont{tables: make(map[string][]byte, numTables)}
+	for i := 0; i < numTables; i++ {
+		record := data[12+i*16:]
+		tag := string(record[:4])
+		offset := int(binary.BigEndian.Uint32(record[8:]))
+		length := int(binary.BigEndian.Uint32(record[12:]))
+		if offset < 0 || length < 0 || offset+length > len(data) {
+			return nil, errUnsupportedTrueType
+		}
+		font.tables[tag] = data[offset : offset+length]
+	}
+
+	head, maxp, loca, glyf := font.tables["head"], font.tables["maxp"], font.tables["loca"], font.tables["glyf"]
+	if len(head) < 54 || len(maxp) < 6 || loca == nil || glyf == nil {
+		return nil, errUnsupportedTrueType
+	}
+
+	font.numGlyphs = int(binary.BigEndian.Uint16(maxp[4:]))
+	longLoca := binary.BigEndian.Uint16(head[50:]) == 1
+	font.loca = make([]uint32, font.numGlyphs+1)
+	for i := range font.loca {
+		switch {
+		case longLoca && len(loca) >= (i+1)*4:
+			font.loca[i] = binary.BigEndian.Uint32(loca[i*4:])
+		case !longLoca && len(loca) >= (i+1)*2:
+			font.loca[i] = uint32(binary.BigEndian.Uint16(loca[i*2:])) * 2
+		default:
+			return nil, errUnsupportedTrueType
+		}
+		if int(font.loca[i]) > len(glyf) || (i > 0 && font.loca[i] < font.loca[i-1]) {
+			return nil, errUnsupportedTrueType
+		}
+	}
+
+	return font, nil
+}
+
+// glyph возвращает данные глифа из таблицы glyf
+func (f *trueTypeFont) glyph(gid int) []byte {
+	if gid < 0 || gid >= f.numGlyphs {
+		return nil
+	}
+	return f.tables["glyf"][f.loca[gid]:f.loca[gid+1]]
+}
+
+// components возвращает глифы, из которых собран составной глиф
+func (f *trueTypeFont) components(gid int) []int {
+	data := f.glyph(gid)
+	if len(data) < 10 || int16(binary.BigEndian.Uint16(data)) >= 0 {
+		return nil
+	}
+
+	var components []int
+	for pos := 10; pos+4 <= len(data); {
+		flags := binary.BigEndian.Uint16(data[pos:])
+		components = append(components, int(binary.BigEndian.Uint16(data[pos+2:])))
+		pos += 4
+
+		if flags&glyphArgsAreWords != 0 {
+			pos += 4
+		} else {
+			pos += 2
+		}
+		switch {
+		case flags&glyphHaveScale != 0:
+			pos += 2
+		case flags&glyphHaveXYScale != 0:
+			pos += 4
+		case flags&glyphHaveTwoByTwo != 0:
+			pos += 8
+		}
+
+		if flags&glyphMoreComponents == 0 {
+			break
+		}
+	}
+	return components
+}
+
+// closeGlyphs дополняет набор глифов компонентами составных глифов и глифом .notdef
+func (f *trueTypeFont) closeGlyphs(glyphs map[int]bool) map[int]bool {
+	closed := map[int]bool{0: true}
+	stack := make([]int, 0, len(glyphs))
+	for gid := range glyphs {
+		stack = append(stack, gid)
+	}
+
+	for len(stack) > 0 {
+		gid := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if closed[gid] || gid < 0 || gid >= f.numGlyphs {
+			continue
+		}
+		closed[gid] = true
+		stack = append(stack, f.components(gid)...)
+	}
+	return closed
+}
+
+// subset собирает программу шрифта, в которой сохранены только указанные глифы.
+// Остальные глифы становятся пустыми, но сохраняют свои номера, поэтому CIDToGIDMap,
+// cmap и ширины в PDF остаются верными. При simple=false удаляются cmap и post,
+// не нужные CID-шрифтам
+func (f *trueTypeFont) subset(glyphs map[int]bool, simple bool) []byte {
+	keep := f.closeGlyphs(glyphs)
+
+	// Таблица glyf только с сохраненными глифами и длинная loca к ней
+	var glyf []byte
+	loca := make([]byte, (f.numGlyphs+1)*4)
+	for gid := 0; gid < f.numGlyphs; gid++ {
+		binary.BigEndian.PutUint32(loca[gid*4:], uint32(len(glyf)))
+		if keep[gid] {
+			glyf = append(glyf, f.glyph(gid)...)
+			for len(glyf)%4 != 0 {
+				glyf = append(glyf, 0)
+			}
+		}
+	}
+	binary.BigEndian.PutUint32(loca[f.numGlyphs*4:], uint32(len(glyf)))
+
+	head := append([]byte(nil), f.tables["head"]...)
+	binary.BigEndian.PutUint32(head[8:], 0)  // checkSumAdjustment
+	binary.BigEndian.PutUint16(head[50:], 1) // indexToLocFormat: длинная loca
+
+	tables := map[string][]byte{"glyf": glyf, "loca": loca, "head": head}
+	for tag, data := range f.tables {
+		if _, replaced := tables[tag]; replaced || !trueTypeSubsetTables[tag] {
+			continue
+		}
+		if !simple && (tag == "cmap" || tag == "post") {
+			continue
+		}
+		tables[tag] = data
+	}
+
+	font := writeTrueType(tables)
+	adjustment := 0xB1B0AFBA - trueTypeChecksum(font)
+	binary.BigEndian.PutUint32(font[trueTypeTableOffset(font, "head")+8:], adjustment)
+	return font
+}
+
+// writeTrueType собирает файл шрифта из таблиц, упорядоченных по тегу
+func writeTrueType(tables map[string][]byte) []byte {
+	tags := make([]string, 0, len(tables))
+	for tag := range tables {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	numTables := len(tags)
+	entrySelector := 0
+	for 1<<(entrySelector+1) <= numTables {
+		entrySelector++
+	}
+	searchRange := (1 << entrySelector) * 16
+
+	header := make([]byte, 12+numTables*16)
+	binary.BigEndian.PutUint32(header, 0x00010000)
+	binary.BigEndian.PutUint16(header[4:], uint16(numTables))
+	binary.BigEndian.PutUint16(header[6:], uint16(searchRange))
+	binary.BigEndian.PutUint16(header[8:], uint16(entrySelector))
+	binary.BigEndian.PutUint16(header[10:], uint16(numTables*16-searchRange))
+
+	font := header
+	for i, tag := range tags {
+		data := tables[tag]
+		record := font[12+i*16:]
+		copy(record, tag)
+		binary.BigEndian.PutUint32(record[4:], trueTypeChecksum(data))
+		binary.BigEndian.PutUint32(record[8:], uint32(len(font)))
+		binary.BigEndian.PutUint32(record[12:], uint32(len(data)))
+
+		font = append(font, data...)
+		for len(font)%4 != 0 {
+			font = append(font, 0)
+		}
+	}
+	return font
+}
+
+// trueTypeTableOffset возвращает смещение таблицы в собранном файле шрифта
+func trueTypeTableOffset(font []byte, tag string) int {
+	numTables := int(binary.BigEndian.Uint16(font[4:]))
+	for i := 0; i < numTables; i++ {
+		record := font[12+i*16:]
+		if string(record[:4]) == tag {
+			return int(binary.BigEndian.Uint32(record[8:]))
+		}
+	}
+	return 0
+}
+
+// trueTypeChecksum контрольная сумма таблицы: сумма 32-битных слов с дополнением нулями
+func trueTypeChecksum(data []byte) uint32 {
+	var sum uint32
+	for i := 0; i < len(data); i += 4 {
+		var word [4]byte
+		copy(word[:], data[i:])
+		sum += binary.BigEndian.Uint32(word[:])
+	}
+	return sum
+}
+
+// cmapLookup возвращает функцию поиска глифа в подтаблице cmap с указанными
+// платформой и кодировкой или nil, если подтаблицы нет
+func (f *trueTypeFont) cmapLookup(platformID, encodingID uint16) func(code uint32) int {
+	cmap := f.tables["cmap"]
+	if len(cmap) < 4 {
+		return nil
+	}
+
+	numTables := int(binary.BigEndian.Uint16(cmap[2:]))
+	for i := 0; i < numTables && 4+(i+1)*8 <= len(cmap); i++ {
+		record := cmap[4+i*8:]
+		if binary.BigEndian.Uint16(record) != platformID || binary.BigEndian.Uint16(record[2:]) != encodingID {
+			continue
+		}
+		offset := int(binary.BigEndian.Uint32(record[4:]))
+		if offset+4 > len(cmap) {
+			return nil
+		}
+		return cmapSubtableLookup(cmap[offset:])
+	}
+	return nil
+}
+
+// cmapSubtableLookup поддерживает форматы подтаблиц 0, 4, 6 и 12
+func cmapSubtableLookup(table []byte) func(code uint32) int {
+	u16 := func(pos int) int {
+		if pos < 0 || pos+2 > len(table) {
+			return 0
+		}
+		return int(binary.BigEndian.Uint16(table[pos:]))
+	}
+	u32 := func(pos int) uint32 {
+		if pos < 0 || pos+4 > len(table) {
+			return 0
+		}
+		return binary.BigEndian.Uint32(table[pos:])
+	}
+
+	switch u16(0) {
+	case 0:
+		return func(code uint32) int {
+			if code > 255 || 6+int(code) >= len(table) {
+				return 0
+			}
+			return int(table[6+code])
+		}
+
+	case 4:
+		segCount := u16(6) / 2
+		endCodes, startCodes, deltas, rangeOffsets := 14, 16+segCount*2, 16+segCount*4, 16+segCount*6
+		return func(code uint32) int {
+			if code > 0xFFFF {
+				return 0
+			}
+			for i := 0; i < segCount; i++ {
+				if uint32(u16(endCodes+i*2)) < code {
+					continue
+				}
+				start := uint32(u16(startCodes + i*2))
+				if start > code {
+					return 0
+				}
+				delta := u16(deltas + i*2)
+				rangeOffset := u16(rangeOffsets + i*2)
+				if rangeOffset == 0 {
+					return (int(code) + delta) & 0xFFFF
+				}
+				glyph := u16(rangeOffsets + i*2 + rangeOffset + int(code-start)*2)
+				if glyph == 0 {
+					return 0
+				}
+				return (glyph + delta) & 0xFFFF
+			}
+			return 0
+		}
+
+	case 6:
+		first, count := uint32(u16(6)), uint32(u16(8))
+		return func(code uint32) int {
+			if code < first || code >= first+count {
+				return 0
+			}
+			return u16(10 + int(code-first)*2)
+		}
+
+	case 12:
+		groups := int(u32(12))
+		return func(code uint32) int {
+			for i := 0; i < groups; i++ {
+				group := 16 + i*12
+				start, end := u32(group), u32(group+4)
+				if code >= start && code <= end {
+					return int(u32(group+8) + code - start)
+				}
+			}
+			return 0
+		}
+	}
+
+	return nil
+}
+
+// glyphNames возвращает номера глифов по именам из таблицы post (форматы 1 и 2)
+func (f *trueTypeFont) glyphNames() map[string]int {
+	post := f.tables["post"]
+	if len(post) < 32 {
+		return nil
+	}
+
+	names := make(map[string]int)
+	switch binary.BigEndian.Uint32(post) {
+	case 0x00010000:
+		for gid := 0; gid < f.numGlyphs && gid < len(macGlyphNames); gid++ {
+			names[macGlyphNames[gid]] = gid
+		}
+
+	case 0x00020000:
+		if len(post) < 34 {
+			return nil
+		}
+		count := int(binary.BigEndian.Uint16(post[32:]))
+		indexes := post[34:]
+		if len(indexes) < count*2 {
+			return nil
+		}
+
+		// Имена с индексами от 258 хранятся паскаль-строками после массива индексов
+		var custom []string
+		for pos := 34 + count*2; pos < len(post); {
+			length := int(post[pos])
+			if pos+1+length > len(post) {
+				break
+			}
+			custom = append(custom, string(post[pos+1:pos+1+length]))
+			pos += 1 + length
+		}
+
+		for gid := 0; gid < count; gid++ {
+			index := int(binary.BigEndian.Uint16(indexes[gid*2:]))
+			switch {
+			case index < len(macGlyphNames):
+				names[macGlyphNames[index]] = gid
+			case index-len(macGlyphNames) < len(custom):
+				names[custom[index-len(macGlyphNames)]] = gid
+			}
+		}
+
+	default:
+		return nil
+	}
+
+	return names
+}
+
+// macGlyphNames стандартный порядок имен глифов Macintosh (таблица post)
+var macGlyphNames = strings.Fields(`
+.notdef .null nonmarkingreturn space exclam quotedbl numbersign dollar percent
+ampersand quotesingle parenleft parenright asterisk plus comma hyphen period slash
+zero one two three four five six seven eight nine colon semicolon less equal greater
+question at A B C D E F G H I J K L M N O P Q R S T U V W X Y Z bracketleft backslash
+bracketright asciicircum underscore grave a b c d e f g h i j k l m n o p q r s t u v w
+x y z braceleft bar braceright asciitilde Adieresis Aring Ccedilla Eacute Ntilde
+Odieresis Udieresis aacute agrave acircumflex adieresis atilde aring ccedilla eacute
+egrave ecircumflex edieresis iacute igrave icircumflex idieresis ntilde oacute ograve
+ocircumflex odieresis otilde uacute ugrave ucircumflex udieresis dagger degree cent
+sterling section bullet paragraph germandbls registered copyright trademark acute
+dieresis notequal AE Oslash infinity plusminus lessequal greaterequal yen mu
+partialdiff summation product pi integral ordfeminine ordmasculine Omega ae oslash
+questiondown exclamdown logicalnot radical florin approxequal Delta guillemotleft
+guillemotright ellipsis nonbreakingspace Agrave Atilde Otilde OE oe endash emdash
+quotedblleft quotedblright quoteleft quoteright divide lozenge ydieresis Ydieresis
+fraction currency guilsinglleft guilsinglright fi fl daggerdbl periodcentered
+quotesinglbase quotedblbase perthousand Acircumflex Ecircumflex Aacute Edieresis
+Egrave Iacute Icircumflex Idieresis Igrave Oacute Ocircumflex apple Ograve Uacute
+Ucircumflex Ugrave dotlessi circumflex tilde macron breve dotaccent ring cedilla
+hungarumlaut ogonek caron Lslash lslash Scaron scaron Zcaron zcaron brokenbar Eth eth
+Yacute yacute Thorn thorn minus multiply onesuperior twosuperior threesuperior
+onehalf onequarter threequarters franc Gbreve gbreve Idotaccent Scedilla scedilla
+Cacute cacute Ccaron ccaron dcroat`)
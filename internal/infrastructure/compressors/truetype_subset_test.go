@@ -0,0 +1,243 @@
+package compressors
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// buildTestTrueType собирает минимальный шрифт: .notdef, «A», «B» и составной глиф из «A»,
+// cmap (3,1) формата 4 для A и B, post формата 2 и таблицу GSUB
+func buildTestTrueType(t *testing.T) []byte {
+	t.Helper()
+
+	simpleGlyph := func(marker byte) []byte {
+		glyph := make([]byte, 12)
+		binary.BigEndian.PutUint16(glyph, 1) // numberOfContours
+		glyph[11] = marker
+		return glyph
+	}
+	composite := make([]byte, 18)
+	binary.BigEndian.PutUint16(composite, 0xFFFF) // numberOfContours = -1
+	binary.BigEndian.PutUint16(composite[10:], glyphArgsAreWords)
+	binary.BigEndian.PutUint16(composite[12:], 1)
+
+	glyphs := [][]byte{simpleGlyph(0), simpleGlyph('A'), simpleGlyph('B'), composite}
+	var glyf []byte
+	loca := make([]byte, (len(glyphs)+1)*2)
+	for i, glyph := range glyphs {
+		binary.BigEndian.PutUint16(loca[i*2:], uint16(len(glyf)/2))
+		glyf = append(glyf, glyph...)
+	}
+	binary.BigEndian.PutUint16(loca[len(glyphs)*2:], uint16(len(glyf)/2))
+
+	head := make([]byte, 54)
+	binary.BigEndian.PutUint32(head, 0x00010000)
+	maxp := make([]byte, 6)
+	binary.BigEndian.PutUint32(maxp, 0x00005000)
+	binary.BigEndian.PutUint16(maxp[4:], uint16(len(glyphs)))
+
+	// Формат 4: сегмент 0x41–0x42 → глифы 1–2 и завершающий сегмент 0xFFFF
+	subtable := make([]byte, 32)
+	for i, v := range []uint16{4, 32, 0, 4, 4, 1, 0, 0x42, 0xFFFF, 0, 0x41, 0xFFFF, 0xFFC0, 1, 0, 0} {
+		binary.BigEndian.PutUint16(subtable[i*2:], v)
+	}
+	cmap := []byte{0, 0, 0, 1, 0, 3, 0, 1, 0, 0, 0, 12}
+	cmap = append(cmap, subtable...)
+
+	post := make([]byte, 34)
+	binary.BigEndian.PutUint32(post, 0x00020000)
+	binary.BigEndian.PutUint16(post[32:], uint16(len(glyphs)))
+	for _, index := range []uint16{0, 36, 37, 258} {
+		post = binary.BigEndian.AppendUint16(post, index)
+	}
+	post = append(post, 6)
+	post = append(post, "A.comp"...)
+
+	return writeTrueType(map[string][]byte{
+		"head": head, "maxp": maxp, "loca": loca, "glyf": glyf,
+		"cmap": cmap, "post": post, "GSUB": make([]byte, 64),
+	})
+}
+
+func TestTrueTypeSubset(t *testing.T) {
+	font, err := parseTrueType(buildTestTrueType(t))
+	if err != nil {
+		t.Fatalf("parseTrueType: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		glyphs        map[int]bool
+		simple        bool
+		expectedKept  []int
+		expectedEmpty []int
+		expectedCmap  bool
+	}{
+		{
+			name:          "Composite keeps its components",
+			glyphs:        map[int]bool{3: true},
+			simple:        true,
+			expectedKept:  []int{0, 1, 3},
+			expectedEmpty: []int{2},
+			expectedCmap:  true,
+		},
+		{
+			name:          "CID font drops cmap and post",
+			glyphs:        map[int]bool{2: true},
+			simple:        false,
+			expectedKept:  []int{0, 2},
+			expectedEmpty: []int{1, 3},
+			expectedCmap:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := font.subset(tt.glyphs, tt.simple)
+			if sum := trueTypeChecksum(data); sum != 0xB1B0AFBA {
+				t.Errorf("Expected font checksum 0xB1B0AFBA, got %#x", sum)
+			}
+
+			subset, err := parseTrueType(data)
+			if err != nil {
+				t.Fatalf("parseTrueType(subset): %v", err)
+			}
+			if subset.numGlyphs != font.numGlyphs {
+				t.Fatalf("Expected %d glyphs, got %d", font.numGlyphs, subset.numGlyphs)
+			}
+			for _, gid := range tt.expectedKept {
+				// Глифы выравниваются по 4 байта
+				if !bytes.HasPrefix(subset.glyph(gid), font.glyph(gid)) {
+					t.Errorf("Glyph %d was not kept", gid)
+				}
+			}
+			for _, gid := range tt.expectedEmpty {
+				if len(subset.glyph(gid)) != 0 {
+					t.Errorf("Glyph %d was expected to be empty", gid)
+				}
+			}
+			if _, found := subset.tables["GSUB"]; found {
+				t.Error("GSUB must be dropped")
+			}
+			if _, found := subset.tables["cmap"]; found != tt.expectedCmap {
+				t.Errorf("Expected cmap presence %v, got %v", tt.expectedCmap, found)
+			}
+		})
+	}
+}
+
+func TestTrueTypeSubsetGoRegular(t *testing.T) {
+	font, err := parseTrueType(goregular.TTF)
+	if err != nil {
+		t.Fatalf("parseTrueType: %v", err)
+	}
+	reference, err := sfnt.Parse(goregular.TTF)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Глифы текста. Составных глифов в Go Regular нет: их компоненты проверяет TestTrueTypeSubset
+	const text = "Åé%Жw"
+	var buf, refBuf sfnt.Buffer
+	used := map[int]bool{}
+	for _, r := range text {
+		gid, err := reference.GlyphIndex(&refBuf, r)
+		if err != nil || gid == 0 {
+			t.Fatalf("Glyph for %q not found: %v", r, err)
+		}
+		used[int(gid)] = true
+	}
+	kept := font.closeGlyphs(used)
+
+	const ppem = fixed.Int26_6(64 << 6)
+	for _, simple := range []bool{true, false} {
+		data := font.subset(used, simple)
+		if !simple {
+			// sfnt не читает шрифт без cmap и post: они возвращаются из исходного шрифта
+			parsed, err := parseTrueType(data)
+			if err != nil {
+				t.Fatalf("parseTrueType(subset): %v", err)
+			}
+			parsed.tables["cmap"], parsed.tables["post"] = font.tables["cmap"], font.tables["post"]
+			data = writeTrueType(parsed.tables)
+		}
+		subset, err := sfnt.Parse(data)
+		if err != nil {
+			t.Fatalf("Subset (simple %v) is not parsed by sfnt: %v", simple, err)
+		}
+		if subset.NumGlyphs() != reference.NumGlyphs() {
+			t.Fatalf("Expected %d glyphs, got %d", reference.NumGlyphs(), subset.NumGlyphs())
+		}
+
+		for gid := 0; gid < reference.NumGlyphs(); gid++ {
+			index := sfnt.GlyphIndex(gid)
+			segments, err := subset.LoadGlyph(&buf, index, ppem, nil)
+			if err != nil {
+				t.Fatalf("Glyph %d (simple %v): %v", gid, simple, err)
+			}
+			if !kept[gid] {
+				if len(segments) != 0 {
+					t.Errorf("Glyph %d (simple %v) was expected to be empty", gid, simple)
+				}
+				continue
+			}
+			expected, err := reference.LoadGlyph(&refBuf, index, ppem, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(segments, expected) {
+				t.Errorf("Glyph %d (simple %v) outline differs from the original", gid, simple)
+			}
+			advance, _ := subset.GlyphAdvance(&buf, index, ppem, 0)
+			expectedAdvance, _ := reference.GlyphAdvance(&refBuf, index, ppem, 0)
+			if advance != expectedAdvance {
+				t.Errorf("Glyph %d (simple %v) advance %v, expected %v", gid, simple, advance, expectedAdvance)
+			}
+		}
+
+		if simple {
+			for _, r := range text {
+				gid, _ := subset.GlyphIndex(&buf, r)
+				if !used[int(gid)] {
+					t.Errorf("cmap of the subset maps %q to glyph %d", r, gid)
+				}
+			}
+		}
+	}
+}
+
+func TestTrueTypeLookups(t *testing.T) {
+	font, err := parseTrueType(buildTestTrueType(t))
+	if err != nil {
+		t.Fatalf("parseTrueType: %v", err)
+	}
+
+	lookup := font.cmapLookup(3, 1)
+	if lookup == nil {
+		t.Fatal("cmap (3,1) not found")
+	}
+	for code, expected := range map[uint32]int{'A': 1, 'B': 2, 'C': 0, 0x40: 0} {
+		if gid := lookup(code); gid != expected {
+			t.Errorf("cmap[%#x]: expected glyph %d, got %d", code, expected, gid)
+		}
+	}
+	if font.cmapLookup(3, 0) != nil {
+		t.Error("cmap (3,0) must be absent")
+	}
+
+	if len(macGlyphNames) != 258 {
+		t.Fatalf("Expected 258 standard glyph names, got %d", len(macGlyphNames))
+	}
+	names := font.glyphNames()
+	for name, expected := range map[string]int{".notdef": 0, "A": 1, "B": 2, "A.comp": 3} {
+		if gid, found := names[name]; !found || gid != expected {
+			t.Errorf("post[%s]: expected glyph %d, got %d (found %v)", name, expected, gid, found)
+		}
+	}
+}
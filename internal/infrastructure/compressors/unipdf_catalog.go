@@ -0,0 +1,292 @@
+package compressors
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+
+	"compress/internal/domain/entities"
+)
+
+// finishUniPDFDocument переносит в сжатый документ то, что UniPDF теряет при пересборке
+// документа по страницам: словарь Info и XMP (если метаданные не удаляются), OutputIntents
+// для PDF/A и встроенные файлы (если вложения не удаляются). Затем применяет к результату
+// политики метаданных, аннотаций и вложений, оптимизацию шрифтов и уменьшение цветности
+// изображений так же, как PDFCPU.
+// Документ перечитывается и переписывается pdfcpu, только если есть что переносить или
+// удалять. Если pdfcpu не читает исходный документ или результат, политики применить нельзя
+// и возвращается ошибка entities.ErrPostProcessingFailed
+func finishUniPDFDocument(inputPath, outputPath string, config *entities.CompressionConfig, originalSize int64) (stats cleanupStats, err error) {
+	src, err := readPDFCPUContext(inputPath, newPDFCPUConfiguration(config))
+	if err != nil {
+		return stats, fmt.Errorf("%w: pdfcpu не читает исходный документ: %v", entities.ErrPostProcessingFailed, err)
+	}
+	srcRoot, err := src.Catalog()
+	if err != nil {
+		return stats, fmt.Errorf("%w: pdfcpu не читает каталог исходного документа: %v", entities.ErrPostProcessingFailed, err)
+	}
+	if !uniPDFFinishNeeded(src, srcRoot, config) {
+		return stats, nil
+	}
+
+	dst, err := readPDFCPUContext(outputPath, newPDFCPUConfiguration(config))
+	if err != nil {
+		return stats, fmt.Errorf("%w: pdfcpu не читает результат UniPDF: %v", entities.ErrPostProcessingFailed, err)
+	}
+	dstRoot, err := dst.Catalog()
+	if err != nil {
+		return stats, fmt.Errorf("%w: pdfcpu не читает каталог результата UniPDF: %v", entities.ErrPostProcessingFailed, err)
+	}
+
+	var catalogKeys []string
+	if !config.RemoveMetadata {
+		catalogKeys = append(catalogKeys, "Metadata")
+	}
+	if config.PDFAPart > 0 {
+		catalogKeys = append(catalogKeys, "OutputIntents")
+	}
+
+	copier := newObjectCopier(src, dst)
+	for _, key := range catalogKeys {
+		obj, found := srcRoot.Find(key)
+		if !found {
+			continue
+		}
+		if _, exists := dstRoot.Find(key); exists {
+			continue
+		}
+
+		copied, err := copier.copy(obj)
+		if err != nil {
+			return stats, fmt.Errorf("ошибка копирования %s: %w", key, err)
+		}
+		dstRoot.Update(key, copied)
+	}
+
+	// Встроенные файлы исходного документа: переносятся или учитываются как удаленные
+	if embedded := embeddedFilesTree(src, srcRoot); embedded != nil {
+		if config.RemoveAttachments {
+			stats.Attachments += countNameTreeEntries(src, embedded)
+		} else if err := restoreEmbeddedFiles(dst, dstRoot, copier, embedded); err != nil {
+			return stats, err
+		}
+	}
+
+	// Info движка заменяется исходным, чтобы политика применялась к ключам документа
+	dst.Info = nil
+	if src.Info != nil {
+		copied, err := copier.copy(*src.Info)
+		if err != nil {
+			return stats, fmt.Errorf("ошибка копирования Info: %w", err)
+		}
+		if ref, ok := copied.(types.IndirectRef); ok {
+			dst.Info = &ref
+		}
+	}
+
+	if err := applyMetadataPolicy(dst, config, originalSize); err != nil {
+		return stats, err
+	}
+
+	cleanup, err := applyCleanup(dst, config)
+	if err != nil {
+		return stats, err
+	}
+	stats.Annotations += cleanup.Annotations
+	stats.Attachments += cleanup.Attachments
+	stats.Thumbnails = cleanup.Thumbnails
+	stats.JavaScript = cleanup.JavaScript
+	stats.Destinations = cleanup.Destinations
+	stats.PieceInfo = cleanup.PieceInfo
+	stats.Fonts = cleanup.Fonts
+
+	// Разрешение и качество изображений UniPDF уже применил: перекодируются только
+	// изображения, цветность которых уменьшается
+	if config.ImageCompression && config.ConvertGrayscale {
+		images, err := recompressImages(dst, config, true)
+		if err != nil {
+			return stats, fmt.Errorf("ошибка уменьшения цветности изображений: %w", err)
+		}
+		stats.Images = *images
+		printColorReduction(images)
+	}
+
+	stats.Streams = applyStreamRecompression(dst, config)
+
+	// Объектные и xref-потоки записываются так же, как их записал UniPDF
+	useObjectStreams := config.UniPDFOptions().UseObjectStreams
+	dst.Configuration.WriteObjectStream = useObjectStreams
+	dst.Configuration.WriteXRefStream = useObjectStreams
+
+	tmpPath := outputPath + ".finish"
+	if err := api.WriteContextFile(dst, tmpPath); err != nil {
+		_ = os.Remove(tmpPath)
+		return stats, err
+	}
+	if err := os.Rename(tmpPath, outputPath); err != nil {
+		return stats, err
+	}
+	return stats, nil
+}
+
+// uniPDFFinishNeeded проверяет, есть ли что переносить из исходного документа в результат
+// UniPDF или удалять из него: включенные операции очистки и пересжатия, словарь Info
+// (переносится или фильтруется политикой), XMP, OutputIntents для PDF/A и вложения
+func uniPDFFinishNeeded(src *model.Context, srcRoot types.Dict, config *entities.CompressionConfig) bool {
+	if config.StampMetadata || config.OptimizeFonts || config.RecompressStreams ||
+		config.RemoveAttachments || config.RemoveAnnotations || config.RemoveThumbnails ||
+		config.RemoveJavaScript || config.RemoveUnusedDestinations || config.RemovePieceInfo ||
+		config.ImageCompression && config.ConvertGrayscale {
+		return true
+	}
+	if src.Info != nil {
+		return true
+	}
+	if _, found := srcRoot.Find("Metadata"); found && !config.RemoveMetadata {
+		return true
+	}
+	if _, found := srcRoot.Find("OutputIntents"); found && config.PDFAPart > 0 {
+		return true
+	}
+	return embeddedFilesTree(src, srcRoot) != nil
+}
+
+// embeddedFilesTree возвращает дерево имен встроенных файлов каталога или nil
+func embeddedFilesTree(ctx *model.Context, rootDict types.Dict) types.Object {
+	obj, found := rootDict.Find("Names")
+	if !found {
+		return nil
+	}
+	names, err := ctx.DereferenceDict(obj)
+	if err != nil || names == nil {
+		return nil
+	}
+	embedded, _ := names.Find("EmbeddedFiles")
+	return embedded
+}
+
+// restoreEmbeddedFiles копирует дерево встроенных файлов в словарь Names целевого каталога.
+// Остальные деревья имен (Dests, JavaScript) не переносятся: они ссылаются на страницы исходного документа
+func restoreEmbeddedFiles(dst *model.Context, dstRoot types.Dict, copier *objectCopier, embedded types.Object) error {
+	names := types.Dict{}
+	if obj, found := dstRoot.Find("Names"); found {
+		d, err := dst.DereferenceDict(obj)
+		if err != nil {
+			return err
+		}
+		if d != nil {
+			names = d
+		}
+	}
+	if _, exists := names.Find("EmbeddedFiles"); exists {
+		return nil
+	}
+
+	copied, err := copier.copy(embedded)
+	if err != nil {
+		return fmt.Errorf("ошибка копирования вложений: %w", err)
+	}
+	names.Update("EmbeddedFiles", copied)
+	dstRoot.Update("Names", names)
+
+	return nil
+}
+
+// objectCopier копирует объекты между документами pdfcpu, заводя в целевом документе
+// новые косвенные объекты. Каждый исходный объект копируется один раз
+type objectCopier struct {
+	src, dst *model.Context
+	refs     map[int]types.IndirectRef
+}
+
+// newObjectCopier создает копировщик объектов из src в dst
+func newObjectCopier(src, dst *model.Context) *objectCopier {
+	return &objectCopier{
+		src:  src,
+		dst:  dst,
+		refs: make(map[int]types.IndirectRef),
+	}
+}
+
+// copy возвращает копию объекта, пригодную для вставки в целевой документ
+func (c *objectCopier) copy(obj types.Object) (types.Object, error) {
+	switch o := obj.(type) {
+	case types.IndirectRef:
+		objNr := o.ObjectNumber.Value()
+		if ref, found := c.refs[objNr]; found {
+			return ref, nil
+		}
+
+		// Ссылку резервируем до копирования содержимого, чтобы корректно обработать циклы
+		ref, err := c.dst.IndRefForNewObject(nil)
+		if err != nil {
+			return nil, err
+		}
+		c.refs[objNr] = *ref
+
+		target, err := c.src.Dereference(o)
+		if err != nil {
+			return nil, err
+		}
+		copied, err := c.copy(target)
+		if err != nil {
+			return nil, err
+		}
+
+		entry, found := c.dst.FindTableEntryLight(ref.ObjectNumber.Value())
+		if !found {
+			return nil, fmt.Errorf("объект %d не найден", ref.ObjectNumber.Value())
+		}
+		entry.Object = copied
+		return *ref, nil
+
+	case types.Dict:
+		d, err := c.copyDict(o)
+		if err != nil {
+			return nil, err
+		}
+		return d, nil
+
+	case types.StreamDict:
+		d, err := c.copyDict(o.Dict)
+		if err != nil {
+			return nil, err
+		}
+		sd := o
+		sd.Dict = d
+		sd.StreamLengthObjNr = nil
+		return sd, nil
+
+	case types.Array:
+		a := make(types.Array, 0, len(o))
+		for _, item := range o {
+			copied, err := c.copy(item)
+			if err != nil {
+				return nil, err
+			}
+			a = append(a, copied)
+		}
+		return a, nil
+
+	case nil:
+		return nil, nil
+	}
+
+	return obj.Clone(), nil
+}
+
+// copyDict копирует словарь со всеми значениями
+func (c *objectCopier) copyDict(d types.Dict) (types.Dict, error) {
+	copied := types.Dict{}
+	for key, value := range d {
+		v, err := c.copy(value)
+		if err != nil {
+			return nil, err
+		}
+		copied[key] = v
+	}
+	return copied, nil
+}
@@ -0,0 +1,25 @@
+package compressors
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"compress/internal/domain/entities"
+)
+
+func TestFinishUniPDFDocumentUnreadableOutput(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.pdf")
+	output := filepath.Join(dir, "out.pdf")
+	writeTestPDF(t, input, 1)
+	if err := os.WriteFile(output, []byte("%PDF-1.7\nnot a document\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := finishUniPDFDocument(input, output, entities.NewCompressionConfig(50), 0)
+	if !errors.Is(err, entities.ErrPostProcessingFailed) {
+		t.Errorf("Expected ErrPostProcessingFailed, got %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package compressors
 import (
 	"fmt"
 	"os"
+	"sync"
 
 	"github.com/unidoc/unipdf/v3/common"
 	"github.com/unidoc/unipdf/v3/model"
@@ -11,6 +12,37 @@ import (
 	"compress/internal/domain/entities"
 )
 
+// uniPDFLicenseEnv переменная окружения с лицензионным ключом UniPDF
+const uniPDFLicenseEnv = "UNIDOC_LICENSE_API_KEY"
+
+// Логгер и лицензия UniPDF общие для процесса: настраиваются не в каждом воркере при сжатии
+// очередного файла, а заново только при смене ключа (например, в TUI). Неудачная настройка
+// не запоминается и повторяется при следующем сжатии
+var (
+	uniPDFSetupMu sync.Mutex
+	uniPDFLicense string // Ключ, с которым UniPDF успешно настроен; пусто — не настроен
+)
+
+// setupUniPDF настраивает логгер UniPDF и передает ему лицензионный ключ
+func setupUniPDF(licenseKey string) error {
+	uniPDFSetupMu.Lock()
+	defer uniPDFSetupMu.Unlock()
+
+	if uniPDFLicense == licenseKey {
+		return nil
+	}
+
+	common.SetLogger(common.NewConsoleLogger(common.LogLevelInfo))
+	if os.Getenv(uniPDFLicenseEnv) != licenseKey {
+		fmt.Printf("🔑 Устанавливаем лицензионный ключ UniPDF...\n")
+		if err := os.Setenv(uniPDFLicenseEnv, licenseKey); err != nil {
+			return fmt.Errorf("не удалось установить лицензионный ключ UniPDF: %w", err)
+		}
+	}
+	uniPDFLicense = licenseKey
+	return nil
+}
+
 // UniPDFCompressor реализация компрессора с использованием UniPDF
 type UniPDFCompressor struct{}
 
@@ -23,13 +55,10 @@ func NewUniPDFCompressor() *UniPDFCompressor {
 func (u *UniPDFCompressor) Compress(inputPath, outputPath string, config *entities.CompressionConfig) (*entities.CompressionResult, error) {
 	fmt.Printf("🔄 Сжатие PDF с уровнем %d%% (UniPDF)...\n", config.Level)
 
-	// Инициализируем логгер
-	common.SetLogger(common.NewConsoleLogger(common.LogLevelInfo))
-
 	// Проверяем лицензионный ключ из конфигурации или переменной окружения
 	licenseKey := config.UniPDFLicenseKey
 	if licenseKey == "" {
-		licenseKey = os.Getenv("UNIDOC_LICENSE_API_KEY")
+		licenseKey = os.Getenv(uniPDFLicenseEnv)
 	}
 
 	if licenseKey == "" {
@@ -40,9 +69,15 @@ func (u *UniPDFCompressor) Compress(inputPath, outputPath string, config *entiti
 		}, fmt.Errorf("UniPDF лицензия не настроена. Установите лицензионный ключ в конфигурации или используйте алгоритм 'pdfcpu'")
 	}
 
-	// Устанавливаем лицензионный ключ
-	fmt.Printf("🔑 Устанавливаем лицензионный ключ UniPDF...\n")
-	os.Setenv("UNIDOC_LICENSE_API_KEY", licenseKey) // Получаем исходный размер файла
+	if err := setupUniPDF(licenseKey); err != nil {
+		return &entities.CompressionResult{
+			OriginalSize: 0,
+			Success:      false,
+			Error:        err,
+		}, err
+	}
+
+	// Получаем исходный размер файла
 	originalInfo, err := os.Stat(inputPath)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка получения информации об исходном файле: %w", err)
@@ -62,15 +97,8 @@ func (u *UniPDFCompressor) Compress(inputPath, outputPath string, config *entiti
 	// Создаем writer с оптимизацией
 	pdfWriter := model.NewPdfWriter()
 
-	// Настраиваем оптимизацию в зависимости от уровня сжатия
-	optimizer := optimize.New(optimize.Options{
-		CombineDuplicateDirectObjects:   true,
-		CombineIdenticalIndirectObjects: true,
-		ImageUpperPPI:                   float64(150 - config.Level), // чем выше уровень, тем ниже PPI
-		ImageQuality:                    100 - config.Level,          // чем выше уровень, тем ниже качество
-	})
-
-	pdfWriter.SetOptimizer(optimizer)
+	// Настраиваем оптимизацию по уровню сжатия и параметрам из конфигурации
+	pdfWriter.SetOptimizer(optimize.New(uniPDFOptimizeOptions(config.UniPDFOptions())))
 
 	// Копируем страницы
 	numPages, err := pdfReader.GetNumPages()
@@ -122,6 +150,28 @@ func (u *UniPDFCompressor) Compress(inputPath, outputPath string, config *entiti
 		}, fmt.Errorf("ошибка записи файла: %w", err)
 	}
 
+	// UniPDF собирает документ по страницам и не переносит Info, XMP, OutputIntents и вложения
+	// каталога: переносим их и применяем те же политики, что и PDFCPU
+	if err := outputFile.Close(); err != nil {
+		return &entities.CompressionResult{
+			OriginalSize: originalInfo.Size(),
+			Success:      false,
+			Error:        err,
+		}, fmt.Errorf("ошибка записи файла: %w", err)
+	}
+	if config.RemoveMetadata {
+		fmt.Printf("🧹 Удаление метаданных (сохраняются: %v)\n", config.KeepMetadataKeys)
+	}
+	cleanup, err := finishUniPDFDocument(inputPath, outputPath, config, originalInfo.Size())
+	if err != nil {
+		_ = os.Remove(outputPath)
+		return &entities.CompressionResult{
+			OriginalSize: originalInfo.Size(),
+			Success:      false,
+			Error:        err,
+		}, fmt.Errorf("ошибка обработки каталога: %w", err)
+	}
+
 	// Получаем размер сжатого файла
 	compressedInfo, err := os.Stat(outputPath)
 	if err != nil {
@@ -137,9 +187,27 @@ func (u *UniPDFCompressor) Compress(inputPath, outputPath string, config *entiti
 		CompressedSize: compressedInfo.Size(),
 		Success:        true,
 	}
+	cleanup.apply(result)
 
 	result.CalculateCompressionRatio()
 
 	fmt.Printf("✅ Сжатие завершено: %s\n", outputPath)
 	return result, nil
 }
+
+// uniPDFOptimizeOptions переносит параметры оптимизатора из конфигурации в UniPDF
+func uniPDFOptimizeOptions(options entities.UniPDFOptions) optimize.Options {
+	return optimize.Options{
+		CombineDuplicateDirectObjects:   options.CombineDuplicateDirectObjects,
+		CombineIdenticalIndirectObjects: options.CombineIdenticalIndirectObjects,
+		CombineDuplicateStreams:         options.CombineDuplicateStreams,
+		CompressStreams:                 options.CompressStreams,
+		UseObjectStreams:                options.UseObjectStreams,
+		CleanUnusedResources:            options.CleanUnusedResources,
+		CleanContentstream:              options.CleanContentstream,
+		CleanFonts:                      options.CleanFonts,
+		SubsetFonts:                     options.SubsetFonts,
+		ImageUpperPPI:                   options.ImageUpperPPI,
+		ImageQuality:                    options.ImageQuality,
+	}
+}
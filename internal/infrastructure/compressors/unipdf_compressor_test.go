@@ -0,0 +1,20 @@
+package compressors
+
+import (
+	"os"
+	"testing"
+)
+
+func TestSetupUniPDFLicenseChange(t *testing.T) {
+	t.Setenv(uniPDFLicenseEnv, "")
+	t.Cleanup(func() { uniPDFLicense = "" })
+
+	for _, key := range []string{"first-key", "first-key", "second-key"} {
+		if err := setupUniPDF(key); err != nil {
+			t.Fatalf("setupUniPDF(%q): %v", key, err)
+		}
+		if got := os.Getenv(uniPDFLicenseEnv); got != key {
+			t.Errorf("Expected license %q after setup, got %q", key, got)
+		}
+	}
+}
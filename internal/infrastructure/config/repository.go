@@ -54,9 +54,11 @@ func (r *Repository) createDefaultConfig() *entities.Config {
 			ReplaceOriginal: false,
 		},
 		Compression: entities.AppCompressionConfig{
-			Level:     50,
-			Algorithm: "pdfcpu",
-			AutoStart: false,
+			Level:            50,
+			Algorithm:        "pdfcpu",
+			AutoStart:        false,
+			SignaturePolicy:  entities.SignaturePolicySkip,
+			OutputValidation: entities.OutputValidationRelaxed,
 		},
 		Processing: entities.ProcessingConfig{
 			ParallelWorkers: 2,
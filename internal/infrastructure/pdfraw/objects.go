@@ -0,0 +1,422 @@
+// Package pdfraw читает структуру PDF без загрузки документа целиком: таблицы перекрестных
+// ссылок, трейлеры и отдельные объекты по их смещениям. Используется там, где полный разбор
+// pdfcpu избыточен: сведения о файле перед сжатием и добавочное сохранение
+package pdfraw
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// Object значение PDF: nil, bool, int64, float64, Name, String, Array, Dict, Ref или *Stream
+type Object interface{}
+
+// Name имя PDF без начального символа /
+type Name string
+
+// String строка PDF (литеральная или шестнадцатеричная) в раскодированном виде
+type String []byte
+
+// Array массив PDF
+type Array []Object
+
+// Dict словарь PDF
+type Dict map[Name]Object
+
+// Ref ссылка на косвенный объект
+type Ref struct {
+	Num, Gen int
+}
+
+// Stream поток: словарь и положение данных в файле
+type Stream struct {
+	Dict   Dict
+	Offset int64 // Смещение первого байта данных
+	Length int64 // Длина данных по /Length
+}
+
+// errShort объект не помещается в прочитанный фрагмент файла
+var errShort = errors.New("объект PDF не помещается в прочитанный фрагмент")
+
+// ErrSyntax нарушение синтаксиса PDF
+var ErrSyntax = errors.New("ошибка синтаксиса PDF")
+
+// Int возвращает целое значение ключа; ссылки не разыменовываются
+func (d Dict) Int(key Name) (int64, bool) {
+	switch v := d[key].(type) {
+	case int64:
+		return v, true
+	case float64:
+		return int64(v), true
+	}
+	return 0, false
+}
+
+// Name возвращает имя под ключом или пустую строку
+func (d Dict) Name(key Name) Name {
+	name, _ := d[key].(Name)
+	return name
+}
+
+// parser разбирает объекты PDF во фрагменте файла buf, начинающемся со смещения base
+type parser struct {
+	buf  []byte
+	pos  int
+	base int64
+	eof  bool // Фрагмент доходит до конца данных
+}
+
+// isWhitespace пробельные символы PDF
+func isWhitespace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+// isDelimiter разделители PDF
+func isDelimiter(c byte) bool {
+	return bytes.IndexByte([]byte("()<>[]{}/%"), c) >= 0
+}
+
+// skipSpace пропускает пробелы и комментарии
+func (p *parser) skipSpace() {
+	for p.pos < len(p.buf) {
+		c := p.buf[p.pos]
+		if c == '%' {
+			for p.pos < len(p.buf) && p.buf[p.pos] != '\n' && p.buf[p.pos] != '\r' {
+				p.pos++
+			}
+			continue
+		}
+		if !isWhitespace(c) {
+			return
+		}
+		p.pos++
+	}
+}
+
+// peekToken возвращает обычное слово (число или ключевое слово) без сдвига позиции
+func (p *parser) peekToken() []byte {
+	end := p.pos
+	for end < len(p.buf) && !isWhitespace(p.buf[end]) && !isDelimiter(p.buf[end]) {
+		end++
+	}
+	return p.buf[p.pos:end]
+}
+
+// keyword читает ожидаемое ключевое слово
+func (p *parser) keyword(word string) error {
+	p.skipSpace()
+	token := p.peekToken()
+	if p.pos+len(token) >= len(p.buf) {
+		return errShort
+	}
+	if string(token) != word {
+		return fmt.Errorf("%w: ожидалось %q на смещении %d", ErrSyntax, word, p.base+int64(p.pos))
+	}
+	p.pos += len(token)
+	return nil
+}
+
+// object разбирает следующий объект. Ссылки вида "12 0 R" возвращаются как Ref
+func (p *parser) object() (Object, error) {
+	p.skipSpace()
+	if p.pos >= len(p.buf) {
+		return nil, errShort
+	}
+
+	switch c := p.buf[p.pos]; {
+	case c == '/':
+		return p.name()
+	case c == '(':
+		return p.literalString()
+	case c == '[':
+		return p.array()
+	case c == '<':
+		if p.pos+1 >= len(p.buf) {
+			return nil, errShort
+		}
+		if p.buf[p.pos+1] == '<' {
+			return p.dict()
+		}
+		return p.hexString()
+	case c == '+' || c == '-' || c == '.' || c >= '0' && c <= '9':
+		return p.number()
+	}
+
+	token := p.peekToken()
+	if p.pos+len(token) >= len(p.buf) {
+		return nil, errShort
+	}
+	p.pos += len(token)
+	switch string(token) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("%w: неожиданное слово %q на смещении %d", ErrSyntax, token, p.base+int64(p.pos-len(token)))
+}
+
+// name разбирает имя с экранированием #xx
+func (p *parser) name() (Object, error) {
+	p.pos++
+	var name []byte
+	for p.pos < len(p.buf) {
+		c := p.buf[p.pos]
+		if isWhitespace(c) || isDelimiter(c) {
+			return Name(name), nil
+		}
+		if c == '#' && p.pos+2 < len(p.buf) {
+			if v, err := strconv.ParseUint(string(p.buf[p.pos+1:p.pos+3]), 16, 8); err == nil {
+				name = append(name, byte(v))
+				p.pos += 3
+				continue
+			}
+		}
+		name = append(name, c)
+		p.pos++
+	}
+	return nil, errShort
+}
+
+// number разбирает число; целое, за которым следуют поколение и R, становится ссылкой
+func (p *parser) number() (Object, error) {
+	token := p.peekToken()
+	if p.pos+len(token) >= len(p.buf) {
+		return nil, errShort
+	}
+	p.pos += len(token)
+
+	if bytes.ContainsAny(token, ".") {
+		v, err := strconv.ParseFloat(string(token), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: число %q", ErrSyntax, token)
+		}
+		return v, nil
+	}
+	v, err := strconv.ParseInt(string(token), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: число %q", ErrSyntax, token)
+	}
+
+	// Ссылка "num gen R"
+	save := p.pos
+	p.skipSpace()
+	gen := p.peekToken()
+	if len(gen) > 0 && gen[0] >= '0' && gen[0] <= '9' && p.pos+len(gen) < len(p.buf) {
+		p.pos += len(gen)
+		p.skipSpace()
+		r := p.peekToken()
+		if p.pos+len(r) >= len(p.buf) && !p.eof {
+			return nil, errShort
+		}
+		if string(r) == "R" {
+			g, err := strconv.Atoi(string(gen))
+			if err == nil && v >= 0 {
+				p.pos++
+				return Ref{Num: int(v), Gen: g}, nil
+			}
+		}
+	} else if p.pos+len(gen) >= len(p.buf) && !p.eof {
+		return nil, errShort
+	}
+	p.pos = save
+	return v, nil
+}
+
+// literalString разбирает строку в круглых скобках
+func (p *parser) literalString() (Object, error) {
+	p.pos++
+	var s []byte
+	depth := 1
+	for p.pos < len(p.buf) {
+		c := p.buf[p.pos]
+		p.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				return String(s), nil
+			}
+		case '\\':
+			if p.pos >= len(p.buf) {
+				return nil, errShort
+			}
+			e := p.buf[p.pos]
+			p.pos++
+			switch e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				if p.pos < len(p.buf) && p.buf[p.pos] == '\n' {
+					p.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for i := 0; i < 2 && p.pos < len(p.buf) && p.buf[p.pos] >= '0' && p.buf[p.pos] <= '7'; i++ {
+						v = v*8 + int(p.buf[p.pos]-'0')
+						p.pos++
+					}
+					c = byte(v)
+				} else {
+					c = e
+				}
+			}
+		}
+		s = append(s, c)
+	}
+	return nil, errShort
+}
+
+// hexString разбирает строку в угловых скобках
+func (p *parser) hexString() (Object, error) {
+	p.pos++
+	var digits []byte
+	for p.pos < len(p.buf) {
+		c := p.buf[p.pos]
+		p.pos++
+		if c == '>' {
+			if len(digits)%2 == 1 {
+				digits = append(digits, '0')
+			}
+			s := make([]byte, len(digits)/2)
+			for i := range s {
+				v, err := strconv.ParseUint(string(digits[2*i:2*i+2]), 16, 8)
+				if err != nil {
+					return nil, fmt.Errorf("%w: шестнадцатеричная строка", ErrSyntax)
+				}
+				s[i] = byte(v)
+			}
+			return String(s), nil
+		}
+		if !isWhitespace(c) {
+			digits = append(digits, c)
+		}
+	}
+	return nil, errShort
+}
+
+// array разбирает массив
+func (p *parser) array() (Object, error) {
+	p.pos++
+	arr := Array{}
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.buf) {
+			return nil, errShort
+		}
+		if p.buf[p.pos] == ']' {
+			p.pos++
+			return arr, nil
+		}
+		obj, err := p.object()
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, obj)
+	}
+}
+
+// dict разбирает словарь
+func (p *parser) dict() (Object, error) {
+	p.pos += 2
+	d := Dict{}
+	for {
+		p.skipSpace()
+		if p.pos+1 >= len(p.buf) {
+			return nil, errShort
+		}
+		if p.buf[p.pos] == '>' && p.buf[p.pos+1] == '>' {
+			p.pos += 2
+			return d, nil
+		}
+		key, err := p.object()
+		if err != nil {
+			return nil, err
+		}
+		name, ok := key.(Name)
+		if !ok {
+			return nil, fmt.Errorf("%w: ключ словаря не является именем", ErrSyntax)
+		}
+		value, err := p.object()
+		if err != nil {
+			return nil, err
+		}
+		d[name] = value
+	}
+}
+
+// appendObject записывает объект в синтаксисе PDF. Ключи словарей упорядочиваются,
+// чтобы результат не зависел от порядка обхода
+func appendObject(buf []byte, obj Object) []byte {
+	switch v := obj.(type) {
+	case nil:
+		return append(buf, "null"...)
+	case bool:
+		return strconv.AppendBool(buf, v)
+	case int64:
+		return strconv.AppendInt(buf, v, 10)
+	case int:
+		return strconv.AppendInt(buf, int64(v), 10)
+	case float64:
+		return strconv.AppendFloat(buf, v, 'f', -1, 64)
+	case Name:
+		buf = append(buf, '/')
+		for _, c := range []byte(v) {
+			if c < '!' || c > '~' || c == '#' || isDelimiter(c) {
+				buf = append(buf, fmt.Sprintf("#%02X", c)...)
+				continue
+			}
+			buf = append(buf, c)
+		}
+		return buf
+	case String:
+		buf = append(buf, '<')
+		for _, c := range []byte(v) {
+			buf = append(buf, fmt.Sprintf("%02X", c)...)
+		}
+		return append(buf, '>')
+	case Ref:
+		return fmt.Appendf(buf, "%d %d R", v.Num, v.Gen)
+	case Array:
+		buf = append(buf, '[')
+		for i, item := range v {
+			if i > 0 {
+				buf = append(buf, ' ')
+			}
+			buf = appendObject(buf, item)
+		}
+		return append(buf, ']')
+	case Dict:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, string(key))
+		}
+		sort.Strings(keys)
+		buf = append(buf, "<<"...)
+		for _, key := range keys {
+			buf = appendObject(buf, Name(key))
+			buf = append(buf, ' ')
+			buf = appendObject(buf, v[Name(key)])
+		}
+		return append(buf, ">>"...)
+	}
+	return append(buf, "null"...)
+}
@@ -0,0 +1,737 @@
+package pdfraw
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+const (
+	// tailSize в пределах этого конца файла ищется последний startxref
+	tailSize = 1024
+	// initialWindow начальный размер фрагмента для разбора объекта
+	initialWindow = 4 << 10
+	// maxWindow предельный размер фрагмента: объекты и таблицы ссылок больше считаются повреждением
+	maxWindow = 64 << 20
+	// maxResolveDepth ограничение цепочки ссылок на ссылки
+	maxResolveDepth = 32
+)
+
+var (
+	// ErrNoXRef не найдена или не разобрана таблица перекрестных ссылок
+	ErrNoXRef = errors.New("таблица перекрестных ссылок PDF не найдена")
+	// ErrEncrypted строки и потоки документа зашифрованы
+	ErrEncrypted = errors.New("документ зашифрован")
+	// ErrUnsupportedFilter фильтр потока не поддерживается
+	ErrUnsupportedFilter = errors.New("фильтр потока не поддерживается")
+)
+
+// Entry запись таблицы перекрестных ссылок
+type Entry struct {
+	Type   int   // 0 — свободный, 1 — объект по смещению, 2 — объект в потоке объектов
+	Offset int64 // Смещение объекта (тип 1)
+	Gen    int   // Поколение (тип 1)
+	Stream int   // Номер потока объектов (тип 2)
+	Index  int   // Индекс в потоке объектов (тип 2)
+}
+
+// Section раздел перекрестных ссылок одной ревизии
+type Section struct {
+	Offset  int64         // Смещение таблицы xref или потока перекрестных ссылок
+	End     int64         // Конец ревизии: байт после %%EOF и перевода строки; 0 — маркер не найден
+	Stream  bool          // Ссылки записаны потоком (PDF 1.5+)
+	Hybrid  bool          // Таблица дополнена потоком ссылок /XRefStm
+	Trailer Dict          // Трейлер или словарь потока перекрестных ссылок
+	Entries map[int]Entry // Записи раздела, включая записи гибридного /XRefStm
+}
+
+// Reader читает объекты PDF через io.ReaderAt, загружая только нужные фрагменты файла
+type Reader struct {
+	r        io.ReaderAt
+	size     int64
+	sections []Section // От последней ревизии к первой
+	entries  map[int]Entry
+	objStms  map[int][]Object
+}
+
+// Open читает цепочку разделов перекрестных ссылок от последнего startxref по /Prev
+func Open(r io.ReaderAt, size int64) (*Reader, error) {
+	reader := &Reader{r: r, size: size, entries: map[int]Entry{}, objStms: map[int][]Object{}}
+
+	offset, err := reader.lastStartXRef()
+	if err != nil {
+		return nil, err
+	}
+
+	visited := map[int64]bool{}
+	for {
+		if visited[offset] {
+			break
+		}
+		visited[offset] = true
+
+		section, err := reader.readSection(offset)
+		if err != nil {
+			return nil, err
+		}
+		reader.sections = append(reader.sections, section)
+		for num, entry := range section.Entries {
+			if _, known := reader.entries[num]; !known {
+				reader.entries[num] = entry
+			}
+		}
+
+		prev, ok := section.Trailer.Int("Prev")
+		if !ok {
+			break
+		}
+		offset = prev
+	}
+
+	return reader, nil
+}
+
+// Size размер файла
+func (r *Reader) Size() int64 {
+	return r.size
+}
+
+// Sections разделы перекрестных ссылок от последней ревизии к первой
+func (r *Reader) Sections() []Section {
+	return r.sections
+}
+
+// Trailer трейлер последней ревизии
+func (r *Reader) Trailer() Dict {
+	return r.sections[0].Trailer
+}
+
+// Encrypted документ зашифрован
+func (r *Reader) Encrypted() bool {
+	_, ok := r.Trailer()["Encrypt"]
+	return ok
+}
+
+// Entries действующие записи всех ревизий: для каждого объекта берется последняя
+func (r *Reader) Entries() map[int]Entry {
+	return r.entries
+}
+
+// Catalog словарь каталога документа
+func (r *Reader) Catalog() (Dict, error) {
+	root, err := r.Resolve(r.Trailer()["Root"])
+	if err != nil {
+		return nil, err
+	}
+	catalog, ok := root.(Dict)
+	if !ok {
+		return nil, fmt.Errorf("%w: каталог не является словарем", ErrSyntax)
+	}
+	return catalog, nil
+}
+
+// Resolve разыменовывает ссылки; отсутствующий объект дает nil
+func (r *Reader) Resolve(obj Object) (Object, error) {
+	for depth := 0; depth < maxResolveDepth; depth++ {
+		ref, ok := obj.(Ref)
+		if !ok {
+			return obj, nil
+		}
+		var err error
+		if obj, err = r.Object(ref.Num); err != nil {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("%w: слишком длинная цепочка ссылок", ErrSyntax)
+}
+
+// Object читает косвенный объект по номеру в его последней ревизии
+func (r *Reader) Object(num int) (Object, error) {
+	entry, ok := r.entries[num]
+	switch {
+	case !ok || entry.Type == 0:
+		return nil, nil
+	case entry.Type == 2:
+		return r.compressedObject(entry)
+	}
+	return r.ObjectAt(entry.Offset)
+}
+
+// ObjectAt разбирает объект "num gen obj ... endobj" по смещению
+func (r *Reader) ObjectAt(offset int64) (Object, error) {
+	var obj Object
+	err := r.parseAt(offset, func(p *parser) error {
+		var err error
+		obj, err = p.indirectObject()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if stream, ok := obj.(*Stream); ok {
+		length, err := r.Resolve(stream.Dict["Length"])
+		if err != nil {
+			return nil, err
+		}
+		switch v := length.(type) {
+		case int64:
+			stream.Length = v
+		case float64:
+			stream.Length = int64(v)
+		default:
+			return nil, fmt.Errorf("%w: у потока на смещении %d нет длины", ErrSyntax, offset)
+		}
+		if stream.Length < 0 || stream.Offset+stream.Length > r.size {
+			return nil, fmt.Errorf("%w: поток на смещении %d выходит за конец файла", ErrSyntax, offset)
+		}
+	}
+	return obj, nil
+}
+
+// StreamData возвращает байты потока в том виде, в каком они записаны в файле
+func (r *Reader) StreamData(stream *Stream) ([]byte, error) {
+	data := make([]byte, stream.Length)
+	if _, err := r.r.ReadAt(data, stream.Offset); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return data, nil
+}
+
+// DecodeStream возвращает раскодированные данные потока. Поддерживается только FlateDecode
+// с предикторами PNG; потоки зашифрованных документов не раскодируются
+func (r *Reader) DecodeStream(stream *Stream) ([]byte, error) {
+	if r.Encrypted() {
+		return nil, ErrEncrypted
+	}
+	data, err := r.StreamData(stream)
+	if err != nil {
+		return nil, err
+	}
+
+	filter, err := r.Resolve(stream.Dict["Filter"])
+	if err != nil {
+		return nil, err
+	}
+	params, err := r.Resolve(stream.Dict["DecodeParms"])
+	if err != nil {
+		return nil, err
+	}
+	return decode(data, filter, params)
+}
+
+// decode применяет фильтры потока
+func decode(data []byte, filter, params Object) ([]byte, error) {
+	if arr, ok := filter.(Array); ok {
+		switch len(arr) {
+		case 0:
+			return data, nil
+		case 1:
+			filter = arr[0]
+			if p, ok := params.(Array); ok && len(p) > 0 {
+				params = p[0]
+			}
+		default:
+			return nil, ErrUnsupportedFilter
+		}
+	}
+
+	switch filter {
+	case nil:
+		return data, nil
+	case Name("FlateDecode"):
+	default:
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedFilter, filter)
+	}
+
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	decoded, err := io.ReadAll(zr)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, err
+	}
+
+	parms, _ := params.(Dict)
+	predictor, _ := parms.Int("Predictor")
+	if predictor < 10 {
+		if predictor > 1 {
+			return nil, fmt.Errorf("%w: предиктор TIFF", ErrUnsupportedFilter)
+		}
+		return decoded, nil
+	}
+	columns, ok := parms.Int("Columns")
+	if !ok {
+		columns = 1
+	}
+	colors, ok := parms.Int("Colors")
+	if !ok {
+		colors = 1
+	}
+	bpc, ok := parms.Int("BitsPerComponent")
+	if !ok {
+		bpc = 8
+	}
+	return unpredictPNG(decoded, int((colors*bpc+7)/8), int((columns*colors*bpc+7)/8))
+}
+
+// unpredictPNG снимает предикторы PNG: каждая строка начинается с байта типа фильтра
+func unpredictPNG(data []byte, bpp, rowSize int) ([]byte, error) {
+	if rowSize <= 0 || bpp <= 0 {
+		return nil, fmt.Errorf("%w: параметры предиктора", ErrSyntax)
+	}
+	out := make([]byte, 0, len(data)/(rowSize+1)*rowSize)
+	prev := make([]byte, rowSize)
+	for len(data) >= rowSize+1 {
+		kind, row := data[0], append([]byte(nil), data[1:rowSize+1]...)
+		data = data[rowSize+1:]
+		for i := range row {
+			var left, upLeft byte
+			if i >= bpp {
+				left, upLeft = row[i-bpp], prev[i-bpp]
+			}
+			up := prev[i]
+			switch kind {
+			case 0:
+			case 1:
+				row[i] += left
+			case 2:
+				row[i] += up
+			case 3:
+				row[i] += byte((int(left) + int(up)) / 2)
+			case 4:
+				row[i] += paeth(left, up, upLeft)
+			default:
+				return nil, fmt.Errorf("%w: тип фильтра PNG %d", ErrSyntax, kind)
+			}
+		}
+		out = append(out, row...)
+		prev = row
+	}
+	return out, nil
+}
+
+// paeth предсказатель Paeth из PNG
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := abs(p-int(a)), abs(p-int(b)), abs(p-int(c))
+	switch {
+	case pa <= pb && pa <= pc:
+		return a
+	case pb <= pc:
+		return b
+	}
+	return c
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// lastStartXRef находит смещение последнего раздела ссылок по хвосту файла
+func (r *Reader) lastStartXRef() (int64, error) {
+	start := max(0, r.size-tailSize)
+	tail := make([]byte, r.size-start)
+	if _, err := r.r.ReadAt(tail, start); err != nil && !errors.Is(err, io.EOF) {
+		return 0, err
+	}
+
+	i := bytes.LastIndex(tail, []byte("startxref"))
+	if i < 0 {
+		return 0, ErrNoXRef
+	}
+	p := &parser{buf: append(tail[i+len("startxref"):], '\n'), eof: true}
+	p.skipSpace()
+	offset, err := strconv.ParseInt(string(p.peekToken()), 10, 64)
+	if err != nil || offset <= 0 || offset >= r.size {
+		return 0, ErrNoXRef
+	}
+	return offset, nil
+}
+
+// parseAt разбирает данные с заданного смещения, увеличивая фрагмент, пока разбор упирается в его конец
+func (r *Reader) parseAt(offset int64, parse func(p *parser) error) error {
+	if offset < 0 || offset >= r.size {
+		return fmt.Errorf("%w: смещение %d вне файла", ErrSyntax, offset)
+	}
+	for window := int64(initialWindow); ; window *= 4 {
+		n := min(window, r.size-offset)
+		buf := make([]byte, n, n+1)
+		if _, err := r.r.ReadAt(buf, offset); err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		if offset+n == r.size {
+			// Конец файла завершает последнее слово так же, как пробел
+			buf = append(buf, '\n')
+		}
+
+		err := parse(&parser{buf: buf, base: offset, eof: offset+n == r.size})
+		if !errors.Is(err, errShort) {
+			return err
+		}
+		if offset+n == r.size || window >= maxWindow {
+			return fmt.Errorf("%w: объект на смещении %d не завершен", ErrSyntax, offset)
+		}
+	}
+}
+
+// readSection разбирает таблицу xref с трейлером или поток перекрестных ссылок
+func (r *Reader) readSection(offset int64) (Section, error) {
+	section := Section{Offset: offset, Entries: map[int]Entry{}}
+
+	var stream *Stream
+	err := r.parseAt(offset, func(p *parser) error {
+		section.Entries = map[int]Entry{}
+		p.skipSpace()
+		if string(p.peekToken()) == "xref" {
+			return p.xrefTable(&section)
+		}
+
+		obj, err := p.indirectObject()
+		if err != nil {
+			return err
+		}
+		s, ok := obj.(*Stream)
+		if !ok || s.Dict.Name("Type") != "XRef" {
+			return ErrNoXRef
+		}
+		stream = s
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrSyntax) {
+			return section, fmt.Errorf("%w: %v", ErrNoXRef, err)
+		}
+		return section, err
+	}
+
+	if stream != nil {
+		section.Stream = true
+		section.Trailer = stream.Dict
+		if err := r.readXRefStream(stream, section.Entries); err != nil {
+			return section, err
+		}
+		section.End = r.revisionEnd(stream.Offset + stream.Length)
+		return section, nil
+	}
+	section.End = r.revisionEnd(section.End)
+
+	// Гибридный файл: объекты в потоках объектов перечислены в дополнительном потоке ссылок
+	if xrefStm, ok := section.Trailer.Int("XRefStm"); ok {
+		hidden, err := r.readSection(xrefStm)
+		if err == nil && hidden.Stream {
+			section.Hybrid = true
+			for num, entry := range hidden.Entries {
+				if known, ok := section.Entries[num]; !ok || known.Type == 0 {
+					section.Entries[num] = entry
+				}
+			}
+		}
+	}
+	return section, nil
+}
+
+// revisionEnd ищет маркер %%EOF после раздела ссылок и возвращает позицию за ним
+// и за переводом строки; 0, если маркер не найден поблизости
+func (r *Reader) revisionEnd(from int64) int64 {
+	if from <= 0 || from >= r.size {
+		return 0
+	}
+	buf := make([]byte, min(tailSize, r.size-from))
+	if _, err := r.r.ReadAt(buf, from); err != nil && !errors.Is(err, io.EOF) {
+		return 0
+	}
+	i := bytes.Index(buf, []byte("%%EOF"))
+	if i < 0 {
+		return 0
+	}
+	end := i + len("%%EOF")
+	if end < len(buf) && buf[end] == '\r' {
+		end++
+	}
+	if end < len(buf) && buf[end] == '\n' {
+		end++
+	}
+	return from + int64(end)
+}
+
+// xrefTable разбирает классическую таблицу ссылок и трейлер
+func (p *parser) xrefTable(section *Section) error {
+	if err := p.keyword("xref"); err != nil {
+		return err
+	}
+	for {
+		p.skipSpace()
+		token := p.peekToken()
+		if p.pos+len(token) >= len(p.buf) {
+			return errShort
+		}
+		if string(token) == "trailer" {
+			p.pos += len(token)
+			trailer, err := p.object()
+			if err != nil {
+				return err
+			}
+			dict, ok := trailer.(Dict)
+			if !ok {
+				return fmt.Errorf("%w: трейлер не является словарем", ErrSyntax)
+			}
+			section.Trailer = dict
+			section.End = p.base + int64(p.pos)
+			return nil
+		}
+
+		first, err := p.integer()
+		if err != nil {
+			return err
+		}
+		count, err := p.integer()
+		if err != nil {
+			return err
+		}
+		for i := int64(0); i < count; i++ {
+			offset, err := p.integer()
+			if err != nil {
+				return err
+			}
+			gen, err := p.integer()
+			if err != nil {
+				return err
+			}
+			p.skipSpace()
+			kind := p.peekToken()
+			if p.pos+len(kind) >= len(p.buf) {
+				return errShort
+			}
+			p.pos += len(kind)
+
+			entry := Entry{Offset: offset, Gen: int(gen)}
+			switch string(kind) {
+			case "n":
+				entry.Type = 1
+			case "f":
+			default:
+				return fmt.Errorf("%w: запись таблицы ссылок %q", ErrSyntax, kind)
+			}
+			section.Entries[int(first+i)] = entry
+		}
+	}
+}
+
+// integer читает целое без распознавания ссылок
+func (p *parser) integer() (int64, error) {
+	p.skipSpace()
+	token := p.peekToken()
+	if p.pos+len(token) >= len(p.buf) {
+		return 0, errShort
+	}
+	v, err := strconv.ParseInt(string(token), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: ожидалось целое, получено %q", ErrSyntax, token)
+	}
+	p.pos += len(token)
+	return v, nil
+}
+
+// indirectObject разбирает "num gen obj <объект> endobj"; у потока определяется начало данных
+func (p *parser) indirectObject() (Object, error) {
+	if _, err := p.integer(); err != nil {
+		return nil, err
+	}
+	if _, err := p.integer(); err != nil {
+		return nil, err
+	}
+	if err := p.keyword("obj"); err != nil {
+		return nil, err
+	}
+	obj, err := p.object()
+	if err != nil {
+		return nil, err
+	}
+
+	dict, ok := obj.(Dict)
+	if !ok {
+		return obj, nil
+	}
+	p.skipSpace()
+	if token := p.peekToken(); string(token) != "stream" {
+		if p.pos+len(token) >= len(p.buf) && !p.eof {
+			return nil, errShort
+		}
+		return dict, nil
+	}
+	p.pos += len("stream")
+	// За ключевым словом stream следует CRLF или LF
+	if p.pos < len(p.buf) && p.buf[p.pos] == '\r' {
+		p.pos++
+	}
+	if p.pos < len(p.buf) && p.buf[p.pos] == '\n' {
+		p.pos++
+	}
+	if p.pos >= len(p.buf) {
+		return nil, errShort
+	}
+	stream := &Stream{Dict: dict, Offset: p.base + int64(p.pos)}
+	if length, ok := dict.Int("Length"); ok {
+		stream.Length = length
+	}
+	return stream, nil
+}
+
+// readXRefStream раскодирует записи потока перекрестных ссылок
+func (r *Reader) readXRefStream(stream *Stream, entries map[int]Entry) error {
+	raw, err := r.StreamData(stream)
+	if err != nil {
+		return err
+	}
+	// Поток перекрестных ссылок не шифруется, поэтому раскодируется и в зашифрованных файлах
+	data, err := decode(raw, stream.Dict["Filter"], stream.Dict["DecodeParms"])
+	if err != nil {
+		return err
+	}
+
+	widths, ok := stream.Dict["W"].(Array)
+	if !ok || len(widths) != 3 {
+		return fmt.Errorf("%w: массив /W потока ссылок", ErrSyntax)
+	}
+	var w [3]int
+	rowSize := 0
+	for i, v := range widths {
+		width, ok := v.(int64)
+		if !ok || width < 0 || width > 8 {
+			return fmt.Errorf("%w: массив /W потока ссылок", ErrSyntax)
+		}
+		w[i] = int(width)
+		rowSize += w[i]
+	}
+	if rowSize == 0 {
+		return fmt.Errorf("%w: массив /W потока ссылок", ErrSyntax)
+	}
+
+	size, _ := stream.Dict.Int("Size")
+	index := Array{int64(0), size}
+	if arr, ok := stream.Dict["Index"].(Array); ok {
+		index = arr
+	}
+
+	for i := 0; i+1 < len(index); i += 2 {
+		first, ok1 := index[i].(int64)
+		count, ok2 := index[i+1].(int64)
+		if !ok1 || !ok2 {
+			return fmt.Errorf("%w: массив /Index потока ссылок", ErrSyntax)
+		}
+		for n := int64(0); n < count; n++ {
+			if len(data) < rowSize {
+				return fmt.Errorf("%w: поток ссылок короче /Index", ErrSyntax)
+			}
+			row := data[:rowSize]
+			data = data[rowSize:]
+
+			kind := int64(1)
+			if w[0] > 0 {
+				kind = field(row[:w[0]])
+			}
+			f2 := field(row[w[0] : w[0]+w[1]])
+			f3 := field(row[w[0]+w[1]:])
+
+			var entry Entry
+			switch kind {
+			case 0:
+			case 1:
+				entry = Entry{Type: 1, Offset: f2, Gen: int(f3)}
+			case 2:
+				entry = Entry{Type: 2, Stream: int(f2), Index: int(f3)}
+			default:
+				// Неизвестные типы записей по стандарту считаются ссылками на null
+				continue
+			}
+			entries[int(first+n)] = entry
+		}
+	}
+	return nil
+}
+
+// field значение поля записи потока ссылок (big-endian)
+func field(b []byte) int64 {
+	var v int64
+	for _, c := range b {
+		v = v<<8 | int64(c)
+	}
+	return v
+}
+
+// compressedObject читает объект из потока объектов
+func (r *Reader) compressedObject(entry Entry) (Object, error) {
+	objects, ok := r.objStms[entry.Stream]
+	if !ok {
+		var err error
+		if objects, err = r.readObjectStream(entry.Stream); err != nil {
+			return nil, err
+		}
+		r.objStms[entry.Stream] = objects
+	}
+	if entry.Index < 0 || entry.Index >= len(objects) {
+		return nil, fmt.Errorf("%w: индекс %d вне потока объектов %d", ErrSyntax, entry.Index, entry.Stream)
+	}
+	return objects[entry.Index], nil
+}
+
+// readObjectStream разбирает все объекты потока объектов
+func (r *Reader) readObjectStream(num int) ([]Object, error) {
+	entry, ok := r.entries[num]
+	if !ok || entry.Type != 1 {
+		return nil, fmt.Errorf("%w: поток объектов %d не найден", ErrSyntax, num)
+	}
+	obj, err := r.ObjectAt(entry.Offset)
+	if err != nil {
+		return nil, err
+	}
+	stream, ok := obj.(*Stream)
+	if !ok {
+		return nil, fmt.Errorf("%w: объект %d не является потоком объектов", ErrSyntax, num)
+	}
+	data, err := r.DecodeStream(stream)
+	if err != nil {
+		return nil, err
+	}
+
+	count, _ := stream.Dict.Int("N")
+	first, _ := stream.Dict.Int("First")
+	if count < 0 || first < 0 || first > int64(len(data)) {
+		return nil, fmt.Errorf("%w: заголовок потока объектов %d", ErrSyntax, num)
+	}
+
+	header := &parser{buf: append(data[:first:first], '\n'), eof: true}
+	offsets := make([]int64, count)
+	for i := range offsets {
+		if _, err := header.integer(); err != nil {
+			return nil, fmt.Errorf("%w: заголовок потока объектов %d", ErrSyntax, num)
+		}
+		if offsets[i], err = header.integer(); err != nil {
+			return nil, fmt.Errorf("%w: заголовок потока объектов %d", ErrSyntax, num)
+		}
+	}
+
+	body := append(data[first:len(data):len(data)], '\n')
+	objects := make([]Object, count)
+	for i, offset := range offsets {
+		if offset < 0 || offset >= int64(len(body)) {
+			return nil, fmt.Errorf("%w: смещение в потоке объектов %d", ErrSyntax, num)
+		}
+		p := &parser{buf: body, pos: int(offset), eof: true}
+		if objects[i], err = p.object(); err != nil {
+			if errors.Is(err, errShort) {
+				return nil, fmt.Errorf("%w: объект %d потока объектов %d не завершен", ErrSyntax, i, num)
+			}
+			return nil, err
+		}
+	}
+	return objects, nil
+}
@@ -0,0 +1,256 @@
+package pdfraw
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+// appendRevision дописывает объекты и классическую таблицу ссылок с трейлером
+func appendRevision(pdf []byte, objects map[int]string, trailer string) []byte {
+	offsets := map[int]int{}
+	maxNum := 0
+	for num := 1; len(offsets) < len(objects); num++ {
+		body, ok := objects[num]
+		if !ok {
+			continue
+		}
+		offsets[num] = len(pdf)
+		pdf = fmt.Appendf(pdf, "%d 0 obj\n%s\nendobj\n", num, body)
+		maxNum = num
+	}
+
+	xref := len(pdf)
+	pdf = append(pdf, "xref\n"...)
+	for num := 1; num <= maxNum; num++ {
+		if offset, ok := offsets[num]; ok {
+			pdf = fmt.Appendf(pdf, "%d 1\n%010d 00000 n \n", num, offset)
+		}
+	}
+	return fmt.Appendf(pdf, "trailer\n%s\nstartxref\n%d\n%%%%EOF\n", trailer, xref)
+}
+
+func TestOpenIncrementalUpdates(t *testing.T) {
+	pdf := appendRevision([]byte("%PDF-1.4\n"), map[int]string{
+		1: "<< /Type /Catalog /Pages 2 0 R >>",
+		2: "<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		3: "<< /Type /Page /Parent 2 0 R /Title (a\\(b\\)\\101) >>",
+	}, "<< /Size 4 /Root 1 0 R >>")
+	first := len(pdf)
+	pdf = appendRevision(pdf, map[int]string{
+		2: "<< /Type /Pages /Kids [3 0 R 4 0 R] /Count 2 >>",
+		4: "<< /Type /Page /Parent 2 0 R /Name /A#20B >>",
+	}, fmt.Sprintf("<< /Size 5 /Root 1 0 R /Prev %d >>", bytes.LastIndex(pdf[:first], []byte("\nxref"))+1))
+
+	reader, err := Open(bytes.NewReader(pdf), int64(len(pdf)))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if len(reader.Sections()) != 2 {
+		t.Fatalf("Expected 2 sections, got %d", len(reader.Sections()))
+	}
+
+	catalog, err := reader.Catalog()
+	if err != nil {
+		t.Fatalf("Catalog: %v", err)
+	}
+	pages, err := reader.Resolve(catalog["Pages"])
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if count, _ := pages.(Dict).Int("Count"); count != 2 {
+		t.Errorf("Expected the latest revision with 2 pages, got %d", count)
+	}
+
+	page, _ := reader.Object(3)
+	if title := string(page.(Dict)["Title"].(String)); title != "a(b)A" {
+		t.Errorf("Expected literal string escapes decoded, got %q", title)
+	}
+	page, _ = reader.Object(4)
+	if name := page.(Dict).Name("Name"); name != "A B" {
+		t.Errorf("Expected name escapes decoded, got %q", name)
+	}
+}
+
+func TestOpenXRefStream(t *testing.T) {
+	compress := func(data []byte) []byte {
+		var buf bytes.Buffer
+		zw := zlib.NewWriter(&buf)
+		zw.Write(data)
+		zw.Close()
+		return buf.Bytes()
+	}
+
+	pdf := []byte("%PDF-1.5\n")
+	offsets := []int{}
+	addObject := func(header string, data []byte) {
+		offsets = append(offsets, len(pdf))
+		pdf = append(pdf, header...)
+		if data != nil {
+			pdf = append(pdf, "stream\r\n"...)
+			pdf = append(pdf, data...)
+			pdf = append(pdf, "\nendstream"...)
+		}
+		pdf = append(pdf, "\nendobj\n"...)
+	}
+
+	// Каталог и дерево страниц лежат в потоке объектов 3
+	objects := "<< /Type /Catalog /Pages 2 0 R >> << /Type /Pages /Kids [] /Count 7 >>"
+	header := "1 0 2 34 "
+	objStm := compress([]byte(header + objects))
+	addObject(fmt.Sprintf("3 0 obj\n<< /Type /ObjStm /N 2 /First %d /Filter /FlateDecode /Length 4 0 R >>\n", len(header)), objStm)
+	addObject(fmt.Sprintf("4 0 obj\n%d", len(objStm)), nil)
+
+	// Записи потока ссылок с предиктором PNG Up: тип, смещение (2 байта), индекс
+	rows := [][]byte{
+		{0, 0, 0, 0},
+		{2, 0, 3, 0},
+		{2, 0, 3, 1},
+		{1, byte(offsets[0] >> 8), byte(offsets[0]), 0},
+		{1, byte(offsets[1] >> 8), byte(offsets[1]), 0},
+		{1, byte(len(pdf) >> 8), byte(len(pdf)), 0},
+	}
+	var predicted []byte
+	prev := make([]byte, 4)
+	for _, row := range rows {
+		predicted = append(predicted, 2)
+		for i := range row {
+			predicted = append(predicted, row[i]-prev[i])
+		}
+		prev = row
+	}
+	xref := len(pdf)
+	data := compress(predicted)
+	addObject(fmt.Sprintf("5 0 obj\n<< /Type /XRef /Size 6 /W [1 2 1] /Root 1 0 R /Filter /FlateDecode /DecodeParms << /Predictor 12 /Columns 4 >> /Length %d >>\n", len(data)), data)
+	pdf = fmt.Appendf(pdf, "startxref\n%d\n%%%%EOF", xref)
+
+	reader, err := Open(bytes.NewReader(pdf), int64(len(pdf)))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if !reader.Sections()[0].Stream {
+		t.Error("Expected a cross-reference stream section")
+	}
+
+	catalog, err := reader.Catalog()
+	if err != nil {
+		t.Fatalf("Catalog: %v", err)
+	}
+	pages, err := reader.Resolve(catalog["Pages"])
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if count, _ := pages.(Dict).Int("Count"); count != 7 {
+		t.Errorf("Expected page count 7 from the object stream, got %d", count)
+	}
+
+	// Переписанная ревизия сохраняет поток объектов и записи на него
+	var out bytes.Buffer
+	if _, err := reader.RewriteRevisions(&out, 0); err != nil {
+		t.Fatalf("RewriteRevisions: %v", err)
+	}
+	rewritten, err := Open(bytes.NewReader(out.Bytes()), int64(out.Len()))
+	if err != nil {
+		t.Fatalf("Open rewritten: %v", err)
+	}
+	if catalog, err = rewritten.Catalog(); err != nil {
+		t.Fatalf("Catalog of the rewritten file: %v", err)
+	}
+	if pages, err = rewritten.Resolve(catalog["Pages"]); err != nil || pages.(Dict)["Count"] != int64(7) {
+		t.Errorf("Expected page count 7 after rewriting, got %v (%v)", pages, err)
+	}
+}
+
+func TestOpenWithoutXRef(t *testing.T) {
+	for _, data := range []string{"hello", "%PDF-1.4\nstartxref\n5\n%%EOF", "%PDF-1.4\nstartxref\n999\n%%EOF"} {
+		if _, err := Open(bytes.NewReader([]byte(data)), int64(len(data))); err == nil {
+			t.Errorf("Expected an error for %q", data)
+		}
+	}
+}
+
+func TestRewriteRevisions(t *testing.T) {
+	content := bytes.Repeat([]byte("0 0 m 100 100 l S\n"), 200)
+	pdf := appendRevision([]byte("%PDF-1.4\n%\xE2\xE3\xCF\xD3\n"), map[int]string{
+		1: "<< /Type /Catalog /Pages 2 0 R >>",
+		2: "<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		3: "<< /Type /Page /Parent 2 0 R /MediaBox [0 0 200 200] /Contents 4 0 R >>",
+		4: fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+	}, "<< /Size 5 /Root 1 0 R /ID [<01> <02>] >>")
+	signed := len(pdf)
+	pdf = appendRevision(pdf, map[int]string{
+		3: "<< /Type /Page /Parent 2 0 R /MediaBox [0 0 200 200] /Contents 5 0 R >>",
+		5: fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+	}, fmt.Sprintf("<< /Size 6 /Root 1 0 R /ID [<01> <03>] /Prev %d >>", bytes.LastIndex(pdf, []byte("\nxref"))+1))
+
+	reader, err := Open(bytes.NewReader(pdf), int64(len(pdf)))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		keep         int64
+		recompressed int
+	}{
+		{"Signed first revision kept", int64(signed), 1},
+		{"All revisions rewritten", 0, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			stats, err := reader.RewriteRevisions(&out, tt.keep)
+			if err != nil {
+				t.Fatalf("RewriteRevisions: %v", err)
+			}
+			if stats.RecompressedStreams != tt.recompressed {
+				t.Errorf("Expected %d recompressed streams, got %d", tt.recompressed, stats.RecompressedStreams)
+			}
+			if !bytes.Equal(out.Bytes()[:tt.keep], pdf[:tt.keep]) {
+				t.Error("Kept revision is not byte-identical")
+			}
+			if out.Len() >= len(pdf) {
+				t.Errorf("Expected a smaller file, got %d bytes from %d", out.Len(), len(pdf))
+			}
+
+			rewritten, err := Open(bytes.NewReader(out.Bytes()), int64(out.Len()))
+			if err != nil {
+				t.Fatalf("Open rewritten: %v", err)
+			}
+			if len(rewritten.Sections()) != 2 {
+				t.Errorf("Expected both revisions to remain, got %d", len(rewritten.Sections()))
+			}
+			page, err := rewritten.Object(3)
+			if err != nil {
+				t.Fatal(err)
+			}
+			contents, err := rewritten.Resolve(page.(Dict)["Contents"])
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := rewritten.DecodeStream(contents.(*Stream))
+			if err != nil || !bytes.Equal(data, content) {
+				t.Errorf("Content stream of the latest revision changed: %v", err)
+			}
+
+			ctx, err := api.ReadContext(bytes.NewReader(out.Bytes()), model.NewDefaultConfiguration())
+			if err != nil {
+				t.Fatalf("pdfcpu cannot read the result: %v", err)
+			}
+			if err := api.ValidateContext(ctx); err != nil {
+				t.Errorf("pdfcpu validation failed: %v", err)
+			}
+		})
+	}
+
+	var out bytes.Buffer
+	if _, err := reader.RewriteRevisions(&out, int64(signed-1)); !errors.Is(err, ErrRevisionBoundary) {
+		t.Errorf("Expected ErrRevisionBoundary for a cut inside a revision, got %v", err)
+	}
+}
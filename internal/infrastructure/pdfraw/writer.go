@@ -0,0 +1,455 @@
+package pdfraw
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// ErrRevisionBoundary граница сохраняемой части не совпадает с концом ревизии
+var ErrRevisionBoundary = errors.New("граница не совпадает с концом ревизии документа")
+
+// headerLimit заголовок файла (версия и двоичный комментарий) ищется в этом начале файла
+const headerLimit = 1024
+
+// Revision ревизия документа: исходная версия или добавочное обновление
+type Revision struct {
+	Start    int64     // Первый байт ревизии
+	End      int64     // Байт после %%EOF ревизии
+	Sections []Section // Разделы ссылок ревизии, от последнего к первому
+}
+
+// RewriteStats итоги перезаписи ревизий
+type RewriteStats struct {
+	Rewritten           int // Переписанные ревизии
+	RecompressedStreams int // Потоки, сжатые или пересжатые Deflate с максимальной степенью
+}
+
+// Revisions возвращает ревизии документа от первой к последней. Разделы первой страницы
+// линеаризованного файла лежат внутри своей ревизии и к ней же относятся
+func (r *Reader) Revisions() ([]Revision, error) {
+	var revisions []Revision
+	for i := len(r.sections) - 1; i >= 0; i-- {
+		section := r.sections[i]
+		if section.End == 0 {
+			return nil, fmt.Errorf("%w: у раздела ссылок на смещении %d нет маркера %%%%EOF", ErrSyntax, section.Offset)
+		}
+
+		n := len(revisions)
+		if n > 0 && section.End <= revisions[n-1].End {
+			revisions[n-1].Sections = append([]Section{section}, revisions[n-1].Sections...)
+			continue
+		}
+
+		start := int64(0)
+		if n > 0 {
+			start = revisions[n-1].End
+		}
+		if section.Offset < start {
+			return nil, fmt.Errorf("%w: разделы ссылок не упорядочены по ревизиям", ErrSyntax)
+		}
+		revisions = append(revisions, Revision{Start: start, End: section.End, Sections: []Section{section}})
+	}
+	return revisions, nil
+}
+
+// RewriteRevisions записывает документ, в котором первые keep байт скопированы без изменений,
+// а каждая следующая ревизия переписана отдельно: объекты ревизии заново сериализуются,
+// потоки (кроме зашифрованных и XMP) сжимаются Deflate, если так они становятся меньше, и ревизия
+// получает свою таблицу ссылок с /Prev на предыдущую. Номера и поколения объектов сохраняются,
+// поэтому набор ревизий и их содержимое остаются прежними. keep должен быть 0 или концом ревизии
+func (r *Reader) RewriteRevisions(w io.Writer, keep int64) (RewriteStats, error) {
+	var stats RewriteStats
+
+	revisions, err := r.Revisions()
+	if err != nil {
+		return stats, err
+	}
+
+	first := 0
+	for first < len(revisions) && revisions[first].End <= keep {
+		first++
+	}
+	if keep > 0 && (first == 0 || revisions[first-1].End != keep) {
+		return stats, fmt.Errorf("%w: %d", ErrRevisionBoundary, keep)
+	}
+
+	buffered := bufio.NewWriter(w)
+	out := &countingWriter{w: buffered}
+	prefix := keep
+	if keep == 0 {
+		prefix = r.headerSize(revisions[0])
+	}
+	if _, err := io.Copy(out, io.NewSectionReader(r.r, 0, prefix)); err != nil {
+		return stats, err
+	}
+
+	prevXRef := int64(-1)
+	if first > 0 {
+		prevXRef = revisions[first-1].Sections[0].Offset
+	}
+	nextNum := r.maxObjectNumber() + 1
+
+	for i := first; i < len(revisions); i++ {
+		rw := revisionWriter{reader: r, out: out, revision: revisions[i], linearized: i == 0}
+		if prevXRef, err = rw.write(prevXRef, &nextNum); err != nil {
+			return stats, err
+		}
+		stats.Rewritten++
+		stats.RecompressedStreams += rw.recompressed
+	}
+
+	return stats, buffered.Flush()
+}
+
+// headerSize длина заголовка файла: байты до первого объекта первой ревизии
+func (r *Reader) headerSize(revision Revision) int64 {
+	header := int64(headerLimit)
+	for _, section := range revision.Sections {
+		for _, entry := range section.Entries {
+			if entry.Type == 1 && entry.Offset < header {
+				header = entry.Offset
+			}
+		}
+	}
+	return min(header, r.size)
+}
+
+// maxObjectNumber наибольший номер объекта во всех ревизиях
+func (r *Reader) maxObjectNumber() int {
+	maxNum := 0
+	for _, section := range r.sections {
+		if size, ok := section.Trailer.Int("Size"); ok && int(size)-1 > maxNum {
+			maxNum = int(size) - 1
+		}
+		for num := range section.Entries {
+			maxNum = max(maxNum, num)
+		}
+	}
+	return maxNum
+}
+
+// countingWriter считает записанные байты: по ним вычисляются смещения объектов
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// revisionWriter переписывает одну ревизию
+type revisionWriter struct {
+	reader       *Reader
+	out          *countingWriter
+	revision     Revision
+	linearized   bool // Первая ревизия: словарь линеаризации отбрасывается, он описывает прежний файл
+	recompressed int
+}
+
+// write записывает объекты ревизии, ее раздел ссылок и трейлер. Возвращает смещение раздела ссылок
+func (rw *revisionWriter) write(prevXRef int64, nextNum *int) (int64, error) {
+	entries := map[int]Entry{}
+	for i := len(rw.revision.Sections) - 1; i >= 0; i-- {
+		for num, entry := range rw.revision.Sections[i].Entries {
+			entries[num] = entry
+		}
+	}
+
+	// Потоки ссылок ревизии не копируются: вместо них пишется новый, под первым из их номеров
+	xrefNum, useStream := -1, false
+	for _, section := range rw.revision.Sections {
+		useStream = useStream || section.Stream || section.Hybrid
+		if !section.Stream {
+			continue
+		}
+		for num, entry := range entries {
+			if entry.Type == 1 && entry.Offset == section.Offset {
+				delete(entries, num)
+				if xrefNum < 0 || num < xrefNum {
+					xrefNum = num
+				}
+			}
+		}
+	}
+	for _, entry := range entries {
+		useStream = useStream || entry.Type == 2
+	}
+	if useStream && xrefNum < 0 {
+		xrefNum = *nextNum
+		*nextNum++
+	}
+
+	nums := make([]int, 0, len(entries))
+	for num := range entries {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+
+	written := map[int]Entry{}
+	for _, num := range nums {
+		entry := entries[num]
+		if entry.Type != 1 {
+			written[num] = entry
+			continue
+		}
+		offset, err := rw.writeObject(num, entry)
+		if err != nil {
+			return 0, err
+		}
+		if offset < 0 {
+			written[num] = Entry{Gen: entry.Gen}
+			continue
+		}
+		written[num] = Entry{Type: 1, Offset: offset, Gen: entry.Gen}
+	}
+
+	xref := rw.out.n
+	if useStream {
+		written[xrefNum] = Entry{Type: 1, Offset: xref}
+	}
+
+	trailer := Dict{}
+	for key, value := range rw.revision.Sections[0].Trailer {
+		switch key {
+		case "Prev", "XRefStm", "Type", "W", "Index", "Filter", "DecodeParms", "Length", "DL":
+		default:
+			trailer[key] = value
+		}
+	}
+	size, _ := trailer.Int("Size")
+	for num := range written {
+		size = max(size, int64(num)+1)
+	}
+	trailer["Size"] = size
+	if prevXRef >= 0 {
+		trailer["Prev"] = prevXRef
+	}
+
+	var err error
+	if useStream {
+		err = rw.writeXRefStream(xrefNum, written, trailer)
+	} else {
+		err = rw.writeXRefTable(written, trailer)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if _, err := fmt.Fprintf(rw.out, "startxref\n%d\n%%%%EOF\n", xref); err != nil {
+		return 0, err
+	}
+	return xref, nil
+}
+
+// writeObject копирует объект ревизии. Возвращает его новое смещение или -1, если объект отброшен
+func (rw *revisionWriter) writeObject(num int, entry Entry) (int64, error) {
+	obj, err := rw.reader.ObjectAt(entry.Offset)
+	if err != nil {
+		return 0, fmt.Errorf("объект %d: %w", num, err)
+	}
+
+	stream, isStream := obj.(*Stream)
+	if dict, ok := obj.(Dict); ok && rw.linearized && dict["Linearized"] != nil {
+		return -1, nil
+	}
+
+	offset := rw.out.n
+	buf := fmt.Appendf(nil, "%d %d obj\n", num, entry.Gen)
+	if !isStream {
+		buf = appendObject(buf, obj)
+		buf = append(buf, "\nendobj\n"...)
+		_, err := rw.out.Write(buf)
+		return offset, err
+	}
+
+	data, err := rw.reader.StreamData(stream)
+	if err != nil {
+		return 0, fmt.Errorf("объект %d: %w", num, err)
+	}
+	dict := Dict{}
+	for key, value := range stream.Dict {
+		dict[key] = value
+	}
+	if !rw.reader.Encrypted() {
+		if recompressed, ok := recompressStream(dict, data); ok {
+			data = recompressed
+			rw.recompressed++
+		}
+	}
+	dict["Length"] = int64(len(data))
+
+	buf = appendObject(buf, dict)
+	buf = append(buf, "\nstream\n"...)
+	buf = append(buf, data...)
+	buf = append(buf, "\nendstream\nendobj\n"...)
+	_, err = rw.out.Write(buf)
+	return offset, err
+}
+
+// recompressStream сжимает поток без фильтра или пересжимает поток Deflate с максимальной
+// степенью. Изменяет словарь и возвращает новые данные, если они меньше прежних.
+// Поток XMP не сжимается: PDF/A требует, чтобы метаданные читались без декодирования
+func recompressStream(dict Dict, data []byte) ([]byte, bool) {
+	if dict.Name("Type") == "Metadata" {
+		return nil, false
+	}
+
+	plain := data
+	switch filter := dict["Filter"]; {
+	case filter == nil:
+		if dict["DecodeParms"] != nil {
+			return nil, false
+		}
+	case filter == Name("FlateDecode") || isSingleFlate(filter):
+		zr, err := zlib.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, false
+		}
+		if plain, err = io.ReadAll(zr); err != nil {
+			return nil, false
+		}
+	default:
+		return nil, false
+	}
+
+	var buf bytes.Buffer
+	zw, _ := zlib.NewWriterLevel(&buf, zlib.BestCompression)
+	if _, err := zw.Write(plain); err != nil || zw.Close() != nil {
+		return nil, false
+	}
+	if buf.Len() >= len(data) {
+		return nil, false
+	}
+	if dict["Filter"] == nil {
+		dict["Filter"] = Name("FlateDecode")
+	}
+	return buf.Bytes(), true
+}
+
+// isSingleFlate фильтр задан массивом из одного FlateDecode
+func isSingleFlate(filter Object) bool {
+	arr, ok := filter.(Array)
+	return ok && len(arr) == 1 && arr[0] == Name("FlateDecode")
+}
+
+// writeXRefTable записывает классическую таблицу ссылок и трейлер
+func (rw *revisionWriter) writeXRefTable(entries map[int]Entry, trailer Dict) error {
+	buf := []byte("xref\n")
+	for _, run := range entryRuns(entries) {
+		buf = fmt.Appendf(buf, "%d %d\n", run[0], len(run))
+		for _, num := range run {
+			entry := entries[num]
+			kind := byte('f')
+			if entry.Type == 1 {
+				kind = 'n'
+			}
+			buf = fmt.Appendf(buf, "%010d %05d %c \n", entry.Offset*int64(entry.Type), entry.Gen, kind)
+		}
+	}
+	buf = append(buf, "trailer\n"...)
+	buf = appendObject(buf, trailer)
+	buf = append(buf, '\n')
+	_, err := rw.out.Write(buf)
+	return err
+}
+
+// writeXRefStream записывает поток перекрестных ссылок; словарь трейлера входит в словарь потока
+func (rw *revisionWriter) writeXRefStream(num int, entries map[int]Entry, trailer Dict) error {
+	maxField2, maxField3 := int64(0), int64(0)
+	for _, entry := range entries {
+		f2, f3 := entryFields(entry)
+		maxField2, maxField3 = max(maxField2, f2), max(maxField3, f3)
+	}
+	w2, w3 := byteWidth(maxField2), byteWidth(maxField3)
+
+	var rows []byte
+	index := Array{}
+	for _, run := range entryRuns(entries) {
+		index = append(index, int64(run[0]), int64(len(run)))
+		for _, n := range run {
+			entry := entries[n]
+			f2, f3 := entryFields(entry)
+			rows = append(rows, byte(entry.Type))
+			rows = appendField(rows, f2, w2)
+			rows = appendField(rows, f3, w3)
+		}
+	}
+
+	var data bytes.Buffer
+	zw, _ := zlib.NewWriterLevel(&data, zlib.BestCompression)
+	zw.Write(rows)
+	zw.Close()
+
+	dict := Dict{}
+	for key, value := range trailer {
+		dict[key] = value
+	}
+	dict["Type"] = Name("XRef")
+	dict["W"] = Array{int64(1), int64(w2), int64(w3)}
+	dict["Index"] = index
+	dict["Filter"] = Name("FlateDecode")
+	dict["Length"] = int64(data.Len())
+
+	buf := fmt.Appendf(nil, "%d 0 obj\n", num)
+	buf = appendObject(buf, dict)
+	buf = append(buf, "\nstream\n"...)
+	buf = append(buf, data.Bytes()...)
+	buf = append(buf, "\nendstream\nendobj\n"...)
+	_, err := rw.out.Write(buf)
+	return err
+}
+
+// entryRuns группирует номера объектов в непрерывные подразделы
+func entryRuns(entries map[int]Entry) [][]int {
+	nums := make([]int, 0, len(entries))
+	for num := range entries {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+
+	var runs [][]int
+	for _, num := range nums {
+		if n := len(runs); n > 0 && runs[n-1][len(runs[n-1])-1] == num-1 {
+			runs[n-1] = append(runs[n-1], num)
+			continue
+		}
+		runs = append(runs, []int{num})
+	}
+	return runs
+}
+
+// entryFields второе и третье поля записи потока ссылок
+func entryFields(entry Entry) (int64, int64) {
+	switch entry.Type {
+	case 1:
+		return entry.Offset, int64(entry.Gen)
+	case 2:
+		return int64(entry.Stream), int64(entry.Index)
+	}
+	return 0, int64(entry.Gen)
+}
+
+// byteWidth количество байт для записи значения (не меньше одного)
+func byteWidth(v int64) int {
+	width := 1
+	for v > 0xFF {
+		v >>= 8
+		width++
+	}
+	return width
+}
+
+// appendField записывает значение в width байт (big-endian)
+func appendField(buf []byte, v int64, width int) []byte {
+	for i := width - 1; i >= 0; i-- {
+		buf = append(buf, byte(v>>(8*i)))
+	}
+	return buf
+}
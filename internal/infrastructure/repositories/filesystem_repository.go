@@ -1,13 +1,19 @@
 package repositories
 
 import (
+	"bytes"
+	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
 
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+
 	"compress/internal/domain/entities"
+	"compress/internal/infrastructure/pdfraw"
 )
 
 // FileSystemRepository реализация репозитория для работы с файловой системой
@@ -18,21 +24,138 @@ func NewFileSystemRepository() *FileSystemRepository {
 	return &FileSystemRepository{}
 }
 
-// GetFileInfo получает информацию о PDF файле
+// GetFileInfo получает информацию о PDF файле. Читаются только таблицы перекрестных ссылок
+// и нужные объекты каталога; файл целиком загружается лишь при поврежденных ссылках
 func (r *FileSystemRepository) GetFileInfo(path string) (*entities.PDFDocument, error) {
-	info, err := os.Stat(path)
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
 	if err != nil {
 		return nil, err
 	}
 
+	structure, err := inspectXRef(file, info.Size())
+	if err != nil {
+		// Таблица ссылок не читается: pdfcpu восстанавливает ее по всему файлу
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		structure = inspectFullFile(data)
+	}
+
 	return &entities.PDFDocument{
-		Path:         path,
-		Size:         info.Size(),
-		ModifiedTime: info.ModTime(),
-		Pages:        0, // TODO: Можно добавить определение количества страниц
+		Path:            path,
+		Size:            info.Size(),
+		ModifiedTime:    info.ModTime(),
+		Pages:           structure.Pages,
+		Signatures:      structure.Signatures,
+		SignedBytes:     structure.SignedBytes,
+		Revisions:       structure.Revisions,
+		PDFAPart:        structure.PDFAPart,
+		PDFAConformance: structure.PDFAConformance,
+		OutputIntents:   structure.OutputIntents,
 	}, nil
 }
 
+// pdfStructure сведения о документе, получаемые из его объектов
+type pdfStructure struct {
+	Pages           int
+	Signatures      int
+	SignedBytes     int64
+	Revisions       int
+	PDFAPart        int
+	PDFAConformance string
+	OutputIntents   int
+}
+
+// inspectXRef собирает сведения о документе, читая с диска только разделы перекрестных ссылок
+// и объекты каталога. Ошибка означает, что таблица ссылок повреждена
+func inspectXRef(file io.ReaderAt, size int64) (pdfStructure, error) {
+	reader, err := pdfraw.Open(file, size)
+	if err != nil {
+		return pdfStructure{}, err
+	}
+	catalog, err := reader.Catalog()
+	if err != nil {
+		return pdfStructure{}, err
+	}
+
+	structure := pdfStructure{Revisions: len(reader.Sections())}
+	structure.Signatures, structure.SignedBytes = countSignatures(reader, catalog)
+
+	// Разделы ссылок первой страницы линеаризованного файла ревизией не являются
+	header := make([]byte, min(size, linearizedHeaderSize))
+	if _, err := file.ReadAt(header, 0); err == nil && structure.Revisions > 1 && bytes.Contains(header, []byte("/Linearized")) {
+		structure.Revisions--
+	}
+
+	if pages, err := reader.Resolve(catalog["Pages"]); err == nil {
+		if pagesDict, ok := pages.(pdfraw.Dict); ok {
+			if count, err := reader.Resolve(pagesDict["Count"]); err == nil {
+				if count, ok := count.(int64); ok && count > 0 {
+					structure.Pages = int(count)
+				}
+			}
+		}
+	}
+
+	if intents, err := reader.Resolve(catalog["OutputIntents"]); err == nil {
+		if intents, ok := intents.(pdfraw.Array); ok {
+			structure.OutputIntents = len(intents)
+		}
+	}
+
+	// XMP зашифрованного документа не раскодируется, заявление PDF/A в нем не ищется
+	if metadata, err := reader.Resolve(catalog["Metadata"]); err == nil {
+		if stream, ok := metadata.(*pdfraw.Stream); ok {
+			if xmp, err := reader.DecodeStream(stream); err == nil {
+				structure.PDFAPart, structure.PDFAConformance = parsePDFAClaim(xmp)
+			}
+		}
+	}
+
+	return structure, nil
+}
+
+// inspectFullFile разбирает байты PDF средствами pdfcpu без валидации и оптимизации.
+// Для нечитаемых и зашифрованных файлов сведения о структуре остаются пустыми, а подписи
+// и ревизии ищутся в байтах файла
+func inspectFullFile(data []byte) pdfStructure {
+	structure := pdfStructure{Revisions: countRevisions(data)}
+
+	// Числа /ByteRange не шифруются и не сжимаются, поэтому подписи находятся и в зашифрованных документах
+	structure.Signatures, structure.SignedBytes = scanSignatureByteRanges(data)
+
+	pdfConfig := model.NewDefaultConfiguration()
+	pdfConfig.ValidationMode = model.ValidationNone
+
+	ctx, err := api.ReadContext(bytes.NewReader(data), pdfConfig)
+	if err != nil {
+		return structure
+	}
+	if err := ctx.EnsurePageCount(); err != nil {
+		return structure
+	}
+
+	structure.Pages = ctx.PageCount
+	structure.Signatures = max(structure.Signatures, countSignedFields(ctx))
+	structure.OutputIntents = countOutputIntents(ctx)
+	structure.PDFAPart, structure.PDFAConformance = parsePDFAClaim(catalogMetadata(ctx))
+
+	return structure
+}
+
+// ValidatePDF заново открывает PDF, проверяет его структуру средствами pdfcpu в строгом
+// или мягком режиме и обходит все страницы с их потоками содержимого
+func (r *FileSystemRepository) ValidatePDF(path string, strict bool) error {
+	return validatePDF(path, strict)
+}
+
 // FileExists проверяет существование файла
 func (r *FileSystemRepository) FileExists(path string) bool {
 	_, err := os.Stat(path)
@@ -0,0 +1,79 @@
+package repositories
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGetFileInfoFromXRef(t *testing.T) {
+	const xmp = `<x:xmpmeta><rdf:Description pdfaid:part="2" pdfaid:conformance="b"/></x:xmpmeta>`
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R /AcroForm << /Fields [4 0 R] >> /Metadata 6 0 R /OutputIntents [<< /S /GTS_PDFA1 >>] >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R >>",
+		"<< /FT /Sig /T (Signature1) /V 5 0 R >>",
+		"<< /Type /Sig /ByteRange [0 10 20 30] /Contents <00> >>",
+		fmt.Sprintf("<< /Type /Metadata /Subtype /XML /Length %d >>\nstream\n%s\nendstream", len(xmp), xmp),
+	}
+
+	var pdf strings.Builder
+	pdf.WriteString("%PDF-1.7\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = pdf.Len()
+		fmt.Fprintf(&pdf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := pdf.Len()
+	fmt.Fprintf(&pdf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&pdf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&pdf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	// Добавочное обновление дерева страниц
+	update := pdf.Len()
+	fmt.Fprintf(&pdf, "2 0 obj\n<< /Type /Pages /Kids [3 0 R 3 0 R] /Count 2 >>\nendobj\n")
+	updateXRef := pdf.Len()
+	fmt.Fprintf(&pdf, "xref\n2 1\n%010d 00000 n \ntrailer\n<< /Size %d /Root 1 0 R /Prev %d >>\nstartxref\n%d\n%%%%EOF\n",
+		update, len(objects)+1, xref, updateXRef)
+
+	path := filepath.Join(t.TempDir(), "signed.pdf")
+	if err := os.WriteFile(path, []byte(pdf.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := NewFileSystemRepository().GetFileInfo(path)
+	if err != nil {
+		t.Fatalf("GetFileInfo: %v", err)
+	}
+	if doc.Pages != 2 || doc.Signatures != 1 || doc.SignedBytes != 50 || doc.Revisions != 2 {
+		t.Errorf("Expected 2 pages, 1 signature over 50 bytes, 2 revisions; got %d, %d over %d, %d",
+			doc.Pages, doc.Signatures, doc.SignedBytes, doc.Revisions)
+	}
+	if doc.PDFAPart != 2 || doc.PDFAConformance != "B" || doc.OutputIntents != 1 {
+		t.Errorf("Expected PDF/A-2B with 1 output intent, got part %d %q, %d intents", doc.PDFAPart, doc.PDFAConformance, doc.OutputIntents)
+	}
+	if doc.Size != int64(pdf.Len()) {
+		t.Errorf("Expected size %d, got %d", pdf.Len(), doc.Size)
+	}
+}
+
+func TestGetFileInfoDamagedXRef(t *testing.T) {
+	// Неверный startxref: сведения собираются полным чтением файла
+	data := "%PDF-1.7\n1 0 obj\n<< /Type /Sig /ByteRange [0 10 20 30] >>\nendobj\nstartxref\n9999\n%%EOF\n"
+	path := filepath.Join(t.TempDir(), "damaged.pdf")
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := NewFileSystemRepository().GetFileInfo(path)
+	if err != nil {
+		t.Fatalf("GetFileInfo: %v", err)
+	}
+	if doc.Signatures != 1 {
+		t.Errorf("Expected the byte range scan to find 1 signature, got %d", doc.Signatures)
+	}
+}
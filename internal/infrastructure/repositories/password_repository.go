@@ -0,0 +1,99 @@
+package repositories
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"compress/internal/domain/entities"
+)
+
+// PasswordRepository собирает пароли для зашифрованных PDF из конфигурации,
+// общего файла паролей и файлов-спутников в директориях документов
+type PasswordRepository struct {
+	config entities.PasswordConfig
+
+	mu        sync.Mutex
+	fileCache []string
+	fileRead  bool
+	sidecars  map[string][]string
+}
+
+// NewPasswordRepository создает новый репозиторий паролей
+func NewPasswordRepository(config entities.PasswordConfig) *PasswordRepository {
+	return &PasswordRepository{
+		config:   config,
+		sidecars: make(map[string][]string),
+	}
+}
+
+// Passwords возвращает пароли-кандидаты для файла: сначала из файла-спутника
+// его директории, затем из конфигурации и общего файла паролей
+func (r *PasswordRepository) Passwords(pdfPath string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var candidates []string
+	if r.config.Sidecar != "" {
+		candidates = append(candidates, r.sidecarPasswords(filepath.Dir(pdfPath))...)
+	}
+	candidates = append(candidates, r.config.List...)
+	if r.config.File != "" {
+		if !r.fileRead {
+			r.fileCache = readPasswordFile(r.config.File)
+			r.fileRead = true
+		}
+		candidates = append(candidates, r.fileCache...)
+	}
+
+	return uniquePasswords(candidates)
+}
+
+// sidecarPasswords читает (с кешированием) файл-спутник указанной директории
+func (r *PasswordRepository) sidecarPasswords(dir string) []string {
+	if passwords, found := r.sidecars[dir]; found {
+		return passwords
+	}
+
+	passwords := readPasswordFile(filepath.Join(dir, r.config.Sidecar))
+	r.sidecars[dir] = passwords
+	return passwords
+}
+
+// readPasswordFile читает пароли по одному на строку; пустые строки и строки,
+// начинающиеся с #, пропускаются. Отсутствующий файл дает пустой список
+func readPasswordFile(path string) []string {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var passwords []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		passwords = append(passwords, line)
+	}
+
+	return passwords
+}
+
+// uniquePasswords убирает повторы, сохраняя порядок
+func uniquePasswords(passwords []string) []string {
+	seen := make(map[string]bool, len(passwords))
+	result := make([]string, 0, len(passwords))
+	for _, password := range passwords {
+		if seen[password] {
+			continue
+		}
+		seen[password] = true
+		result = append(result, password)
+	}
+	return result
+}
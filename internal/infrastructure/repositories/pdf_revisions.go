@@ -0,0 +1,32 @@
+package repositories
+
+import (
+	"bytes"
+	"regexp"
+)
+
+// revisionEndPattern конец ревизии: ссылка на ее таблицу перекрестных ссылок и маркер %%EOF
+var revisionEndPattern = regexp.MustCompile(`startxref\s+(\d+)\s+%%EOF`)
+
+// linearizedHeaderSize в пределах этого начала файла находится словарь линеаризации
+const linearizedHeaderSize = 1024
+
+// countRevisions считает ревизии документа: исходную и добавочные обновления, каждое из которых
+// дописывает в конец файла свою таблицу ссылок и %%EOF. Трейлер первой страницы линеаризованного
+// файла ревизией не является: обычно он ссылается на смещение 0, иначе вычитается отдельно
+func countRevisions(data []byte) int {
+	revisions, firstPageTrailers := 0, 0
+	for _, match := range revisionEndPattern.FindAllSubmatch(data, -1) {
+		if string(match[1]) == "0" {
+			firstPageTrailers++
+			continue
+		}
+		revisions++
+	}
+
+	header := data[:min(len(data), linearizedHeaderSize)]
+	if firstPageTrailers == 0 && revisions > 1 && bytes.Contains(header, []byte("/Linearized")) {
+		revisions--
+	}
+	return revisions
+}
@@ -0,0 +1,38 @@
+package repositories
+
+import "testing"
+
+func TestCountRevisions(t *testing.T) {
+	const (
+		body     = "%PDF-1.7\n1 0 obj\n<< /Type /Catalog >>\nendobj\n"
+		revision = "xref\n0 2\ntrailer\n<< /Size 2 >>\nstartxref\n%d\n%%%%EOF\n"
+	)
+
+	tests := []struct {
+		name     string
+		data     string
+		expected int
+	}{
+		{"Single revision", body + "xref\ntrailer\n<<>>\nstartxref\n45\n%%EOF\n", 1},
+		{"Two incremental updates", body + "startxref\n45\n%%EOF\n2 0 obj\n<<>>\nendobj\nstartxref\n120\n%%EOF\r\nstartxref 300 %%EOF", 3},
+		{
+			name:     "Linearized with first-page trailer at offset 0",
+			data:     "%PDF-1.7\n1 0 obj\n<< /Linearized 1 /L 1000 >>\nendobj\ntrailer\n<<>>\nstartxref\n0\n%%EOF\nstartxref\n700\n%%EOF\n",
+			expected: 1,
+		},
+		{
+			name:     "Linearized with first-page trailer offset",
+			data:     "%PDF-1.7\n1 0 obj\n<< /Linearized 1 /L 1000 >>\nendobj\ntrailer\n<<>>\nstartxref\n60\n%%EOF\nstartxref\n700\n%%EOF\n",
+			expected: 1,
+		},
+		{"Not a PDF", "hello", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if revisions := countRevisions([]byte(tt.data)); revisions != tt.expected {
+				t.Errorf("Expected %d revisions, got %d", tt.expected, revisions)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package repositories
+
+import (
+	"regexp"
+	"strconv"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+
+	"compress/internal/infrastructure/pdfraw"
+)
+
+// maxFieldDepth ограничение глубины дерева полей формы (защита от циклов)
+const maxFieldDepth = 32
+
+// byteRangePattern массив /ByteRange словаря подписи: [смещение1 длина1 смещение2 длина2]
+var byteRangePattern = regexp.MustCompile(`/ByteRange\s*\[\s*(\d+)\s+(\d+)\s+(\d+)\s+(\d+)\s*\]`)
+
+// countSignedFields считает заполненные поля подписи (FT Sig со значением V) в AcroForm
+func countSignedFields(ctx *model.Context) int {
+	rootDict, err := ctx.Catalog()
+	if err != nil {
+		return 0
+	}
+
+	obj, found := rootDict.Find("AcroForm")
+	if !found {
+		return 0
+	}
+	acroForm, err := ctx.DereferenceDict(obj)
+	if err != nil || acroForm == nil {
+		return 0
+	}
+
+	obj, found = acroForm.Find("Fields")
+	if !found {
+		return 0
+	}
+	fields, err := ctx.DereferenceArray(obj)
+	if err != nil {
+		return 0
+	}
+
+	return countSignedFieldsIn(ctx, fields, "", 0)
+}
+
+// countSignedFieldsIn обходит поля и их потомков; тип поля FT наследуется от родителя
+func countSignedFieldsIn(ctx *model.Context, fields types.Array, inheritedType string, depth int) int {
+	if depth > maxFieldDepth {
+		return 0
+	}
+
+	count := 0
+	for _, obj := range fields {
+		field, err := ctx.DereferenceDict(obj)
+		if err != nil || field == nil {
+			continue
+		}
+
+		fieldType := inheritedType
+		if ft := field.NameEntry("FT"); ft != nil {
+			fieldType = *ft
+		}
+
+		if _, signed := field.Find("V"); fieldType == "Sig" && signed {
+			count++
+			continue
+		}
+
+		if kidsObj, found := field.Find("Kids"); found {
+			kids, err := ctx.DereferenceArray(kidsObj)
+			if err == nil {
+				count += countSignedFieldsIn(ctx, kids, fieldType, depth+1)
+			}
+		}
+	}
+
+	return count
+}
+
+// scanSignatureByteRanges ищет массивы /ByteRange в байтах файла. Числа в них не шифруются
+// и не сжимаются, поэтому подписи находятся и в зашифрованных документах.
+// Возвращает количество подписей и конец последней подписанной ревизии.
+func scanSignatureByteRanges(data []byte) (signatures int, signedBytes int64) {
+	for _, match := range byteRangePattern.FindAllSubmatch(data, -1) {
+		offset, err1 := strconv.ParseInt(string(match[3]), 10, 64)
+		length, err2 := strconv.ParseInt(string(match[4]), 10, 64)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		signatures++
+		if end := offset + length; end > signedBytes && end <= int64(len(data)) {
+			signedBytes = end
+		}
+	}
+
+	return signatures, signedBytes
+}
+
+// signatureScan подписи, найденные в объектах документа
+type signatureScan struct {
+	reader      *pdfraw.Reader
+	size        int64 // Размер файла: /ByteRange за его пределами не учитывается
+	signedBytes int64 // Конец последней подписанной ревизии
+}
+
+// countSignatures считает подписи по объектам каталога: заполненные поля подписи AcroForm
+// и подписи в словаре разрешений /Perms (DocMDP, UR3), которые могут не входить в форму.
+// Возвращает количество подписей и конец последней подписанной ревизии
+func countSignatures(reader *pdfraw.Reader, catalog pdfraw.Dict) (int, int64) {
+	scan := &signatureScan{reader: reader, size: reader.Size()}
+
+	signatures := 0
+	if acroForm, err := reader.Resolve(catalog["AcroForm"]); err == nil {
+		if acroForm, ok := acroForm.(pdfraw.Dict); ok {
+			if fields, err := reader.Resolve(acroForm["Fields"]); err == nil {
+				if fields, ok := fields.(pdfraw.Array); ok {
+					signatures = scan.signedFields(fields, "", 0)
+				}
+			}
+		}
+	}
+
+	permissions := 0
+	if perms, err := reader.Resolve(catalog["Perms"]); err == nil {
+		if perms, ok := perms.(pdfraw.Dict); ok {
+			for _, obj := range perms {
+				if scan.signature(obj) {
+					permissions++
+				}
+			}
+		}
+	}
+
+	return max(signatures, permissions), scan.signedBytes
+}
+
+// signedFields обходит поля и их потомков; тип поля FT наследуется от родителя
+func (s *signatureScan) signedFields(fields pdfraw.Array, inheritedType pdfraw.Name, depth int) int {
+	if depth > maxFieldDepth {
+		return 0
+	}
+
+	count := 0
+	for _, obj := range fields {
+		field, err := s.reader.Resolve(obj)
+		if err != nil {
+			continue
+		}
+		fieldDict, ok := field.(pdfraw.Dict)
+		if !ok {
+			continue
+		}
+
+		fieldType := inheritedType
+		if ft := fieldDict.Name("FT"); ft != "" {
+			fieldType = ft
+		}
+
+		if value, signed := fieldDict["V"]; fieldType == "Sig" && signed {
+			s.signature(value)
+			count++
+			continue
+		}
+
+		if kids, err := s.reader.Resolve(fieldDict["Kids"]); err == nil {
+			if kids, ok := kids.(pdfraw.Array); ok {
+				count += s.signedFields(kids, fieldType, depth+1)
+			}
+		}
+	}
+
+	return count
+}
+
+// signature проверяет, что объект — словарь подписи с /ByteRange, и учитывает конец подписанной части
+func (s *signatureScan) signature(obj pdfraw.Object) bool {
+	sig, err := s.reader.Resolve(obj)
+	if err != nil {
+		return false
+	}
+	sigDict, ok := sig.(pdfraw.Dict)
+	if !ok {
+		return false
+	}
+	byteRange, ok := sigDict["ByteRange"].(pdfraw.Array)
+	if !ok {
+		return false
+	}
+
+	if len(byteRange) == 4 {
+		offset, ok1 := byteRange[2].(int64)
+		length, ok2 := byteRange[3].(int64)
+		if end := offset + length; ok1 && ok2 && end > s.signedBytes && end <= s.size {
+			s.signedBytes = end
+		}
+	}
+	return true
+}
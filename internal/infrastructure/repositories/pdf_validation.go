@@ -0,0 +1,90 @@
+package repositories
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// validatePDF читает документ с валидацией pdfcpu и проверяет, что каждая страница доступна
+func validatePDF(path string, strict bool) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	pdfConfig := model.NewDefaultConfiguration()
+	pdfConfig.ValidationMode = model.ValidationRelaxed
+	if strict {
+		pdfConfig.ValidationMode = model.ValidationStrict
+	}
+
+	ctx, err := api.ReadContext(file, pdfConfig)
+	if err != nil {
+		return fmt.Errorf("ошибка чтения PDF: %w", err)
+	}
+	if err := api.ValidateContext(ctx); err != nil {
+		return fmt.Errorf("ошибка валидации PDF: %w", err)
+	}
+	if err := ctx.EnsurePageCount(); err != nil {
+		return fmt.Errorf("ошибка чтения дерева страниц: %w", err)
+	}
+
+	return walkPages(ctx)
+}
+
+// walkPages обходит все страницы документа и декодирует их потоки содержимого
+func walkPages(ctx *model.Context) error {
+	if ctx.PageCount == 0 {
+		return fmt.Errorf("документ не содержит страниц")
+	}
+
+	for pageNr := 1; pageNr <= ctx.PageCount; pageNr++ {
+		pageDict, _, _, err := ctx.PageDict(pageNr, false)
+		if err != nil {
+			return fmt.Errorf("страница %d: %w", pageNr, err)
+		}
+		if pageDict == nil {
+			return fmt.Errorf("страница %d не найдена", pageNr)
+		}
+
+		obj, found := pageDict.Find("Contents")
+		if !found {
+			continue
+		}
+		obj, err = ctx.Dereference(obj)
+		if err != nil {
+			return fmt.Errorf("страница %d: содержимое: %w", pageNr, err)
+		}
+
+		var streams []types.Object
+		switch contents := obj.(type) {
+		case types.StreamDict:
+			streams = append(streams, contents)
+		case types.Array:
+			streams = contents
+		case nil:
+		default:
+			return fmt.Errorf("страница %d: некорректный тип содержимого", pageNr)
+		}
+
+		for _, stream := range streams {
+			sd, _, err := ctx.DereferenceStreamDict(stream)
+			if err != nil {
+				return fmt.Errorf("страница %d: поток содержимого: %w", pageNr, err)
+			}
+			if sd == nil {
+				continue
+			}
+			if err := sd.Decode(); err != nil {
+				return fmt.Errorf("страница %d: поток содержимого не декодируется: %w", pageNr, err)
+			}
+		}
+	}
+
+	return nil
+}
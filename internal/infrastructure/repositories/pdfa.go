@@ -0,0 +1,78 @@
+package repositories
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+// Заявление PDF/A в XMP записывается атрибутом (pdfaid:part="2") или элементом (<pdfaid:part>2</pdfaid:part>)
+var (
+	pdfaPartPattern        = regexp.MustCompile(`pdfaid:part\s*(?:=\s*["'](\d)["']|>\s*(\d)\s*<)`)
+	pdfaConformancePattern = regexp.MustCompile(`pdfaid:conformance\s*(?:=\s*["']([A-Za-z])["']|>\s*([A-Za-z])\s*<)`)
+)
+
+// catalogMetadata возвращает декодированный XMP поток каталога или nil
+func catalogMetadata(ctx *model.Context) []byte {
+	rootDict, err := ctx.Catalog()
+	if err != nil {
+		return nil
+	}
+
+	obj, found := rootDict.Find("Metadata")
+	if !found {
+		return nil
+	}
+
+	sd, _, err := ctx.DereferenceStreamDict(obj)
+	if err != nil || sd == nil {
+		return nil
+	}
+	if err := sd.Decode(); err != nil {
+		return nil
+	}
+
+	return sd.Content
+}
+
+// countOutputIntents возвращает количество OutputIntent в каталоге
+func countOutputIntents(ctx *model.Context) int {
+	rootDict, err := ctx.Catalog()
+	if err != nil {
+		return 0
+	}
+
+	obj, found := rootDict.Find("OutputIntents")
+	if !found {
+		return 0
+	}
+
+	intents, err := ctx.DereferenceArray(obj)
+	if err != nil {
+		return 0
+	}
+	return len(intents)
+}
+
+// parsePDFAClaim извлекает из XMP часть и уровень соответствия PDF/A.
+// Если заявления нет, возвращает 0 и пустую строку
+func parsePDFAClaim(xmp []byte) (int, string) {
+	match := pdfaPartPattern.FindSubmatch(xmp)
+	if match == nil {
+		return 0, ""
+	}
+
+	part, err := strconv.Atoi(string(match[1]) + string(match[2]))
+	if err != nil || part <= 0 {
+		return 0, ""
+	}
+
+	conformance := ""
+	if match := pdfaConformancePattern.FindSubmatch(xmp); match != nil {
+		conformance = strings.ToUpper(string(match[1]) + string(match[2]))
+	}
+
+	return part, conformance
+}
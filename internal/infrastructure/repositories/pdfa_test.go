@@ -0,0 +1,46 @@
+package repositories
+
+import "testing"
+
+func TestParsePDFAClaim(t *testing.T) {
+	tests := []struct {
+		name                string
+		xmp                 string
+		expectedPart        int
+		expectedConformance string
+	}{
+		{
+			name:                "Attributes",
+			xmp:                 `<rdf:Description rdf:about="" pdfaid:part="2" pdfaid:conformance="B"/>`,
+			expectedPart:        2,
+			expectedConformance: "B",
+		},
+		{
+			name:                "Elements",
+			xmp:                 "<pdfaid:part>1</pdfaid:part>\n<pdfaid:conformance>a</pdfaid:conformance>",
+			expectedPart:        1,
+			expectedConformance: "A",
+		},
+		{
+			name:                "Part without conformance",
+			xmp:                 `<rdf:Description pdfaid:part='4'/>`,
+			expectedPart:        4,
+			expectedConformance: "",
+		},
+		{
+			name:         "No claim",
+			xmp:          `<rdf:Description dc:format="application/pdf"/>`,
+			expectedPart: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			part, conformance := parsePDFAClaim([]byte(tt.xmp))
+			if part != tt.expectedPart || conformance != tt.expectedConformance {
+				t.Errorf("parsePDFAClaim() = %d %q, want %d %q",
+					part, conformance, tt.expectedPart, tt.expectedConformance)
+			}
+		})
+	}
+}
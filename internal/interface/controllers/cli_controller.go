@@ -118,6 +118,7 @@ func (c *CLIController) askForCompressionLevel() int {
 // showCompressionResult показывает результат сжатия файла
 func (c *CLIController) showCompressionResult(result *entities.CompressionResult, outputPath string) {
 	fmt.Println("\n📊 Результаты сжатия:")
+	fmt.Printf("Страниц: %d\n", result.Pages)
 	fmt.Printf("Исходный размер: %.2f MB\n", float64(result.OriginalSize)/1024/1024)
 	fmt.Printf("Сжатый размер: %.2f MB\n", float64(result.CompressedSize)/1024/1024)
 	fmt.Printf("Сжатие: %.1f%%\n", result.CompressionRatio)
@@ -141,8 +142,8 @@ func (c *CLIController) showDirectoryResult(result *usecases.DirectoryCompressio
 
 	// Показываем статистику по каждому файлу
 	for i, fileResult := range result.Results {
-		fmt.Printf("\n[%d] Сжатие: %.1f%%, Сэкономлено: %.2f MB\n",
-			i+1, fileResult.CompressionRatio, float64(fileResult.SavedSpace)/1024/1024)
+		fmt.Printf("\n[%d] Страниц: %d, Сжатие: %.1f%%, Сэкономлено: %.2f MB\n",
+			i+1, fileResult.Pages, fileResult.CompressionRatio, float64(fileResult.SavedSpace)/1024/1024)
 	}
 
 	// Показываем ошибки, если есть
@@ -0,0 +1,207 @@
+package tui
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"compress/internal/domain/entities"
+
+	"github.com/rivo/tview"
+)
+
+// MaxAnalysisRows количество изображений и шрифтов в отчете анализа
+const MaxAnalysisRows = 10
+
+// fontOptimizationTitles названия оптимизаций шрифтов для отображения
+var fontOptimizationTitles = map[string]string{
+	entities.FontOptimizationUnused:    "не используется",
+	entities.FontOptimizationDuplicate: "дубликат",
+	entities.FontOptimizationSubset:    "подмножество",
+}
+
+// categoryTitles названия категорий анализа для отображения
+var categoryTitles = map[string]string{
+	entities.CategoryImages:        "Изображения",
+	entities.CategoryFonts:         "Шрифты",
+	entities.CategoryContent:       "Содержимое страниц",
+	entities.CategoryEmbeddedFiles: "Вложенные файлы",
+	entities.CategoryMetadata:      "Метаданные",
+	entities.CategoryThumbnails:    "Миниатюры",
+	entities.CategoryUnused:        "Неиспользуемые объекты",
+	entities.CategoryStructure:     "Служебная часть",
+}
+
+// SetAnalyzer устанавливает callbacks анализа PDF и получения списка файлов
+func (m *Manager) SetAnalyzer(
+	analyze func(path string) (*entities.PDFAnalysis, error),
+	listFiles func(directory string) ([]string, error),
+) {
+	m.onAnalyze = analyze
+	m.listPDFFiles = listFiles
+}
+
+// createAnalysisScreen создает экран анализа размера PDF
+func (m *Manager) createAnalysisScreen() {
+	m.analysisList = tview.NewList().ShowSecondaryText(false)
+	m.analysisList.SetBorder(true).
+		SetTitle("📄 PDF файлы (Enter - анализ)").
+		SetTitleAlign(tview.AlignCenter)
+	m.analysisList.SetSelectedFunc(func(index int, mainText, secondaryText string, shortcut rune) {
+		m.analyzeFile(mainText)
+	})
+
+	m.analysisView = tview.NewTextView().
+		SetDynamicColors(true).
+		SetScrollable(true).
+		SetWrap(false)
+	m.analysisView.SetBorder(true).
+		SetTitle("🔬 Анализ размера (ESC - главное меню)").
+		SetTitleAlign(tview.AlignCenter)
+}
+
+// createAnalysisLayout создает layout для экрана анализа
+func (m *Manager) createAnalysisLayout() *tview.Flex {
+	return tview.NewFlex().
+		SetDirection(tview.FlexColumn).
+		AddItem(m.analysisList, 0, 1, true).
+		AddItem(m.analysisView, 0, 2, false)
+}
+
+// refreshAnalysisFiles заполняет список PDF файлами исходной директории
+func (m *Manager) refreshAnalysisFiles() {
+	m.analysisList.Clear()
+
+	if m.listPDFFiles == nil || m.onAnalyze == nil {
+		m.analysisView.SetText("[red]Анализ недоступен[white]")
+		return
+	}
+
+	sourceDir := m.configData.Scanner.SourceDirectory
+	files, err := m.listPDFFiles(sourceDir)
+	if err != nil {
+		m.analysisView.SetText(fmt.Sprintf("[red]Ошибка чтения директории %s: %v[white]", sourceDir, err))
+		return
+	}
+	if len(files) == 0 {
+		m.analysisView.SetText(fmt.Sprintf("[yellow]В директории %s нет PDF файлов[white]", sourceDir))
+		return
+	}
+
+	for _, file := range files {
+		name := file
+		if rel, err := filepath.Rel(sourceDir, file); err == nil {
+			name = rel
+		}
+		m.analysisList.AddItem(name, "", 0, nil)
+	}
+	m.analysisView.SetText("Выберите файл и нажмите Enter")
+}
+
+// analyzeFile запускает анализ файла в фоне и выводит отчет
+func (m *Manager) analyzeFile(name string) {
+	path := filepath.Join(m.configData.Scanner.SourceDirectory, name)
+	m.analysisView.SetText(fmt.Sprintf("[yellow]⏳ Анализ %s...[white]", name))
+
+	go func() {
+		analysis, err := m.onAnalyze(path)
+
+		var text string
+		if err != nil {
+			text = fmt.Sprintf("[red]❌ %v[white]", err)
+		} else {
+			text = formatAnalysis(name, analysis)
+		}
+
+		m.app.QueueUpdateDraw(func() {
+			m.analysisView.SetText(text)
+			m.analysisView.ScrollToBeginning()
+		})
+	}()
+}
+
+// formatAnalysis формирует текст отчета анализа
+func formatAnalysis(name string, analysis *entities.PDFAnalysis) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "[yellow]📁 Файл:[white] %s\n", name)
+	fmt.Fprintf(&b, "[yellow]💾 Размер:[white] %s, страниц: %d\n\n", formatBytes(analysis.FileSize), analysis.Pages)
+
+	b.WriteString("[green]📊 Категории:[white]\n")
+	for _, category := range analysis.Categories {
+		title := categoryTitles[category.Name]
+		if title == "" {
+			title = category.Name
+		}
+		fmt.Fprintf(&b, "  %-24s [cyan]%10s[white] %5.1f%%  (%d объектов)\n",
+			title, formatBytes(category.Bytes), category.Percent, category.Objects)
+	}
+
+	if len(analysis.Images) > 0 {
+		fmt.Fprintf(&b, "\n[green]🖼  Изображения (%d):[white]\n", len(analysis.Images))
+		for i, image := range analysis.Images {
+			if i == MaxAnalysisRows {
+				fmt.Fprintf(&b, "  ... еще %d\n", len(analysis.Images)-MaxAnalysisRows)
+				break
+			}
+			ppi := "-"
+			if image.PPI > 0 {
+				ppi = fmt.Sprintf("%.0f", image.PPI)
+			}
+			fmt.Fprintf(&b, "  #%-6d %5dx%-5d ppi %-5s %-16s %-10s [cyan]%10s[white]\n",
+				image.ObjectNumber, image.Width, image.Height, ppi, image.Filter, image.ColorSpace, formatBytes(image.Bytes))
+		}
+	}
+
+	if len(analysis.Fonts) > 0 {
+		fmt.Fprintf(&b, "\n[green]🔤 Шрифты (%d):[white]\n", len(analysis.Fonts))
+		if analysis.FontSavings > 0 {
+			fmt.Fprintf(&b, "  Оптимизация шрифтов сэкономит [cyan]%s[white]\n", formatBytes(analysis.FontSavings))
+		}
+		for i, font := range analysis.Fonts {
+			if i == MaxAnalysisRows {
+				fmt.Fprintf(&b, "  ... еще %d\n", len(analysis.Fonts)-MaxAnalysisRows)
+				break
+			}
+			embedding := "[yellow]не встроен[white]"
+			switch {
+			case font.Embedded && font.Subset:
+				embedding = "подмножество"
+			case font.Embedded:
+				embedding = "[red]полный[white]"
+			}
+			fmt.Fprintf(&b, "  %-32s %-12s %s [cyan]%10s[white]%s\n",
+				font.Name, font.Subtype, embedding, formatBytes(font.Bytes), formatFontSavings(font))
+		}
+	}
+
+	return b.String()
+}
+
+// formatFontSavings форматирует оптимизации шрифта и экономию от них
+func formatFontSavings(font entities.FontAnalysis) string {
+	if len(font.Optimizations) == 0 {
+		return ""
+	}
+	titles := make([]string, 0, len(font.Optimizations))
+	for _, optimization := range font.Optimizations {
+		title := fontOptimizationTitles[optimization]
+		if title == "" {
+			title = optimization
+		}
+		titles = append(titles, title)
+	}
+	return fmt.Sprintf("  [green]−%s[white] (%s)", formatBytes(font.Savings), strings.Join(titles, ", "))
+}
+
+// formatBytes форматирует размер в байтах для отображения
+func formatBytes(size int64) string {
+	switch {
+	case size >= 1024*1024:
+		return fmt.Sprintf("%.2f MB", float64(size)/1024/1024)
+	case size >= 1024:
+		return fmt.Sprintf("%.1f KB", float64(size)/1024)
+	default:
+		return fmt.Sprintf("%d B", size)
+	}
+}
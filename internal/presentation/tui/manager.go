@@ -33,6 +33,47 @@ type ConfigData struct {
 		EnablePNG        bool   `yaml:"enable_png"`
 		JPEGQuality      int    `yaml:"jpeg_quality"`
 		PNGQuality       int    `yaml:"png_quality"`
+		// Порог экономии
+		MinSavingsPercent float64 `yaml:"min_savings_percent"`
+		MinSavingsBytes   int64   `yaml:"min_savings_bytes"`
+		// Пароли для зашифрованных PDF
+		Passwords entities.PasswordConfig `yaml:"passwords"`
+		// Политика для подписанных PDF
+		SignaturePolicy string `yaml:"signature_policy"`
+		// Режим сохранения PDF/A
+		PreservePDFA bool `yaml:"preserve_pdfa"`
+		// Стратегии алгоритма best
+		BestStrategies []entities.StrategyConfig `yaml:"best_strategies"`
+		// Целевой размер и профили
+		TargetSizeMB float64                  `yaml:"target_size_mb"`
+		Profiles     []entities.ProfileConfig `yaml:"profiles"`
+		// Строгость проверки сжатых PDF
+		OutputValidation string `yaml:"output_validation"`
+		// Политика метаданных
+		Metadata entities.MetadataConfig `yaml:"metadata"`
+		// Сохраняемые подтипы аннотаций
+		KeepAnnotations []string `yaml:"keep_annotations"`
+		// Линеаризация результата и путь к qpdf
+		OptimizeForWeb bool   `yaml:"optimize_for_web"`
+		QPDFPath       string `yaml:"qpdf_path"`
+		// Уменьшение цветности изображений
+		ColorReduction string `yaml:"color_reduction"`
+		// Внешняя программа сжатия
+		ExternalCommand entities.ExternalCommandConfig `yaml:"external_command"`
+		// Параметры оптимизатора UniPDF
+		UniPDF entities.UniPDFOptionsConfig `yaml:"unipdf"`
+		// Удаление редко нужных данных
+		Cleanup entities.CleanupConfig `yaml:"cleanup"`
+		// Смена формата изображений и путь к cwebp
+		ImageConversion string `yaml:"image_conversion"`
+		CWebPPath       string `yaml:"cwebp_path"`
+		// Дизеринг и уменьшение разрешения PNG
+		PNGDithering bool `yaml:"png_dithering"`
+		PNGResize    bool `yaml:"png_resize"`
+		// Метаданные изображений
+		ImageMetadata string `yaml:"image_metadata"`
+		// Рамка изображений
+		ImageResize entities.ImageResizeConfig `yaml:"image_resize"`
 	} `yaml:"compression"`
 	Processing struct {
 		ParallelWorkers int `yaml:"parallel_workers"`
@@ -71,9 +112,13 @@ type Manager struct {
 	progressView *tview.TextView
 	logView      *tview.TextView
 	statusBar    *tview.TextView
+	analysisList *tview.List
+	analysisView *tview.TextView
 
 	// Callbacks
 	onStartProcessing func()
+	onAnalyze         func(path string) (*entities.PDFAnalysis, error)
+	listPDFFiles      func(directory string) ([]string, error)
 
 	// Состояние
 	configData   ConfigData
@@ -128,58 +173,7 @@ func (m *Manager) loadConfig() {
 	configPath := "config.yaml"
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
 		// Создаем конфигурацию по умолчанию
-		m.configData = ConfigData{
-			Scanner: struct {
-				SourceDirectory string `yaml:"source_directory"`
-				TargetDirectory string `yaml:"target_directory"`
-				ReplaceOriginal bool   `yaml:"replace_original"`
-			}{
-				SourceDirectory: "./pdfs",
-				TargetDirectory: "./compressed",
-				ReplaceOriginal: false,
-			},
-			Compression: struct {
-				Level            int    `yaml:"level"`
-				Algorithm        string `yaml:"algorithm"`
-				AutoStart        bool   `yaml:"auto_start"`
-				UniPDFLicenseKey string `yaml:"unipdf_license_key"`
-				EnableJPEG       bool   `yaml:"enable_jpeg"`
-				EnablePNG        bool   `yaml:"enable_png"`
-				JPEGQuality      int    `yaml:"jpeg_quality"`
-				PNGQuality       int    `yaml:"png_quality"`
-			}{
-				Level:            50,
-				Algorithm:        "pdfcpu",
-				AutoStart:        false,
-				UniPDFLicenseKey: "",
-				EnableJPEG:       false,
-				EnablePNG:        false,
-				JPEGQuality:      30,
-				PNGQuality:       25,
-			},
-			Processing: struct {
-				ParallelWorkers int `yaml:"parallel_workers"`
-				TimeoutSeconds  int `yaml:"timeout_seconds"`
-				RetryAttempts   int `yaml:"retry_attempts"`
-			}{
-				ParallelWorkers: 2,
-				TimeoutSeconds:  30,
-				RetryAttempts:   3,
-			},
-			Output: struct {
-				LogLevel     string `yaml:"log_level"`
-				ProgressBar  bool   `yaml:"progress_bar"`
-				LogToFile    bool   `yaml:"log_to_file"`
-				LogFileName  string `yaml:"log_file_name"`
-				LogMaxSizeMB int    `yaml:"log_max_size_mb"`
-			}{
-				LogLevel:     "info",
-				ProgressBar:  true,
-				LogToFile:    true,
-				LogFileName:  "compress.log",
-				LogMaxSizeMB: 10,
-			},
-		}
+		m.configData = defaultConfigData()
 		m.saveConfig()
 		return
 	}
@@ -192,6 +186,41 @@ func (m *Manager) loadConfig() {
 	yaml.Unmarshal(data, &m.configData)
 }
 
+// defaultConfigData возвращает конфигурацию по умолчанию
+func defaultConfigData() ConfigData {
+	var data ConfigData
+
+	data.Scanner.SourceDirectory = "./pdfs"
+	data.Scanner.TargetDirectory = "./compressed"
+	data.Scanner.ReplaceOriginal = false
+
+	data.Compression.Level = 50
+	data.Compression.Algorithm = "pdfcpu"
+	data.Compression.AutoStart = false
+	data.Compression.UniPDFLicenseKey = ""
+	data.Compression.EnableJPEG = false
+	data.Compression.EnablePNG = false
+	data.Compression.JPEGQuality = 30
+	data.Compression.PNGQuality = 25
+	data.Compression.SignaturePolicy = entities.SignaturePolicySkip
+	data.Compression.OutputValidation = entities.OutputValidationRelaxed
+	data.Compression.ColorReduction = entities.ColorReductionAuto
+	data.Compression.ImageConversion = entities.ImageConversionOff
+	data.Compression.ImageMetadata = entities.ImageMetadataKeepOrientationICC
+
+	data.Processing.ParallelWorkers = 2
+	data.Processing.TimeoutSeconds = 30
+	data.Processing.RetryAttempts = 3
+
+	data.Output.LogLevel = "info"
+	data.Output.ProgressBar = true
+	data.Output.LogToFile = true
+	data.Output.LogFileName = "compress.log"
+	data.Output.LogMaxSizeMB = 10
+
+	return data
+}
+
 // saveConfig сохраняет конфигурацию
 func (m *Manager) saveConfig() {
 	data, err := yaml.Marshal(&m.configData)
@@ -206,11 +235,13 @@ func (m *Manager) createUI() {
 	m.createMainMenu()
 	m.createConfigScreen()
 	m.createProcessingScreen()
+	m.createAnalysisScreen()
 	// m.createResultsScreen()
 
 	m.pages.AddPage("menu", m.mainMenu, true, true)
 	m.pages.AddPage("config", m.configForm, true, false)
 	m.pages.AddPage("processing", m.createProcessingLayout(), true, false)
+	m.pages.AddPage("analysis", m.createAnalysisLayout(), true, false)
 
 	m.currentScreen = entities.UIScreenMenu
 }
@@ -224,6 +255,9 @@ func (m *Manager) createMainMenu() {
 		AddItem("⚙️ Конфигурация", "Настроить параметры сжатия и обработки", '2', func() {
 			m.switchToScreen(entities.UIScreenConfig)
 		}).
+		AddItem("🔬 Анализ PDF", "Показать, из чего состоит размер выбранного PDF", '3', func() {
+			m.switchToScreen(entities.UIScreenAnalysis)
+		}).
 		AddItem("❌ Выход", "Закрыть приложение", 'q', func() {
 			m.Cleanup()
 			m.app.Stop()
@@ -257,12 +291,7 @@ func (m *Manager) createConfigScreen() {
 				m.configData.Compression.Level = level
 			}
 		}).
-		AddDropDown("Алгоритм", []string{"pdfcpu", "unipdf"}, func() int {
-			if m.configData.Compression.Algorithm == "unipdf" {
-				return 1
-			}
-			return 0
-		}(), func(option string, optionIndex int) {
+		AddDropDown("Алгоритм", algorithmOptions, algorithmIndex(m.configData.Compression.Algorithm), func(option string, optionIndex int) {
 			m.configData.Compression.Algorithm = option
 			m.updateLicenseFieldVisibility()
 		}).
@@ -275,9 +304,7 @@ func (m *Manager) createConfigScreen() {
 		AddCheckbox("Сжимать JPEG", m.configData.Compression.EnableJPEG, func(checked bool) {
 			m.configData.Compression.EnableJPEG = checked
 		}).
-		AddDropDown("Качество JPEG (%)", []string{"10", "15", "20", "25", "30", "35", "40", "45", "50"}, func() int {
-			return (m.configData.Compression.JPEGQuality - 10) / 5
-		}(), func(option string, optionIndex int) {
+		AddDropDown("Качество JPEG (%)", qualityOptions(m.configData.Compression.JPEGQuality), qualityIndex(m.configData.Compression.JPEGQuality), func(option string, optionIndex int) {
 			if quality, err := strconv.Atoi(option); err == nil {
 				m.configData.Compression.JPEGQuality = quality
 			}
@@ -285,13 +312,46 @@ func (m *Manager) createConfigScreen() {
 		AddCheckbox("Сжимать PNG", m.configData.Compression.EnablePNG, func(checked bool) {
 			m.configData.Compression.EnablePNG = checked
 		}).
-		AddDropDown("Качество PNG (%)", []string{"10", "15", "20", "25", "30", "35", "40", "45", "50"}, func() int {
-			return (m.configData.Compression.PNGQuality - 10) / 5
-		}(), func(option string, optionIndex int) {
+		AddDropDown("Качество PNG (%)", qualityOptions(m.configData.Compression.PNGQuality), qualityIndex(m.configData.Compression.PNGQuality), func(option string, optionIndex int) {
 			if quality, err := strconv.Atoi(option); err == nil {
 				m.configData.Compression.PNGQuality = quality
 			}
 		}).
+		AddCheckbox("Сохранять PDF/A", m.configData.Compression.PreservePDFA, func(checked bool) {
+			m.configData.Compression.PreservePDFA = checked
+		}).
+		AddInputField("Целевой размер PDF, MB (0 - выкл.)", formatTargetSize(m.configData.Compression.TargetSizeMB), 10, nil, func(text string) {
+			if size, err := strconv.ParseFloat(text, 64); err == nil && size >= 0 {
+				m.configData.Compression.TargetSizeMB = size
+			}
+		}).
+		AddDropDown("Проверка результата", outputValidationOptions, outputValidationIndex(m.configData.Compression.OutputValidation), func(option string, optionIndex int) {
+			m.configData.Compression.OutputValidation = option
+		}).
+		AddCheckbox("Отметка сжатия в метаданных", m.configData.Compression.Metadata.Stamp, func(checked bool) {
+			m.configData.Compression.Metadata.Stamp = checked
+		}).
+		AddCheckbox("Линеаризация (быстрый веб-просмотр)", m.configData.Compression.OptimizeForWeb, func(checked bool) {
+			m.configData.Compression.OptimizeForWeb = checked
+		}).
+		AddDropDown("Цвет изображений", colorReductionOptions, colorReductionIndex(m.configData.Compression.ColorReduction), func(option string, optionIndex int) {
+			m.configData.Compression.ColorReduction = option
+		}).
+		AddDropDown("Смена формата изображений", imageConversionOptions, imageConversionIndex(m.configData.Compression.ImageConversion), func(option string, optionIndex int) {
+			m.configData.Compression.ImageConversion = option
+		}).
+		AddCheckbox("Дизеринг палитры PNG", m.configData.Compression.PNGDithering, func(checked bool) {
+			m.configData.Compression.PNGDithering = checked
+		}).
+		AddDropDown("Метаданные изображений", imageMetadataOptions, imageMetadataIndex(m.configData.Compression.ImageMetadata), func(option string, optionIndex int) {
+			m.configData.Compression.ImageMetadata = option
+		}).
+		AddInputField("Рамка изображений, пикс. (ШxВ, пусто - выкл.)", formatImageBox(m.configData.Compression.ImageResize), 12, nil, func(text string) {
+			if width, height, ok := parseImageBox(text); ok {
+				m.configData.Compression.ImageResize.MaxWidth = imageBoxLimit(width)
+				m.configData.Compression.ImageResize.MaxHeight = imageBoxLimit(height)
+			}
+		}).
 		AddButton("Сохранить", func() {
 			m.saveConfig()
 			m.switchToScreen(entities.UIScreenMenu)
@@ -381,6 +441,9 @@ func (m *Manager) setupKeyBindings() {
 			case '2':
 				m.switchToScreen(entities.UIScreenConfig)
 				return nil
+			case '3':
+				m.switchToScreen(entities.UIScreenAnalysis)
+				return nil
 			case 'q', 'Q':
 				m.Cleanup()
 				m.app.Stop()
@@ -409,6 +472,11 @@ func (m *Manager) switchToScreen(screen entities.UIScreen) {
 		m.pages.SwitchToPage("config")
 	case entities.UIScreenProcessing:
 		m.pages.SwitchToPage("processing")
+	case entities.UIScreenAnalysis:
+		// Список файлов строится по текущей исходной директории
+		m.refreshAnalysisFiles()
+		m.pages.SwitchToPage("analysis")
+		m.app.SetFocus(m.analysisList)
 	}
 }
 
@@ -481,6 +549,12 @@ func (m *Manager) updateProgress(status entities.ProcessingStatus) {
 	if status.SkippedFiles > 0 {
 		progressText += fmt.Sprintf("\n  • Пропущено: [yellow]%d[white]", status.SkippedFiles)
 	}
+	if status.SkippedEncryptedFiles > 0 {
+		progressText += fmt.Sprintf("\n  • Зашифрованных без пароля: [yellow]%d[white]", status.SkippedEncryptedFiles)
+	}
+	if status.TargetMissedFiles > 0 {
+		progressText += fmt.Sprintf("\n  • Не уложились в целевой размер: [yellow]%d[white]", status.TargetMissedFiles)
+	}
 
 	// Статистика сжатия
 	if status.TotalOriginalSize > 0 {
@@ -497,6 +571,24 @@ func (m *Manager) updateProgress(status entities.ProcessingStatus) {
 		)
 	}
 
+	// Статистика по типам файлов
+	if len(status.TypeStats) > 0 {
+		progressText += "\n\n[green]🗂  По типам файлов:[white]"
+		for _, stats := range status.TypeStats {
+			progressText += fmt.Sprintf("\n  • %s: [cyan]%d[white]", stats.Type, stats.Files)
+			if stats.OriginalSize > 0 {
+				progressText += fmt.Sprintf(" | %.2f MB → %.2f MB | [green]−%.1f%%[white]",
+					float64(stats.OriginalSize)/1024/1024,
+					float64(stats.CompressedSize)/1024/1024,
+					stats.Compression(),
+				)
+			}
+			if stats.SkippedFiles > 0 || stats.FailedFiles > 0 {
+				progressText += fmt.Sprintf(" | пропущено [yellow]%d[white], ошибок [red]%d[white]", stats.SkippedFiles, stats.FailedFiles)
+			}
+		}
+	}
+
 	// Время выполнения
 	progressText += fmt.Sprintf(
 		"\n\n[yellow]⏱️  Время:[white]\n"+
@@ -709,6 +801,147 @@ func (m *Manager) updateLicenseFieldVisibility() {
 	}
 }
 
+// formatTargetSize форматирует целевой размер для поля ввода
+func formatTargetSize(sizeMB float64) string {
+	return strconv.FormatFloat(sizeMB, 'f', -1, 64)
+}
+
+// formatImageBox форматирует рамку изображений для поля ввода: 2560x1440, 2560x0 или пусто
+func formatImageBox(box entities.ImageResizeConfig) string {
+	if box.GetMaxWidth() == 0 && box.GetMaxHeight() == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%dx%d", box.GetMaxWidth(), box.GetMaxHeight())
+}
+
+// imageBoxLimit возвращает ограничение рамки для конфигурации; 0 — ограничение не задано
+func imageBoxLimit(value int) *int {
+	if value == 0 {
+		return nil
+	}
+	return &value
+}
+
+// parseImageBox разбирает рамку изображений из поля ввода; пустая строка выключает рамку
+func parseImageBox(text string) (int, int, bool) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return 0, 0, true
+	}
+	parts := strings.Split(strings.ToLower(text), "x")
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+	width, errWidth := strconv.Atoi(strings.TrimSpace(parts[0]))
+	height, errHeight := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if errWidth != nil || errHeight != nil || width < 0 || height < 0 {
+		return 0, 0, false
+	}
+	return width, height, true
+}
+
+// algorithmOptions алгоритмы сжатия PDF в порядке выпадающего списка
+var algorithmOptions = []string{"pdfcpu", "unipdf", "best", "external"}
+
+// algorithmIndex возвращает позицию алгоритма в выпадающем списке (pdfcpu по умолчанию)
+func algorithmIndex(algorithm string) int {
+	for i, option := range algorithmOptions {
+		if option == algorithm {
+			return i
+		}
+	}
+	return 0
+}
+
+// outputValidationOptions режимы проверки сжатых PDF в порядке выпадающего списка
+var outputValidationOptions = []string{entities.OutputValidationRelaxed, entities.OutputValidationStrict}
+
+// outputValidationIndex возвращает позицию режима проверки в выпадающем списке (relaxed по умолчанию)
+func outputValidationIndex(mode string) int {
+	for i, option := range outputValidationOptions {
+		if option == mode {
+			return i
+		}
+	}
+	return 0
+}
+
+// colorReductionOptions режимы цветности изображений в порядке выпадающего списка
+var colorReductionOptions = []string{
+	entities.ColorReductionAuto,
+	entities.ColorReductionOff,
+	entities.ColorReductionGray,
+	entities.ColorReductionBitonal,
+}
+
+// colorReductionIndex возвращает позицию режима цветности в выпадающем списке (auto по умолчанию)
+func colorReductionIndex(mode string) int {
+	for i, option := range colorReductionOptions {
+		if option == mode {
+			return i
+		}
+	}
+	return 0
+}
+
+// imageConversionOptions режимы смены формата изображений в порядке выпадающего списка
+var imageConversionOptions = []string{
+	entities.ImageConversionOff,
+	entities.ImageConversionJPEG,
+	entities.ImageConversionWebP,
+}
+
+// imageConversionIndex возвращает позицию режима смены формата в выпадающем списке (off по умолчанию)
+func imageConversionIndex(mode string) int {
+	for i, option := range imageConversionOptions {
+		if option == mode {
+			return i
+		}
+	}
+	return 0
+}
+
+// imageMetadataOptions политики метаданных изображений в порядке выпадающего списка
+var imageMetadataOptions = []string{
+	entities.ImageMetadataKeepOrientationICC,
+	entities.ImageMetadataStripAll,
+	entities.ImageMetadataKeepAll,
+}
+
+// qualityOptions варианты качества изображений для выпадающего списка: от 10 до 100 с шагом 10
+// и текущее значение, если оно задано в конфигурации вне этого шага
+func qualityOptions(current int) []string {
+	var options []string
+	for quality := 10; quality <= 100; quality += 10 {
+		if current > quality-10 && current < quality {
+			options = append(options, strconv.Itoa(current))
+		}
+		options = append(options, strconv.Itoa(quality))
+	}
+	return options
+}
+
+// qualityIndex возвращает позицию качества в списке qualityOptions
+func qualityIndex(current int) int {
+	for i, option := range qualityOptions(current) {
+		if option == strconv.Itoa(current) {
+			return i
+		}
+	}
+	return 0
+}
+
+// imageMetadataIndex возвращает позицию политики метаданных в выпадающем списке
+// (keep_orientation_icc по умолчанию)
+func imageMetadataIndex(policy string) int {
+	for i, option := range imageMetadataOptions {
+		if option == policy {
+			return i
+		}
+	}
+	return 0
+}
+
 // refreshConfigForm синхронизирует значения формы с текущими данными конфигурации
 func (m *Manager) refreshConfigForm() {
 	if m.configForm == nil {
@@ -733,12 +966,7 @@ func (m *Manager) refreshConfigForm() {
 	}
 	// 4: Алгоритм (DropDown)
 	if item := m.configForm.GetFormItem(4); item != nil {
-		dd := item.(*tview.DropDown)
-		if m.configData.Compression.Algorithm == "unipdf" {
-			dd.SetCurrentOption(1)
-		} else {
-			dd.SetCurrentOption(0)
-		}
+		item.(*tview.DropDown).SetCurrentOption(algorithmIndex(m.configData.Compression.Algorithm))
 	}
 	// 5: Лицензия UniPDF (Input)
 	if item := m.configForm.GetFormItem(5); item != nil {
@@ -748,6 +976,46 @@ func (m *Manager) refreshConfigForm() {
 	if item := m.configForm.GetFormItem(6); item != nil {
 		item.(*tview.Checkbox).SetChecked(m.configData.Compression.AutoStart)
 	}
+	// 11: Сохранять PDF/A (Checkbox)
+	if item := m.configForm.GetFormItem(11); item != nil {
+		item.(*tview.Checkbox).SetChecked(m.configData.Compression.PreservePDFA)
+	}
+	// 12: Целевой размер (Input)
+	if item := m.configForm.GetFormItem(12); item != nil {
+		item.(*tview.InputField).SetText(formatTargetSize(m.configData.Compression.TargetSizeMB))
+	}
+	// 13: Проверка результата (DropDown)
+	if item := m.configForm.GetFormItem(13); item != nil {
+		item.(*tview.DropDown).SetCurrentOption(outputValidationIndex(m.configData.Compression.OutputValidation))
+	}
+	// 14: Отметка сжатия в метаданных (Checkbox)
+	if item := m.configForm.GetFormItem(14); item != nil {
+		item.(*tview.Checkbox).SetChecked(m.configData.Compression.Metadata.Stamp)
+	}
+	// 15: Линеаризация (Checkbox)
+	if item := m.configForm.GetFormItem(15); item != nil {
+		item.(*tview.Checkbox).SetChecked(m.configData.Compression.OptimizeForWeb)
+	}
+	// 16: Цвет изображений (DropDown)
+	if item := m.configForm.GetFormItem(16); item != nil {
+		item.(*tview.DropDown).SetCurrentOption(colorReductionIndex(m.configData.Compression.ColorReduction))
+	}
+	// 17: Смена формата изображений (DropDown)
+	if item := m.configForm.GetFormItem(17); item != nil {
+		item.(*tview.DropDown).SetCurrentOption(imageConversionIndex(m.configData.Compression.ImageConversion))
+	}
+	// 18: Дизеринг палитры PNG (Checkbox)
+	if item := m.configForm.GetFormItem(18); item != nil {
+		item.(*tview.Checkbox).SetChecked(m.configData.Compression.PNGDithering)
+	}
+	// 19: Метаданные изображений (DropDown)
+	if item := m.configForm.GetFormItem(19); item != nil {
+		item.(*tview.DropDown).SetCurrentOption(imageMetadataIndex(m.configData.Compression.ImageMetadata))
+	}
+	// 20: Рамка изображений (InputField)
+	if item := m.configForm.GetFormItem(20); item != nil {
+		item.(*tview.InputField).SetText(formatImageBox(m.configData.Compression.ImageResize))
+	}
 
 	m.updateLicenseFieldVisibility()
 }
@@ -769,6 +1037,30 @@ func (m *Manager) GetConfig() *entities.Config {
 			EnablePNG:        m.configData.Compression.EnablePNG,
 			JPEGQuality:      m.configData.Compression.JPEGQuality,
 			PNGQuality:       m.configData.Compression.PNGQuality,
+			// Порог экономии
+			MinSavingsPercent: m.configData.Compression.MinSavingsPercent,
+			MinSavingsBytes:   m.configData.Compression.MinSavingsBytes,
+			Passwords:         m.configData.Compression.Passwords,
+			SignaturePolicy:   m.configData.Compression.SignaturePolicy,
+			PreservePDFA:      m.configData.Compression.PreservePDFA,
+			BestStrategies:    m.configData.Compression.BestStrategies,
+			TargetSizeMB:      m.configData.Compression.TargetSizeMB,
+			Profiles:          m.configData.Compression.Profiles,
+			OutputValidation:  m.configData.Compression.OutputValidation,
+			Metadata:          m.configData.Compression.Metadata,
+			KeepAnnotations:   m.configData.Compression.KeepAnnotations,
+			OptimizeForWeb:    m.configData.Compression.OptimizeForWeb,
+			QPDFPath:          m.configData.Compression.QPDFPath,
+			ColorReduction:    m.configData.Compression.ColorReduction,
+			ExternalCommand:   m.configData.Compression.ExternalCommand,
+			UniPDF:            m.configData.Compression.UniPDF,
+			Cleanup:           m.configData.Compression.Cleanup,
+			ImageConversion:   m.configData.Compression.ImageConversion,
+			CWebPPath:         m.configData.Compression.CWebPPath,
+			PNGDithering:      m.configData.Compression.PNGDithering,
+			PNGResize:         m.configData.Compression.PNGResize,
+			ImageMetadata:     m.configData.Compression.ImageMetadata,
+			ImageResize:       m.configData.Compression.ImageResize,
 		},
 		Processing: entities.ProcessingConfig{
 			ParallelWorkers: m.configData.Processing.ParallelWorkers,
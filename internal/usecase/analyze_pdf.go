@@ -0,0 +1,56 @@
+package usecases
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"compress/internal/domain/entities"
+	"compress/internal/domain/repositories"
+)
+
+// AnalyzePDFUseCase сценарий анализа размера PDF («куда ушли байты»)
+type AnalyzePDFUseCase struct {
+	analyzer repositories.PDFAnalyzer
+	fileRepo repositories.FileRepository
+}
+
+// NewAnalyzePDFUseCase создает новый сценарий анализа PDF
+func NewAnalyzePDFUseCase(
+	analyzer repositories.PDFAnalyzer,
+	fileRepo repositories.FileRepository,
+) *AnalyzePDFUseCase {
+	return &AnalyzePDFUseCase{
+		analyzer: analyzer,
+		fileRepo: fileRepo,
+	}
+}
+
+// Execute выполняет анализ PDF файла
+func (uc *AnalyzePDFUseCase) Execute(path string) (*entities.PDFAnalysis, error) {
+	if !uc.fileRepo.FileExists(path) {
+		return nil, entities.ErrFileNotFound
+	}
+
+	analysis, err := uc.analyzer.Analyze(path)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка анализа файла: %w", err)
+	}
+
+	return analysis, nil
+}
+
+// ListFiles возвращает PDF файлы директории, доступные для анализа
+func (uc *AnalyzePDFUseCase) ListFiles(directory string) ([]string, error) {
+	if !uc.fileRepo.FileExists(directory) {
+		return nil, entities.ErrDirectoryNotFound
+	}
+	return uc.fileRepo.ListPDFFiles(directory)
+}
+
+// WriteReport записывает результат анализа в формате JSON
+func (uc *AnalyzePDFUseCase) WriteReport(w io.Writer, analysis *entities.PDFAnalysis) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(analysis)
+}
@@ -90,7 +90,7 @@ func (uc *CompressDirectoryUseCase) Execute(inputDir, outputDir string, compress
 		}
 
 		// Выполняем сжатие
-		compressionResult, err := uc.compressor.Compress(inputFile, outputFile, config)
+		compressionResult, err := uc.compressor.Compress(inputFile, outputFile, config.WithSource(fileInfo))
 		if err != nil {
 			result.Errors = append(result.Errors, fmt.Errorf("ошибка сжатия файла %s: %w", fileName, err))
 			result.FailedCount++
@@ -99,6 +99,7 @@ func (uc *CompressDirectoryUseCase) Execute(inputDir, outputDir string, compress
 
 		// Устанавливаем исходный размер и вычисляем коэффициент сжатия
 		compressionResult.OriginalSize = fileInfo.Size
+		compressionResult.Pages = fileInfo.Pages
 		compressionResult.CalculateCompressionRatio()
 
 		result.Results = append(result.Results, compressionResult)
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"compress/internal/domain/entities"
 	"compress/internal/domain/repositories"
@@ -12,8 +13,9 @@ import (
 
 // CompressImageUseCase обрабатывает сжатие изображений
 type CompressImageUseCase struct {
-	logger     repositories.Logger
-	compressor compressors.ImageCompressor
+	logger           repositories.Logger
+	compressor       compressors.ImageCompressor
+	progressReporter func(entities.ProcessingStatus)
 }
 
 // NewCompressImageUseCase создает новый UseCase для сжатия изображений
@@ -24,11 +26,49 @@ func NewCompressImageUseCase(logger repositories.Logger, compressor compressors.
 	}
 }
 
-// CompressImage сжимает одно изображение
-func (uc *CompressImageUseCase) CompressImage(inputPath, outputPath string, config *entities.AppCompressionConfig) error {
+// SetProgressReporter устанавливает функцию для отчета о прогрессе
+func (uc *CompressImageUseCase) SetProgressReporter(reporter func(entities.ProcessingStatus)) {
+	uc.progressReporter = reporter
+}
+
+// reportProgress отправляет обновление прогресса
+func (uc *CompressImageUseCase) reportProgress(status *entities.ProcessingStatus) {
+	if uc.progressReporter != nil {
+		uc.progressReporter(*status)
+	}
+}
+
+// CompressImage сжимает одно изображение и возвращает формат записанного файла.
+// finalPath — путь, где окажется результат: файл с новым расширением рядом с ним или
+// с исходным файлом не перезаписывается, такая смена формата не рассматривается
+func (uc *CompressImageUseCase) CompressImage(inputPath, outputPath, finalPath string, config *entities.AppCompressionConfig) (string, error) {
 	format := compressors.GetImageFormat(inputPath)
 	if format == "" {
-		return fmt.Errorf("неподдерживаемый формат изображения: %s", inputPath)
+		return "", fmt.Errorf("неподдерживаемый формат изображения: %s", inputPath)
+	}
+
+	// Режим цветности auto определяется тем же уровнем сжатия, что и для PDF
+	levelConfig := entities.NewCompressionConfig(config.Level).WithColorReduction(config.ColorReduction)
+	conversion := config.GetImageConversion()
+	options := compressors.ImageOptions{
+		ConvertGrayscale: levelConfig.ConvertGrayscale,
+		ConvertBitonal:   levelConfig.ConvertBitonal,
+		ConvertToJPEG:    conversion == entities.ImageConversionJPEG || conversion == entities.ImageConversionWebP,
+		WebP:             conversion == entities.ImageConversionWebP,
+		Metadata:         config.GetImageMetadata(),
+		MaxWidth:         config.ImageResize.GetMaxWidth(),
+		MaxHeight:        config.ImageResize.GetMaxHeight(),
+		MaxMegapixels:    config.ImageResize.GetMaxMegapixels(),
+		Upscale:          config.ImageResize.GetUpscale(),
+		Filter:           config.ImageResize.GetFilter(),
+	}
+	if options.ConvertToJPEG && format != compressors.ImageFormatJPEG && convertedPathTaken(inputPath, finalPath, compressors.ImageFormatJPEG) {
+		options.ConvertToJPEG = false
+		uc.logger.Warning(fmt.Sprintf("Перевод в JPEG отключен: файл с таким именем уже существует: %s", inputPath))
+	}
+	if options.WebP && convertedPathTaken(inputPath, finalPath, compressors.ImageFormatWebP) {
+		options.WebP = false
+		uc.logger.Warning(fmt.Sprintf("Перевод в WebP отключен: файл с таким именем уже существует: %s", inputPath))
 	}
 
 	// Проверяем, включено ли сжатие для данного формата
@@ -36,113 +76,291 @@ func (uc *CompressImageUseCase) CompressImage(inputPath, outputPath string, conf
 	case "jpeg":
 		if !config.EnableJPEG {
 			uc.logger.Info(fmt.Sprintf("Пропуск JPEG файла (сжатие отключено): %s", inputPath))
-			return nil
+			return "", nil
 		}
-		return uc.compressor.CompressJPEG(inputPath, outputPath, config.JPEGQuality)
+		options.Quality = config.JPEGQuality
+		return uc.compressor.CompressJPEG(inputPath, outputPath, options)
 	case "png":
 		if !config.EnablePNG {
 			uc.logger.Info(fmt.Sprintf("Пропуск PNG файла (сжатие отключено): %s", inputPath))
-			return nil
+			return "", nil
 		}
-		return uc.compressor.CompressPNG(inputPath, outputPath, config.PNGQuality)
+		options.Quality = config.PNGQuality
+		options.Dither = config.PNGDithering
+		options.Resize = config.PNGResize
+		return uc.compressor.CompressPNG(inputPath, outputPath, options)
 	default:
-		return fmt.Errorf("неподдерживаемый формат изображения: %s", format)
+		return "", fmt.Errorf("неподдерживаемый формат изображения: %s", format)
 	}
 }
 
-// ProcessImagesInDirectory обрабатывает все изображения в директории
-func (uc *CompressImageUseCase) ProcessImagesInDirectory(sourceDir, targetDir string, config *entities.AppCompressionConfig, replaceOriginal bool) (*ProcessingResult, error) {
-	result := &ProcessingResult{
-		ProcessedFiles:  make([]string, 0),
-		FailedFiles:     make([]ProcessingError, 0),
-		SuccessfulFiles: 0,
-		TotalFiles:      0,
+// convertedPathTaken проверяет, существует ли файл с расширением формата format рядом
+// с исходным файлом или по итоговому пути
+func convertedPathTaken(inputPath, finalPath, format string) bool {
+	for _, p := range []string{inputPath, finalPath} {
+		if _, err := os.Stat(convertedPath(p, format)); err == nil {
+			return true
+		}
 	}
+	return false
+}
+
+// convertedPath возвращает путь с расширением формата изображения
+func convertedPath(p, format string) string {
+	return strings.TrimSuffix(p, filepath.Ext(p)) + compressors.ImageExtension(format)
+}
 
+// ProcessImagesInDirectory обрабатывает все изображения в директории
+func (uc *CompressImageUseCase) ProcessImagesInDirectory(sourceDir, targetDir string, config *entities.AppCompressionConfig, replaceOriginal bool) (*ProcessingResult, error) {
 	// Если включены изображения, проверяем настройки
 	if !config.EnableJPEG && !config.EnablePNG {
 		uc.logger.Info("Сжатие изображений отключено в конфигурации")
-		return result, nil
+		return newProcessingResult(), nil
+	}
+
+	status := entities.NewProcessingStatus(0)
+	status.SetPhase(entities.PhaseScanning, "Сканирование изображений...")
+	uc.reportProgress(status)
+
+	files, err := uc.FindImages(sourceDir, config)
+	if err != nil {
+		status.Fail(err)
+		uc.reportProgress(status)
+		return newProcessingResult(), err
 	}
+	status.TotalFiles = len(files)
 
-	// Рекурсивно обходим директорию
+	result := uc.ProcessImages(files, sourceDir, targetDir, config, replaceOriginal, status)
+	status.Complete()
+	uc.reportProgress(status)
+	return result, nil
+}
+
+// FindImages возвращает изображения включенных форматов в директории и ее поддиректориях
+func (uc *CompressImageUseCase) FindImages(sourceDir string, config *entities.AppCompressionConfig) ([]string, error) {
+	var files []string
 	err := filepath.Walk(sourceDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			uc.logger.Error(fmt.Sprintf("Ошибка доступа к файлу %s: %v", path, err))
 			return nil // Продолжаем обработку других файлов
 		}
 
-		// Пропускаем директории
-		if info.IsDir() {
-			return nil
+		// Пропускаем директории, не изображения и изображения с отключенным сжатием
+		if !info.IsDir() && compressors.IsImageFile(path) && isImageFormatEnabled(path, config) {
+			files = append(files, path)
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка обхода директории %s: %w", sourceDir, err)
+	}
+	return files, nil
+}
 
-		// Проверяем, является ли файл изображением
-		if !compressors.IsImageFile(path) {
-			return nil // Не изображение, пропускаем
-		}
+// ProcessImages сжимает найденные изображения, добавляя результат каждого в status
+// и отправляя обновление прогресса. status.TotalFiles уже должен учитывать files
+func (uc *CompressImageUseCase) ProcessImages(files []string, sourceDir, targetDir string, config *entities.AppCompressionConfig, replaceOriginal bool, status *entities.ProcessingStatus) *ProcessingResult {
+	result := newProcessingResult()
+	result.TotalFiles = len(files)
 
-		result.TotalFiles++
+	status.SetPhase(entities.PhaseCompressing, "Сжатие изображений...")
+	uc.reportProgress(status)
 
-		// Определяем путь выходного файла
-		var outputPath string
-		if replaceOriginal {
-			outputPath = path
-		} else {
-			relPath, err := filepath.Rel(sourceDir, path)
-			if err != nil {
-				uc.logger.Error(fmt.Sprintf("Не удалось получить относительный путь для %s: %v", path, err))
-				result.FailedFiles = append(result.FailedFiles, ProcessingError{
-					FilePath: path,
-					Error:    err,
-				})
-				return nil
-			}
-			outputPath = filepath.Join(targetDir, relPath)
-
-			// Создаем директорию для выходного файла
-			outputDir := filepath.Dir(outputPath)
-			if err := os.MkdirAll(outputDir, 0755); err != nil {
-				uc.logger.Error(fmt.Sprintf("Не удалось создать директорию %s: %v", outputDir, err))
-				result.FailedFiles = append(result.FailedFiles, ProcessingError{
-					FilePath: path,
-					Error:    err,
-				})
-				return nil
-			}
-		}
-
-		// Сжимаем изображение
-		uc.logger.Info(fmt.Sprintf("Сжатие изображения: %s", path))
-		err = uc.CompressImage(path, outputPath, config)
-		if err != nil {
-			uc.logger.Error(fmt.Sprintf("Ошибка сжатия изображения %s: %v", path, err))
+	for _, path := range files {
+		fileResult := uc.processImage(path, sourceDir, targetDir, config, replaceOriginal, status, result)
+		if fileResult.Error != nil {
+			uc.logger.Error(fmt.Sprintf("Ошибка сжатия изображения %s: %v", path, fileResult.Error))
 			result.FailedFiles = append(result.FailedFiles, ProcessingError{
 				FilePath: path,
-				Error:    err,
+				Error:    fileResult.Error,
 			})
-		} else {
-			result.ProcessedFiles = append(result.ProcessedFiles, path)
-			result.SuccessfulFiles++
-			uc.logger.Info(fmt.Sprintf("Изображение успешно сжато: %s", path))
 		}
+		result.Results = append(result.Results, fileResult)
+		status.AddResult(fileResult)
+		uc.reportProgress(status)
+	}
 
-		return nil
-	})
+	return result
+}
 
+// processImage сжимает одно изображение из директории и возвращает его результат
+func (uc *CompressImageUseCase) processImage(path, sourceDir, targetDir string, config *entities.AppCompressionConfig, replaceOriginal bool, status *entities.ProcessingStatus, result *ProcessingResult) *entities.CompressionResult {
+	fileResult := &entities.CompressionResult{
+		CurrentFile: path,
+		FileType:    imageFileType(path),
+	}
+	fail := func(err error) *entities.CompressionResult {
+		fileResult.Success = false
+		fileResult.Error = err
+		return fileResult
+	}
+
+	info, err := os.Stat(path)
 	if err != nil {
-		return result, fmt.Errorf("ошибка обхода директории %s: %w", sourceDir, err)
+		return fail(fmt.Errorf("ошибка получения информации о файле: %w", err))
 	}
+	fileResult.OriginalSize = info.Size()
+	status.SetCurrentFile(path, info.Size())
+	uc.reportProgress(status)
 
-	return result, nil
+	relPath, err := filepath.Rel(sourceDir, path)
+	if err != nil {
+		return fail(fmt.Errorf("не удалось получить относительный путь: %w", err))
+	}
+
+	// Определяем путь выходного файла
+	var outputPath, finalPath string
+	if replaceOriginal {
+		// Сжимаем во временный файл, оригинал заменяется только после проверки экономии
+		outputPath, finalPath = path+".tmp", path
+	} else {
+		outputPath = filepath.Join(targetDir, relPath)
+		finalPath = outputPath
+
+		// Создаем директорию для выходного файла
+		outputDir := filepath.Dir(outputPath)
+		if err := os.MkdirAll(outputDir, 0755); err != nil {
+			return fail(fmt.Errorf("не удалось создать директорию %s: %w", outputDir, err))
+		}
+	}
+
+	// Сжимаем изображение
+	uc.logger.Info(fmt.Sprintf("Сжатие изображения: %s", path))
+	// Профиль может задать для группы файлов уровень, режим цветности, качество и рамку
+	fileConfig, profile := config.ForFile(relPath)
+	fileResult.Profile = profile
+	format, err := uc.CompressImage(path, outputPath, finalPath, fileConfig)
+	if err == nil {
+		err = uc.finishImage(path, outputPath, format, config, replaceOriginal, result, fileResult)
+	}
+	if err != nil {
+		if replaceOriginal {
+			_ = os.Remove(outputPath)
+		}
+		return fail(err)
+	}
+	return fileResult
+}
+
+// newProcessingResult создает пустой результат обработки изображений
+func newProcessingResult() *ProcessingResult {
+	return &ProcessingResult{
+		ProcessedFiles:  make([]string, 0),
+		FailedFiles:     make([]ProcessingError, 0),
+		SkippedFiles:    make([]ProcessingSkip, 0),
+		ConvertedFiles:  make([]ProcessingConversion, 0),
+		Results:         make([]*entities.CompressionResult, 0),
+		SuccessfulFiles: 0,
+		TotalFiles:      0,
+	}
+}
+
+// imageFileType возвращает тип файла изображения для статистики
+func imageFileType(path string) entities.FileType {
+	if compressors.GetImageFormat(path) == compressors.ImageFormatPNG {
+		return entities.FileTypePNG
+	}
+	return entities.FileTypeJPEG
+}
+
+// finishImage проверяет экономию после сжатия изображения: принимает результат
+// (в режиме замены подменяет оригинал) или оставляет файл исходным. Если изображение
+// записано в другом формате, результат получает новое расширение. Размеры и исход
+// записываются в fileResult
+func (uc *CompressImageUseCase) finishImage(path, outputPath, format string, config *entities.AppCompressionConfig, replaceOriginal bool, result *ProcessingResult, fileResult *entities.CompressionResult) error {
+	outputInfo, err := os.Stat(outputPath)
+	if err != nil {
+		return fmt.Errorf("не удалось получить информацию о сжатом файле: %w", err)
+	}
+	fileResult.Success = true
+	fileResult.CompressedSize = outputInfo.Size()
+	fileResult.CalculateCompressionRatio()
+
+	if ok, reason := config.CheckSavings(fileResult.OriginalSize, fileResult.CompressedSize); !ok {
+		if err := keepOriginalFile(path, outputPath, replaceOriginal); err != nil {
+			return err
+		}
+		fileResult.Skip(reason)
+		result.SkippedFiles = append(result.SkippedFiles, ProcessingSkip{
+			FilePath: path,
+			Reason:   reason,
+		})
+		uc.logger.Warning(fmt.Sprintf("Изображение оставлено без изменений (%s): %s", reason, path))
+		return nil
+	}
+
+	converted := format != compressors.GetImageFormat(path)
+	if replaceOriginal {
+		finalPath := path
+		if converted {
+			finalPath = convertedPath(path, format)
+		}
+		if err := os.Rename(outputPath, finalPath); err != nil {
+			return fmt.Errorf("не удалось заменить оригинальный файл: %w", err)
+		}
+		if converted {
+			if err := os.Remove(path); err != nil {
+				return fmt.Errorf("не удалось удалить оригинальный файл после смены формата: %w", err)
+			}
+		}
+		outputPath = finalPath
+	} else if converted {
+		finalPath := convertedPath(outputPath, format)
+		if err := os.Rename(outputPath, finalPath); err != nil {
+			return fmt.Errorf("не удалось переименовать сжатый файл: %w", err)
+		}
+		outputPath = finalPath
+	}
+
+	if converted {
+		result.ConvertedFiles = append(result.ConvertedFiles, ProcessingConversion{
+			From: path,
+			To:   outputPath,
+		})
+		uc.logger.Info(fmt.Sprintf("Изображение сохранено в формате %s: %s → %s", strings.ToUpper(format), path, outputPath))
+	}
+
+	result.ProcessedFiles = append(result.ProcessedFiles, path)
+	result.SuccessfulFiles++
+	uc.logger.Info(fmt.Sprintf("Изображение успешно сжато: %s", path))
+	return nil
+}
+
+// isImageFormatEnabled проверяет, включено ли сжатие для формата изображения
+func isImageFormatEnabled(path string, config *entities.AppCompressionConfig) bool {
+	switch compressors.GetImageFormat(path) {
+	case "jpeg":
+		return config.EnableJPEG
+	case "png":
+		return config.EnablePNG
+	default:
+		return false
+	}
 }
 
 // ProcessingResult результат обработки изображений
 type ProcessingResult struct {
 	ProcessedFiles  []string
 	FailedFiles     []ProcessingError
+	SkippedFiles    []ProcessingSkip
 	SuccessfulFiles int
 	TotalFiles      int
+	// Изображения, записанные в другом формате (с новым расширением)
+	ConvertedFiles []ProcessingConversion
+	// Результаты сжатия каждого изображения в порядке обработки
+	Results []*entities.CompressionResult
+}
+
+// ProcessingConversion исходный путь изображения и путь результата в новом формате
+type ProcessingConversion struct {
+	From string
+	To   string
+}
+
+// ProcessingSkip файл, оставленный без изменений
+type ProcessingSkip struct {
+	FilePath string
+	Reason   string
 }
 
 // ProcessingError ошибка обработки файла
@@ -60,13 +60,14 @@ func (uc *CompressPDFUseCase) Execute(inputPath string, outputPath string, compr
 	}
 
 	// Выполняем сжатие
-	result, err := uc.compressor.Compress(inputPath, outputPath, config)
+	result, err := uc.compressor.Compress(inputPath, outputPath, config.WithSource(fileInfo))
 	if err != nil {
 		return nil, fmt.Errorf("ошибка сжатия файла: %w", err)
 	}
 
 	// Устанавливаем исходный размер
 	result.OriginalSize = fileInfo.Size
+	result.Pages = fileInfo.Pages
 	result.CalculateCompressionRatio()
 
 	return result, nil
@@ -0,0 +1,38 @@
+package usecases
+
+import (
+	"os"
+
+	"compress/internal/domain/entities"
+	"compress/internal/domain/repositories"
+)
+
+// fileInfoCache сведения о файлах, уже прочитанные воркером. Запись действительна, пока
+// размер и время изменения файла совпадают с сохраненными
+type fileInfoCache struct {
+	fileRepo repositories.FileRepository
+	entries  map[string]*entities.PDFDocument
+}
+
+// newFileInfoCache создает кэш воркера
+func newFileInfoCache(fileRepo repositories.FileRepository) *fileInfoCache {
+	return &fileInfoCache{fileRepo: fileRepo, entries: map[string]*entities.PDFDocument{}}
+}
+
+// get возвращает сведения о файле, читая его только при первом обращении или после изменения
+func (c *fileInfoCache) get(path string) (*entities.PDFDocument, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if doc, ok := c.entries[path]; ok && doc.Size == info.Size() && doc.ModifiedTime.Equal(info.ModTime()) {
+		return doc, nil
+	}
+
+	doc, err := c.fileRepo.GetFileInfo(path)
+	if err != nil {
+		return nil, err
+	}
+	c.entries[path] = doc
+	return doc, nil
+}
@@ -0,0 +1,54 @@
+package usecases
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"compress/internal/domain/entities"
+)
+
+// keepOriginalFile отбрасывает результат сжатия и оставляет файл исходным:
+// в режиме замены удаляет временный файл, иначе копирует оригинал в выходной путь
+func keepOriginalFile(inputPath, outputPath string, replaceOriginal bool) error {
+	if replaceOriginal {
+		if err := os.Remove(outputPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("не удалось удалить временный файл %s: %w", outputPath, err)
+		}
+		return nil
+	}
+
+	return copyFile(inputPath, outputPath)
+}
+
+// keepOriginalResult оставляет файл исходным и отмечает результат пропущенным по причине reason.
+// Если сохранить оригинал не удалось, результат становится ошибкой
+func keepOriginalResult(result *entities.CompressionResult, inputPath, outputPath string, replaceOriginal bool, reason string) {
+	if err := keepOriginalFile(inputPath, outputPath, replaceOriginal); err != nil {
+		result.Success = false
+		result.Error = fmt.Errorf("ошибка сохранения оригинального файла: %w", err)
+		return
+	}
+	result.Skip(reason)
+}
+
+// copyFile копирует файл src в dst, перезаписывая dst
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("не удалось открыть файл %s: %w", src, err)
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("не удалось создать файл %s: %w", dst, err)
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return fmt.Errorf("не удалось скопировать файл %s: %w", src, err)
+	}
+
+	return out.Close()
+}
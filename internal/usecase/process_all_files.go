@@ -12,9 +12,10 @@ import (
 
 // ProcessAllFilesUseCase сценарий для обработки всех поддерживаемых типов файлов
 type ProcessAllFilesUseCase struct {
-	pdfProcessor   *ProcessPDFsUseCase
-	imageProcessor *CompressImageUseCase
-	logger         repositories.Logger
+	pdfProcessor     *ProcessPDFsUseCase
+	imageProcessor   *CompressImageUseCase
+	logger           repositories.Logger
+	progressReporter func(entities.ProcessingStatus)
 }
 
 // NewProcessAllFilesUseCase создает новый сценарий обработки всех файлов
@@ -30,53 +31,103 @@ func NewProcessAllFilesUseCase(
 	}
 }
 
-// Execute выполняет обработку всех поддерживаемых файлов
+// SetProgressReporter устанавливает функцию для отчета о прогрессе обработки PDF и изображений
+func (uc *ProcessAllFilesUseCase) SetProgressReporter(reporter func(entities.ProcessingStatus)) {
+	uc.progressReporter = reporter
+	uc.pdfProcessor.SetProgressReporter(reporter)
+	uc.imageProcessor.SetProgressReporter(reporter)
+}
+
+// reportProgress отправляет обновление прогресса
+func (uc *ProcessAllFilesUseCase) reportProgress(status *entities.ProcessingStatus) {
+	if uc.progressReporter != nil {
+		uc.progressReporter(*status)
+	}
+}
+
+// Execute выполняет обработку всех поддерживаемых файлов. PDF и изображения учитываются
+// в одном статусе: общий прогресс, суммарная экономия и статистика по типам файлов
 func (uc *ProcessAllFilesUseCase) Execute(config *entities.Config) error {
 	uc.logger.Info("Начинаем обработку файлов")
 	uc.logger.Info("Исходная директория: %s", config.Scanner.SourceDirectory)
 
-	var processedPDFs, processedImages bool
+	processPDFs, processImages := uc.shouldProcessPDFs(config), uc.shouldProcessImages(config)
+	if !processPDFs && !processImages {
+		uc.logger.Warning("Не выбрано ни одного типа файлов для обработки")
+		return fmt.Errorf("не выбрано ни одного типа файлов для обработки")
+	}
+
+	status := entities.NewProcessingStatus(0)
+
+	// Конфигурация проверяется один раз до обработки PDF и изображений
+	if err := config.Compression.Validate(); err != nil {
+		err = fmt.Errorf("ошибка валидации конфигурации: %w", err)
+		uc.logger.Error("%v", err)
+		status.Fail(err)
+		uc.reportProgress(status)
+		return err
+	}
+
+	// Изображения находим заранее, чтобы прогресс сразу учитывал все файлы
+	var images []string
+	if processImages {
+		status.SetPhase(entities.PhaseScanning, "Сканирование изображений...")
+		uc.reportProgress(status)
+		var err error
+		images, err = uc.imageProcessor.FindImages(config.Scanner.SourceDirectory, &config.Compression)
+		if err != nil {
+			uc.logger.Error("Ошибка обработки изображений: %v", err)
+			status.Fail(err)
+			uc.reportProgress(status)
+			return fmt.Errorf("ошибка обработки изображений: %w", err)
+		}
+		status.TotalFiles = len(images)
+	}
 
 	// Обрабатываем PDF файлы
-	if uc.shouldProcessPDFs(config) {
+	if processPDFs {
 		uc.logger.Info("Обработка PDF файлов...")
-		err := uc.pdfProcessor.Execute(config)
+		err := uc.pdfProcessor.ExecuteWithStatus(config, status)
 		if err != nil {
 			uc.logger.Error("Ошибка обработки PDF файлов: %v", err)
 			return fmt.Errorf("ошибка обработки PDF файлов: %w", err)
 		}
-		processedPDFs = true
 		uc.logger.Info("Обработка PDF файлов завершена")
 	}
 
 	// Обрабатываем изображения
-	if uc.shouldProcessImages(config) {
+	if processImages {
 		uc.logger.Info("Обработка изображений...")
-		result, err := uc.imageProcessor.ProcessImagesInDirectory(
+		result := uc.imageProcessor.ProcessImages(
+			images,
 			config.Scanner.SourceDirectory,
 			config.Scanner.TargetDirectory,
 			&config.Compression,
 			config.Scanner.ReplaceOriginal,
+			status,
 		)
-		if err != nil {
-			uc.logger.Error("Ошибка обработки изображений: %v", err)
-			return fmt.Errorf("ошибка обработки изображений: %w", err)
-		}
 
 		// Логируем результаты обработки изображений
-		uc.logger.Info("Обработка изображений завершена. Всего файлов: %d, Успешно: %d, Ошибок: %d",
-			result.TotalFiles, result.SuccessfulFiles, len(result.FailedFiles))
+		uc.logger.Info("Обработка изображений завершена. Всего файлов: %d, Успешно: %d, Пропущено: %d, Ошибок: %d",
+			result.TotalFiles, result.SuccessfulFiles, len(result.SkippedFiles), len(result.FailedFiles))
+
+		for _, converted := range result.ConvertedFiles {
+			uc.logger.Info("Изображение %s сохранено как %s", converted.From, converted.To)
+		}
+
+		for _, skipped := range result.SkippedFiles {
+			uc.logger.Warning("Изображение %s оставлено без изменений: %s", skipped.FilePath, skipped.Reason)
+		}
 
 		for _, failed := range result.FailedFiles {
 			uc.logger.Error("Не удалось обработать изображение %s: %v", failed.FilePath, failed.Error)
 		}
-
-		processedImages = true
 	}
 
-	if !processedPDFs && !processedImages {
-		uc.logger.Warning("Не выбрано ни одного типа файлов для обработки")
-		return fmt.Errorf("не выбрано ни одного типа файлов для обработки")
+	status.Complete()
+	uc.reportProgress(status)
+	if status.ProcessedFiles > 0 {
+		logProcessingSummary(uc.logger, status)
 	}
 
 	uc.logger.Info("Обработка всех файлов завершена успешно")
@@ -1,11 +1,11 @@
 package usecases
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"sync"
-	"time"
 
 	"compress/internal/domain/entities"
 	"compress/internal/domain/repositories"
@@ -18,6 +18,10 @@ type ProcessPDFsUseCase struct {
 	configRepo       repositories.ConfigRepository
 	logger           repositories.Logger
 	progressReporter func(entities.ProcessingStatus)
+	// Сборка компрессора по конфигурации запуска (алгоритм, стратегии, внешняя команда)
+	compressorFactory func(config *entities.Config) repositories.PDFCompressor
+	// Сжатие без перезаписи подписанных ревизий (политика подписей incremental)
+	revisionCompressor repositories.PDFCompressor
 }
 
 // NewProcessPDFsUseCase создает новый сценарий обработки PDF
@@ -40,6 +44,27 @@ func (uc *ProcessPDFsUseCase) SetProgressReporter(reporter func(entities.Process
 	uc.progressReporter = reporter
 }
 
+// SetCompressorFactory устанавливает сборку компрессора по конфигурации запуска.
+// Без нее используется компрессор, переданный при создании сценария
+func (uc *ProcessPDFsUseCase) SetCompressorFactory(factory func(config *entities.Config) repositories.PDFCompressor) {
+	uc.compressorFactory = factory
+}
+
+// SetRevisionCompressor устанавливает компрессор, который сжимает ревизии документа по отдельности,
+// копируя подписанные без изменений. Без него подписанные документы при политике incremental
+// не сжимаются, а старые ревизии отбрасываются на любом уровне
+func (uc *ProcessPDFsUseCase) SetRevisionCompressor(compressor repositories.PDFCompressor) {
+	uc.revisionCompressor = compressor
+}
+
+// compressorFor возвращает компрессор для запуска с указанной конфигурацией
+func (uc *ProcessPDFsUseCase) compressorFor(config *entities.Config) repositories.PDFCompressor {
+	if uc.compressorFactory != nil {
+		return uc.compressorFactory(config)
+	}
+	return uc.compressor
+}
+
 // reportProgress отправляет обновление прогресса
 func (uc *ProcessPDFsUseCase) reportProgress(status *entities.ProcessingStatus) {
 	if uc.progressReporter != nil {
@@ -49,8 +74,25 @@ func (uc *ProcessPDFsUseCase) reportProgress(status *entities.ProcessingStatus)
 
 // Execute выполняет автоматическую обработку PDF файлов согласно конфигурации
 func (uc *ProcessPDFsUseCase) Execute(config *entities.Config) error {
-	// Фаза 1: Инициализация
 	status := entities.NewProcessingStatus(0)
+	if err := uc.ExecuteWithStatus(config, status); err != nil {
+		return err
+	}
+
+	// Финальная фаза
+	status.Complete()
+	uc.reportProgress(status)
+	if status.ProcessedFiles > 0 {
+		logProcessingSummary(uc.logger, status)
+	}
+	return nil
+}
+
+// ExecuteWithStatus обрабатывает PDF файлы, добавляя их в общий статус обработки: число
+// найденных файлов прибавляется к status.TotalFiles, результаты — к статистике. Статус
+// не завершается, чтобы после PDF в нем же могли быть обработаны другие файлы
+func (uc *ProcessPDFsUseCase) ExecuteWithStatus(config *entities.Config, status *entities.ProcessingStatus) error {
+	// Фаза 1: Инициализация
 	status.SetPhase(entities.PhaseInitializing, "Инициализация обработки...")
 	uc.reportProgress(status)
 
@@ -67,6 +109,20 @@ func (uc *ProcessPDFsUseCase) Execute(config *entities.Config) error {
 
 	uc.logInfo("║ Алгоритм: %s", config.Compression.Algorithm)
 	uc.logInfo("║ Уровень сжатия: %d%%", config.Compression.Level)
+	if config.Compression.TargetSizeMB > 0 {
+		uc.logInfo("║ Целевой размер: %.2f MB", config.Compression.TargetSizeMB)
+	}
+	if len(config.Compression.Profiles) > 0 {
+		uc.logInfo("║ Профилей: %d", len(config.Compression.Profiles))
+	}
+	if config.Compression.PreservePDFA {
+		uc.logInfo("║ Режим PDF/A: соответствие сохраняется")
+	}
+	if config.Compression.OptimizeForWeb {
+		uc.logInfo("║ Линеаризация: включена (быстрый веб-просмотр)")
+	}
+	uc.logInfo("║ Цветность изображений: %s", config.Compression.GetColorReduction())
+	uc.logInfo("║ Проверка результата: %s", config.Compression.GetOutputValidation())
 	uc.logInfo("║ Параллельных воркеров: %d", config.Processing.ParallelWorkers)
 	uc.logInfo("╚════════════════════════════════════════════════════════════")
 
@@ -103,16 +159,21 @@ func (uc *ProcessPDFsUseCase) Execute(config *entities.Config) error {
 
 	if len(files) == 0 {
 		uc.logWarning("⚠️  PDF файлы не найдены в директории: %s", config.Scanner.SourceDirectory)
-		status.Complete()
-		uc.reportProgress(status)
 		return nil
 	}
 
-	status.TotalFiles = len(files)
+	status.TotalFiles += len(files)
 	uc.logSuccess("✓ Найдено файлов для обработки: %d", len(files))
 
 	// Создаем конфигурацию сжатия
 	compressionConfig := entities.NewCompressionConfigWithLicense(config.Compression.Level, config.Compression.UniPDFLicenseKey)
+	compressionConfig.StrictValidation = config.Compression.GetOutputValidation() == entities.OutputValidationStrict
+	compressionConfig.KeepMetadataKeys = config.Compression.Metadata.GetKeep()
+	compressionConfig.StampMetadata = config.Compression.Metadata.Stamp
+	compressionConfig.KeepAnnotations = config.Compression.GetKeepAnnotations()
+	compressionConfig.OptimizeForWeb = config.Compression.OptimizeForWeb
+	compressionConfig = compressionConfig.WithColorReduction(config.Compression.ColorReduction).WithCleanup(config.Compression.Cleanup)
+	compressionConfig.UniPDF = config.Compression.UniPDF
 
 	if err := uc.configRepo.ValidateConfig(compressionConfig); err != nil {
 		err = fmt.Errorf("ошибка валидации конфигурации сжатия: %w", err)
@@ -140,10 +201,13 @@ func (uc *ProcessPDFsUseCase) Execute(config *entities.Config) error {
 
 	var wg sync.WaitGroup
 
+	// Компрессор собирается для каждого запуска: алгоритм мог быть изменен в интерфейсе
+	compressor := uc.compressorFor(config)
+
 	// Запускаем воркеров
 	for w := 0; w < workers; w++ {
 		wg.Add(1)
-		go uc.worker(w, jobs, results, &wg, config, compressionConfig, status)
+		go uc.worker(w, jobs, results, &wg, compressor, config, compressionConfig, status)
 	}
 
 	// Отправляем задачи воркерам
@@ -162,6 +226,7 @@ func (uc *ProcessPDFsUseCase) Execute(config *entities.Config) error {
 	fileCounter := 0
 	for result := range results {
 		fileCounter++
+		result.FileType = entities.FileTypePDF
 		status.AddResult(result)
 
 		// Обновляем текущий файл
@@ -172,54 +237,79 @@ func (uc *ProcessPDFsUseCase) Execute(config *entities.Config) error {
 
 		// Логируем результат обработки файла
 		fileName := filepath.Base(result.CurrentFile)
-		if result.Success && result.Error == nil {
-			uc.logSuccess("[%d/%d] ✓ %s", fileCounter, status.TotalFiles, fileName)
-			uc.logInfo("    └─ Размер: %.2f MB → %.2f MB",
+		if result.Skipped && result.Error == nil {
+			if result.Encrypted {
+				uc.logWarning("[%d/%d] 🔐 %s", fileCounter, len(files), fileName)
+			} else {
+				uc.logWarning("[%d/%d] ⏭ %s", fileCounter, len(files), fileName)
+			}
+			uc.logWarning("    └─ Оставлен без изменений: %s", result.SkipReason)
+		} else if result.Success && result.Error == nil {
+			uc.logSuccess("[%d/%d] ✓ %s", fileCounter, len(files), fileName)
+			uc.logInfo("    └─ Размер: %.2f MB → %.2f MB | Страниц: %d",
 				float64(result.OriginalSize)/1024/1024,
-				float64(result.CompressedSize)/1024/1024)
+				float64(result.CompressedSize)/1024/1024,
+				result.Pages)
 			uc.logInfo("    └─ Сжатие: %.1f%% | Сэкономлено: %.2f MB",
 				result.CompressionRatio,
 				float64(result.SavedSpace)/1024/1024)
+			if result.Profile != "" {
+				uc.logInfo("    └─ Профиль: %s", result.Profile)
+			}
+			if result.TargetSize > 0 {
+				if result.TargetReached {
+					uc.logInfo("    └─ Целевой размер %.2f MB достигнут: уровень %d, попыток: %d",
+						float64(result.TargetSize)/1024/1024, result.Level, result.Attempts)
+				} else {
+					uc.logWarning("    └─ ⚠️  Целевой размер %.2f MB недостижим: минимум %.2f MB (уровень %d, попыток: %d)",
+						float64(result.TargetSize)/1024/1024, float64(result.CompressedSize)/1024/1024,
+						result.Level, result.Attempts)
+				}
+			}
+			if result.Strategy != "" {
+				uc.logInfo("    └─ Стратегия: %s", result.Strategy)
+			}
+			if result.RemovedAnnotations > 0 || result.RemovedAttachments > 0 {
+				uc.logInfo("    └─ Удалено аннотаций: %d, вложений: %d",
+					result.RemovedAnnotations, result.RemovedAttachments)
+			}
+			if result.RemovedThumbnails > 0 || result.RemovedScripts > 0 || result.RemovedDestinations > 0 ||
+				result.RemovedPieceInfo > 0 || result.RemovedRevisions > 0 {
+				uc.logInfo("    └─ Удалено миниатюр %d, сценариев %d, назначений %d, PieceInfo %d, ревизий %d",
+					result.RemovedThumbnails, result.RemovedScripts, result.RemovedDestinations,
+					result.RemovedPieceInfo, result.RemovedRevisions)
+			}
+			if result.FontSavings > 0 {
+				uc.logInfo("    └─ Шрифты: удалено %d, объединено %d, подмножеств %d (−%.2f MB)",
+					result.RemovedFonts, result.DeduplicatedFonts, result.SubsettedFonts,
+					float64(result.FontSavings)/1024/1024)
+			}
+			if result.GrayscaleImages > 0 || result.BitonalImages > 0 {
+				uc.logInfo("    └─ Цветность изображений: в оттенки серого %d, в 1 бит %d",
+					result.GrayscaleImages, result.BitonalImages)
+			}
+			if result.StreamSavings > 0 {
+				uc.logInfo("    └─ Пересжато потоков: %d (−%.2f MB)",
+					result.RecompressedStreams, float64(result.StreamSavings)/1024/1024)
+			}
+			if result.PDFA != "" {
+				uc.logInfo("    └─ Соответствие %s сохранено", result.PDFA)
+			}
+			if result.Linearized {
+				uc.logInfo("    └─ Линеаризован (быстрый веб-просмотр)")
+			}
+			switch result.SignatureOutcome {
+			case entities.SignatureInvalidated:
+				uc.logWarning("    └─ ⚠️  Подписи недействительны после сжатия (подписей: %d)", result.Signatures)
+			case entities.SignaturePreserved:
+				uc.logInfo("    └─ Подписанные ревизии сохранены (подписей: %d)", result.Signatures)
+			}
 		} else {
-			uc.logError("[%d/%d] ✗ %s", fileCounter, status.TotalFiles, fileName)
+			uc.logError("[%d/%d] ✗ %s", fileCounter, len(files), fileName)
 			uc.logError("    └─ Ошибка: %v", result.Error)
 		}
 	}
 
-	// Финальная фаза
-	status.Complete()
-	uc.reportProgress(status)
-
-	// Логируем итоговую статистику
-	uc.logInfo("")
-	uc.logInfo("╔════════════════════════════════════════════════════════════")
-	uc.logInfo("║ Обработка завершена")
-	uc.logInfo("╠════════════════════════════════════════════════════════════")
-	uc.logInfo("║ Время выполнения: %s", status.FormatElapsedTime())
-	uc.logInfo("╠════════════════════════════════════════════════════════════")
-	uc.logInfo("║ Статистика файлов:")
-	uc.logInfo("║   • Всего: %d", status.TotalFiles)
-	uc.logSuccess("║   • Успешно: %d", status.SuccessfulFiles)
-
-	if status.FailedFiles > 0 {
-		uc.logError("║   • Ошибок: %d", status.FailedFiles)
-	}
-
-	if status.SkippedFiles > 0 {
-		uc.logWarning("║   • Пропущено: %d", status.SkippedFiles)
-	}
-
-	if status.TotalOriginalSize > 0 {
-		uc.logInfo("╠════════════════════════════════════════════════════════════")
-		uc.logInfo("║ Статистика сжатия:")
-		uc.logInfo("║   • Исходный размер: %.2f MB", float64(status.TotalOriginalSize)/1024/1024)
-		uc.logInfo("║   • Сжатый размер: %.2f MB", float64(status.TotalCompressedSize)/1024/1024)
-		uc.logSuccess("║   • Среднее сжатие: %.1f%%", status.AverageCompression)
-		uc.logSuccess("║   • Сэкономлено: %.2f MB", float64(status.TotalSavedSpace)/1024/1024)
-	}
-
-	uc.logInfo("╚════════════════════════════════════════════════════════════")
-
 	return nil
 }
 
@@ -229,12 +319,14 @@ func (uc *ProcessPDFsUseCase) worker(
 	jobs <-chan string,
 	results chan<- *entities.CompressionResult,
 	wg *sync.WaitGroup,
+	compressor repositories.PDFCompressor,
 	config *entities.Config,
 	compressionConfig *entities.CompressionConfig,
 	status *entities.ProcessingStatus,
 ) {
 	defer wg.Done()
 
+	infoCache := newFileInfoCache(uc.fileRepo)
 	for inputFile := range jobs {
 		fileName := filepath.Base(inputFile)
 
@@ -265,7 +357,7 @@ func (uc *ProcessPDFsUseCase) worker(
 		}
 
 		// Получаем информацию о файле
-		fileInfo, err := uc.fileRepo.GetFileInfo(inputFile)
+		fileInfo, err := infoCache.get(inputFile)
 		if err != nil {
 			results <- &entities.CompressionResult{
 				CurrentFile: inputFile,
@@ -275,21 +367,115 @@ func (uc *ProcessPDFsUseCase) worker(
 			continue
 		}
 
-		// Выполняем сжатие с повторными попытками
+		// Настройки файла с учетом профиля
+		relPath, err := filepath.Rel(config.Scanner.SourceDirectory, inputFile)
+		if err != nil {
+			relPath = fileName
+		}
+		fileCompression, profile := config.Compression.ForFile(relPath)
+
+		// При политике incremental подписанные ревизии копируются без изменений: для этого
+		// нужны граница подписанной части и компрессор, сохраняющий ревизии
+		preserveSignatures := fileInfo.IsSigned() && fileCompression.GetSignaturePolicy() == entities.SignaturePolicyIncremental
+		if preserveSignatures && (fileInfo.SignedBytes == 0 || uc.revisionCompressor == nil) {
+			result := &entities.CompressionResult{
+				CurrentFile:      inputFile,
+				Pages:            fileInfo.Pages,
+				OriginalSize:     fileInfo.Size,
+				Success:          true,
+				Signatures:       fileInfo.Signatures,
+				SignatureOutcome: entities.SignatureSkipped,
+				Profile:          profile,
+			}
+			keepOriginalResult(result, inputFile, outputFile, config.Scanner.ReplaceOriginal, "подписанную часть документа нельзя сохранить без изменений")
+			results <- result
+			continue
+		}
+
+		// Подписанный документ при политике skip не сжимается: перезапись сломает подпись
+		if fileInfo.IsSigned() && fileCompression.GetSignaturePolicy() == entities.SignaturePolicySkip {
+			result := &entities.CompressionResult{
+				CurrentFile:      inputFile,
+				Pages:            fileInfo.Pages,
+				OriginalSize:     fileInfo.Size,
+				Success:          true,
+				Signatures:       fileInfo.Signatures,
+				SignatureOutcome: entities.SignatureSkipped,
+				Profile:          profile,
+			}
+			if err := keepOriginalFile(inputFile, outputFile, config.Scanner.ReplaceOriginal); err != nil {
+				result.Success = false
+				result.Error = fmt.Errorf("ошибка сохранения оригинального файла: %w", err)
+			} else {
+				result.Skip(fmt.Sprintf("документ подписан (подписей: %d)", fileInfo.Signatures))
+			}
+			results <- result
+			continue
+		}
+
+		fileConfig := compressionConfig
+		if fileCompression.Level != compressionConfig.Level {
+			fileConfig = compressionConfig.WithLevel(fileCompression.Level)
+		}
+		if fileCompression.ColorReduction != config.Compression.ColorReduction {
+			fileConfig = fileConfig.WithColorReduction(fileCompression.ColorReduction)
+		}
+		if fileCompression.UniPDF != config.Compression.UniPDF {
+			fileConfig = fileConfig.WithUniPDFOptions(fileCompression.UniPDF)
+		}
+		if fileCompression.Cleanup != config.Compression.Cleanup {
+			fileConfig = fileConfig.WithCleanup(fileCompression.Cleanup)
+		}
+
+		// В режиме PDF/A документ с заявленным соответствием сжимается без операций, нарушающих его
+		pdfaMode := fileCompression.PreservePDFA && fileInfo.IsPDFA()
+		if pdfaMode {
+			fileConfig = fileConfig.ForPDFA(fileInfo.PDFAPart)
+		}
+		fileConfig = fileConfig.WithWebOptimization(fileCompression.OptimizeForWeb).WithSource(fileInfo)
+
+		// Полная перезапись отбрасывает добавочные обновления. Если старые ревизии удалять
+		// нельзя, каждая ревизия сжимается отдельно. Для подписанных документов действует политика подписей
+		keepRevisions := preserveSignatures ||
+			(fileInfo.HasIncrementalUpdates() && !fileInfo.IsSigned() && !fileConfig.RemoveOldRevisions && uc.revisionCompressor != nil)
+
+		// Выполняем сжатие с повторными попытками; в режиме целевого размера подбираем уровень.
+		// Сжатие с сохранением ревизий не зависит от уровня, подбирать нечего
 		var result *entities.CompressionResult
-		for attempt := 0; attempt < config.Processing.RetryAttempts; attempt++ {
-			result, err = uc.compressor.Compress(inputFile, outputFile, compressionConfig)
+		if keepRevisions {
+			result, err = uc.compressWithRetry(uc.revisionCompressor, inputFile, outputFile, fileConfig, config.Processing.RetryAttempts)
+			// Ревизии неподписанного документа, которые нельзя разобрать по отдельности, сливаются полной перезаписью
+			if !preserveSignatures && errors.Is(err, entities.ErrRevisionsNotRewritable) {
+				keepRevisions = false
+				result, err = uc.compressWithRetry(compressor, inputFile, outputFile, fileConfig, config.Processing.RetryAttempts)
+			}
 			if err == nil {
-				break
+				result.Level = fileConfig.Level
 			}
+		} else if target := fileCompression.TargetSizeBytes(); target > 0 {
+			steps := fileConfig.TargetSizeSteps(config.Compression.UsesImageQuality())
+			result, err = uc.compressToTarget(compressor, inputFile, outputFile, fileInfo, steps, target, config.Processing.RetryAttempts)
+		} else {
+			result, err = uc.compressWithRetry(compressor, inputFile, outputFile, fileConfig, config.Processing.RetryAttempts)
+			if err == nil {
+				result.Level = fileConfig.Level
+			}
+		}
 
-			if attempt < config.Processing.RetryAttempts-1 {
-				if uc.logger != nil {
-					uc.logger.Warning("Попытка %d/%d для файла %s не удалась: %v",
-						attempt+1, config.Processing.RetryAttempts, fileName, err)
-				}
-				time.Sleep(time.Second * 2) // Пауза перед повторной попыткой
+		// Ревизии не удалось переписать по отдельности: подписи остаются в исходном файле
+		if preserveSignatures && errors.Is(err, entities.ErrRevisionsNotRewritable) {
+			result := &entities.CompressionResult{
+				CurrentFile:      inputFile,
+				Pages:            fileInfo.Pages,
+				OriginalSize:     fileInfo.Size,
+				Success:          true,
+				Signatures:       fileInfo.Signatures,
+				SignatureOutcome: entities.SignatureSkipped,
+				Profile:          profile,
 			}
+			keepOriginalResult(result, inputFile, outputFile, config.Scanner.ReplaceOriginal, err.Error())
+			results <- result
+			continue
 		}
 
 		if err != nil {
@@ -298,6 +484,7 @@ func (uc *ProcessPDFsUseCase) worker(
 				OriginalSize: fileInfo.Size,
 				Success:      false,
 				Error:        err,
+				Profile:      profile,
 			}
 			continue
 		}
@@ -305,8 +492,89 @@ func (uc *ProcessPDFsUseCase) worker(
 		// Устанавливаем исходный размер и пересчитываем статистику
 		result.CurrentFile = inputFile
 		result.OriginalSize = fileInfo.Size
+		result.Pages = fileInfo.Pages
+		result.Profile = profile
 		result.CalculateCompressionRatio()
 
+		// Компрессор сам отказался от файла (например, зашифрован без пароля)
+		if result.Skipped {
+			if err := keepOriginalFile(inputFile, outputFile, config.Scanner.ReplaceOriginal); err != nil {
+				result.Success = false
+				result.Error = fmt.Errorf("ошибка сохранения оригинального файла: %w", err)
+			} else {
+				result.Skip(result.SkipReason)
+			}
+			results <- result
+			continue
+		}
+
+		// Заново открываем результат и проверяем его до того, как он будет принят:
+		// при ошибке выходной файл удаляется, а оригинал остается нетронутым
+		outputInfo, err := uc.validateOutput(infoCache, fileInfo, result, outputFile, fileConfig.StrictValidation)
+		if err != nil {
+			result.Success = false
+			result.Error = err
+			_ = os.Remove(outputFile)
+			results <- result
+			continue
+		}
+
+		// Если соответствие PDF/A потеряно, возвращаемся к оригиналу
+		if pdfaMode {
+			result.PDFA = fileInfo.PDFAName()
+			if err := uc.verifyPDFA(fileInfo, outputInfo); err != nil {
+				if keepErr := keepOriginalFile(inputFile, outputFile, config.Scanner.ReplaceOriginal); keepErr != nil {
+					result.Success = false
+					result.Error = fmt.Errorf("ошибка сохранения оригинального файла: %w", keepErr)
+				} else {
+					result.Skip(err.Error())
+				}
+				results <- result
+				continue
+			}
+		}
+
+		// Компрессоры переписывают документ целиком, поэтому подписи становятся недействительными.
+		// При сохранении ревизий подписанная часть результата сверяется с исходной побайтно
+		if fileInfo.IsSigned() {
+			result.Signatures = fileInfo.Signatures
+			result.SignatureOutcome = entities.SignatureInvalidated
+		}
+		if preserveSignatures {
+			preserved, err := preservesSignedRevision(inputFile, outputFile, fileInfo.SignedBytes)
+			if err != nil || !preserved {
+				if err == nil {
+					err = fmt.Errorf("подписанная часть документа изменилась")
+				}
+				result.Success = false
+				result.Error = fmt.Errorf("ошибка проверки подписанной ревизии: %w", err)
+				_ = os.Remove(outputFile)
+				results <- result
+				continue
+			}
+			result.SignatureOutcome = entities.SignaturePreserved
+		}
+
+		// Полная перезапись отбрасывает старые ревизии
+		if fileInfo.HasIncrementalUpdates() && !keepRevisions {
+			result.RemovedRevisions = fileInfo.Revisions - 1
+		}
+
+		// Если экономия ниже порога, оставляем файл исходным
+		if ok, reason := fileCompression.CheckSavings(result.OriginalSize, result.CompressedSize); !ok {
+			if err := keepOriginalFile(inputFile, outputFile, config.Scanner.ReplaceOriginal); err != nil {
+				result.Success = false
+				result.Error = fmt.Errorf("ошибка сохранения оригинального файла: %w", err)
+			} else {
+				result.Skip(reason)
+				if result.Signatures > 0 {
+					result.SignatureOutcome = entities.SignatureSkipped
+				}
+			}
+			results <- result
+			continue
+		}
+
 		// Если заменяем оригинал, переименовываем временный файл
 		if config.Scanner.ReplaceOriginal {
 			if err := uc.replaceOriginalFile(inputFile, outputFile); err != nil {
@@ -330,7 +598,54 @@ func (uc *ProcessPDFsUseCase) worker(
 	}
 }
 
-// replaceOriginalFile заменяет оригинальный файл сжатым
+// validateOutput проверяет сжатый файл: структура по валидации pdfcpu, обход всех страниц
+// и совпадение количества страниц с исходным. Возвращает сведения о сжатом файле
+func (uc *ProcessPDFsUseCase) validateOutput(
+	infoCache *fileInfoCache,
+	input *entities.PDFDocument,
+	result *entities.CompressionResult,
+	outputPath string,
+	strict bool,
+) (*entities.PDFDocument, error) {
+	output, err := infoCache.get(outputPath)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения информации о сжатом файле: %w", err)
+	}
+
+	// Повторно зашифрованный результат компрессор проверил до шифрования
+	if !result.Validated {
+		if err := uc.fileRepo.ValidatePDF(outputPath, strict); err != nil {
+			return nil, fmt.Errorf("%w: %v", entities.ErrOutputValidationFailed, err)
+		}
+	}
+
+	if err := input.VerifyPageCount(output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// verifyPDFA проверяет, что сжатый документ сохранил заявление PDF/A и проходит валидацию pdfcpu.
+// Если исходный документ сам не проходит строгую валидацию, от результата требуется мягкая
+func (uc *ProcessPDFsUseCase) verifyPDFA(input, output *entities.PDFDocument) error {
+	if err := input.VerifyPDFA(output); err != nil {
+		return err
+	}
+
+	err := uc.fileRepo.ValidatePDF(output.Path, true)
+	if err != nil && uc.fileRepo.ValidatePDF(input.Path, true) != nil {
+		err = uc.fileRepo.ValidatePDF(output.Path, false)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", entities.ErrPDFAConformanceLost, err)
+	}
+
+	return nil
+}
+
+// replaceOriginalFile заменяет оригинальный файл сжатым.
+// Вызывается только для результата, прошедшего validateOutput
 func (uc *ProcessPDFsUseCase) replaceOriginalFile(originalFile, tempFile string) error {
 	// Проверяем существование временного файла
 	if _, err := os.Stat(tempFile); os.IsNotExist(err) {
@@ -376,6 +691,12 @@ func (uc *ProcessPDFsUseCase) replaceOriginalFile(originalFile, tempFile string)
 }
 
 // Методы для логирования
+func (uc *ProcessPDFsUseCase) logDebug(format string, args ...interface{}) {
+	if uc.logger != nil {
+		uc.logger.Debug(format, args...)
+	}
+}
+
 func (uc *ProcessPDFsUseCase) logInfo(format string, args ...interface{}) {
 	if uc.logger != nil {
 		uc.logger.Info(format, args...)
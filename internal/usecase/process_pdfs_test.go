@@ -0,0 +1,114 @@
+package usecases
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"compress/internal/domain/entities"
+)
+
+// revisionsFileRepo описывает каждый PDF как одностраничный документ с заданным числом ревизий
+type revisionsFileRepo struct {
+	revisions int
+}
+
+func (r *revisionsFileRepo) GetFileInfo(path string) (*entities.PDFDocument, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	return &entities.PDFDocument{Path: path, Size: info.Size(), ModifiedTime: info.ModTime(), Pages: 1, Revisions: r.revisions}, nil
+}
+
+func (r *revisionsFileRepo) FileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func (r *revisionsFileRepo) CreateDirectory(path string) error {
+	return os.MkdirAll(path, 0o755)
+}
+
+func (r *revisionsFileRepo) ListPDFFiles(directory string) ([]string, error) {
+	return filepath.Glob(filepath.Join(directory, "*.pdf"))
+}
+
+func (r *revisionsFileRepo) ValidatePDF(string, bool) error {
+	return nil
+}
+
+// acceptingConfigRepo принимает любую конфигурацию сжатия
+type acceptingConfigRepo struct{}
+
+func (acceptingConfigRepo) GetCompressionConfig(level int) (*entities.CompressionConfig, error) {
+	return entities.NewCompressionConfig(level), nil
+}
+
+func (acceptingConfigRepo) ValidateConfig(*entities.CompressionConfig) error {
+	return nil
+}
+
+// namedCompressor записывает результат вдвое меньше исходного и отмечает свой вызов
+type namedCompressor struct {
+	name  string
+	calls *[]string
+}
+
+func (c namedCompressor) Compress(inputPath, outputPath string, config *entities.CompressionConfig) (*entities.CompressionResult, error) {
+	*c.calls = append(*c.calls, c.name)
+	info, err := os.Stat(inputPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(outputPath, make([]byte, info.Size()/2), 0o644); err != nil {
+		return nil, err
+	}
+	return &entities.CompressionResult{CompressedSize: info.Size() / 2, Success: true}, nil
+}
+
+func TestProcessIncrementalUpdates(t *testing.T) {
+	tests := []struct {
+		name             string
+		level            int
+		compressor       string
+		removedRevisions int
+	}{
+		{"Low level keeps revisions", 15, "revisions", 0},
+		{"Higher level drops old revisions", 50, "full", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := t.TempDir()
+			if err := os.WriteFile(filepath.Join(source, "updated.pdf"), make([]byte, 1000), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			var calls []string
+			uc := NewProcessPDFsUseCase(namedCompressor{"full", &calls}, &revisionsFileRepo{revisions: 3}, acceptingConfigRepo{}, nil)
+			uc.SetRevisionCompressor(namedCompressor{"revisions", &calls})
+
+			config := &entities.Config{
+				Scanner:     entities.ScannerConfig{SourceDirectory: source, TargetDirectory: t.TempDir()},
+				Compression: entities.AppCompressionConfig{Level: tt.level, Algorithm: "pdfcpu"},
+				Processing:  entities.ProcessingConfig{ParallelWorkers: 1, RetryAttempts: 1},
+			}
+			status := entities.NewProcessingStatus(0)
+			if err := uc.ExecuteWithStatus(config, status); err != nil {
+				t.Fatal(err)
+			}
+
+			result := status.LastResult
+			if result == nil || !result.Success || result.Skipped {
+				t.Fatalf("Expected the file to be compressed, got %+v", result)
+			}
+			if len(calls) != 1 || calls[0] != tt.compressor {
+				t.Errorf("Expected the %s compressor, got calls %v", tt.compressor, calls)
+			}
+			if result.RemovedRevisions != tt.removedRevisions {
+				t.Errorf("Expected %d removed revisions, got %d", tt.removedRevisions, result.RemovedRevisions)
+			}
+		})
+	}
+}
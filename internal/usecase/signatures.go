@@ -0,0 +1,56 @@
+package usecases
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+)
+
+// signatureCompareChunk размер блока при сравнении подписанной части файлов
+const signatureCompareChunk = 64 * 1024
+
+// preservesSignedRevision проверяет, что первые signedBytes байт выходного файла
+// совпадают с исходным, то есть подписанная ревизия осталась нетронутой
+// и изменения дописаны инкрементальным обновлением
+func preservesSignedRevision(inputPath, outputPath string, signedBytes int64) (bool, error) {
+	if signedBytes <= 0 {
+		return false, nil
+	}
+
+	input, err := os.Open(inputPath)
+	if err != nil {
+		return false, fmt.Errorf("не удалось открыть файл %s: %w", inputPath, err)
+	}
+	defer input.Close()
+
+	output, err := os.Open(outputPath)
+	if err != nil {
+		return false, fmt.Errorf("не удалось открыть файл %s: %w", outputPath, err)
+	}
+	defer output.Close()
+
+	inputBuf := make([]byte, signatureCompareChunk)
+	outputBuf := make([]byte, signatureCompareChunk)
+	for remaining := signedBytes; remaining > 0; {
+		n := int64(len(inputBuf))
+		if remaining < n {
+			n = remaining
+		}
+
+		if _, err := io.ReadFull(input, inputBuf[:n]); err != nil {
+			return false, fmt.Errorf("ошибка чтения файла %s: %w", inputPath, err)
+		}
+		if _, err := io.ReadFull(output, outputBuf[:n]); err != nil {
+			// Выходной файл короче подписанной части
+			return false, nil
+		}
+		if !bytes.Equal(inputBuf[:n], outputBuf[:n]) {
+			return false, nil
+		}
+
+		remaining -= n
+	}
+
+	return true, nil
+}
@@ -0,0 +1,65 @@
+package usecases
+
+import (
+	"compress/internal/domain/entities"
+	"compress/internal/domain/repositories"
+)
+
+// logProcessingSummary записывает в журнал итоговую статистику обработки и, если обработаны
+// файлы нескольких типов, статистику по каждому типу
+func logProcessingSummary(logger repositories.Logger, status *entities.ProcessingStatus) {
+	if logger == nil {
+		return
+	}
+
+	logger.Info("")
+	logger.Info("╔════════════════════════════════════════════════════════════")
+	logger.Info("║ Обработка завершена")
+	logger.Info("╠════════════════════════════════════════════════════════════")
+	logger.Info("║ Время выполнения: %s", status.FormatElapsedTime())
+	logger.Info("╠════════════════════════════════════════════════════════════")
+	logger.Info("║ Статистика файлов:")
+	logger.Info("║   • Всего: %d", status.TotalFiles)
+	logger.Success("║   • Успешно: %d", status.SuccessfulFiles)
+
+	if status.FailedFiles > 0 {
+		logger.Error("║   • Ошибок: %d", status.FailedFiles)
+	}
+
+	if status.SkippedFiles > 0 {
+		logger.Warning("║   • Пропущено: %d", status.SkippedFiles)
+	}
+
+	if status.SkippedEncryptedFiles > 0 {
+		logger.Warning("║   • Из них зашифрованных без пароля: %d", status.SkippedEncryptedFiles)
+	}
+
+	if status.TargetMissedFiles > 0 {
+		logger.Warning("║   • Не уложились в целевой размер: %d", status.TargetMissedFiles)
+	}
+
+	if status.TotalOriginalSize > 0 {
+		logger.Info("╠════════════════════════════════════════════════════════════")
+		logger.Info("║ Статистика сжатия:")
+		logger.Info("║   • Исходный размер: %.2f MB", float64(status.TotalOriginalSize)/1024/1024)
+		logger.Info("║   • Сжатый размер: %.2f MB", float64(status.TotalCompressedSize)/1024/1024)
+		logger.Success("║   • Среднее сжатие: %.1f%%", status.AverageCompression)
+		logger.Success("║   • Сэкономлено: %.2f MB", float64(status.TotalSavedSpace)/1024/1024)
+	}
+
+	if len(status.TypeStats) > 1 {
+		logger.Info("╠════════════════════════════════════════════════════════════")
+		logger.Info("║ По типам файлов:")
+		for _, stats := range status.TypeStats {
+			logger.Info("║   • %s: файлов %d (успешно %d, пропущено %d, ошибок %d)",
+				stats.Type, stats.Files, stats.SuccessfulFiles, stats.SkippedFiles, stats.FailedFiles)
+			if stats.OriginalSize > 0 {
+				logger.Info("║     %.2f MB → %.2f MB, сжатие %.1f%%, сэкономлено %.2f MB",
+					float64(stats.OriginalSize)/1024/1024, float64(stats.CompressedSize)/1024/1024,
+					stats.Compression(), float64(stats.SavedSpace)/1024/1024)
+			}
+		}
+	}
+
+	logger.Info("╚════════════════════════════════════════════════════════════")
+}
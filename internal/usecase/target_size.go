@@ -0,0 +1,115 @@
+package usecases
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"compress/internal/domain/entities"
+	"compress/internal/domain/repositories"
+)
+
+// compressWithRetry сжимает файл, повторяя попытку при ошибке
+func (uc *ProcessPDFsUseCase) compressWithRetry(
+	compressor repositories.PDFCompressor,
+	inputFile, outputFile string,
+	compressionConfig *entities.CompressionConfig,
+	retryAttempts int,
+) (*entities.CompressionResult, error) {
+	if retryAttempts < 1 {
+		retryAttempts = 1
+	}
+
+	var (
+		result *entities.CompressionResult
+		err    error
+	)
+	for attempt := 0; attempt < retryAttempts; attempt++ {
+		result, err = compressor.Compress(inputFile, outputFile, compressionConfig)
+		if err == nil {
+			break
+		}
+
+		if attempt < retryAttempts-1 {
+			uc.logWarning("Попытка %d/%d для файла %s не удалась: %v",
+				attempt+1, retryAttempts, filepath.Base(inputFile), err)
+			time.Sleep(time.Second * 2) // Пауза перед повторной попыткой
+		}
+	}
+
+	return result, err
+}
+
+// compressToTarget подбирает двоичным поиском самую мягкую из настроек steps (упорядочены
+// от мягких к агрессивным, см. TargetSizeSteps), при которой файл укладывается в целевой размер. Если цель недостижима, в outputFile
+// остается наименьший полученный результат с TargetReached = false
+func (uc *ProcessPDFsUseCase) compressToTarget(
+	compressor repositories.PDFCompressor,
+	inputFile, outputFile string,
+	fileInfo *entities.PDFDocument,
+	steps []*entities.CompressionConfig,
+	target int64,
+	retryAttempts int,
+) (*entities.CompressionResult, error) {
+	targetMB := float64(target) / 1024 / 1024
+
+	// Файл уже укладывается в цель: самая мягкая настройка — не трогать его
+	if fileInfo.Size <= target {
+		result := &entities.CompressionResult{
+			OriginalSize:  fileInfo.Size,
+			Success:       true,
+			TargetSize:    target,
+			TargetReached: true,
+		}
+		result.Skip(fmt.Sprintf("файл уже меньше целевого размера %.2f MB", targetMB))
+		return result, nil
+	}
+
+	attemptFile := outputFile + ".attempt"
+	defer os.Remove(attemptFile)
+
+	var best *entities.CompressionResult
+	attempts := 0
+	for low, high := 0, len(steps)-1; low <= high; {
+		mid := (low + high) / 2
+		step := steps[mid]
+
+		result, err := uc.compressWithRetry(compressor, inputFile, attemptFile, step, retryAttempts)
+		attempts++
+		if err != nil {
+			return result, err
+		}
+		if result.Skipped {
+			result.Attempts = attempts
+			return result, nil
+		}
+
+		fits := result.CompressedSize <= target
+		uc.logDebug("🎯 %s: уровень %d, качество %d%% → %.2f MB (цель %.2f MB)",
+			filepath.Base(inputFile), step.Level, step.ImageQuality,
+			float64(result.CompressedSize)/1024/1024, targetMB)
+
+		// Подходящий результат всегда мягче предыдущего подходящего; неподходящий
+		// сохраняется только как запасной, пока ни одна настройка не подошла
+		keep := fits || best == nil || (!best.TargetReached && result.CompressedSize < best.CompressedSize)
+		if keep {
+			if err := os.Rename(attemptFile, outputFile); err != nil {
+				return nil, fmt.Errorf("ошибка сохранения результата подбора: %w", err)
+			}
+			result.Level = step.Level
+			result.TargetReached = fits
+			best = result
+		}
+
+		if fits {
+			high = mid - 1
+		} else {
+			low = mid + 1
+		}
+	}
+
+	best.TargetSize = target
+	best.Attempts = attempts
+	return best, nil
+}
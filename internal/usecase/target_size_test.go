@@ -0,0 +1,97 @@
+package usecases
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"compress/internal/domain/entities"
+)
+
+// sizeByStepCompressor записывает результат, размер которого зависит от уровня и качества
+type sizeByStepCompressor struct {
+	sizes map[int]int64 // Размер результата по уровню
+	calls []int
+}
+
+func (c *sizeByStepCompressor) Compress(inputPath, outputPath string, config *entities.CompressionConfig) (*entities.CompressionResult, error) {
+	size := c.sizes[config.Level]
+	if config.Level == 90 {
+		// Шаги качества после уровня 90 уменьшают результат
+		size -= int64(25-config.ImageQuality) * 10
+	}
+	c.calls = append(c.calls, config.Level)
+	if err := os.WriteFile(outputPath, make([]byte, size), 0o644); err != nil {
+		return nil, err
+	}
+	return &entities.CompressionResult{CompressedSize: size, Success: true}, nil
+}
+
+func TestCompressToTarget(t *testing.T) {
+	sizes := map[int]int64{10: 900, 20: 800, 30: 700, 40: 600, 50: 500, 60: 400, 70: 300, 80: 250, 90: 200}
+	steps := entities.NewCompressionConfig(50).TargetSizeSteps(true)
+
+	tests := []struct {
+		name          string
+		target        int64
+		steps         []*entities.CompressionConfig
+		expectedLevel int
+		expectedSize  int64
+		reached       bool
+	}{
+		{"Least aggressive level that fits", 450, steps, 60, 400, true},
+		{"Exact fit", 700, steps, 30, 700, true},
+		{"Quality step after maximum level", 105, steps, 90, 100, true},
+		{"Unreachable target keeps the smallest result", 10, steps, 90, 50, false},
+		{"Unreachable without quality steps", 105, steps[:9], 90, 200, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			input := filepath.Join(dir, "in.pdf")
+			output := filepath.Join(dir, "out.pdf")
+			compressor := &sizeByStepCompressor{sizes: sizes}
+			uc := &ProcessPDFsUseCase{}
+
+			result, err := uc.compressToTarget(compressor, input, output, &entities.PDFDocument{Size: 1000}, tt.steps, tt.target, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if result.Level != tt.expectedLevel || result.CompressedSize != tt.expectedSize || result.TargetReached != tt.reached {
+				t.Errorf("Expected level %d, %d bytes, reached %v; got level %d, %d bytes, reached %v",
+					tt.expectedLevel, tt.expectedSize, tt.reached, result.Level, result.CompressedSize, result.TargetReached)
+			}
+			if info, err := os.Stat(output); err != nil || info.Size() != tt.expectedSize {
+				t.Errorf("Output file does not hold the selected result: %v", err)
+			}
+
+			// Двоичный поиск: не больше ⌈log2(n+1)⌉ попыток
+			maxAttempts := 0
+			for n := len(tt.steps) + 1; n > 1; n = (n + 1) / 2 {
+				maxAttempts++
+			}
+			if result.Attempts != len(compressor.calls) || result.Attempts > maxAttempts {
+				t.Errorf("Expected at most %d attempts, got %d (calls %v)", maxAttempts, result.Attempts, compressor.calls)
+			}
+			if _, err := os.Stat(output + ".attempt"); !os.IsNotExist(err) {
+				t.Error("Attempt file is not removed")
+			}
+		})
+	}
+}
+
+func TestCompressToTargetAlreadySmaller(t *testing.T) {
+	compressor := &sizeByStepCompressor{}
+	uc := &ProcessPDFsUseCase{}
+
+	result, err := uc.compressToTarget(compressor, "in.pdf", "out.pdf", &entities.PDFDocument{Size: 100},
+		entities.NewCompressionConfig(50).TargetSizeSteps(true), 200, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Skipped || !result.TargetReached || len(compressor.calls) != 0 {
+		t.Errorf("Expected skipped result without compression, got skipped %v, calls %v", result.Skipped, compressor.calls)
+	}
+}